@@ -0,0 +1,247 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AddAll 批量添加结点，只加锁和排序一次。要么全部添加成功，要么环保持不变。
+// 实现了 WeightedNode 的结点副本数为 virtualNodeCount 乘以 Weight()
+func (c *ConsistentHash) AddAll(nodes []Node, virtualNodeCount int) error {
+	if err := c.checkReplicas(virtualNodeCount); err != nil {
+		return err
+	}
+	batch := make(map[string]struct{}, len(nodes))
+	counts := make([]int, len(nodes))
+	points := 0
+	for i, node := range nodes {
+		if err := checkNode(node); err != nil {
+			return err
+		}
+		if _, ok := batch[node.Key()]; ok {
+			return newNodeError(node.Key(), ErrNodeExists)
+		}
+		batch[node.Key()] = struct{}{}
+		count, err := c.replicasFor(node, virtualNodeCount)
+		if err != nil {
+			return err
+		}
+		counts[i] = count
+		points += count
+	}
+
+	return c.update(len(nodes), points*c.pointsPerReplica(), func(staged *ConsistentHash) error {
+		return staged.addAll(nodes, counts)
+	})
+}
+
+// counts 为每个结点的副本数，调用方需持有写锁并已校验参数
+func (c *ConsistentHash) addAll(nodes []Node, counts []int) error {
+	for _, node := range nodes {
+		if _, ok := c.nodes[node.Key()]; ok {
+			return newNodeError(node.Key(), ErrNodeExists)
+		}
+	}
+
+	if c.sharesPoints() {
+		// 冲突不会失败，逐个添加即可
+		for i, node := range nodes {
+			virtualNodes, _ := c.addSharedPoints(node.Key(), 0, counts[i])
+			c.nodes[node.Key()] = newConsistentNode(node, virtualNodes)
+			c.recordAdded(node)
+		}
+		return nil
+	}
+
+	points := 0
+	for _, count := range counts {
+		points += count
+	}
+	staged := make(map[uint32]string, points*c.pointsPerReplica())
+	virtualNodes := make([][]uint32, len(nodes))
+	for i, node := range nodes {
+		v, err := c.stageVirtualNodes(node.Key(), 0, counts[i], staged)
+		if err != nil {
+			return err
+		}
+		virtualNodes[i] = v
+	}
+
+	c.commitVirtualNodes(staged)
+	for i, node := range nodes {
+		c.nodes[node.Key()] = newConsistentNode(node, virtualNodes[i])
+		c.recordAdded(node)
+	}
+	return nil
+}
+
+// RemoveAll 批量删除结点，只重建一次 hashSortedNodes。不存在的结点不影响其他结点的删除，
+// 它们的 key 会在错误中列出，错误满足 errors.Is(err, ErrNodeNotFound)。
+func (c *ConsistentHash) RemoveAll(keys []string) error {
+	var missing []string
+	c.removeBatch(func(nodes map[string]consistentNode) []string {
+		found := make([]string, 0, len(keys))
+		seen := make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if _, ok := nodes[k]; !ok {
+				missing = append(missing, k)
+				continue
+			}
+			found = append(found, k)
+		}
+		return found
+	})
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// RemoveWhere 删除 pred 返回 true 的结点并返回它们。pred 调用期间其他写操作会等待，pred 中不能再修改环。
+func (c *ConsistentHash) RemoveWhere(pred func(Node) bool) []Node {
+	return c.removeBatch(func(nodes map[string]consistentNode) []string {
+		var keys []string
+		for k, n := range nodes {
+			if pred(n.node) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		return keys
+	})
+}
+
+// 删除 pick 选出的结点，pick 执行期间只持有 writeMu，可以在其中查询环。开启 WithLazyRemoval 时原地删除
+func (c *ConsistentHash) removeBatch(pick func(nodes map[string]consistentNode) []string) []Node {
+	if c.maxTombstones == 0 {
+		var removed []Node
+		c.update(0, 0, func(staged *ConsistentHash) error {
+			removed = staged.removeNodes(pick(staged.nodes))
+			return nil
+		})
+		return removed
+	}
+	c.writeMu.Lock()
+	c.Lock()
+	c.lazyInit()
+	c.Unlock()
+	// 其他写操作都需要 writeMu，持有它时 nodes 不会变化
+	keys := pick(c.nodes)
+	c.Lock()
+	defer c.unlock()
+	return c.removeNodesLazy(keys)
+}
+
+// 删除一组已存在的结点，一次遍历重建 hashSortedNodes，调用方需持有写锁
+func (c *ConsistentHash) removeNodes(keys []string) []Node {
+	if len(keys) == 0 {
+		return nil
+	}
+	removed := make([]Node, 0, len(keys))
+	for _, k := range keys {
+		cNode := c.nodes[k]
+		for _, v := range c.releasePoints(k, cNode.virtualNodes) {
+			delete(c.circle, v)
+		}
+		delete(c.nodes, k)
+		c.recordRemoved(cNode.node)
+		removed = append(removed, cNode.node)
+	}
+
+	// circle 中已经没有被删除的虚拟结点，据此原地过滤
+	sorted := c.hashSortedNodes[:0]
+	for _, v := range c.hashSortedNodes {
+		if _, ok := c.circle[v]; ok {
+			sorted = append(sorted, v)
+		}
+	}
+	c.hashSortedNodes = sorted
+	return removed
+}
+
+// Batch 把多个 Add/Remove 合并为一次原子变更，读者只会看到变更前或变更后的环
+type Batch struct {
+	c   *ConsistentHash
+	ops []batchOp
+}
+
+type batchOp struct {
+	remove   bool
+	node     Node
+	replicas int
+	key      string
+}
+
+// BatchError 记录 Commit 中第一个失败的操作
+type BatchError struct {
+	// 操作在 Batch 中的序号，从 0 开始
+	Index int
+	Op    string
+	Key   string
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch op %d (%s %s): %v", e.Index, e.Op, e.Key, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+func (c *ConsistentHash) Batch() *Batch {
+	return &Batch{c: c}
+}
+
+func (b *Batch) Add(node Node, replicas int) *Batch {
+	b.ops = append(b.ops, batchOp{node: node, replicas: replicas})
+	return b
+}
+
+func (b *Batch) Remove(key string) *Batch {
+	b.ops = append(b.ops, batchOp{remove: true, key: key})
+	return b
+}
+
+// Commit 在环的副本上依次执行所有操作，全部成功后在同一个写锁内替换环的状态；
+// 任一操作失败时环保持不变，返回 *BatchError
+func (b *Batch) Commit() error {
+	var nodes, points int
+	for _, op := range b.ops {
+		// 非法的副本数在 apply 中报错，这里不据此分配内存
+		if !op.remove && b.c.checkReplicas(op.replicas) == nil {
+			nodes++
+			points += op.replicas
+		}
+	}
+	return b.c.update(nodes, points*b.c.pointsPerReplica(), b.apply)
+}
+
+func (b *Batch) apply(staged *ConsistentHash) error {
+	for i, op := range b.ops {
+		if op.remove {
+			if err := staged.unlinkNode(op.key); err != nil {
+				return &BatchError{Index: i, Op: "remove", Key: op.key, Err: err}
+			}
+			continue
+		}
+
+		if err := checkNode(op.node); err != nil {
+			return &BatchError{Index: i, Op: "add", Err: err}
+		}
+		err := staged.checkReplicas(op.replicas)
+		if err == nil {
+			err = staged.addNode(op.node, op.replicas)
+		}
+		if err != nil {
+			return &BatchError{Index: i, Op: "add", Key: op.node.Key(), Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package consistent_hash
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func batchTestNodes(prefix string, n int) []Node {
+	nodes := make([]Node, n)
+	for i := range nodes {
+		nodes[i] = testNode{prefix + strconv.Itoa(i)}
+	}
+	return nodes
+}
+
+func TestConsistentHash_AddAll(t *testing.T) {
+	nodes := batchTestNodes("node-", 50)
+	all := NewConsistentHash()
+	if err := all.AddAll(nodes, 20); err != nil {
+		t.Fatal(err)
+	}
+	one := NewConsistentHash()
+	for _, n := range nodes {
+		if err := one.AddWithVirtualNode(n, 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !reflect.DeepEqual(all.hashSortedNodes, one.hashSortedNodes) || !reflect.DeepEqual(all.circle, one.circle) {
+		t.Fatal("AddAll and looped Add build different rings")
+	}
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		a, _ := all.GetNode(key)
+		b, _ := one.GetNode(key)
+		if a.Key() != b.Key() {
+			t.Fatalf("GetNode(%s) = %s, want %s", key, a.Key(), b.Key())
+		}
+	}
+}
+
+func TestConsistentHash_AddAllIsAtomic(t *testing.T) {
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 10, "b#0#0": 20, "c#0#0": 30,
+	}))
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	circle := map[uint32]string{10: "a"}
+	sorted := []uint32{10}
+
+	tests := []struct {
+		name     string
+		nodes    []Node
+		sentinel error
+	}{
+		{"nil node", []Node{testNode{"b"}, nil}, ErrNilNode},
+		{"empty key", []Node{testNode{"b"}, testNode{""}}, ErrEmptyKey},
+		{"duplicate in batch", []Node{testNode{"b"}, testNode{"c"}, testNode{"b"}}, ErrNodeExists},
+		{"existing node", []Node{testNode{"b"}, testNode{"a"}}, ErrNodeExists},
+	}
+	for _, tt := range tests {
+		if err := c.AddAll(tt.nodes, 1); !errors.Is(err, tt.sentinel) {
+			t.Errorf("%s: AddAll = %v, want %v", tt.name, err, tt.sentinel)
+		}
+		if !reflect.DeepEqual(c.circle, circle) || !reflect.DeepEqual(c.hashSortedNodes, sorted) || c.Len() != 1 {
+			t.Fatalf("%s: ring changed: %v %v", tt.name, c.circle, c.hashSortedNodes)
+		}
+	}
+	if err := c.AddAll([]Node{testNode{"b"}}, 0); !errors.Is(err, ErrInvalidReplicas) {
+		t.Errorf("AddAll with 0 replicas = %v", err)
+	}
+
+	if err := c.AddAll([]Node{testNode{"b"}, testNode{"c"}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(c.Members()); !equalStrings(got, []string{"a", "b", "c"}) {
+		t.Errorf("Members = %v", got)
+	}
+}
+
+func BenchmarkConsistentHash_AddAll(b *testing.B) {
+	nodes := batchTestNodes("node-", 1000)
+	for i := 0; i < b.N; i++ {
+		c := NewConsistentHash()
+		if err := c.AddAll(nodes, 100); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConsistentHash_AddLoop(b *testing.B) {
+	nodes := batchTestNodes("node-", 1000)
+	for i := 0; i < b.N; i++ {
+		c := NewConsistentHash()
+		for _, n := range nodes {
+			if err := c.AddWithVirtualNode(n, 100); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestConsistentHash_RemoveAll(t *testing.T) {
+	build := func(nodes []Node) *ConsistentHash {
+		c := NewConsistentHash()
+		if err := c.AddAll(nodes, 20); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	nodes := batchTestNodes("node-", 10)
+	c := build(nodes)
+	c.Inc("node-1")
+
+	err := c.RemoveAll([]string{"node-1", "node-3", "node-1", "x", "node-5", "y"})
+	if !errors.Is(err, ErrNodeNotFound) || err.Error() != "node not exist: x, y" {
+		t.Fatalf("RemoveAll = %v", err)
+	}
+	var survivors []Node
+	for _, n := range nodes {
+		switch n.Key() {
+		case "node-1", "node-3", "node-5":
+		default:
+			survivors = append(survivors, n)
+		}
+	}
+	want := build(survivors)
+	if !reflect.DeepEqual(c.hashSortedNodes, want.hashSortedNodes) || !reflect.DeepEqual(c.circle, want.circle) {
+		t.Fatal("ring after RemoveAll differs from a ring built from the survivors")
+	}
+	if c.Len() != 7 || c.totalLoad() != 0 {
+		t.Errorf("Len = %d, totalLoad = %d", c.Len(), c.totalLoad())
+	}
+	if err := c.RemoveAll(nil); err != nil {
+		t.Errorf("RemoveAll(nil) = %v", err)
+	}
+}
+
+func TestConsistentHash_RemoveWhere(t *testing.T) {
+	nodes := batchTestNodes("node-", 10)
+	c := NewConsistentHash()
+	if err := c.AddAll(nodes, 20); err != nil {
+		t.Fatal(err)
+	}
+	before := append([]uint32(nil), c.hashSortedNodes...)
+
+	if removed := c.RemoveWhere(func(Node) bool { return false }); len(removed) != 0 {
+		t.Fatalf("RemoveWhere removed %v", nodeKeys(removed))
+	}
+	if !reflect.DeepEqual(c.hashSortedNodes, before) {
+		t.Fatal("RemoveWhere matching nothing changed the ring")
+	}
+
+	removed := c.RemoveWhere(func(n Node) bool {
+		i, _ := strconv.Atoi(n.Key()[len("node-"):])
+		return i%2 == 0
+	})
+	if len(removed) != 5 {
+		t.Fatalf("RemoveWhere removed %v", nodeKeys(removed))
+	}
+	want := NewConsistentHash()
+	for i := 1; i < 10; i += 2 {
+		if err := want.AddWithVirtualNode(nodes[i], 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(c.hashSortedNodes, want.hashSortedNodes) || !reflect.DeepEqual(c.circle, want.circle) {
+		t.Fatal("ring after RemoveWhere differs from a ring built from the survivors")
+	}
+}
+
+func TestBatch_Commit(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("old-", 4), 50); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.Batch().
+		Remove("old-0").
+		Remove("old-1").
+		Remove("old-3").
+		Add(testNode{"new-0"}, 50).
+		Add(testNode{"new-1"}, 50).
+		Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(c.Members()); !equalStrings(got, []string{"new-0", "new-1", "old-2"}) {
+		t.Fatalf("Members = %v", got)
+	}
+
+	want := NewConsistentHash()
+	if err := want.AddAll([]Node{testNode{"old-2"}, testNode{"new-0"}, testNode{"new-1"}}, 50); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.hashSortedNodes, want.hashSortedNodes) || !reflect.DeepEqual(c.circle, want.circle) {
+		t.Fatal("ring after Commit differs from a ring built directly")
+	}
+}
+
+func TestBatch_CommitFailure(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("old-", 3), 50); err != nil {
+		t.Fatal(err)
+	}
+	sorted := append([]uint32(nil), c.hashSortedNodes...)
+
+	tests := []struct {
+		name     string
+		batch    *Batch
+		index    int
+		op       string
+		sentinel error
+	}{
+		{"unknown remove", c.Batch().Remove("old-0").Remove("x"), 1, "remove", ErrNodeNotFound},
+		{"duplicate add", c.Batch().Remove("old-0").Add(testNode{"new"}, 10).Add(testNode{"new"}, 10), 2, "add", ErrNodeExists},
+		{"remove then remove", c.Batch().Remove("old-0").Remove("old-0"), 1, "remove", ErrNodeNotFound},
+		{"nil node", c.Batch().Add(nil, 10), 0, "add", ErrNilNode},
+		{"zero replicas", c.Batch().Add(testNode{"new"}, 0), 0, "add", ErrInvalidReplicas},
+	}
+	for _, tt := range tests {
+		err := tt.batch.Commit()
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) || batchErr.Index != tt.index || batchErr.Op != tt.op || !errors.Is(err, tt.sentinel) {
+			t.Errorf("%s: Commit = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(c.hashSortedNodes, sorted) || c.Len() != 3 {
+			t.Fatalf("%s: failed Commit changed the ring", tt.name)
+		}
+	}
+
+	// 先删后加同一个结点是合法的
+	if err := c.Batch().Remove("old-0").Add(testNode{"old-0"}, 50).Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.hashSortedNodes, sorted) {
+		t.Fatal("re-adding a node changed the ring")
+	}
+}
+
+func TestBatch_ConcurrentReader(t *testing.T) {
+	c := NewConsistentHash()
+	before := []string{"old-0", "old-1", "old-2"}
+	after := []string{"new-0", "new-1", "old-2"}
+	if err := c.AddAll(batchTestNodes("old-", 3), 50); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		for {
+			select {
+			case <-done:
+				result <- nil
+				return
+			default:
+			}
+			got := nodeKeys(c.Members())
+			if !equalStrings(got, before) && !equalStrings(got, after) {
+				result <- errors.New("reader saw intermediate ring " + strings.Join(got, ","))
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		var err error
+		if i%2 == 0 {
+			err = c.Batch().Remove("old-0").Remove("old-1").Add(testNode{"new-0"}, 50).Add(testNode{"new-1"}, 50).Commit()
+		} else {
+			err = c.Batch().Remove("new-0").Remove("new-1").Add(testNode{"old-0"}, 50).Add(testNode{"old-1"}, 50).Commit()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 200 个结点先各添加 100 个副本，再原地增加到 200 个。有容量提示时 AddAll 的副本按提示分配，
+// 之后的 GrowVirtualNodes 不需要扩容
+func BenchmarkConsistentHash_BuildLargeRing(b *testing.B) {
+	nodes := batchTestNodes("node-", 200)
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{"NoHint", nil},
+		{"ExpectedNodes", []Option{WithExpectedNodes(len(nodes), 200)}},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c, _ := New(tt.opts...)
+				if err := c.AddAll(nodes, 100); err != nil {
+					b.Fatal(err)
+				}
+				for _, n := range nodes {
+					if err := c.GrowVirtualNodes(n.Key(), 100); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
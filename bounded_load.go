@@ -0,0 +1,97 @@
+package consistent_hash
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// 有界负载一致性哈希 (Consistent Hashing with Bounded Loads)
+// https://arxiv.org/abs/1608.01350
+
+const defaultLoadFactor = 1.25
+
+// SetLoadFactor 设置负载上限系数，结点负载不能超过平均负载乘以该系数
+func (c *ConsistentHash) SetLoadFactor(factor float64) error {
+	c.lockWrite()
+	defer c.unlock()
+	return WithLoadFactor(factor)(c)
+}
+
+// GetLeast 从 key 所在位置顺时针找到第一个负载未超过上限的在线结点，
+// 所有结点都达到上限时退回 GetNode 的结果
+func (c *ConsistentHash) GetLeast(key string) (Node, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.nodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+	candidates, err := c.walkKey(key, len(c.nodes))
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoHealthyNodes
+	}
+	limit := c.maxLoad()
+	for _, n := range candidates {
+		if c.load(n.Key())+1 <= limit {
+			return n, nil
+		}
+	}
+	return candidates[0], nil
+}
+
+// 结点允许的最大负载，调用方需持有锁
+func (c *ConsistentHash) maxLoad() int64 {
+	factor := c.loadFactor
+	if factor == 0 {
+		factor = defaultLoadFactor
+	}
+	avg := float64(c.totalLoad()+1) / float64(len(c.nodes))
+	return int64(math.Ceil(avg * factor))
+}
+
+// 结点当前的负载，调用方需持有锁
+func (c *ConsistentHash) load(nodeKey string) int64 {
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(cNode.load)
+}
+
+// 全部结点的负载之和，删除的结点连同负载一起移除，调用方需持有锁
+func (c *ConsistentHash) totalLoad() int64 {
+	var total int64
+	for _, n := range c.nodes {
+		total += atomic.LoadInt64(n.load)
+	}
+	return total
+}
+
+// Inc 增加结点的负载，通常在 GetLeast 选中结点后调用。
+// 计数是结点上的原子变量，只需要读锁，不会与 Add、Remove 等写操作互相等待
+func (c *ConsistentHash) Inc(nodeKey string) {
+	c.RLock()
+	defer c.RUnlock()
+	if cNode, ok := c.nodes[nodeKey]; ok {
+		atomic.AddInt64(cNode.load, 1)
+	}
+}
+
+// Done 减少结点的负载，与 Inc 成对调用，负载不会小于 0
+func (c *ConsistentHash) Done(nodeKey string) {
+	c.RLock()
+	defer c.RUnlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return
+	}
+	for {
+		n := atomic.LoadInt64(cNode.load)
+		if n <= 0 || atomic.CompareAndSwapInt64(cNode.load, n, n-1) {
+			return
+		}
+	}
+}
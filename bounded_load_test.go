@@ -0,0 +1,168 @@
+package consistent_hash
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsistentHash_GetLeast(t *testing.T) {
+	c := NewConsistentHash()
+	if _, err := c.GetLeast("key"); err == nil {
+		t.Fatal("GetLeast on empty ring should fail")
+	}
+	for i := 0; i < 8; i++ {
+		if err := c.AddWithVirtualNode(testNode{"node-" + strconv.Itoa(i)}, 50); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const factor = 1.25
+	if err := c.SetLoadFactor(factor); err != nil {
+		t.Fatal(err)
+	}
+
+	// 少量热点 key 占据绝大部分请求
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.5, 1, 10000)
+	var inflight []string
+	for i := 0; i < 50000; i++ {
+		n, err := c.GetLeast("key-" + strconv.FormatUint(zipf.Uint64(), 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Inc(n.Key())
+		inflight = append(inflight, n.Key())
+
+		limit := int64(math.Ceil(float64(c.totalLoad()) / float64(len(c.nodes)) * factor))
+		if c.load(n.Key()) > limit {
+			t.Fatalf("node %s load %d exceeds bound %d", n.Key(), c.load(n.Key()), limit)
+		}
+		if len(inflight) > 1000 {
+			c.Done(inflight[0])
+			inflight = inflight[1:]
+		}
+	}
+
+	// 与普通一致性哈希对比，确认热点确实存在
+	plain := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		n, _ := c.GetNode("key-" + strconv.FormatUint(zipf.Uint64(), 10))
+		plain[n.Key()]++
+	}
+	maxPlain := 0
+	for _, v := range plain {
+		if v > maxPlain {
+			maxPlain = v
+		}
+	}
+	if maxPlain <= int(math.Ceil(1000/8*factor)) {
+		t.Fatalf("key distribution isn't skewed enough: %v", plain)
+	}
+}
+
+// CollisionChain 下负载已满时由共用该虚拟结点的下一个结点接替，与 GetN 的顺序相同
+func TestConsistentHash_GetLeastChain(t *testing.T) {
+	c := collisionRing(t, CollisionChain)
+	if err := c.AddAll([]Node{testNode{"b"}, testNode{"c"}, testNode{"d"}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.GetLeast("5"); err != nil || n.Key() != "a" {
+		t.Fatalf("GetLeast(5) = %v %v, want a", n, err)
+	}
+	for i := 0; i < 3; i++ {
+		c.Inc("a")
+	}
+	if n, err := c.GetLeast("5"); err != nil || n.Key() != "b" {
+		t.Fatalf("GetLeast(5) with a full = %v %v, want b", n, err)
+	}
+}
+
+func TestConsistentHash_IncDone(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Inc("a")
+	c.Inc("a")
+	c.Inc("b")
+	c.Inc("unknown")
+	if c.load("a") != 2 || c.load("b") != 1 || c.totalLoad() != 3 {
+		t.Fatalf("loads a = %d, b = %d, total = %d", c.load("a"), c.load("b"), c.totalLoad())
+	}
+	c.Done("b")
+	c.Done("b")
+	if c.load("b") != 0 || c.totalLoad() != 2 {
+		t.Fatalf("loads a = %d, b = %d, total = %d", c.load("a"), c.load("b"), c.totalLoad())
+	}
+
+	if err := c.Remove(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.load("a") != 0 || c.totalLoad() != 0 {
+		t.Fatalf("removed node keeps load: total = %d", c.totalLoad())
+	}
+
+	if err := c.SetLoadFactor(1); err == nil {
+		t.Error("SetLoadFactor(1) should fail")
+	}
+}
+
+func TestConsistentHash_IncDoneDuringWrite(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 写操作在副本上执行期间只持有 writeMu，Inc、Done 不需要等待
+	c.writeMu.Lock()
+	done := make(chan struct{})
+	go func() {
+		c.Inc("a")
+		c.Inc("a")
+		c.Done("a")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Inc blocked on writeMu")
+	}
+	c.writeMu.Unlock()
+
+	// 写操作替换状态之后保留期间的计数
+	if err := c.Add(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.load("a") != 1 || c.totalLoad() != 1 {
+		t.Fatalf("load a = %d, total = %d", c.load("a"), c.totalLoad())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Inc("b")
+				c.Done("b")
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := c.Add(testNode{"n-" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	if c.load("b") != 0 || c.totalLoad() != 1 {
+		t.Fatalf("load b = %d, total = %d", c.load("b"), c.totalLoad())
+	}
+}
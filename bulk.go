@@ -0,0 +1,82 @@
+package consistent_hash
+
+import (
+	"runtime"
+	"sync"
+)
+
+// OwnersOf 返回每个 key 所属结点的 key，与 keys 一一对应，结果与对每个 key 调用 GetNode 相同。
+// 所有 key 在同一个快照上查询，不加锁，不统计负载和 Metrics。
+// key 被 ReserveRange 保留或者没有在线结点时对应的结果为空字符串
+func (c *ConsistentHash) OwnersOf(keys []string) []string {
+	return c.OwnersOfParallel(keys, 1)
+}
+
+// OwnersOfParallel 与 OwnersOf 相同，把 keys 分成 workers 段并发计算 hash 和查找，workers 小于 1 时使用 GOMAXPROCS
+func (c *ConsistentHash) OwnersOfParallel(keys []string, workers int) []string {
+	s := c.loadSnapshot()
+	owners := make([]string, len(keys))
+	if s == nil || s.nodeCount == 0 {
+		return owners
+	}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	// 每段至少 minChunk 个 key，太小时 goroutine 的开销超过查找本身
+	const minChunk = 1024
+	if max := (len(keys) + minChunk - 1) / minChunk; workers > max {
+		workers = max
+	}
+	if workers <= 1 {
+		s.locateAll(keys, owners)
+		return owners
+	}
+	var wg sync.WaitGroup
+	chunk := (len(keys) + workers - 1) / workers
+	for start := 0; start < len(keys); start += chunk {
+		end := start + chunk
+		if end > len(keys) {
+			end = len(keys)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			s.locateAll(keys[start:end], owners[start:end])
+		}(start, end)
+	}
+	wg.Wait()
+	return owners
+}
+
+// MapKeys 按所属结点的 key 分组返回 keys，每组保持 keys 中的顺序，查询规则与 OwnersOf 相同。
+// 没有所属结点的 key 放在空字符串下，所有组合起来恰好是 keys
+func (c *ConsistentHash) MapKeys(keys []string) map[string][]string {
+	return c.MapKeysParallel(keys, 1)
+}
+
+// MapKeysParallel 与 MapKeys 相同，并发规则与 OwnersOfParallel 相同
+func (c *ConsistentHash) MapKeysParallel(keys []string, workers int) map[string][]string {
+	owners := c.OwnersOfParallel(keys, workers)
+	// 先计数，每组只分配一次
+	counts := map[string]int{}
+	for _, owner := range owners {
+		counts[owner]++
+	}
+	groups := make(map[string][]string, len(counts))
+	for owner, n := range counts {
+		groups[owner] = make([]string, 0, n)
+	}
+	for i, owner := range owners {
+		groups[owner] = append(groups[owner], keys[i])
+	}
+	return groups
+}
+
+// 按 locate 查找每个 key，没有所属结点的 key 保持为空
+func (s *ringSnapshot) locateAll(keys, owners []string) {
+	for i, key := range keys {
+		if n, err := s.locate(key); err == nil {
+			owners[i] = n.key
+		}
+	}
+}
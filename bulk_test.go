@@ -0,0 +1,111 @@
+package consistent_hash
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func bulkTestKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "object-" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func TestConsistentHash_MapKeys(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 8), 50)
+	c.MarkDown("node-3")
+	c.PinKey("object-7", "node-5")
+	c.ReserveRange(0, 1<<28, "migrating")
+	keys := bulkTestKeys(20000)
+
+	owners := c.OwnersOf(keys)
+	if len(owners) != len(keys) {
+		t.Fatalf("OwnersOf returned %d owners", len(owners))
+	}
+	reserved := 0
+	for i, key := range keys {
+		want := ""
+		if n, err := c.GetNode(key); err == nil {
+			want = n.Key()
+		} else {
+			reserved++
+		}
+		if owners[i] != want {
+			t.Fatalf("OwnersOf[%s] = %q, GetNode = %q", key, owners[i], want)
+		}
+	}
+	if reserved == 0 || owners[7] != "node-5" {
+		t.Fatalf("reserved %d keys, object-7 owned by %s", reserved, owners[7])
+	}
+
+	for _, workers := range []int{0, 1, 3, 16} {
+		if got := c.OwnersOfParallel(keys, workers); !equalStrings(got, owners) {
+			t.Fatalf("OwnersOfParallel(%d) differs from OwnersOf", workers)
+		}
+		groups := c.MapKeysParallel(keys, workers)
+		// 每个 key 恰好出现一次，组内保持输入的顺序
+		var all []string
+		for owner, group := range groups {
+			if len(group) == 0 || owner == "node-3" {
+				t.Fatalf("MapKeys group %q = %d keys", owner, len(group))
+			}
+			prev := -1
+			for _, key := range group {
+				n, _ := strconv.Atoi(key[len("object-"):])
+				if owners[n] != owner {
+					t.Fatalf("MapKeys put %s under %q, owner is %q", key, owner, owners[n])
+				}
+				if n <= prev {
+					t.Fatalf("MapKeys group %q is out of order", owner)
+				}
+				prev = n
+			}
+			all = append(all, group...)
+		}
+		if len(groups[""]) != reserved {
+			t.Fatalf("MapKeys has %d unowned keys, want %d", len(groups[""]), reserved)
+		}
+		sort.Strings(all)
+		sorted := append([]string(nil), keys...)
+		sort.Strings(sorted)
+		if !equalStrings(all, sorted) {
+			t.Fatal("MapKeys doesn't partition the input")
+		}
+	}
+
+	if got := NewConsistentHash().MapKeys(keys[:3]); len(got) != 1 || len(got[""]) != 3 {
+		t.Fatalf("MapKeys on an empty ring = %v", got)
+	}
+	if got := c.MapKeys(nil); len(got) != 0 {
+		t.Fatalf("MapKeys(nil) = %v", got)
+	}
+}
+
+func BenchmarkConsistentHash_MapKeys(b *testing.B) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 64), 100)
+	keys := bulkTestKeys(100000)
+	b.Run("GetNode", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			groups := map[string][]string{}
+			for _, key := range keys {
+				n, _ := c.GetNode(key)
+				groups[n.Key()] = append(groups[n.Key()], key)
+			}
+		}
+	})
+	b.Run("MapKeys", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.MapKeys(keys)
+		}
+	})
+	b.Run("MapKeysParallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.MapKeysParallel(keys, 0)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package consistent_hash
+
+import (
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// CollisionPolicy 决定新的虚拟结点与环上已有的虚拟结点位置相同时的处理方式
+type CollisionPolicy int
+
+const (
+	// CollisionProbe 换一个 probe 重新计算 hash，三次都冲突时顺时针找空位，默认的策略
+	CollisionProbe CollisionPolicy = iota
+	// CollisionError 三次重试都冲突时返回 ErrHashCollision，环保持不变
+	CollisionError
+	// CollisionOverwrite 不重试，后添加的结点覆盖该虚拟结点，原结点失去这个虚拟结点
+	CollisionOverwrite
+	// CollisionChain 不重试，虚拟结点按添加顺序记录所有结点，查询时返回其中第一个在线的结点
+	CollisionChain
+)
+
+func (p CollisionPolicy) String() string {
+	switch p {
+	case CollisionProbe:
+		return "Probe"
+	case CollisionError:
+		return "Error"
+	case CollisionOverwrite:
+		return "Overwrite"
+	case CollisionChain:
+		return "Chain"
+	}
+	return fmt.Sprintf("CollisionPolicy(%d)", int(p))
+}
+
+// WithCollisionPolicy 设置虚拟结点冲突时的处理方式，默认为 CollisionProbe。
+// ketama 模式下不能重试，CollisionProbe 与 libketama 一样由后添加的结点覆盖，即 CollisionOverwrite
+func WithCollisionPolicy(policy CollisionPolicy) Option {
+	return func(c *ConsistentHash) error {
+		if policy < CollisionProbe || policy > CollisionChain {
+			return fmt.Errorf("unknown collision policy %v", policy)
+		}
+		c.collision = policy
+		return nil
+	}
+}
+
+// 实际使用的冲突策略，ketama 模式下 CollisionProbe 按 CollisionOverwrite 处理
+func (c *ConsistentHash) collisionPolicy() CollisionPolicy {
+	if c.ketama && c.collision == CollisionProbe {
+		return CollisionOverwrite
+	}
+	return c.collision
+}
+
+// 冲突时多个结点共用一个位置的策略，虚拟结点直接写入环
+func (c *ConsistentHash) sharesPoints() bool {
+	policy := c.collisionPolicy()
+	return policy == CollisionOverwrite || policy == CollisionChain
+}
+
+// 第 replica 个副本不做冲突重试时的虚拟结点，追加到 dst
+func (c *ConsistentHash) replicaPoints(dst []uint32, buf []byte, nodeKey string, replica int) ([]uint32, []byte) {
+	if c.ketama {
+		digest := md5.Sum([]byte(nodeKey + "-" + strconv.Itoa(replica)))
+		for h := 0; h < ketamaPointsPerReplica; h++ {
+			dst = append(dst, ketamaPoint(digest, h))
+		}
+		return dst, buf
+	}
+	buf, k := c.virtualHash(buf, nodeKey, replica, 0)
+	return append(dst, k), buf
+}
+
+// 按 CollisionOverwrite 或 CollisionChain 添加编号 [from, to) 的副本，调用方需持有写锁
+func (c *ConsistentHash) addSharedPoints(nodeKey string, from, to int) ([]uint32, error) {
+	virtualNodes := make([]uint32, 0, (to-from)*c.pointsPerReplica())
+	var added, points []uint32
+	var buf []byte
+	for i := from; i < to; i++ {
+		points, buf = c.replicaPoints(points[:0], buf, nodeKey, i)
+		for _, k := range points {
+			owner, ok := c.circle[k]
+			switch {
+			case !ok:
+				added = append(added, k)
+				c.circle[k] = nodeKey
+			case owner == nodeKey || c.chainedTo(k, nodeKey):
+				// 同一结点的两个副本冲突，只保留一个
+				continue
+			case c.collisionPolicy() == CollisionChain:
+				owners := c.chains[k]
+				if owners == nil {
+					owners = []string{owner}
+				}
+				if c.chains == nil {
+					c.chains = map[uint32][]string{}
+				}
+				// 副本可能与其他环共享，不能原地追加
+				c.chains[k] = append(owners[:len(owners):len(owners)], nodeKey)
+			default:
+				// 由后添加的结点覆盖，把该虚拟结点从原结点转移过来
+				old := c.nodes[owner]
+				for j, v := range old.virtualNodes {
+					if v == k {
+						old.virtualNodes = append(old.virtualNodes[:j:j], old.virtualNodes[j+1:]...)
+						break
+					}
+				}
+				c.nodes[owner] = old
+				c.circle[k] = nodeKey
+			}
+			virtualNodes = append(virtualNodes, k)
+		}
+	}
+	c.insertSorted(added)
+	return virtualNodes, nil
+}
+
+// 虚拟结点 k 的 chain 中是否有 nodeKey，调用方需持有锁
+func (c *ConsistentHash) chainedTo(k uint32, nodeKey string) bool {
+	for _, owner := range c.chains[k] {
+		if owner == nodeKey {
+			return true
+		}
+	}
+	return false
+}
+
+// 结点 nodeKey 离开 points，返回已经没有结点的虚拟结点。CollisionChain 下仍有其他结点的虚拟结点保留，
+// 由 chain 中的下一个结点接替，调用方需持有写锁
+func (c *ConsistentHash) releasePoints(nodeKey string, points []uint32) []uint32 {
+	if len(c.chains) == 0 {
+		return points
+	}
+	free := make([]uint32, 0, len(points))
+	for _, p := range points {
+		owners, ok := c.chains[p]
+		if !ok {
+			free = append(free, p)
+			continue
+		}
+		rest := make([]string, 0, len(owners)-1)
+		for _, owner := range owners {
+			if owner != nodeKey {
+				rest = append(rest, owner)
+			}
+		}
+		if len(rest) == 1 {
+			delete(c.chains, p)
+		} else {
+			c.chains[p] = rest
+		}
+		c.circle[p] = rest[0]
+	}
+	return free
+}
+
+// chain 中的切片不会原地修改，只需要复制 map
+func copyChains(chains map[uint32][]string) map[uint32][]string {
+	if chains == nil {
+		return nil
+	}
+	copied := make(map[uint32][]string, len(chains))
+	for k, v := range chains {
+		copied[k] = v
+	}
+	return copied
+}
+
+// 虚拟结点 p 的全部结点，按添加顺序排列，调用方需持有锁
+func (c *ConsistentHash) ownersAt(p uint32) []string {
+	if chain, ok := c.chains[p]; ok {
+		return append([]string(nil), chain...)
+	}
+	return []string{c.circle[p]}
+}
+
+// 反序列化时检查 chains 与被多个结点声明的虚拟结点 shared 一一对应，每个 chain 恰好包含声明它的结点。
+// 调用方已经检查过 shared 中的虚拟结点都有 chain
+func checkChains(shared, chains map[uint32][]string) error {
+	for p, chain := range chains {
+		if !sameKeys(chain, shared[p]) {
+			return fmt.Errorf("%w: chain %v of point %d doesn't match its owners %v", ErrCorruptData, chain, p, shared[p])
+		}
+	}
+	return nil
+}
+
+// 不考虑顺序时 a 和 b 是否相同
+func sameKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// 按位置排序的 chain，用于序列化
+func (c *ConsistentHash) sortedChainPoints() []uint32 {
+	points := make([]uint32, 0, len(c.chains))
+	for p := range c.chains {
+		points = append(points, p)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+	return points
+}
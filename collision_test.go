@@ -0,0 +1,255 @@
+package consistent_hash
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func collisionRing(t *testing.T, policy CollisionPolicy) *ConsistentHash {
+	c, err := New(WithCollisionPolicy(policy), WithHash(tableHash(map[string]uint32{
+		"a#0#0": 10, "a#1#0": 20,
+		"b#0#0": 10, "b#0#1": 10, "b#0#2": 10,
+		"c#0#0": 10,
+		"d#0#0": 50,
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithVirtualNode(testNode{"a"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func ownerOfHash(c *ConsistentHash, h uint32) string {
+	n, err := c.GetOwnerByHash(h)
+	if err != nil {
+		return err.Error()
+	}
+	return n.Key()
+}
+
+func TestCollisionPolicy_Error(t *testing.T) {
+	c := collisionRing(t, CollisionError)
+	checksum := c.Checksum()
+	if err := c.AddWithVirtualNode(testNode{"b"}, 1); !errors.Is(err, ErrHashCollision) {
+		t.Fatalf("AddWithVirtualNode err = %v", err)
+	}
+	if err := c.AddAll([]Node{testNode{"d"}, testNode{"b"}}, 1); !errors.Is(err, ErrHashCollision) {
+		t.Fatalf("AddAll err = %v", err)
+	}
+	if c.Checksum() != checksum || c.HasNode("b") || c.HasNode("d") {
+		t.Fatal("failed add changed the ring")
+	}
+
+	// 默认的策略顺时针找空位
+	p := collisionRing(t, CollisionProbe)
+	if err := p.AddWithVirtualNode(testNode{"b"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.nodes["b"].virtualNodes, []uint32{11}) {
+		t.Fatalf("b virtual nodes = %v", p.nodes["b"].virtualNodes)
+	}
+}
+
+func TestCollisionPolicy_Overwrite(t *testing.T) {
+	c := collisionRing(t, CollisionOverwrite)
+	if err := c.AddWithVirtualNode(testNode{"b"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := ownerOfHash(c, 5); got != "b" {
+		t.Fatalf("owner of 5 = %s, want b", got)
+	}
+	if n, _ := c.VirtualNodeCount("a"); n != 1 {
+		t.Fatalf("a has %d replicas after being overwritten", n)
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 被覆盖的虚拟结点随 b 一起删除，不会还给 a
+	c.RemoveByKey("b")
+	if got := ownerOfHash(c, 5); got != "a" || c.TotalPoints() != 1 {
+		t.Fatalf("owner of 5 = %s with %d points", got, c.TotalPoints())
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollisionPolicy_Chain(t *testing.T) {
+	c := collisionRing(t, CollisionChain)
+	if err := c.AddAll([]Node{testNode{"b"}, testNode{"c"}, testNode{"d"}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+	if c.TotalPoints() != 3 || !reflect.DeepEqual(c.chains[10], []string{"a", "b", "c"}) {
+		t.Fatalf("points = %v, chain = %v", c.hashSortedNodes, c.chains[10])
+	}
+	if got := ownerOfHash(c, 5); got != "a" {
+		t.Fatalf("owner of 5 = %s, want a", got)
+	}
+	if nodes, _ := c.GetN("5", 4); !reflect.DeepEqual(nodeKeys(nodes), []string{"a", "b", "c", "d"}) {
+		t.Fatalf("GetN = %v", nodeKeys(nodes))
+	}
+
+	// 查询返回 chain 中第一个在线的结点
+	c.MarkDown("a")
+	if got := ownerOfHash(c, 5); got != "b" {
+		t.Fatalf("owner of 5 with a down = %s, want b", got)
+	}
+	if n, _ := c.GetNode("5"); n.Key() != "b" {
+		t.Fatalf("GetNode with a down = %s, want b", n.Key())
+	}
+	c.MarkUp("a")
+
+	clone := c.Clone()
+	// 删除 chain 的第一个结点，虚拟结点由下一个结点接替
+	if _, err := c.RemoveByKey("a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ownerOfHash(c, 5); got != "b" || !reflect.DeepEqual(c.chains[10], []string{"b", "c"}) {
+		t.Fatalf("after removing a: owner = %s, chain = %v", got, c.chains[10])
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+	// 删除最后一个结点
+	if _, err := c.RemoveByKey("c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.chains[10]; ok || ownerOfHash(c, 5) != "b" || c.TotalPoints() != 2 {
+		t.Fatalf("after removing c: chain = %v, points = %v", c.chains[10], c.hashSortedNodes)
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+	c.RemoveByKey("b")
+	if got := ownerOfHash(c, 5); got != "d" || c.TotalPoints() != 1 {
+		t.Fatalf("after removing b: owner of 5 = %s", got)
+	}
+
+	if got := ownerOfHash(clone, 5); got != "a" || !reflect.DeepEqual(clone.chains[10], []string{"a", "b", "c"}) {
+		t.Fatalf("clone changed: owner = %s, chain = %v", got, clone.chains[10])
+	}
+	if err := clone.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithCollisionPolicy(t *testing.T) {
+	if _, err := New(WithCollisionPolicy(CollisionPolicy(9))); err == nil {
+		t.Fatal("unknown policy accepted")
+	}
+	if CollisionChain.String() != "Chain" || CollisionPolicy(9).String() != "CollisionPolicy(9)" {
+		t.Fatal("unexpected String")
+	}
+	c, _ := New(WithGroupcacheCompatibility())
+	if c.collision != CollisionOverwrite {
+		t.Fatalf("groupcache policy = %v", c.collision)
+	}
+}
+
+// 每种策略下的环都能通过二进制、JSON 和 proto 格式还原，包括 chain 的顺序
+func TestCollisionPolicy_RoundTrip(t *testing.T) {
+	for _, policy := range []CollisionPolicy{CollisionProbe, CollisionError, CollisionOverwrite, CollisionChain} {
+		build := func() *ConsistentHash {
+			c := collisionRing(t, policy)
+			// CollisionError 下 b 与 a 冲突，不能添加
+			if policy != CollisionError {
+				if err := c.AddAll([]Node{testNode{"b"}, testNode{"c"}}, 1); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c.Add(testNode{"d"})
+			c.PinKey("pinned", "d")
+			return c
+		}
+		c := build()
+		for name, roundTrip := range map[string]func(dst *ConsistentHash) error{
+			"binary": func(dst *ConsistentHash) error {
+				data, err := c.MarshalBinary()
+				if err != nil {
+					return err
+				}
+				return dst.UnmarshalBinary(data)
+			},
+			"json": func(dst *ConsistentHash) error {
+				data, err := c.MarshalJSON()
+				if err != nil {
+					return err
+				}
+				return dst.UnmarshalJSON(data)
+			},
+			"proto": func(dst *ConsistentHash) error {
+				return dst.ImportProto(c.ExportProto())
+			},
+		} {
+			dst := collisionRing(t, policy)
+			dst.Reset()
+			if err := roundTrip(dst); err != nil {
+				t.Fatalf("%v %s: %v", policy, name, err)
+			}
+			if err := dst.VerifyRing(); err != nil {
+				t.Fatalf("%v %s: %v", policy, name, err)
+			}
+			if !reflect.DeepEqual(dst.chains, c.chains) || !reflect.DeepEqual(dst.AllPositions(), c.AllPositions()) {
+				t.Fatalf("%v %s: chains %v, want %v", policy, name, dst.chains, c.chains)
+			}
+			for _, key := range []string{"5", "15", "40", "100", "pinned"} {
+				want, wantErr := c.GetN(key, c.Len())
+				if got, err := dst.GetN(key, c.Len()); (err == nil) != (wantErr == nil) || !reflect.DeepEqual(nodeKeys(got), nodeKeys(want)) {
+					t.Fatalf("%v %s: GetN(%s) = %v, %v, want %v, %v", policy, name, key, nodeKeys(got), err, nodeKeys(want), wantErr)
+				}
+			}
+		}
+	}
+}
+
+func TestCollisionPolicy_ChainDecodeErrors(t *testing.T) {
+	c := collisionRing(t, CollisionChain)
+	c.AddAll([]Node{testNode{"b"}, testNode{"c"}}, 1)
+
+	// chain 与共用虚拟结点的结点不一致
+	empty := collisionRing(t, CollisionChain)
+	empty.Reset()
+	r := c.ExportProto()
+	r.Chains[0].NodeKeys = []string{"a", "b"}
+	if err := empty.ImportProto(r); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("ImportProto with a short chain err = %v", err)
+	}
+	r.Chains = nil
+	if err := empty.ImportProto(r); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("ImportProto without chains err = %v", err)
+	}
+
+	// 合并时 chain 可以包含环上已有的结点
+	data, _ := c.MarshalJSON()
+	var ring jsonRing
+	json.Unmarshal(data, &ring)
+	dst := collisionRing(t, CollisionChain)
+	for _, n := range ring.Nodes {
+		if n.Key == "b" {
+			// 只有 a 和 b 共用时与 chain [a b c] 不一致
+			partial, _ := json.Marshal(jsonRing{Nodes: []jsonNode{n}, Chains: ring.Chains})
+			if err := dst.UnmarshalJSON(partial); !errors.Is(err, ErrCorruptData) {
+				t.Fatalf("UnmarshalJSON of b alone err = %v", err)
+			}
+		}
+	}
+	rest, _ := json.Marshal(jsonRing{Nodes: ring.Nodes[1:], Chains: ring.Chains})
+	if err := dst.UnmarshalJSON(rest); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst.chains, c.chains) || ownerOfHash(dst, 5) != "a" {
+		t.Fatalf("merged chains = %v", dst.chains)
+	}
+	rest, _ = json.Marshal(jsonRing{Nodes: []jsonNode{{Key: "e", Points: []uint32{10}}}})
+	if err := dst.UnmarshalJSON(rest); !errors.Is(err, ErrHashCollision) {
+		t.Fatalf("UnmarshalJSON of a shared point without a chain err = %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package consistent_hash
+
+// 排序数组的长度低于容量的 1/compactRatio 时，在副本上修改的操作提交前按实际数量重新分配
+const compactRatio = 4
+
+// Compact 按当前的结点数和虚拟结点数重新分配排序数组和 map，释放大量删除结点之后多余的内存，不改变任何查询结果。
+// RemoveAll、Sync 等在副本上修改的操作在虚拟结点数低于容量的 1/4 且容量超过 WithSortedCapacity、WithExpectedNodes 的提示时会自动收缩，
+// Reset 和原地修改环的操作 (Drain、SetVirtualNodeCount 等) 保留原有的容量，需要时调用 Compact
+func (c *ConsistentHash) Compact() {
+	c.lockWrite()
+	defer c.unlock()
+	c.lazyInit()
+	c.shrinkToFit()
+}
+
+// 是否需要收缩，map 的容量无法获取，与排序数组一起判断。容量不超过 WithSortedCapacity、WithExpectedNodes 的提示时不收缩
+func (c *ConsistentHash) sparse() bool {
+	return len(c.hashSortedNodes) < cap(c.hashSortedNodes)/compactRatio && cap(c.hashSortedNodes) > c.pointCapacity()
+}
+
+// 重新分配排序数组和 map，调用方需持有写锁
+func (c *ConsistentHash) shrinkToFit() {
+	c.hashSortedNodes = append(make([]uint32, 0, len(c.hashSortedNodes)), c.hashSortedNodes...)
+	circle := make(map[uint32]string, len(c.circle))
+	for k, v := range c.circle {
+		circle[k] = v
+	}
+	c.circle = circle
+	nodes := make(map[string]consistentNode, len(c.nodes))
+	for k, v := range c.nodes {
+		nodes[k] = v
+	}
+	c.nodes = nodes
+	c.chains = copyChains(c.chains)
+}
@@ -0,0 +1,106 @@
+package consistent_hash
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func compactOwners(c *ConsistentHash, keys int) []string {
+	owners := make([]string, keys)
+	for i := range owners {
+		n, _ := c.GetNode("key-" + strconv.Itoa(i))
+		owners[i] = n.Key()
+	}
+	return owners
+}
+
+func TestConsistentHash_CompactAfterRemoveAll(t *testing.T) {
+	c := NewConsistentHash()
+	nodes := batchTestNodes("node-", 2000)
+	c.AddAll(nodes, 10)
+	before := cap(c.hashSortedNodes)
+
+	// 在副本上删除大部分结点，提交前自动收缩
+	var keys []string
+	for _, n := range nodes[50:] {
+		keys = append(keys, n.Key())
+	}
+	if err := c.RemoveAll(keys); err != nil {
+		t.Fatal(err)
+	}
+	if got := cap(c.hashSortedNodes); got != c.TotalPoints() || got >= before/compactRatio {
+		t.Fatalf("cap after RemoveAll = %d, %d points, %d before", got, c.TotalPoints(), before)
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只删除少量结点时保留容量
+	c.AddAll(nodes[50:], 10)
+	before = cap(c.hashSortedNodes)
+	c.RemoveAll(keys[:10])
+	if got := cap(c.hashSortedNodes); got != before {
+		t.Fatalf("cap after removing a few nodes = %d, want %d", got, before)
+	}
+}
+
+func TestConsistentHash_Compact(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 1000), 10)
+	c.Reset()
+	if cap(c.hashSortedNodes) == 0 {
+		t.Fatal("Reset released the capacity")
+	}
+	c.Compact()
+	if got := cap(c.hashSortedNodes); got != 0 {
+		t.Fatalf("cap after Compact = %d", got)
+	}
+
+	c.AddAll(batchTestNodes("node-", 200), 20)
+	c.MarkDown("node-3")
+	// 原地减少虚拟结点
+	for i := 0; i < 200; i++ {
+		c.SetVirtualNodeCount("node-"+strconv.Itoa(i), 1)
+	}
+	owners := compactOwners(c, 2000)
+	checksum, version := c.Checksum(), c.Version()
+	c.Compact()
+	if got := cap(c.hashSortedNodes); got != c.TotalPoints() {
+		t.Fatalf("cap after Compact = %d, %d points", got, c.TotalPoints())
+	}
+	if !equalStrings(compactOwners(c, 2000), owners) || c.Checksum() != checksum || c.Version() != version {
+		t.Fatal("Compact changed the ring")
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+
+	var zero ConsistentHash
+	zero.Compact()
+	if err := zero.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 反复扩容到 5000 个结点再缩减到 50 个，报告每轮之后的堆内存
+func BenchmarkConsistentHash_CompactChurn(b *testing.B) {
+	nodes := batchTestNodes("node-", 5000)
+	keys := nodeKeys(nodes[50:])
+	var stats runtime.MemStats
+	var heap uint64
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := NewConsistentHash()
+		c.AddAll(nodes[:50], 20)
+		for round := 0; round < 3; round++ {
+			c.AddAll(nodes[50:], 20)
+			c.RemoveAll(keys)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		heap += stats.HeapInuse
+		runtime.KeepAlive(c)
+	}
+	b.ReportMetric(float64(heap)/float64(b.N), "heap-bytes/op")
+}
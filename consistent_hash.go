@@ -1,12 +1,14 @@
 package consistent_hash
 
 import (
-	"errors"
 	"fmt"
 	"hash/crc32"
+	"math"
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Node interface {
@@ -16,43 +18,240 @@ type Node interface {
 type consistentNode struct {
 	node         Node
 	virtualNodes []uint32
+	// 被 MarkDown 标记为下线，查询时跳过
+	down bool
+	// AddWithRamp 的目标副本数，达到后清零
+	rampTarget int
+	// WithLoadTracking 开启时的查询次数，原子操作
+	hits *uint64
+	// 有界负载一致性哈希使用的负载计数，原子操作，副本之间共用
+	load *int64
+	// SetMeta 设置的元数据
+	meta interface{}
+	// AddWithTTL 设置的有效期和到期时间，ttl 为 0 时不会过期
+	ttl      time.Duration
+	deadline time.Time
+}
+
+func newConsistentNode(node Node, virtualNodes []uint32) consistentNode {
+	return consistentNode{node: node, virtualNodes: virtualNodes, hits: new(uint64), load: new(int64)}
 }
 
 type ConsistentHash struct {
 	hashSortedNodes []uint32
 	circle          map[uint32]string
 	nodes           map[string]consistentNode
+	// CollisionChain 下被多个结点共用的虚拟结点，按添加顺序记录全部结点，第一个与 circle 相同
+	chains map[uint32][]string
+	// PinKey 固定的 key 到结点 key，结点可能已经不在环上
+	pins map[string]string
+	// ReserveRange 保留的区间，按 start 排序，只整体替换，可以与快照和副本共用
+	reserved []reservedRange
 	sync.RWMutex
+	config
+
+	// 写锁内记录的结点变化，释放写锁之后通过 notifier 通知
+	pending  []ringEvent
+	notifier notifier
+	// 每次成功修改环加 1，见 Version
+	version uint64
+	// 上一次通过 Metrics 上报的结点数和虚拟结点数，见 observeGauges
+	gauges [2]int
+	// GetNode 读取的 *ringSnapshot，见 publish
+	snapshot atomic.Value
+	// 写操作之间互斥，持有 writeMu 时可以不加读锁读取环的状态，见 update
+	writeMu sync.Mutex
+}
+
+// 环的配置，Clone 等操作原样保留
+type config struct {
 	hash func(string) uint32
+	// GetNodeBytes 使用的 hash，见 WithBytesHash
+	bytesHash func([]byte) uint32
+	// hash 的算法和 seed，自定义 hash 时为空，见 ExportProto
+	hashName string
+	hashSeed uint64
+	// Add 使用的虚拟结点数
+	defaultReplicas int
+	// 单个结点允许的最大虚拟结点数
+	maxReplicas int
+	// 排序数组的容量，见 WithSortedCapacity
+	sortedCapacity int
+	// 预计的结点数和每个结点的副本数，见 WithExpectedNodes
+	expectedNodes, expectedReplicas int
+	// AddWithWeight 中权重 1 对应的虚拟结点数
+	weightBase int
+	// GetLeast 使用的负载上限系数
+	loadFactor float64
+	// GetReplicas 返回的结点数
+	replicationFactor int
+	// 在线结点不足时 GetN 返回全部在线结点，见 WithDegradedReplicas
+	degradedReplicas bool
+	// 统计每个结点的查询次数，见 WithLoadTracking
+	loadTracking bool
+	// 为 nil 时不统计，见 WithMetrics
+	metrics Metrics
+	// 为 0 时不缓存，见 WithLookupCache
+	lookupCacheSize int
+	// 快照中已删除的虚拟结点的比例上限，为 0 时立即删除，见 WithLazyRemoval
+	maxTombstones float64
+
+	// ketama 模式，见 WithKetama
+	ketama bool
+	// twemproxy 的 hash 配置项，不为空时 ketama 模式使用对应的 key hash，见 WithTwemproxy
+	twemproxyHash string
+	// 虚拟结点冲突的处理方式，见 WithCollisionPolicy
+	collision CollisionPolicy
+	// 虚拟结点 key 的生成方式，见 WithVirtualKeyFunc、WithLegacyVirtualKeys
+	virtualKeyFunc    func(nodeKey string, replica int) string
+	legacyVirtualKeys bool
+	// 虚拟结点的位置减 1，见 WithStathatCompatibility
+	stathat bool
+	// 反序列化时创建结点，见 WithNodeFactory
+	nodeFactory func(key string) Node
+	// 健康检查和 TTL 使用的时钟，为 nil 时使用系统时钟，测试中替换
+	clock clock
 }
 
+const (
+	defaultWeightBase  = 100
+	defaultMaxReplicas = 100000
+)
+
 func NewConsistentHash() *ConsistentHash {
-	return &ConsistentHash{}
+	c, _ := New()
+	return c
 }
 
+// NewConsistentWithCustomHash 使用自定义 hash 创建环，h 为 nil 时使用默认的 crc32
 func NewConsistentWithCustomHash(h func(key string) uint32) *ConsistentHash {
-	return &ConsistentHash{hash: h}
+	if h == nil {
+		return NewConsistentHash()
+	}
+	c, _ := New(WithHash(h))
+	return c
 }
 
+// hash 算法的名字，见 config.hashName
+const (
+	hashCRC32     = "crc32"
+	hashXXHash32  = "xxhash32"
+	hashSipHash24 = "siphash24"
+)
+
 func (c *ConsistentHash) hashKey(key string) uint32 {
+	if c.ketama && c.twemproxyHash == "" {
+		return ketamaHash(key)
+	}
+	if c.hash == nil {
+		// 零值的 ConsistentHash 在第一次 Add 之前没有 hash
+		return defaultHash(key)
+	}
 	return c.hash(key)
 }
 
+// Add 按默认副本数添加结点，结点实现了 WeightedNode 时副本数乘以 Weight()
 func (c *ConsistentHash) Add(node Node) error {
-	return c.AddWithVirtualNode(node, 1)
+	replicas := c.defaultReplicas
+	if replicas == 0 {
+		replicas = 1
+	}
+	replicas, err := c.replicasFor(node, replicas)
+	if err != nil {
+		return err
+	}
+	return c.AddWithVirtualNode(node, replicas)
+}
+
+// AddWithWeight 按权重添加结点，虚拟结点数为 weight 乘以权重基数后四舍五入，至少为 1
+func (c *ConsistentHash) AddWithWeight(node Node, weight float64) error {
+	if !(weight > 0) || math.IsInf(weight, 1) {
+		return fmt.Errorf("weight %v must be greater than 0", weight)
+	}
+	c.RLock()
+	base := c.weightBase
+	c.RUnlock()
+	if base == 0 {
+		base = defaultWeightBase
+	}
+
+	points := math.Round(weight * float64(base))
+	count := 1
+	if points > math.MaxInt32 {
+		// 交给 AddWithVirtualNode 按上限报错
+		count = math.MaxInt32
+	} else if points > 1 {
+		count = int(points)
+	}
+	return c.AddWithVirtualNode(node, count)
+}
+
+// SetWeightBase 设置 AddWithWeight 中权重 1 对应的虚拟结点数，只影响之后添加的结点
+func (c *ConsistentHash) SetWeightBase(base int) error {
+	c.lockWrite()
+	defer c.unlock()
+	return WithWeightBase(base)(c)
 }
 
 func (c *ConsistentHash) AddWithVirtualNode(node Node, virtualNodeCount int) error {
-	if node == nil {
-		return errors.New("node is nil")
+	_, err := c.add(node, virtualNodeCount, false)
+	return err
+}
+
+// AddReported 与 AddWithVirtualNode 相同，同时返回结点添加后拥有的 hash 空间的比例，与之后立即调用 OwnershipFraction 的结果相同
+func (c *ConsistentHash) AddReported(node Node, virtualNodeCount int) (float64, error) {
+	return c.add(node, virtualNodeCount, true)
+}
+
+// report 为 false 时只在开启 Metrics 时计算比例
+func (c *ConsistentHash) add(node Node, virtualNodeCount int, report bool) (float64, error) {
+	if err := checkNode(node); err != nil {
+		return 0, err
+	}
+	if err := c.checkReplicas(virtualNodeCount); err != nil {
+		return 0, err
+	}
+	var observe func()
+	var fraction float64
+	err := c.update(1, virtualNodeCount*c.pointsPerReplica(), func(staged *ConsistentHash) error {
+		if err := staged.addNode(node, virtualNodeCount); err != nil {
+			return err
+		}
+		if staged.metrics != nil || report {
+			fraction = staged.ownedFraction(node.Key())
+		}
+		if staged.metrics != nil {
+			observe = staged.observeTopology(1, 0, fraction)
+		}
+		return nil
+	})
+	if observe != nil {
+		observe()
 	}
+	if err != nil {
+		return 0, err
+	}
+	return fraction, nil
+}
 
-	if virtualNodeCount < 1 {
-		return errors.New("virtualNodeCount can't less 1")
+// 调用方需持有写锁并已校验参数
+func (c *ConsistentHash) addNode(node Node, virtualNodeCount int) error {
+	c.lazyInit()
+	if _, ok := c.nodes[node.Key()]; ok {
+		return newNodeError(node.Key(), ErrNodeExists)
 	}
-	c.Lock()
-	defer c.Unlock()
 
+	virtualNodes, err := c.addVirtualNodes(node.Key(), 0, virtualNodeCount)
+	if err != nil {
+		return err
+	}
+	c.nodes[node.Key()] = newConsistentNode(node, virtualNodes)
+	c.recordAdded(node)
+	return nil
+}
+
+// 零值的 ConsistentHash 在第一次添加结点时初始化，调用方需持有写锁
+func (c *ConsistentHash) lazyInit() {
 	if c.circle == nil {
 		c.circle = map[uint32]string{}
 	}
@@ -60,92 +259,721 @@ func (c *ConsistentHash) AddWithVirtualNode(node Node, virtualNodeCount int) err
 		c.nodes = map[string]consistentNode{}
 	}
 	if c.hash == nil {
-		c.hash = func(key string) uint32 {
-			return crc32.ChecksumIEEE([]byte(key))
-		}
+		c.hash = defaultHash
+		c.hashName = hashCRC32
 	}
+}
 
-	if _, ok := c.nodes[node.Key()]; ok {
-		return fmt.Errorf("node %s already exised", node.Key())
-	}
-
-	// 添加虚拟结点
-	var virtualNodes []uint32
-	for i := 0; i < virtualNodeCount; i++ {
-		var virtualKey *uint32
-		for j := 0; j < 3; j++ { // 防止 hash 冲突，重试 3 次
-			k := c.hashKey(node.Key() + strconv.Itoa(i) + strconv.Itoa(j))
-			_, ok := c.circle[k]
-			if !ok {
-				virtualKey = &k
-				break
+// Clone 返回环的深拷贝，hash 和其他配置保持不变，修改副本不会影响原来的环
+func (c *ConsistentHash) Clone() *ConsistentHash {
+	c.RLock()
+	defer c.RUnlock()
+	clone := c.cloneLocked()
+	clone.publish()
+	return clone
+}
+
+// 深拷贝环的状态，调用方需持有锁
+func (c *ConsistentHash) cloneLocked() *ConsistentHash {
+	clone := &ConsistentHash{
+		hashSortedNodes: append([]uint32(nil), c.hashSortedNodes...),
+		circle:          make(map[uint32]string, len(c.circle)),
+		nodes:           make(map[string]consistentNode, len(c.nodes)),
+		config:          c.config,
+		version:         c.version,
+	}
+	for k, v := range c.circle {
+		clone.circle[k] = v
+	}
+	for k, v := range c.nodes {
+		v.virtualNodes = append([]uint32(nil), v.virtualNodes...)
+		hits := atomic.LoadUint64(v.hits)
+		v.hits = &hits
+		load := atomic.LoadInt64(v.load)
+		v.load = &load
+		clone.nodes[k] = v
+	}
+	clone.chains = copyChains(c.chains)
+	clone.pins = copyPins(c.pins)
+	clone.reserved = c.reserved
+	return clone
+}
+
+// 写操作的副本，与 cloneLocked 不同，未修改的结点直接复用，查询计数继续累加到原来的结点上。
+// 容量按将要增加的结点数和虚拟结点数预留，fn 执行期间不会扩容。调用方需持有 writeMu
+func (c *ConsistentHash) stage(extraNodes, extraPoints int) *ConsistentHash {
+	nodeCap, pointCap := len(c.nodes)+extraNodes, len(c.circle)+extraPoints
+	// 副本按容量提示分配，之后的修改不需要扩容
+	if c.expectedNodes > nodeCap {
+		nodeCap = c.expectedNodes
+	}
+	if hint := c.pointCapacity(); hint > pointCap {
+		pointCap = hint
+	}
+	staged := &ConsistentHash{
+		hashSortedNodes: append(make([]uint32, 0, pointCap), c.hashSortedNodes...),
+		circle:          make(map[uint32]string, pointCap),
+		nodes:           make(map[string]consistentNode, nodeCap),
+		config:          c.config,
+		version:         c.version,
+	}
+	for k, v := range c.circle {
+		staged.circle[k] = v
+	}
+	for k, v := range c.nodes {
+		staged.nodes[k] = v
+	}
+	staged.chains = copyChains(c.chains)
+	// 只有 PinKey、UnpinKey 在写锁内原地修改，fn 需要修改时先复制
+	staged.pins = c.pins
+	staged.reserved = c.reserved
+	return staged
+}
+
+// update 在副本上执行 fn，fn 执行期间不持有环的锁，读者不会被阻塞。extraNodes、extraPoints 为 fn 预计增加的数量。
+// fn 成功后只在替换状态时短暂持有写锁，fn 返回错误时环保持不变
+func (c *ConsistentHash) update(extraNodes, extraPoints int, fn func(staged *ConsistentHash) error) error {
+	c.writeMu.Lock()
+	if c.circle == nil || c.nodes == nil || c.hash == nil {
+		// 零值的 ConsistentHash 需要在写锁内初始化
+		c.Lock()
+		c.lazyInit()
+		c.Unlock()
+	}
+	staged := c.stage(extraNodes, extraPoints)
+	if err := fn(staged); err != nil || staged.version == c.version {
+		// 失败或者没有修改
+		c.writeMu.Unlock()
+		return err
+	}
+	if staged.sparse() {
+		// 删除了大部分虚拟结点，不再保留原来的容量
+		staged.shrinkToFit()
+	}
+	// 快照也在锁外生成
+	staged.publish()
+
+	c.Lock()
+	c.replaceState(staged)
+	c.pending = append(c.pending, staged.pending...)
+	c.version = staged.version
+	c.snapshot.Store(staged.loadSnapshot())
+	c.unlock()
+	return nil
+}
+
+// 修改环之前先获取 writeMu 再获取写锁，用 unlock 同时释放
+func (c *ConsistentHash) lockWrite() {
+	c.writeMu.Lock()
+	c.Lock()
+}
+
+// 用 staged 的状态替换环的状态，配置保持不变，调用方需持有写锁
+func (c *ConsistentHash) replaceState(staged *ConsistentHash) {
+	c.hashSortedNodes = staged.hashSortedNodes
+	c.circle = staged.circle
+	c.nodes = staged.nodes
+	c.chains = staged.chains
+	c.pins = staged.pins
+	c.reserved = staged.reserved
+}
+
+// 反序列化时根据 key 创建结点，见 WithNodeFactory
+func (c *ConsistentHash) newNode(key string) Node {
+	if c.nodeFactory != nil {
+		return c.nodeFactory(key)
+	}
+	return StringNode(key)
+}
+
+func checkNode(node Node) error {
+	if node == nil {
+		return ErrNilNode
+	}
+	if node.Key() == "" {
+		return ErrEmptyKey
+	}
+	return nil
+}
+
+func (c *ConsistentHash) checkReplicas(count int) error {
+	max := c.maxReplicas
+	if max == 0 {
+		max = defaultMaxReplicas
+	}
+	if count < 1 {
+		return fmt.Errorf("%w %d, can't less 1", ErrInvalidReplicas, count)
+	}
+	if count > max {
+		return fmt.Errorf("%w %d, can't exceed %d", ErrInvalidReplicas, count, max)
+	}
+	return nil
+}
+
+// 添加编号 [from, to) 的虚拟结点并返回它们的 hash，调用方需持有写锁
+func (c *ConsistentHash) addVirtualNodes(nodeKey string, from, to int) ([]uint32, error) {
+	if c.sharesPoints() {
+		return c.addSharedPoints(nodeKey, from, to)
+	}
+
+	// 先在 staged 中生成全部虚拟结点，都成功后再写入环，失败时环保持不变
+	staged := make(map[uint32]string, (to-from)*c.pointsPerReplica())
+	virtualNodes, err := c.stageVirtualNodes(nodeKey, from, to, staged)
+	if err != nil {
+		return nil, err
+	}
+	c.commitVirtualNodes(staged)
+	return virtualNodes, nil
+}
+
+// 生成编号 [from, to) 的虚拟结点并记录到 staged 中，不修改环。
+// 与环上或 staged 中已有的虚拟结点冲突时换一个 probe 重新计算 hash，三次都冲突时从最后一次的位置
+// 顺时针找第一个空位。结果只取决于 (nodeKey, 编号) 和已占用的位置，环未满时总是成功，调用方需持有锁
+func (c *ConsistentHash) stageVirtualNodes(nodeKey string, from, to int, staged map[uint32]string) ([]uint32, error) {
+	if c.ketama {
+		return c.stageKetamaPoints(nodeKey, from, to, staged)
+	}
+
+	occupied := func(k uint32) bool {
+		_, ok := c.circle[k]
+		_, dup := staged[k]
+		return ok || dup
+	}
+	virtualNodes := make([]uint32, 0, to-from)
+	var buf []byte
+	for i := from; i < to; i++ {
+		var k uint32
+		found := false
+		// 前三次与之前的版本相同，已有的环重建时位置不变
+		for j := 0; j < 3 && !found; j++ {
+			buf, k = c.virtualHash(buf, nodeKey, i, j)
+			found = !occupied(k)
+		}
+		if !found {
+			if c.collision == CollisionError || uint64(len(c.circle))+uint64(len(staged)) > math.MaxUint32 {
+				return nil, newNodeError(nodeKey, ErrHashCollision)
+			}
+			for occupied(k) {
+				k++
 			}
 		}
-		if virtualKey == nil {
-			// 重试三次还是冲突
-			return fmt.Errorf("node %s hash collision", node.Key())
+		staged[k] = nodeKey
+		virtualNodes = append(virtualNodes, k)
+	}
+	return virtualNodes, nil
+}
+
+// 虚拟结点的 hash，与 c.hashKey(c.virtualKey(nodeKey, replica, probe)) 相同。
+// 默认的虚拟结点 key 在可以直接对 []byte 计算 hash 时拼接到 buf 中，避免为每个虚拟结点分配字符串
+func (c *ConsistentHash) virtualHash(buf []byte, nodeKey string, replica, probe int) ([]byte, uint32) {
+	bh := c.bytesHashFunc()
+	if bh == nil || c.virtualKeyFunc != nil || c.legacyVirtualKeys {
+		k := c.hashKey(c.virtualKey(nodeKey, replica, probe))
+		if c.stathat {
+			k--
 		}
-		c.circle[*virtualKey] = node.Key()
-		virtualNodes = append(virtualNodes, *virtualKey)
+		return buf, k
 	}
-	c.hashSortedNodes = append(c.hashSortedNodes, virtualNodes...)
-	c.nodes[node.Key()] = consistentNode{node: node, virtualNodes: virtualNodes}
+	buf = append(buf[:0], nodeKey...)
+	buf = append(buf, '#')
+	buf = strconv.AppendInt(buf, int64(replica), 10)
+	buf = append(buf, '#')
+	buf = strconv.AppendInt(buf, int64(probe), 10)
+	return buf, bh(buf)
+}
 
-	//虚拟结点排序
-	sort.Slice(c.hashSortedNodes, func(i, j int) bool {
-		return c.hashSortedNodes[i] < c.hashSortedNodes[j]
-	})
+// 可以直接对 []byte 计算的 hash，没有时返回 nil
+func (c *ConsistentHash) bytesHashFunc() func([]byte) uint32 {
+	switch {
+	case c.ketama:
+		return nil
+	case c.bytesHash != nil:
+		return c.bytesHash
+	case c.hashName == hashCRC32 || c.hash == nil:
+		return crc32.ChecksumIEEE
+	}
+	return nil
+}
+
+// 把 stageVirtualNodes 生成的虚拟结点写入环，只排序一次，调用方需持有写锁
+func (c *ConsistentHash) commitVirtualNodes(staged map[uint32]string) {
+	virtualNodes := make([]uint32, 0, len(staged))
+	for k, nodeKey := range staged {
+		c.circle[k] = nodeKey
+		virtualNodes = append(virtualNodes, k)
+	}
+	c.insertSorted(virtualNodes)
+}
+
+// 第 replica 个虚拟结点第 probe 次尝试时参与 hash 的字符串
+func (c *ConsistentHash) virtualKey(nodeKey string, replica, probe int) string {
+	switch {
+	case c.virtualKeyFunc != nil:
+		if probe == 0 {
+			return c.virtualKeyFunc(nodeKey, replica)
+		}
+		return c.virtualKeyFunc(nodeKey, replica) + "#" + strconv.Itoa(probe)
+	case c.legacyVirtualKeys:
+		// 旧的拼接方式有歧义，例如 "server1" 的第 11 个副本与 "server11" 的第 1 个副本相同
+		return nodeKey + strconv.Itoa(replica) + strconv.Itoa(probe)
+	default:
+		// replica 和 probe 都不含 "#"，从右往左解析总是唯一的
+		return nodeKey + "#" + strconv.Itoa(replica) + "#" + strconv.Itoa(probe)
+	}
+}
+
+// 调用方需持有写锁
+func (c *ConsistentHash) insertSorted(virtualNodes []uint32) {
+	if len(virtualNodes) == 0 {
+		return
+	}
+	// 只对新的虚拟结点排序，再从后往前归并到已排序的数组中，复杂度 O(N + k log k)
+	added := append([]uint32(nil), virtualNodes...)
+	sort.Slice(added, func(i, j int) bool { return added[i] < added[j] })
+
+	n := len(c.hashSortedNodes)
+	c.hashSortedNodes = append(c.hashSortedNodes, added...)
+	sorted := c.hashSortedNodes
+	i, j := n-1, len(added)-1
+	for k := len(sorted) - 1; j >= 0; k-- {
+		if i >= 0 && sorted[i] > added[j] {
+			sorted[k] = sorted[i]
+			i--
+		} else {
+			sorted[k] = added[j]
+			j--
+		}
+	}
+}
+
+// WithSortedCapacity、WithExpectedNodes 提示的虚拟结点数，取较大的一个
+func (c *ConsistentHash) pointCapacity() int {
+	if expected := c.expectedNodes * c.expectedReplicas * c.pointsPerReplica(); expected > c.sortedCapacity {
+		return expected
+	}
+	return c.sortedCapacity
+}
+
+// 每个副本对应的虚拟结点数
+func (c *ConsistentHash) pointsPerReplica() int {
+	if c.ketama {
+		return ketamaPointsPerReplica
+	}
+	return 1
+}
+
+// 从 circle 和 hashSortedNodes 中删除虚拟结点，调用方需持有写锁
+func (c *ConsistentHash) removeVirtualNodes(virtualNodes []uint32) {
+	// Add 方法保证了此处不需要考虑 hash 冲突
+	for _, v := range virtualNodes {
+		delete(c.circle, v)
+	}
 
+	// 对要删除的虚拟结点排序后，一次遍历原地压缩 hashSortedNodes
+	removed := append([]uint32(nil), virtualNodes...)
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	sorted := c.hashSortedNodes[:0]
+	j := 0
+	for _, v := range c.hashSortedNodes {
+		for j < len(removed) && removed[j] < v {
+			j++
+		}
+		if j < len(removed) && removed[j] == v {
+			continue
+		}
+		sorted = append(sorted, v)
+	}
+	c.hashSortedNodes = sorted
+}
+
+// SetVirtualNodeCount 原地调整结点的虚拟结点数，保留下来的虚拟结点位置不变，
+// 增加时按原有规则生成后续编号的虚拟结点，减少时删除编号最大的虚拟结点
+func (c *ConsistentHash) SetVirtualNodeCount(nodeKey string, count int) error {
+	if err := c.checkReplicas(count); err != nil {
+		return err
+	}
+	c.lockWrite()
+	defer c.unlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return newNodeError(nodeKey, ErrNodeNotFound)
+	}
+
+	current := len(cNode.virtualNodes) / c.pointsPerReplica()
+	switch {
+	case count > current:
+		return c.grow(nodeKey, count-current)
+	case count < current:
+		return c.shrink(nodeKey, func(current int) int { return current - count })
+	}
 	return nil
 }
 
 func (c *ConsistentHash) Remove(node Node) error {
-	c.Lock()
-	defer c.Unlock()
-	cNode, ok := c.nodes[node.Key()]
+	if node == nil {
+		return ErrNilNode
+	}
+	_, err := c.remove(node.Key())
+	return err
+}
+
+// RemoveByKey 按结点的 key 删除结点，返回环中保存的结点，即添加时传入的对象
+func (c *ConsistentHash) RemoveByKey(key string) (Node, error) {
+	return c.remove(key)
+}
+
+// ReplaceNode 用 node 替换环中 key 相同的结点，虚拟结点和在线状态不变，已有 key 的归属不会变化。
+// 结点不存在时返回 ErrNodeNotFound
+func (c *ConsistentHash) ReplaceNode(node Node) error {
+	if err := checkNode(node); err != nil {
+		return err
+	}
+	c.lockWrite()
+	defer c.unlock()
+	key := node.Key()
+	cNode, ok := c.nodes[key]
 	if !ok {
-		return fmt.Errorf("node %s not exist", node.Key())
+		return newNodeError(key, ErrNodeNotFound)
 	}
-	delete(c.nodes, node.Key())
+	cNode.node = node
+	c.nodes[key] = cNode
+	c.recordReplaced(node)
+	return nil
+}
 
-	// Add 方法保证了此处不需要考虑 hash 冲突
-	for _, v := range cNode.virtualNodes {
-		delete(c.circle, v)
+func (c *ConsistentHash) remove(key string) (Node, error) {
+	return c.removeIf(key, nil)
+}
+
+// 返回删除的结点。cond 不为 nil 时只在它返回 true 时删除结点，不删除时返回 nil, nil
+func (c *ConsistentHash) removeIf(key string, cond func(consistentNode) bool) (Node, error) {
+	if c.maxTombstones > 0 {
+		return c.removeLazy(key, cond)
 	}
+	var observe func()
+	var removed Node
+	err := c.update(0, 0, func(staged *ConsistentHash) error {
+		n, ok := staged.nodes[key]
+		if ok && cond != nil && !cond(n) {
+			return nil
+		}
+		var fraction float64
+		if staged.metrics != nil {
+			fraction = staged.ownedFraction(key)
+		}
+		if err := staged.unlinkNode(key); err != nil {
+			return err
+		}
+		removed = n.node
+		observe = staged.observeTopology(0, 1, fraction)
+		return nil
+	})
+	if observe != nil {
+		observe()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
 
-	// 二分查找删除
-	for _, v := range cNode.virtualNodes {
-		i := sort.Search(len(c.hashSortedNodes), func(i int) bool {
-			return c.hashSortedNodes[i] >= v
-		})
-		c.hashSortedNodes = append(c.hashSortedNodes[:i], c.hashSortedNodes[i+1:]...)
+// 调用方需持有写锁
+func (c *ConsistentHash) unlinkNode(key string) error {
+	cNode, ok := c.nodes[key]
+	if !ok {
+		return newNodeError(key, ErrNodeNotFound)
 	}
+	delete(c.nodes, key)
+	c.removeVirtualNodes(c.releasePoints(key, cNode.virtualNodes))
+	c.recordRemoved(cNode.node)
 	return nil
 }
 
+// Reset 删除全部结点、PinKey 固定的 key 和 ReserveRange 保留的区间，hash 和其他配置保持不变，之后的行为与新建的环相同
+func (c *ConsistentHash) Reset() {
+	c.lockWrite()
+	defer c.unlock()
+	c.lazyInit()
+	c.recordAllRemoved()
+	c.replaceState(&ConsistentHash{
+		// 保留容量，重新添加结点时不需要再扩容
+		hashSortedNodes: c.hashSortedNodes[:0],
+		circle:          map[uint32]string{},
+		nodes:           map[string]consistentNode{},
+	})
+}
+
 func (c *ConsistentHash) GetNode(key string) (Node, error) {
+	node, _, err := c.GetNodeVersioned(key)
+	return node, err
+}
+
+// GetNodeBytes 与 GetNode(string(key)) 相同，默认的 crc32 和 WithBytesHash 设置的 hash 不需要转换为字符串
+func (c *ConsistentHash) GetNodeBytes(key []byte) (Node, error) {
+	s := c.loadSnapshot()
+	node, err := s.getNodeBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	if s.metrics != nil {
+		s.metrics.ObserveLookup(node.Key())
+	}
+	return node, nil
+}
+
+// GetNodeVersioned 与 GetNode 相同，同时返回查询时环的 Version，调用方可据此判断结果是否过期
+func (c *ConsistentHash) GetNodeVersioned(key string) (Node, uint64, error) {
+	s := c.loadSnapshot()
+	node, err := s.getNode(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	// 快照不持有锁，缓慢的 Metrics 不会阻塞写操作
+	if s.metrics != nil {
+		s.metrics.ObserveLookup(node.Key())
+	}
+	return node, s.version, nil
+}
+
+// GetNodeKey 与 GetNode 相同，只返回结点的 key，不需要取出 Node
+func (c *ConsistentHash) GetNodeKey(key string) (string, error) {
+	s := c.loadSnapshot()
+	n, err := s.find(key)
+	if err != nil {
+		return "", err
+	}
+	s.hit(n)
+	if s.metrics != nil {
+		s.metrics.ObserveLookup(n.key)
+	}
+	return n.key, nil
+}
+
+// GetOwnerByHash 返回 hash 值 h 所属的结点，h 应由环的 hash 算法计算，GetNode(key) 与
+// GetOwnerByHash(hash(key)) 的结果相同
+func (c *ConsistentHash) GetOwnerByHash(h uint32) (Node, error) {
+	s := c.loadSnapshot()
+	if s == nil || s.nodeCount == 0 {
+		return nil, ErrEmptyRing
+	}
+	node, err := s.owner(h)
+	if err != nil {
+		return nil, err
+	}
+	if s.metrics != nil {
+		s.metrics.ObserveLookup(node.Key())
+	}
+	return node, nil
+}
+
+// GetNodeWithPoint 与 GetNode 相同，同时返回 key 落到的虚拟结点，跳过离线结点时为所属结点的虚拟结点
+func (c *ConsistentHash) GetNodeWithPoint(key string) (Node, uint32, error) {
+	r, err := c.Lookup(key)
+	return r.Node, r.Point, err
+}
+
+// LookupResult 记录一次查找的结果，KeyHash 为 key 的 hash，Point 为 key 落到的虚拟结点
+type LookupResult struct {
+	Node    Node
+	Point   uint32
+	KeyHash uint32
+}
+
+// Lookup 与 GetNodeWithPoint 相同，同时返回 key 的 hash，可以据此计算 key 到虚拟结点的距离
+func (c *ConsistentHash) Lookup(key string) (LookupResult, error) {
+	s := c.loadSnapshot()
+	if s == nil || s.nodeCount == 0 {
+		return LookupResult{}, ErrEmptyRing
+	}
+	h := s.hash(key)
+	if err := checkReserved(s.reserved, h); err != nil {
+		return LookupResult{}, err
+	}
+	i, n := s.ownerAt(h)
+	if n == nil {
+		return LookupResult{}, ErrNoHealthyNodes
+	}
+	s.hit(n)
+	if s.metrics != nil {
+		s.metrics.ObserveLookup(n.key)
+	}
+	return LookupResult{Node: n.node, Point: s.points[i], KeyHash: h}, nil
+}
+
+// 与 GetNode 相同但不统计负载和 Metrics，调用方需持有锁
+func (c *ConsistentHash) lookup(key string) (Node, error) {
+	if len(c.nodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+	return c.healthyFrom(c.getPosition(c.hashKey(key)))
+}
+
+// GetN 从 key 所在位置顺时针返回 n 个不同的在线物理结点，第一个即 GetNode 的结果。
+// 环上在线的物理结点不足 n 个时返回 *InsufficientNodesError，WithDegradedReplicas(true) 时返回全部在线结点。
+func (c *ConsistentHash) GetN(key string, n int) ([]Node, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%w %d, can't less 1", ErrInvalidCount, n)
+	}
 	c.RLock()
 	defer c.RUnlock()
 
 	if len(c.nodes) == 0 {
-		return nil, errors.New("node size is 0")
+		return nil, ErrEmptyRing
+	}
+	h := c.hashKey(key)
+	if _, pinned := c.pinnedNode(key); !pinned {
+		if err := checkReserved(c.reserved, h); err != nil {
+			return nil, err
+		}
+	}
+	nodes := c.pinFirst(key, c.walk(c.getPosition(h), n))
+	if err := c.checkReplicaCount(len(nodes), n); err != nil {
+		return nil, err
+	}
+	if c.loadTracking {
+		for _, node := range nodes {
+			c.recordHit(node)
+		}
+	}
+	return nodes, nil
+}
+
+// GetTwo 与 WithDegradedReplicas(true) 时的 GetN(key, 2) 相同，返回 key 所属的结点和顺时针方向下一个不同的在线结点，但不分配内存。
+// 只有一个在线结点时 backup 为 nil
+func (c *ConsistentHash) GetTwo(key string) (primary, backup Node, err error) {
+	s := c.loadSnapshot()
+	if s == nil || s.nodeCount == 0 {
+		return nil, nil, ErrEmptyRing
+	}
+	h := s.hash(key)
+	p := s.pinned(key)
+	if p == nil {
+		if err := checkReserved(s.reserved, h); err != nil {
+			return nil, nil, err
+		}
+	}
+	i, first := s.ownerAt(h)
+	if first == nil {
+		return nil, nil, ErrNoHealthyNodes
+	}
+	if p != nil && p != first {
+		return s.hit(p), s.hit(first), nil
+	}
+	// 从同一个下标开始，CollisionChain 下同一虚拟结点上的其余结点排在前面
+	if _, second := s.next(i, first); second != nil {
+		backup = s.hit(second)
+	}
+	return s.hit(first), backup, nil
+}
+
+// Members 返回按 key 排序的物理结点快照
+func (c *ConsistentHash) Members() []Node {
+	c.RLock()
+	defer c.RUnlock()
+
+	members := make([]Node, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		members = append(members, n.node)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Key() < members[j].Key()
+	})
+	return members
+}
+
+func (c *ConsistentHash) HasNode(key string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	_, ok := c.nodes[key]
+	return ok
+}
+
+func (c *ConsistentHash) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.nodes)
+}
+
+// VirtualNodeCount 返回结点的副本数，即添加时的 virtualNodeCount
+func (c *ConsistentHash) VirtualNodeCount(nodeKey string) (int, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return 0, newNodeError(nodeKey, ErrNodeNotFound)
 	}
-	hash := c.hashKey(key)
-	i := c.getPosition(hash)
+	return len(cNode.virtualNodes) / c.pointsPerReplica(), nil
+}
 
-	return c.nodes[c.circle[c.hashSortedNodes[i]]].node, nil
+// TotalPoints 返回环上虚拟结点的总数，ketama 模式下每个副本对应 4 个虚拟结点
+func (c *ConsistentHash) TotalPoints() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.hashSortedNodes)
 }
 
+// ReplicaCounts 返回每个结点的副本数，与对每个结点调用 VirtualNodeCount 相同，只加一次锁
+func (c *ConsistentHash) ReplicaCounts() map[string]int {
+	c.RLock()
+	defer c.RUnlock()
+	counts := make(map[string]int, len(c.nodes))
+	for k, n := range c.nodes {
+		counts[k] = len(n.virtualNodes) / c.pointsPerReplica()
+	}
+	return counts
+}
+
+// Positions 返回结点的虚拟结点在环上的位置，从小到大排序，返回的是副本，修改它不会影响环。
+// CollisionChain 下包括与其他结点共用的位置
+func (c *ConsistentHash) Positions(nodeKey string) ([]uint32, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return nil, newNodeError(nodeKey, ErrNodeNotFound)
+	}
+	return sortedPositions(cNode.virtualNodes), nil
+}
+
+// AllPositions 返回每个结点的 Positions，只加一次锁
+func (c *ConsistentHash) AllPositions() map[string][]uint32 {
+	c.RLock()
+	defer c.RUnlock()
+	positions := make(map[string][]uint32, len(c.nodes))
+	for k, n := range c.nodes {
+		positions[k] = sortedPositions(n.virtualNodes)
+	}
+	return positions
+}
+
+// virtualNodes 按编号排列，复制后排序
+func sortedPositions(virtualNodes []uint32) []uint32 {
+	positions := append([]uint32(nil), virtualNodes...)
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+	return positions
+}
+
+// 第一个不小于 hash 的虚拟结点，hash 超过最大的虚拟结点时回到环的起点
 func (c *ConsistentHash) getPosition(hash uint32) int {
-	i := sort.Search(len(c.hashSortedNodes), func(i int) bool { return c.hashSortedNodes[i] >= hash })
+	i := searchPoints(c.hashSortedNodes, hash)
+	if i == len(c.hashSortedNodes) {
+		return 0
+	}
+	return i
+}
 
-	if i < len(c.hashSortedNodes) {
-		if i == len(c.hashSortedNodes)-1 {
-			return 0
-		} else {
-			return i
-		}
-	} else {
-		return len(c.hashSortedNodes) - 1
+// hash 所属结点的 key，环为空时返回空，调用方需持有锁
+func (c *ConsistentHash) ownerOf(hash uint32) string {
+	if len(c.hashSortedNodes) == 0 {
+		return ""
+	}
+	return c.circle[c.hashSortedNodes[c.getPosition(hash)]]
+}
+
+// hash 所属的结点，环为空时返回 nil，调用方需持有锁
+func (c *ConsistentHash) ownerNode(hash uint32) Node {
+	if len(c.hashSortedNodes) == 0 {
+		return nil
 	}
+	return c.nodes[c.ownerOf(hash)].node
 }
@@ -0,0 +1,172 @@
+package consistent_hash
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ConsistentHash64 与 ConsistentHash 相同，但 hash 空间为 uint64，
+// 结点和虚拟结点很多时能显著降低虚拟结点之间的冲突概率
+type ConsistentHash64 struct {
+	hashSortedNodes []uint64
+	circle          map[uint64]string
+	nodes           map[string]consistentNode64
+	sync.RWMutex
+	hash func(string) uint64
+}
+
+type consistentNode64 struct {
+	node         Node
+	virtualNodes []uint64
+}
+
+func NewConsistentHash64() *ConsistentHash64 {
+	return NewConsistentWithCustomHash64(defaultHash64)
+}
+
+// NewConsistentWithCustomHash64 使用自定义 hash 创建环，h 为 nil 时使用默认的 fnv-1a
+func NewConsistentWithCustomHash64(h func(key string) uint64) *ConsistentHash64 {
+	if h == nil {
+		h = defaultHash64
+	}
+	return &ConsistentHash64{
+		circle: map[uint64]string{},
+		nodes:  map[string]consistentNode64{},
+		hash:   h,
+	}
+}
+
+func defaultHash64(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func (c *ConsistentHash64) Add(node Node) error {
+	return c.AddWithVirtualNode(node, 1)
+}
+
+func (c *ConsistentHash64) AddWithVirtualNode(node Node, virtualNodeCount int) error {
+	if err := checkNode(node); err != nil {
+		return err
+	}
+
+	if virtualNodeCount < 1 {
+		return ErrInvalidReplicas
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.nodes[node.Key()]; ok {
+		return newNodeError(node.Key(), ErrNodeExists)
+	}
+
+	// 添加虚拟结点
+	virtualNodes := make([]uint64, 0, virtualNodeCount)
+	for i := 0; i < virtualNodeCount; i++ {
+		var virtualKey *uint64
+		for j := 0; j < 3; j++ { // 防止 hash 冲突，重试 3 次
+			k := c.hash(node.Key() + "#" + strconv.Itoa(i) + "#" + strconv.Itoa(j))
+			_, ok := c.circle[k]
+			if !ok {
+				virtualKey = &k
+				break
+			}
+		}
+		if virtualKey == nil {
+			// 重试三次还是冲突
+			for _, v := range virtualNodes {
+				delete(c.circle, v)
+			}
+			return newNodeError(node.Key(), ErrHashCollision)
+		}
+		c.circle[*virtualKey] = node.Key()
+		virtualNodes = append(virtualNodes, *virtualKey)
+	}
+	c.insertSorted(virtualNodes)
+	c.nodes[node.Key()] = consistentNode64{node: node, virtualNodes: virtualNodes}
+	return nil
+}
+
+// 与 ConsistentHash.insertSorted 相同，只对新的虚拟结点排序再归并，调用方需持有写锁
+func (c *ConsistentHash64) insertSorted(virtualNodes []uint64) {
+	added := append([]uint64(nil), virtualNodes...)
+	sort.Slice(added, func(i, j int) bool { return added[i] < added[j] })
+
+	n := len(c.hashSortedNodes)
+	c.hashSortedNodes = append(c.hashSortedNodes, added...)
+	sorted := c.hashSortedNodes
+	i, j := n-1, len(added)-1
+	for k := len(sorted) - 1; j >= 0; k-- {
+		if i >= 0 && sorted[i] > added[j] {
+			sorted[k] = sorted[i]
+			i--
+		} else {
+			sorted[k] = added[j]
+			j--
+		}
+	}
+}
+
+func (c *ConsistentHash64) Remove(node Node) error {
+	if err := checkNode(node); err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	cNode, ok := c.nodes[node.Key()]
+	if !ok {
+		return newNodeError(node.Key(), ErrNodeNotFound)
+	}
+	delete(c.nodes, node.Key())
+
+	for _, v := range cNode.virtualNodes {
+		delete(c.circle, v)
+	}
+
+	// 对要删除的虚拟结点排序后，一次遍历原地压缩 hashSortedNodes
+	removed := append([]uint64(nil), cNode.virtualNodes...)
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	sorted := c.hashSortedNodes[:0]
+	j := 0
+	for _, v := range c.hashSortedNodes {
+		for j < len(removed) && removed[j] < v {
+			j++
+		}
+		if j < len(removed) && removed[j] == v {
+			continue
+		}
+		sorted = append(sorted, v)
+	}
+	c.hashSortedNodes = sorted
+	return nil
+}
+
+func (c *ConsistentHash64) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.nodes)
+}
+
+func (c *ConsistentHash64) GetNode(key string) (Node, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.nodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+	i := c.getPosition(c.hash(key))
+
+	return c.nodes[c.circle[c.hashSortedNodes[i]]].node, nil
+}
+
+// 第一个不小于 hash 的虚拟结点，超过最大的虚拟结点时回到环的起点
+func (c *ConsistentHash64) getPosition(hash uint64) int {
+	i := sort.Search(len(c.hashSortedNodes), func(i int) bool { return c.hashSortedNodes[i] >= hash })
+	if i == len(c.hashSortedNodes) {
+		return 0
+	}
+	return i
+}
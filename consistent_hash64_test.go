@@ -0,0 +1,136 @@
+package consistent_hash
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash64_GetNode(t *testing.T) {
+	// 环: 10(a) 20(b) 30(c)
+	c := NewConsistentWithCustomHash64(func(key string) uint64 {
+		switch key {
+		case "a#0#0":
+			return 10
+		case "b#0#0":
+			return 20
+		case "c#0#0":
+			return 30
+		}
+		h, _ := strconv.ParseUint(key, 10, 64)
+		return h
+	})
+	if _, err := c.GetNode("1"); err == nil {
+		t.Fatal("GetNode on empty ring should fail")
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.Add(testNode{k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"5", "a"},
+		{"10", "a"},
+		{"11", "b"},
+		{"25", "c"},
+		{"30", "c"},
+		{"31", "a"},
+		{"18446744073709551615", "a"},
+	}
+	for _, tt := range tests {
+		n, err := c.GetNode(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Key() != tt.want {
+			t.Errorf("GetNode(%s) = %s, want %s", tt.key, n.Key(), tt.want)
+		}
+	}
+
+	if err := c.Remove(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.GetNode("11"); n.Key() != "c" {
+		t.Errorf("GetNode(11) = %s after removing b, want c", n.Key())
+	}
+	if err := c.Remove(testNode{"b"}); err == nil {
+		t.Error("remove unknown node should fail")
+	}
+}
+
+func TestConsistentHash64_InvalidNode(t *testing.T) {
+	c := NewConsistentHash64()
+	for _, tt := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"add nil", c.Add(nil), ErrNilNode},
+		{"add empty key", c.Add(testNode{""}), ErrEmptyKey},
+		{"remove nil", c.Remove(nil), ErrNilNode},
+		{"remove empty key", c.Remove(testNode{""}), ErrEmptyKey},
+	} {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len = %d, want 0", c.Len())
+	}
+}
+
+func TestConsistentHash64_AddRemove(t *testing.T) {
+	// nil 时使用默认的 hash
+	c := NewConsistentWithCustomHash64(nil)
+	for i := 0; i < 20; i++ {
+		if err := c.AddWithVirtualNode(testNode{"node-" + strconv.Itoa(i)}, 50); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 20; i += 3 {
+		if err := c.Remove(testNode{"node-" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.hashSortedNodes) != len(c.circle) || len(c.circle) != c.Len()*50 {
+		t.Fatalf("ring has %d sorted and %d points for %d nodes", len(c.hashSortedNodes), len(c.circle), c.Len())
+	}
+	for i, p := range c.hashSortedNodes {
+		if i > 0 && c.hashSortedNodes[i-1] >= p {
+			t.Fatal("hashSortedNodes isn't strictly sorted")
+		}
+		if _, ok := c.circle[p]; !ok {
+			t.Fatalf("point %d isn't on the circle", p)
+		}
+	}
+	if n, err := c.GetNode("key"); err != nil || n.Key() != c.circle[c.hashSortedNodes[c.getPosition(defaultHash64("key"))]] {
+		t.Fatalf("GetNode = %v, %v", n, err)
+	}
+}
+
+func TestConsistentHash64_ManyNodes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds 2.5M virtual nodes")
+	}
+	c := NewConsistentHash64()
+	for i := 0; i < 5000; i++ {
+		if err := c.AddWithVirtualNode(testNode{"node-" + strconv.Itoa(i)}, 500); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Len() != 5000 || len(c.circle) != 5000*500 {
+		t.Fatalf("ring has %d nodes and %d points", c.Len(), len(c.circle))
+	}
+	if _, err := c.GetNode("key"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(c.hashSortedNodes); i++ {
+		if c.hashSortedNodes[i-1] >= c.hashSortedNodes[i] {
+			t.Fatal("hashSortedNodes isn't strictly sorted")
+		}
+	}
+}
@@ -1,6 +1,15 @@
 package consistent_hash
 
 import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -12,6 +21,1341 @@ func (t testNode) Key() string {
 	return t.key
 }
 
+// tableHash 优先按表返回 hash，其次把 key 当作十进制数字，便于构造虚拟结点位置已知的环
+func tableHash(table map[string]uint32) func(string) uint32 {
+	return func(key string) uint32 {
+		if h, ok := table[key]; ok {
+			return h
+		}
+		if h, err := strconv.ParseUint(key, 10, 32); err == nil {
+			return uint32(h)
+		}
+		return crc32.ChecksumIEEE([]byte(key))
+	}
+}
+
+func nodeKeys(nodes []Node) []string {
+	keys := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		keys = append(keys, n.Key())
+	}
+	return keys
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func TestConsistentHash_Add(t *testing.T) {
 
 }
+
+func TestConsistentHash_GetN(t *testing.T) {
+	// 环: 10(a) 20(b) 30(a) 40(c)
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 10, "a#1#0": 30, "b#0#0": 20, "c#0#0": 40,
+	}))
+	if err := c.AddWithVirtualNode(testNode{"a"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(testNode{"c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		n    int
+		want []string
+	}{
+		{"25", 1, []string{"a"}},
+		{"25", 2, []string{"a", "c"}},
+		// 越过环顶部后回到起点，跳过已经出现过的 a
+		{"25", 3, []string{"a", "c", "b"}},
+		{"15", 3, []string{"b", "a", "c"}},
+	}
+	for _, tt := range tests {
+		nodes, err := c.GetN(tt.key, tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := nodeKeys(nodes); !equalStrings(got, tt.want) {
+			t.Errorf("GetN(%s, %d) = %v, want %v", tt.key, tt.n, got, tt.want)
+		}
+		first, _ := c.GetNode(tt.key)
+		if first.Key() != nodes[0].Key() {
+			t.Errorf("GetN(%s, %d)[0] = %s, GetNode = %s", tt.key, tt.n, nodes[0].Key(), first.Key())
+		}
+	}
+
+	if _, err := c.GetN("25", 0); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("GetN with n=0 err = %v", err)
+	}
+	if _, err := NewConsistentHash().GetN("25", 1); err == nil {
+		t.Error("GetN on empty ring should fail")
+	}
+}
+
+func TestConsistentHash_GetNInsufficient(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		opts := []Option{WithReplicationFactor(5)}
+		if allow {
+			opts = append(opts, WithDegradedReplicas(true))
+		}
+		c, _ := New(opts...)
+		c.AddAll(batchTestNodes("node-", 4), 10)
+		c.MarkDown("node-3")
+
+		// 下线的结点不计入
+		for name, get := range map[string]func() ([]Node, error){
+			"GetN":        func() ([]Node, error) { return c.GetN("key", 4) },
+			"GetReplicas": func() ([]Node, error) { return c.GetReplicas("key") },
+			"GetNSpread":  func() ([]Node, error) { return c.GetNSpread("key", 4) },
+		} {
+			nodes, err := get()
+			if !allow {
+				var insufficient *InsufficientNodesError
+				if !errors.Is(err, ErrInsufficientNodes) || !errors.As(err, &insufficient) || insufficient.Available != 3 {
+					t.Fatalf("strict %s err = %v", name, err)
+				}
+				continue
+			}
+			if err != nil || len(nodes) != 3 {
+				t.Fatalf("degraded %s = %v, %v", name, nodeKeys(nodes), err)
+			}
+			seen := map[Node]bool{}
+			for _, n := range nodes {
+				if seen[n] || n.Key() == "node-3" {
+					t.Fatalf("degraded %s = %v", name, nodeKeys(nodes))
+				}
+				seen[n] = true
+			}
+		}
+
+		// n 与在线结点数相同时两种模式的结果一样
+		if nodes, err := c.GetN("key", 3); err != nil || len(nodes) != 3 {
+			t.Fatalf("GetN(3) = %v, %v", nodeKeys(nodes), err)
+		}
+		if nodes, err := c.GetNSpread("key", 3); err != nil || len(nodes) != 3 {
+			t.Fatalf("GetNSpread(3) = %v, %v", nodeKeys(nodes), err)
+		}
+		for _, n := range []int{0, -1} {
+			if _, err := c.GetN("key", n); err == nil {
+				t.Fatalf("GetN(%d) should fail", n)
+			}
+			if _, err := c.GetNSpread("key", n); err == nil {
+				t.Fatalf("GetNSpread(%d) should fail", n)
+			}
+		}
+
+		for i := 0; i < 3; i++ {
+			c.MarkDown("node-" + strconv.Itoa(i))
+		}
+		if _, err := c.GetN("key", 2); !errors.Is(err, ErrNoHealthyNodes) {
+			t.Fatalf("GetN with all nodes down err = %v", err)
+		}
+	}
+}
+
+func TestConsistentHash_GetNConsecutivePoints(t *testing.T) {
+	// a 连续占据 10 20 30，b 在 40
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 10, "a#1#0": 20, "a#2#0": 30, "b#0#0": 40,
+	}))
+	if err := c.AddWithVirtualNode(testNode{"a"}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := c.GetN("5", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(nodes); !equalStrings(got, []string{"a", "b"}) {
+		t.Errorf("GetN = %v, want [a b]", got)
+	}
+}
+
+func TestConsistentHash_Members(t *testing.T) {
+	c := NewConsistentHash()
+	if got := c.Members(); len(got) != 0 {
+		t.Fatalf("Members on empty ring = %v", nodeKeys(got))
+	}
+
+	for _, k := range []string{"c", "a", "b"} {
+		if err := c.AddWithVirtualNode(testNode{k}, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Add(testNode{"a"}); err == nil {
+		t.Fatal("add duplicate node should fail")
+	}
+	if got := nodeKeys(c.Members()); !equalStrings(got, []string{"a", "b", "c"}) {
+		t.Errorf("Members = %v, want [a b c]", got)
+	}
+	if c.Len() != 3 {
+		t.Errorf("Len = %d, want 3", c.Len())
+	}
+
+	if err := c.Remove(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(c.Members()); !equalStrings(got, []string{"a", "c"}) {
+		t.Errorf("Members = %v, want [a c]", got)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want 2", c.Len())
+	}
+	if c.HasNode("b") || !c.HasNode("a") || !c.HasNode("c") {
+		t.Error("HasNode doesn't reflect removal")
+	}
+}
+
+func TestConsistentHash_MembersDetached(t *testing.T) {
+	c := NewConsistentHash()
+	for _, k := range []string{"a", "b"} {
+		if err := c.AddWithVirtualNode(testNode{k}, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, _ := c.GetNode("key")
+
+	members := c.Members()
+	members[0] = testNode{"x"}
+	members = append(members[:0], testNode{"y"})
+	_ = members
+
+	if got := nodeKeys(c.Members()); !equalStrings(got, []string{"a", "b"}) {
+		t.Errorf("Members = %v after mutating snapshot", got)
+	}
+	if c.HasNode("x") || c.HasNode("y") {
+		t.Error("mutating snapshot leaked into ring")
+	}
+	if got, _ := c.GetNode("key"); got.Key() != want.Key() {
+		t.Errorf("GetNode = %s after mutating snapshot, want %s", got.Key(), want.Key())
+	}
+}
+
+func TestConsistentHash_AddWithWeight(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.SetWeightBase(10); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key    string
+		weight float64
+		want   int
+	}{
+		{"a", 1, 10},
+		{"b", 2, 20},
+		{"c", 0.25, 3},
+		{"d", 0.01, 1},
+		{"e", 1.04, 10},
+	}
+	for _, tt := range tests {
+		if err := c.AddWithWeight(testNode{tt.key}, tt.weight); err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.VirtualNodeCount(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("weight %v: VirtualNodeCount = %d, want %d", tt.weight, got, tt.want)
+		}
+	}
+
+	for _, w := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if err := c.AddWithWeight(testNode{"x"}, w); err == nil {
+			t.Errorf("AddWithWeight(%v) should fail", w)
+		}
+	}
+	if err := c.SetWeightBase(0); err == nil {
+		t.Error("SetWeightBase(0) should fail")
+	}
+	if _, err := c.VirtualNodeCount("x"); err == nil {
+		t.Error("VirtualNodeCount of unknown node should fail")
+	}
+}
+
+func TestConsistentHash_AddWithWeightDistribution(t *testing.T) {
+	// crc32 对相近的短 key 分布不均，这里用 xxhash 排除 hash 本身的偏差
+	c := NewConsistentHashXX()
+	if err := c.SetWeightBase(200); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithWeight(testNode{"a"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithWeight(testNode{"b"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithWeight(testNode{"c"}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 300000; i++ {
+		n, err := c.GetNode("key-" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[n.Key()]++
+	}
+
+	for _, light := range []string{"a", "c"} {
+		ratio := float64(counts["b"]) / float64(counts[light])
+		if ratio < 1.6 || ratio > 2.4 {
+			t.Errorf("b/%s = %.2f (%v), want about 2", light, ratio, counts)
+		}
+	}
+}
+
+func TestConsistentHash_SetVirtualNodeCount(t *testing.T) {
+	c := NewConsistentHash()
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.AddWithVirtualNode(testNode{k}, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := make([]string, 100000)
+	before := make([]string, len(keys))
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+		n, _ := c.GetNode(keys[i])
+		before[i] = n.Key()
+	}
+
+	if err := c.SetVirtualNodeCount("a", 150); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.VirtualNodeCount("a"); n != 150 {
+		t.Fatalf("VirtualNodeCount = %d, want 150", n)
+	}
+	if len(c.hashSortedNodes) != 350 || len(c.circle) != 350 {
+		t.Fatalf("ring has %d points, want 350", len(c.hashSortedNodes))
+	}
+	moved := 0
+	for i, k := range keys {
+		n, _ := c.GetNode(k)
+		if n.Key() == before[i] {
+			continue
+		}
+		moved++
+		// 只有落在新增虚拟结点区间里的 key 会移动，且只会移到 a 上
+		if n.Key() != "a" {
+			t.Fatalf("key %s moved from %s to %s", k, before[i], n.Key())
+		}
+	}
+	if moved == 0 {
+		t.Error("no key moved to the new points")
+	}
+
+	// 缩回 100 后恢复原有分布
+	if err := c.SetVirtualNodeCount("a", 100); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.hashSortedNodes) != 300 || len(c.circle) != 300 {
+		t.Fatalf("ring has %d points, want 300", len(c.hashSortedNodes))
+	}
+	for i, k := range keys {
+		if n, _ := c.GetNode(k); n.Key() != before[i] {
+			t.Fatalf("key %s owned by %s after shrink, want %s", k, n.Key(), before[i])
+		}
+	}
+
+	if err := c.SetVirtualNodeCount("x", 10); err == nil {
+		t.Error("SetVirtualNodeCount of unknown node should fail")
+	}
+	if err := c.SetVirtualNodeCount("a", 0); err == nil {
+		t.Error("SetVirtualNodeCount with count 0 should fail")
+	}
+}
+
+func TestConsistentHash_GetNodeBeforeAdd(t *testing.T) {
+	rings := map[string]*ConsistentHash{
+		"NewConsistentHash":           NewConsistentHash(),
+		"NewConsistentWithCustomHash": NewConsistentWithCustomHash(func(key string) uint32 { return crc32.ChecksumIEEE([]byte(key)) }),
+		"zero value":                  {},
+	}
+	for name, c := range rings {
+		if _, err := c.GetNode("key"); !errors.Is(err, ErrEmptyRing) {
+			t.Errorf("%s: GetNode = %v, want ErrEmptyRing", name, err)
+		}
+		if _, err := c.GetN("key", 2); !errors.Is(err, ErrEmptyRing) {
+			t.Errorf("%s: GetN = %v, want ErrEmptyRing", name, err)
+		}
+		if c.hashKey("key") != defaultHash("key") && name != "NewConsistentWithCustomHash" {
+			t.Errorf("%s: hashKey doesn't fall back to crc32", name)
+		}
+	}
+}
+
+func TestConsistentHash_GetNodeRacesFirstAdd(t *testing.T) {
+	for _, c := range []*ConsistentHash{NewConsistentHash(), {}} {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					n, err := c.GetNode("key-" + strconv.Itoa(j))
+					if err != nil && !errors.Is(err, ErrEmptyRing) {
+						t.Error(err)
+						return
+					}
+					if err == nil && n.Key() != "a" {
+						t.Errorf("GetNode = %s, want a", n.Key())
+						return
+					}
+				}
+			}()
+		}
+		if err := c.AddWithVirtualNode(testNode{"a"}, 10); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+	}
+}
+
+func TestConsistentHash_GetNodePosition(t *testing.T) {
+	// 环: 10(a) 20(b) 30(c)
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 10, "b#0#0": 20, "c#0#0": 30,
+	}))
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.Add(testNode{k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"smaller than all points", "0", "a"},
+		{"equal to the first point", "10", "a"},
+		{"between points", "15", "b"},
+		{"equal to a middle point", "20", "b"},
+		{"between the last two points", "25", "c"},
+		{"equal to the last point", "30", "c"},
+		{"greater than all points", "31", "a"},
+		{"top of the ring", "4294967295", "a"},
+	}
+	for _, tt := range tests {
+		n, err := c.GetNode(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Key() != tt.want {
+			t.Errorf("%s: GetNode(%s) = %s, want %s", tt.name, tt.key, n.Key(), tt.want)
+		}
+	}
+
+	// 最大的虚拟结点变化时，其余区间的归属不变
+	if err := c.Remove(testNode{"c"}); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"15": "b", "20": "b", "25": "a", "31": "a"} {
+		if n, _ := c.GetNode(key); n.Key() != want {
+			t.Errorf("GetNode(%s) = %s after removing c, want %s", key, n.Key(), want)
+		}
+	}
+}
+
+func TestConsistentHash_AddCollisionProbes(t *testing.T) {
+	// b 的第 2 个副本三次重试都与 a 冲突，顺时针找到下一个空位
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 10, "a#1#0": 11,
+		"b#0#0": 20, "b#1#0": 30,
+		"b#2#0": 10, "b#2#1": 11, "b#2#2": 10,
+		"b#3#0": 40,
+	}))
+	if err := c.AddWithVirtualNode(testNode{"a"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithVirtualNode(testNode{"b"}, 4); err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint32{10, 11, 12, 20, 30, 40}; !reflect.DeepEqual(c.hashSortedNodes, want) {
+		t.Fatalf("hashSortedNodes = %v, want %v", c.hashSortedNodes, want)
+	}
+	if !reflect.DeepEqual(c.nodes["b"].virtualNodes, []uint32{20, 30, 12, 40}) {
+		t.Fatalf("b virtual nodes = %v", c.nodes["b"].virtualNodes)
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一结点的副本之间冲突同样按顺时针找空位，hash 溢出时回到环的起点
+	self := NewConsistentWithCustomHash(func(string) uint32 { return math.MaxUint32 })
+	if err := self.AddWithVirtualNode(testNode{"x"}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint32{0, 1, math.MaxUint32}; !reflect.DeepEqual(self.hashSortedNodes, want) {
+		t.Fatalf("hashSortedNodes = %v, want %v", self.hashSortedNodes, want)
+	}
+}
+
+func TestConsistentHash_DenseRingNoCollisionFailure(t *testing.T) {
+	// 只有 1<<16 个取值的 hash，3 次重试很快就会全部冲突
+	narrow := func(key string) uint32 { return crc32.ChecksumIEEE([]byte(key)) >> 16 }
+	build := func(order []Node) *ConsistentHash {
+		c := NewConsistentWithCustomHash(narrow)
+		for _, n := range order {
+			if err := c.AddWithVirtualNode(n, 2000); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := c.VerifyRing(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	nodes := batchTestNodes("node-", 30)
+	c := build(nodes)
+	if c.TotalPoints() != 60000 {
+		t.Fatalf("TotalPoints = %d", c.TotalPoints())
+	}
+	// 相同的添加顺序得到相同的环
+	if build(nodes).Checksum() != c.Checksum() {
+		t.Fatal("rebuilding the ring gave a different layout")
+	}
+	batch := NewConsistentWithCustomHash(narrow)
+	if err := batch.AddAll(nodes, 2000); err != nil {
+		t.Fatal(err)
+	}
+	if batch.TotalPoints() != 60000 {
+		t.Fatalf("AddAll TotalPoints = %d", batch.TotalPoints())
+	}
+}
+
+func TestConsistentHash_RemoveByKey(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.Remove(nil); !errors.Is(err, ErrNilNode) {
+		t.Errorf("Remove(nil) = %v, want ErrNilNode", err)
+	}
+	if _, err := c.RemoveByKey("x"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("RemoveByKey(x) = %v, want ErrNodeNotFound", err)
+	}
+
+	build := func() *ConsistentHash {
+		c := NewConsistentHash()
+		for _, k := range []string{"a", "b", "c"} {
+			if err := c.AddWithVirtualNode(testNode{k}, 50); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return c
+	}
+	byNode, byKey := build(), build()
+	if err := byNode.Remove(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := byKey.RemoveByKey("b"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(byNode.hashSortedNodes, byKey.hashSortedNodes) || !reflect.DeepEqual(byNode.circle, byKey.circle) {
+		t.Error("Remove and RemoveByKey leave different rings")
+	}
+	if _, err := byKey.RemoveByKey("b"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("second RemoveByKey = %v, want ErrNodeNotFound", err)
+	}
+}
+
+func TestConsistentHash_RemoveByKeyReturnsNode(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		c := NewConsistentHash()
+		if lazy {
+			c, _ = New(WithLazyRemoval(0.5))
+		}
+		added := &testNode{"a"}
+		c.AddWithVirtualNode(added, 50)
+		c.AddWithVirtualNode(testNode{"b"}, 50)
+		n, err := c.RemoveByKey("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, ok := n.(*testNode); !ok || p != added {
+			t.Errorf("lazy=%v: RemoveByKey returned %#v, want the added node", lazy, n)
+		}
+		if n, err := c.RemoveByKey("a"); n != nil || !errors.Is(err, ErrNodeNotFound) {
+			t.Errorf("lazy=%v: second RemoveByKey = %v, %v", lazy, n, err)
+		}
+	}
+}
+
+func TestConsistentHash_AddReported(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.ReserveRange(0, math.MaxUint32/8, "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		got, err := c.AddReported(testNode{k}, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := c.OwnershipFraction(k); got != want || got <= 0 {
+			t.Errorf("AddReported(%s) = %v, OwnershipFraction = %v", k, got, want)
+		}
+	}
+	if f, err := c.AddReported(testNode{"a"}, 50); f != 0 || !errors.Is(err, ErrNodeExists) {
+		t.Errorf("duplicate AddReported = %v, %v", f, err)
+	}
+	if _, err := c.AddReported(nil, 50); !errors.Is(err, ErrNilNode) {
+		t.Errorf("AddReported(nil) = %v", err)
+	}
+}
+
+func TestConsistentHash_Clone(t *testing.T) {
+	c := NewConsistentHashXX()
+	if err := c.AddAll(batchTestNodes("node-", 10), 50); err != nil {
+		t.Fatal(err)
+	}
+	c.Inc("node-1")
+	owners := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		owners[key], _ = c.GetKey(key)
+	}
+	sorted := append([]uint32(nil), c.hashSortedNodes...)
+	points := append([]uint32(nil), c.nodes["node-2"].virtualNodes...)
+
+	clone := c.Clone()
+	for key, owner := range owners {
+		if got, _ := clone.GetKey(key); got != owner {
+			t.Fatalf("clone GetKey(%s) = %s, want %s", key, got, owner)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := clone.RemoveByKey("node-" + strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := clone.AddAll(batchTestNodes("new-", 5), 80); err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.SetVirtualNodeCount("node-5", 200); err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.SetVirtualNodeCount("node-6", 1); err != nil {
+		t.Fatal(err)
+	}
+	clone.Inc("node-7")
+	clone.Done("node-1")
+
+	if !reflect.DeepEqual(c.hashSortedNodes, sorted) || !reflect.DeepEqual(c.nodes["node-2"].virtualNodes, points) {
+		t.Fatal("mutating the clone changed the original's points")
+	}
+	if c.load("node-1") != 1 || c.load("node-7") != 0 || c.totalLoad() != 1 {
+		t.Fatalf("mutating the clone changed the original's loads: node-1 = %d, total = %d", c.load("node-1"), c.totalLoad())
+	}
+	if c.Len() != 10 {
+		t.Fatalf("original Len = %d, want 10", c.Len())
+	}
+	for key, owner := range owners {
+		if got, _ := c.GetKey(key); got != owner {
+			t.Fatalf("GetKey(%s) = %s after mutating the clone, want %s", key, got, owner)
+		}
+	}
+}
+
+func TestConsistentHash_Reset(t *testing.T) {
+	var zero ConsistentHash
+	zero.Reset()
+	if err := zero.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewConsistentHashXX()
+	c.Reset()
+	if _, err := c.GetNode("x"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("GetNode on empty ring = %v", err)
+	}
+
+	nodes := batchTestNodes("node-", 10)
+	if err := c.AddAll(nodes, 50); err != nil {
+		t.Fatal(err)
+	}
+	c.Inc("node-1")
+	sorted := append([]uint32(nil), c.hashSortedNodes...)
+	circle := c.Clone().circle
+
+	c.Reset()
+	if c.Len() != 0 || len(c.hashSortedNodes) != 0 || len(c.circle) != 0 || c.totalLoad() != 0 {
+		t.Fatal("Reset left state behind")
+	}
+	if _, err := c.GetNode("x"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("GetNode after Reset = %v", err)
+	}
+	if err := c.AddAll(nodes, 50); err != nil {
+		t.Fatal(err)
+	}
+	// hash 保持 xxhash，重新添加得到相同的环
+	if !reflect.DeepEqual(c.hashSortedNodes, sorted) || !reflect.DeepEqual(c.circle, circle) {
+		t.Fatal("re-adding after Reset produces a different ring")
+	}
+}
+
+func TestConsistentHash_ResetConcurrentReaders(t *testing.T) {
+	c := NewConsistentHash()
+	nodes := batchTestNodes("node-", 5)
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := c.GetN("key", 2); err != nil && !errors.Is(err, ErrEmptyRing) {
+					t.Error(err)
+					return
+				}
+				c.Members()
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		if err := c.AddAll(nodes, 10); err != nil {
+			t.Fatal(err)
+		}
+		c.Reset()
+	}
+	close(done)
+	wg.Wait()
+}
+
+// 原来的做法：追加之后整体排序
+func insertSortedFullSort(sorted, points []uint32) []uint32 {
+	sorted = append(sorted, points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+func randomPoints(r *rand.Rand, n int, seen map[uint32]bool) []uint32 {
+	points := make([]uint32, 0, n)
+	for len(points) < n {
+		p := r.Uint32()
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		points = append(points, p)
+	}
+	return points
+}
+
+func TestConsistentHash_InsertSortedMatchesFullSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		seen := map[uint32]bool{}
+		existing := randomPoints(r, r.Intn(50), seen)
+		sort.Slice(existing, func(i, j int) bool { return existing[i] < existing[j] })
+		added := randomPoints(r, r.Intn(20), seen)
+
+		c := &ConsistentHash{hashSortedNodes: append([]uint32(nil), existing...)}
+		c.insertSorted(added)
+		want := insertSortedFullSort(append([]uint32(nil), existing...), added)
+		if len(want) == 0 {
+			want = existing
+		}
+		if !reflect.DeepEqual(c.hashSortedNodes, want) {
+			t.Fatalf("round %d: insertSorted(%v, %v) = %v, want %v", round, existing, added, c.hashSortedNodes, want)
+		}
+	}
+}
+
+func benchmarkInsert1M(b *testing.B, insert func(sorted, points []uint32) []uint32) {
+	r := rand.New(rand.NewSource(1))
+	seen := map[uint32]bool{}
+	base := randomPoints(r, 1000000, seen)
+	sort.Slice(base, func(i, j int) bool { return base[i] < base[j] })
+	added := randomPoints(r, 200, seen)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// cap 等于 len，每次都要重新分配，两种做法的拷贝开销相同
+		insert(base[:len(base):len(base)], added)
+	}
+}
+
+// 在 100 万个虚拟结点的环上添加一个 200 个虚拟结点的结点
+func BenchmarkConsistentHash_InsertSorted1M(b *testing.B) {
+	benchmarkInsert1M(b, func(sorted, points []uint32) []uint32 {
+		c := &ConsistentHash{hashSortedNodes: sorted}
+		c.insertSorted(points)
+		return c.hashSortedNodes
+	})
+}
+
+func BenchmarkConsistentHash_InsertFullSort1M(b *testing.B) {
+	benchmarkInsert1M(b, insertSortedFullSort)
+}
+
+// 原来的做法：每个虚拟结点二分查找后拼接
+func removeSortedSplice(sorted, points []uint32) []uint32 {
+	for _, v := range points {
+		i := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= v })
+		sorted = append(sorted[:i], sorted[i+1:]...)
+	}
+	return sorted
+}
+
+func TestConsistentHash_RemoveVirtualNodesMatchesSplice(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		seen := map[uint32]bool{}
+		existing := randomPoints(r, 1+r.Intn(50), seen)
+		sort.Slice(existing, func(i, j int) bool { return existing[i] < existing[j] })
+		var removed []uint32
+		for _, i := range r.Perm(len(existing))[:r.Intn(len(existing))] {
+			removed = append(removed, existing[i])
+		}
+
+		c := &ConsistentHash{hashSortedNodes: append([]uint32(nil), existing...), circle: map[uint32]string{}}
+		c.removeVirtualNodes(removed)
+		want := removeSortedSplice(append([]uint32(nil), existing...), removed)
+		if !reflect.DeepEqual(c.hashSortedNodes, want) {
+			t.Fatalf("round %d: removeVirtualNodes(%v, %v) = %v, want %v", round, existing, removed, c.hashSortedNodes, want)
+		}
+	}
+}
+
+func TestConsistentHash_RemoveOwnerOfLastPoint(t *testing.T) {
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 100, "a#1#0": 300, "b#0#0": 200, "b#1#0": math.MaxUint32,
+	}))
+	c.AddKey("a", 2)
+	c.AddKey("b", 2)
+	if _, err := c.RemoveByKey("b"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.hashSortedNodes, []uint32{100, 300}) {
+		t.Fatalf("hashSortedNodes = %v", c.hashSortedNodes)
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+	// 超过最后一个虚拟结点的 hash 回到环的起点
+	if n, err := c.GetNode("4000000000"); err != nil || n.Key() != "a" {
+		t.Fatalf("GetNode after removing the last point = %v, %v", n, err)
+	}
+}
+
+func benchmarkRemove500k(b *testing.B, remove func(sorted, points []uint32) []uint32) {
+	r := rand.New(rand.NewSource(1))
+	base := randomPoints(r, 500000, map[uint32]bool{})
+	removed := append([]uint32(nil), base[:1000]...)
+	sort.Slice(base, func(i, j int) bool { return base[i] < base[j] })
+	sorted := make([]uint32, len(base))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(sorted, base)
+		remove(sorted, removed)
+	}
+}
+
+// 从 50 万个虚拟结点的环上删除一个 1000 个虚拟结点的结点
+func BenchmarkConsistentHash_RemoveVirtualNodes500k(b *testing.B) {
+	benchmarkRemove500k(b, func(sorted, points []uint32) []uint32 {
+		c := &ConsistentHash{hashSortedNodes: sorted, circle: map[uint32]string{}}
+		c.removeVirtualNodes(points)
+		return c.hashSortedNodes
+	})
+}
+
+func BenchmarkConsistentHash_RemoveSplice500k(b *testing.B) {
+	benchmarkRemove500k(b, removeSortedSplice)
+}
+
+func fnvBytes(key []byte) uint32 {
+	h := uint32(2166136261)
+	for _, b := range key {
+		h ^= uint32(b)
+		h *= 16777619
+	}
+	return h
+}
+
+func TestConsistentHash_GetNodeBytes(t *testing.T) {
+	rings := map[string]*ConsistentHash{
+		"default": NewConsistentHash(),
+		"xxhash":  NewConsistentHashXX(),
+		"ketama":  NewKetama(),
+	}
+	for name, opt := range map[string]Option{
+		"bytes hash":  WithBytesHash(fnvBytes),
+		"string hash": WithHash(xxhash32),
+		"seed":        WithSeed(42),
+	} {
+		c, err := New(opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rings[name] = c
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for name, c := range rings {
+		if _, err := c.GetNodeBytes([]byte("k")); err != ErrEmptyRing {
+			t.Fatalf("%s: empty ring err = %v", name, err)
+		}
+		if err := c.AddAll(batchTestNodes("node-", 10), 20); err != nil {
+			t.Fatal(err)
+		}
+		c.MarkDown("node-4")
+		for i := 0; i < 1000; i++ {
+			key := make([]byte, r.Intn(100))
+			r.Read(key)
+			got, err := c.GetNodeBytes(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := c.GetNode(string(key))
+			if got.Key() != want.Key() {
+				t.Fatalf("%s: GetNodeBytes(%x) = %s, GetNode = %s", name, key, got.Key(), want.Key())
+			}
+		}
+	}
+
+	for _, name := range []string{"default", "bytes hash"} {
+		c := rings[name]
+		key := []byte("some-key")
+		if allocs := testing.AllocsPerRun(100, func() { c.GetNodeBytes(key) }); allocs != 0 {
+			t.Errorf("%s: GetNodeBytes allocs = %v", name, allocs)
+		}
+	}
+	if _, err := New(WithBytesHash(nil)); err == nil {
+		t.Error("WithBytesHash(nil) succeeded")
+	}
+}
+
+func TestConsistentHash_GetNodeKey(t *testing.T) {
+	var zero ConsistentHash
+	if _, err := zero.GetNodeKey("k"); err != ErrEmptyRing {
+		t.Fatalf("zero value err = %v", err)
+	}
+	c := NewConsistentHash()
+	if _, err := c.GetNodeKey("k"); err != ErrEmptyRing {
+		t.Fatalf("empty ring err = %v", err)
+	}
+	if err := c.AddAll(batchTestNodes("node-", 10), 20); err != nil {
+		t.Fatal(err)
+	}
+	c.MarkDown("node-2")
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		key := strconv.FormatUint(r.Uint64(), 36)
+		got, err := c.GetNodeKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := c.GetNode(key)
+		if got != want.Key() {
+			t.Fatalf("GetNodeKey(%q) = %s, GetNode = %s", key, got, want.Key())
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { c.GetNodeKey("some-key") }); allocs != 0 {
+		t.Errorf("GetNodeKey allocs = %v", allocs)
+	}
+	for i := 0; i < 10; i++ {
+		c.MarkDown("node-" + strconv.Itoa(i))
+	}
+	if _, err := c.GetNodeKey("k"); err != ErrNoHealthyNodes {
+		t.Fatalf("all down err = %v", err)
+	}
+}
+
+func BenchmarkConsistentHash_GetNodeKey(b *testing.B) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 100), 100); err != nil {
+		b.Fatal(err)
+	}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	b.Run("GetNode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			n, _ := c.GetNode(keys[i%len(keys)])
+			_ = n.Key()
+		}
+	})
+	b.Run("GetNodeKey", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.GetNodeKey(keys[i%len(keys)])
+		}
+	})
+}
+
+func TestConsistentHash_GetOwnerByHash(t *testing.T) {
+	c := NewConsistentHash()
+	if _, err := c.GetOwnerByHash(0); err != ErrEmptyRing {
+		t.Fatalf("empty ring err = %v", err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 50; round++ {
+		var opts []Option
+		if round%2 == 1 {
+			opts = append(opts, WithSeed(r.Uint64()))
+		}
+		c, _ := New(opts...)
+		nodes := 1 + r.Intn(20)
+		if err := c.AddAll(batchTestNodes("node-", nodes), 1+r.Intn(50)); err != nil {
+			t.Fatal(err)
+		}
+		if nodes > 1 {
+			c.MarkDown("node-" + strconv.Itoa(r.Intn(nodes)))
+		}
+		for i := 0; i < 200; i++ {
+			key := strconv.FormatUint(r.Uint64(), 36)
+			want, _ := c.GetNode(key)
+			got, err := c.GetOwnerByHash(c.hashKey(key))
+			if err != nil || got.Key() != want.Key() {
+				t.Fatalf("GetOwnerByHash(hash(%q)) = %v, %v, GetNode = %s", key, got, err, want.Key())
+			}
+		}
+
+		// 大于最大虚拟结点的 hash 回到第一个虚拟结点
+		sorted := c.hashSortedNodes
+		last := sorted[len(sorted)-1]
+		if last < math.MaxUint32 {
+			wrap, _ := c.GetOwnerByHash(last + 1)
+			first, _ := c.GetOwnerByHash(sorted[0])
+			if wrap.Key() != first.Key() {
+				t.Fatalf("hash after the last point = %s, want %s", wrap.Key(), first.Key())
+			}
+		}
+	}
+}
+
+func TestConsistentHash_GetNodeWithPoint(t *testing.T) {
+	c := NewConsistentHash()
+	if _, _, err := c.GetNodeWithPoint("k"); err != ErrEmptyRing {
+		t.Fatalf("empty ring err = %v", err)
+	}
+	if err := c.AddAll(batchTestNodes("node-", 10), 20); err != nil {
+		t.Fatal(err)
+	}
+	c.MarkDown("node-5")
+
+	isPoint := func(owner string, p uint32) bool {
+		for _, v := range c.nodes[owner].virtualNodes {
+			if v == p {
+				return true
+			}
+		}
+		return false
+	}
+	last := c.hashSortedNodes[len(c.hashSortedNodes)-1]
+	r := rand.New(rand.NewSource(1))
+	check := func(key string) {
+		t.Helper()
+		n, p, err := c.GetNodeWithPoint(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := c.GetNode(key)
+		if n.Key() != want.Key() || !isPoint(n.Key(), p) {
+			t.Fatalf("GetNodeWithPoint(%q) = %s, %d, GetNode = %s", key, n.Key(), p, want.Key())
+		}
+		res, _ := c.Lookup(key)
+		if res.Node != n || res.Point != p || res.KeyHash != c.hashKey(key) {
+			t.Fatalf("Lookup(%q) = %+v", key, res)
+		}
+		if res.KeyHash > last && p > res.KeyHash {
+			t.Fatalf("wrapped key %q landed on %d", key, p)
+		}
+	}
+	for i := 0; i < 2000; i++ {
+		check(strconv.FormatUint(r.Uint64(), 36))
+	}
+	// 找到 hash 大于最大虚拟结点的 key
+	wrapped := 0
+	for i := 0; wrapped < 10 && i < 1000000; i++ {
+		key := "wrap-" + strconv.Itoa(i)
+		if c.hashKey(key) > last {
+			check(key)
+			wrapped++
+		}
+	}
+	if wrapped == 0 {
+		t.Fatal("no key wrapped past the largest point")
+	}
+
+	// 超过最大虚拟结点的 hash 落到第一个在线的虚拟结点
+	for h := last + 1; h > last && h < last+100; h++ {
+		n, _ := c.GetOwnerByHash(h)
+		first, _ := c.GetOwnerByHash(0)
+		if n.Key() != first.Key() {
+			t.Fatalf("hash %d = %s, want %s", h, n.Key(), first.Key())
+		}
+	}
+}
+
+type addrNode struct {
+	key  string
+	addr string
+}
+
+func (n *addrNode) Key() string {
+	return n.key
+}
+
+func TestConsistentHash_ReplaceNode(t *testing.T) {
+	c, _ := New(WithLookupCache(64))
+	for i := 0; i < 5; i++ {
+		k := "node-" + strconv.Itoa(i)
+		if err := c.AddWithVirtualNode(&addrNode{key: k, addr: k + ":80"}, 50); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.MarkDown("node-1")
+	keys := make([]string, 2000)
+	owners := make([]string, len(keys))
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+		owners[i], _ = c.GetNodeKey(keys[i])
+	}
+	checksum := c.Checksum()
+	ch, cancel := c.Watch(4)
+	defer cancel()
+	var callbacks int
+	c.OnNodeAdded(func(Node) { callbacks++ })
+	c.OnNodeRemoved(func(Node) { callbacks++ })
+
+	replaced := &addrNode{key: "node-3", addr: "node-3:8443"}
+	if err := c.ReplaceNode(replaced); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		n, err := c.GetNode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Key() != owners[i] {
+			t.Fatalf("owner of %s changed from %s to %s", key, owners[i], n.Key())
+		}
+		if n.Key() == "node-3" && n != Node(replaced) {
+			t.Fatalf("GetNode(%s) returned the old node", key)
+		}
+	}
+	if c.Checksum() != checksum || c.nodes["node-3"].down || !c.nodes["node-1"].down {
+		t.Fatal("ReplaceNode changed the ring")
+	}
+	if e := <-ch; e.Type != EventReplaced || e.NodeKey != "node-3" || e.Version != c.Version() {
+		t.Fatalf("event = %+v", e)
+	}
+	if callbacks != 0 {
+		t.Fatalf("ReplaceNode triggered %d add/remove callbacks", callbacks)
+	}
+
+	if err := c.ReplaceNode(&addrNode{key: "missing"}); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("unknown key err = %v", err)
+	}
+	if err := c.ReplaceNode(nil); err != ErrNilNode {
+		t.Fatalf("nil node err = %v", err)
+	}
+}
+
+func TestConsistentHash_ReplicaCounts(t *testing.T) {
+	c := NewConsistentHash()
+	if c.TotalPoints() != 0 || len(c.ReplicaCounts()) != 0 {
+		t.Fatal("empty ring has points")
+	}
+	c.Add(testNode{"a"})
+	c.AddWithVirtualNode(testNode{"b"}, 10)
+	c.AddWithVirtualNode(testNode{"c"}, 300)
+	if err := c.ShrinkVirtualNodes("c", 100); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"a": 1, "b": 10, "c": 200}
+	if got := c.ReplicaCounts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReplicaCounts = %v, want %v", got, want)
+	}
+	if c.TotalPoints() != 211 {
+		t.Fatalf("TotalPoints = %d, want 211", c.TotalPoints())
+	}
+
+	counts := c.ReplicaCounts()
+	counts["a"] = 100
+	c.RemoveByKey("b")
+	if n, _ := c.VirtualNodeCount("a"); n != 1 {
+		t.Fatal("ReplicaCounts exposed internal state")
+	}
+	if got := c.ReplicaCounts(); !reflect.DeepEqual(got, map[string]int{"a": 1, "c": 200}) || c.TotalPoints() != 201 {
+		t.Fatalf("after Remove ReplicaCounts = %v, TotalPoints = %d", got, c.TotalPoints())
+	}
+
+	k := NewKetama()
+	k.AddWithVirtualNode(testNode{"a"}, 10)
+	if n, _ := k.VirtualNodeCount("a"); n != 10 || k.TotalPoints() != 40 || k.ReplicaCounts()["a"] != 10 {
+		t.Fatalf("ketama VirtualNodeCount = %d, TotalPoints = %d", n, k.TotalPoints())
+	}
+}
+
+func TestConsistentHash_Positions(t *testing.T) {
+	c := NewConsistentHash()
+	if _, err := c.Positions("a"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Positions of an unknown node err = %v", err)
+	}
+	c.AddAll(batchTestNodes("node-", 4), 50)
+	all := c.AllPositions()
+	if len(all) != 4 {
+		t.Fatalf("AllPositions has %d nodes", len(all))
+	}
+	total := 0
+	for k, positions := range all {
+		got, err := c.Positions(k)
+		if err != nil || !reflect.DeepEqual(got, positions) {
+			t.Fatalf("Positions(%s) = %v, %v, AllPositions has %v", k, got, err, positions)
+		}
+		if !sort.SliceIsSorted(got, func(i, j int) bool { return got[i] < got[j] }) {
+			t.Fatalf("Positions(%s) is not sorted", k)
+		}
+		for _, p := range got {
+			if c.circle[p] != k {
+				t.Fatalf("position %d of %s is owned by %s", p, k, c.circle[p])
+			}
+		}
+		total += len(got)
+	}
+	if total != c.TotalPoints() {
+		t.Fatalf("positions cover %d points, ring has %d", total, c.TotalPoints())
+	}
+
+	// 删除后重新添加，位置不变
+	before, _ := c.Positions("node-1")
+	c.RemoveByKey("node-1")
+	c.AddWithVirtualNode(testNode{"node-1"}, 50)
+	if after, _ := c.Positions("node-1"); !reflect.DeepEqual(before, after) {
+		t.Fatal("positions changed after Remove and re-Add")
+	}
+
+	owners := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		owners[key], _ = c.GetKey(key)
+	}
+	for _, positions := range c.AllPositions() {
+		for i := range positions {
+			positions[i] = 0
+		}
+	}
+	before[0]++
+	for key, owner := range owners {
+		if got, _ := c.GetKey(key); got != owner {
+			t.Fatalf("GetKey(%s) = %s after mutating positions, want %s", key, got, owner)
+		}
+	}
+	if got, _ := c.Positions("node-1"); got[0] != before[0]-1 || got[1] == 0 {
+		t.Fatal("mutating the returned slices changed the ring")
+	}
+}
+
+func TestConsistentHash_GetTwo(t *testing.T) {
+	c := NewConsistentHash()
+	if _, _, err := c.GetTwo("k"); err != ErrEmptyRing {
+		t.Fatalf("empty ring err = %v", err)
+	}
+
+	chain := collisionRing(t, CollisionChain)
+	chain.AddAll([]Node{testNode{"b"}, testNode{"c"}, testNode{"d"}}, 1)
+	rings := map[string]*ConsistentHash{"chain": chain}
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 3, 10, 50} {
+		for _, down := range []int{0, 1, 2} {
+			if down >= n {
+				continue
+			}
+			c, _ := New(WithSeed(r.Uint64()))
+			c.AddAll(batchTestNodes("node-", n), 1+r.Intn(50))
+			for i := 0; i < down; i++ {
+				c.MarkDown("node-" + strconv.Itoa(i))
+			}
+			rings[fmt.Sprintf("%d nodes %d down", n, down)] = c
+		}
+	}
+	for name, c := range rings {
+		for _, key := range []string{"5", "15", "55"} {
+			checkGetTwo(t, name, c, key)
+		}
+		for i := 0; i < 2000; i++ {
+			checkGetTwo(t, name, c, "key-"+strconv.Itoa(i))
+		}
+	}
+
+	chain.MarkDown("a")
+	checkGetTwo(t, "chain with a down", chain, "5")
+	for _, k := range []string{"a", "b", "c", "d"} {
+		chain.MarkDown(k)
+	}
+	if _, _, err := chain.GetTwo("5"); err != ErrNoHealthyNodes {
+		t.Fatalf("all nodes down err = %v", err)
+	}
+}
+
+func checkGetTwo(t *testing.T, name string, c *ConsistentHash, key string) {
+	t.Helper()
+	primary, backup, err := c.GetTwo(key)
+	if err != nil {
+		t.Fatalf("%s: GetTwo(%s) err = %v", name, key, err)
+	}
+	want, err := c.GetN(key, 2)
+	if errors.Is(err, ErrInsufficientNodes) {
+		want, _ = c.GetN(key, 1)
+	}
+	got := []Node{primary}
+	if backup != nil {
+		got = append(got, backup)
+	}
+	if !equalStrings(nodeKeys(got), nodeKeys(want)) {
+		t.Fatalf("%s: GetTwo(%s) = %v, GetN = %v", name, key, nodeKeys(got), nodeKeys(want))
+	}
+}
+
+func BenchmarkConsistentHash_GetTwo(b *testing.B) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 100), 100); err != nil {
+		b.Fatal(err)
+	}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	b.Run("GetN", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.GetN(keys[i%len(keys)], 2)
+		}
+	})
+	b.Run("GetTwo", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.GetTwo(keys[i%len(keys)])
+		}
+	})
+}
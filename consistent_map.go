@@ -0,0 +1,138 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ConsistentMap 每个分片的虚拟结点数
+const mapShardReplicas = 100
+
+// ConsistentMap 把 key→value 分散到多个分片，分片是环上的结点，每个分片有独立的锁，可以并发使用。
+// Resize 只迁移所属分片发生变化的 key
+type ConsistentMap struct {
+	// 写锁只在 Resize 时持有
+	mu     sync.RWMutex
+	ring   *ConsistentHash
+	shards []*mapShard
+}
+
+type mapShard struct {
+	key string
+
+	mu sync.Mutex
+	m  map[string]interface{}
+}
+
+func (s *mapShard) Key() string {
+	return s.key
+}
+
+// NewConsistentMap 创建有 buckets 个分片的 ConsistentMap，opts 用于分片所在的环
+func NewConsistentMap(buckets int, opts ...Option) (*ConsistentMap, error) {
+	ring, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	cm := &ConsistentMap{ring: ring}
+	if err := cm.resize(buckets); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// 调用方需持有 cm.mu 的锁
+func (cm *ConsistentMap) shard(key string) *mapShard {
+	n, _ := cm.ring.GetNode(key)
+	return n.(*mapShard)
+}
+
+func (cm *ConsistentMap) Set(key string, v interface{}) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	s := cm.shard(key)
+	s.mu.Lock()
+	s.m[key] = v
+	s.mu.Unlock()
+}
+
+func (cm *ConsistentMap) Get(key string) (interface{}, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	s := cm.shard(key)
+	s.mu.Lock()
+	v, ok := s.m[key]
+	s.mu.Unlock()
+	return v, ok
+}
+
+func (cm *ConsistentMap) Delete(key string) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	s := cm.shard(key)
+	s.mu.Lock()
+	delete(s.m, key)
+	s.mu.Unlock()
+}
+
+// Len 返回全部分片的 key 数
+func (cm *ConsistentMap) Len() int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	n := 0
+	for _, s := range cm.shards {
+		s.mu.Lock()
+		n += len(s.m)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Buckets 返回分片数
+func (cm *ConsistentMap) Buckets() int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return len(cm.shards)
+}
+
+// Resize 把分片数调整为 buckets，只有所属分片变化的 key 会被迁移。
+// 迁移期间阻塞其他操作
+func (cm *ConsistentMap) Resize(buckets int) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.resize(buckets)
+}
+
+// 调用方需持有 cm.mu 的写锁
+func (cm *ConsistentMap) resize(buckets int) error {
+	if buckets < 1 {
+		return fmt.Errorf("buckets %d can't less 1", buckets)
+	}
+	old := cm.shards
+	shards := make([]*mapShard, buckets)
+	desired := make([]Node, buckets)
+	for i := range shards {
+		if i < len(old) {
+			shards[i] = old[i]
+		} else {
+			shards[i] = &mapShard{key: "shard-" + strconv.Itoa(i), m: map[string]interface{}{}}
+		}
+		desired[i] = shards[i]
+	}
+	if _, _, err := cm.ring.Sync(desired, mapShardReplicas); err != nil {
+		return err
+	}
+	cm.shards = shards
+
+	// 已持有写锁，没有其他操作访问分片
+	for _, s := range old {
+		for k, v := range s.m {
+			if to := cm.shard(k); to != s {
+				to.m[k] = v
+				delete(s.m, k)
+			}
+		}
+	}
+	return nil
+}
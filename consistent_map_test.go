@@ -0,0 +1,123 @@
+package consistent_hash
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConsistentMap(t *testing.T) {
+	cm, err := NewConsistentMap(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.Set("a", 1)
+	cm.Set("b", "two")
+	cm.Set("a", 3)
+	if v, ok := cm.Get("a"); !ok || v != 3 {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+	if cm.Len() != 2 {
+		t.Fatalf("Len = %d", cm.Len())
+	}
+	cm.Delete("a")
+	if _, ok := cm.Get("a"); ok || cm.Len() != 1 {
+		t.Fatal("Delete did not remove the key")
+	}
+
+	if _, err := NewConsistentMap(0); err == nil {
+		t.Fatal("0 buckets accepted")
+	}
+	if err := cm.Resize(0); err == nil || cm.Buckets() != 4 {
+		t.Fatal("invalid Resize changed the map")
+	}
+}
+
+// 记录每个 key 所在的分片
+func mapOwners(cm *ConsistentMap) map[string]string {
+	owners := map[string]string{}
+	for _, s := range cm.shards {
+		for k := range s.m {
+			owners[k] = s.key
+		}
+	}
+	return owners
+}
+
+func TestConsistentMap_Resize(t *testing.T) {
+	cm, _ := NewConsistentMap(8)
+	const n = 20000
+	for i := 0; i < n; i++ {
+		cm.Set("key-"+strconv.Itoa(i), i)
+	}
+
+	for _, tt := range []struct {
+		buckets int
+		// 迁移的比例约为 |新旧分片数之差| / 较大的分片数
+		want float64
+	}{
+		{10, 2.0 / 10},
+		{5, 5.0 / 10},
+		{5, 0},
+	} {
+		before := mapOwners(cm)
+		if err := cm.Resize(tt.buckets); err != nil {
+			t.Fatal(err)
+		}
+		after := mapOwners(cm)
+		if len(after) != n || cm.Len() != n || cm.Buckets() != tt.buckets {
+			t.Fatalf("Resize(%d) lost keys: %d", tt.buckets, len(after))
+		}
+		moved := 0
+		for k, s := range after {
+			if before[k] != s {
+				moved++
+			}
+			if cm.shard(k).key != s {
+				t.Fatalf("key %s is stored in %s, ring routes it elsewhere", k, s)
+			}
+		}
+		if got := float64(moved) / n; got < tt.want*0.7 || got > tt.want*1.3+0.001 {
+			t.Errorf("Resize(%d) moved %.3f of keys, want about %.3f", tt.buckets, got, tt.want)
+		}
+		for i := 0; i < n; i += 997 {
+			if v, ok := cm.Get("key-" + strconv.Itoa(i)); !ok || v != i {
+				t.Fatalf("Get after Resize(%d) = %v, %v", tt.buckets, v, ok)
+			}
+		}
+	}
+}
+
+func TestConsistentMap_Concurrent(t *testing.T) {
+	cm, _ := NewConsistentMap(4)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := strconv.Itoa(g) + "-" + strconv.Itoa(i)
+				cm.Set(key, i)
+				if v, ok := cm.Get(key); !ok || v != i {
+					t.Errorf("Get(%s) = %v, %v", key, v, ok)
+					return
+				}
+				if i%2 == 0 {
+					cm.Delete(key)
+				}
+				cm.Len()
+			}
+		}(g)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			cm.Resize(2 + i%6)
+		}
+	}()
+	wg.Wait()
+	if cm.Len() != 8*250 {
+		t.Fatalf("Len = %d, want %d", cm.Len(), 8*250)
+	}
+}
@@ -0,0 +1,17 @@
+package consistent_hash
+
+import "hash/crc32"
+
+// 短 key 直接查表计算 crc32，避免 []byte(key) 在每次查找时分配内存
+const shortKeyLen = 64
+
+func defaultHash(key string) uint32 {
+	if len(key) > shortKeyLen {
+		return crc32.ChecksumIEEE([]byte(key))
+	}
+	crc := ^uint32(0)
+	for i := 0; i < len(key); i++ {
+		crc = crc32.IEEETable[byte(crc)^key[i]] ^ (crc >> 8)
+	}
+	return ^crc
+}
@@ -0,0 +1,19 @@
+package consistent_hash
+
+import (
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestDefaultHash(t *testing.T) {
+	for _, n := range []int{0, 1, 7, shortKeyLen, shortKeyLen + 1, 200} {
+		key := strings.Repeat("k", n)
+		if got, want := defaultHash(key), crc32.ChecksumIEEE([]byte(key)); got != want {
+			t.Errorf("defaultHash(%d bytes) = %d, want %d", n, got, want)
+		}
+	}
+	if allocs := testing.AllocsPerRun(10, func() { defaultHash("node-1#42") }); allocs != 0 {
+		t.Errorf("defaultHash allocs = %v", allocs)
+	}
+}
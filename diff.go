@@ -0,0 +1,82 @@
+package consistent_hash
+
+import "sort"
+
+// RingDiff 是 Diff 的结果，结点按 key 排序
+type RingDiff struct {
+	// 只在调用 Diff 的环中的结点
+	OnlyInThis []string
+	// 只在参数 other 中的结点
+	OnlyInOther []string
+	// 两个环中都有但拥有的虚拟结点不同的结点
+	Changed []NodeDiff
+	// 三个列表都为空
+	Equal bool
+}
+
+// NodeDiff 记录同一个结点在两个环中拥有的虚拟结点的差别，按 hash 从小到大排序
+type NodeDiff struct {
+	Key         string
+	OnlyInThis  []uint32
+	OnlyInOther []uint32
+}
+
+// Diff 比较两个环的结点和每个结点拥有的虚拟结点，用于排查两个副本的路由不一致。
+// 与 Checksum 一样不比较结点的上下线状态。两个环按地址顺序加读锁，c 和 other 可以是同一个环
+func (c *ConsistentHash) Diff(other *ConsistentHash) RingDiff {
+	unlock := rlockBoth(c, other)
+	defer unlock()
+
+	var d RingDiff
+	these, others := c.pointsByNode(), other.pointsByNode()
+	for k := range c.nodes {
+		if _, ok := other.nodes[k]; !ok {
+			d.OnlyInThis = append(d.OnlyInThis, k)
+			continue
+		}
+		if nd := diffPoints(these[k], others[k]); nd.OnlyInThis != nil || nd.OnlyInOther != nil {
+			nd.Key = k
+			d.Changed = append(d.Changed, nd)
+		}
+	}
+	for k := range other.nodes {
+		if _, ok := c.nodes[k]; !ok {
+			d.OnlyInOther = append(d.OnlyInOther, k)
+		}
+	}
+	sort.Strings(d.OnlyInThis)
+	sort.Strings(d.OnlyInOther)
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Key < d.Changed[j].Key })
+	d.Equal = len(d.OnlyInThis) == 0 && len(d.OnlyInOther) == 0 && len(d.Changed) == 0
+	return d
+}
+
+// 按 circle 中的归属分组的虚拟结点，CollisionOverwrite 下被覆盖的虚拟结点属于覆盖它的结点。调用方需持有锁
+func (c *ConsistentHash) pointsByNode() map[string][]uint32 {
+	points := make(map[string][]uint32, len(c.nodes))
+	for _, p := range c.hashSortedNodes {
+		owner := c.circle[p]
+		points[owner] = append(points[owner], p)
+	}
+	return points
+}
+
+// 比较两个有序数组
+func diffPoints(a, b []uint32) NodeDiff {
+	var d NodeDiff
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			d.OnlyInThis = append(d.OnlyInThis, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			d.OnlyInOther = append(d.OnlyInOther, b[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return d
+}
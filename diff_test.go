@@ -0,0 +1,64 @@
+package consistent_hash
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConsistentHash_Diff(t *testing.T) {
+	newRing := func(replicas map[string]int) *ConsistentHash {
+		c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+			"a#0#0": 100, "a#1#0": 300, "a#2#0": 500,
+			"b#0#0": 200, "b#1#0": 400,
+			"c#0#0": 600,
+		}))
+		for _, k := range []string{"a", "b", "c"} {
+			if n, ok := replicas[k]; ok {
+				c.AddKey(k, n)
+			}
+		}
+		return c
+	}
+
+	base := newRing(map[string]int{"a": 2, "b": 2, "c": 1})
+	tests := []struct {
+		name  string
+		other *ConsistentHash
+		want  RingDiff
+	}{
+		{"identical", newRing(map[string]int{"c": 1, "b": 2, "a": 2}), RingDiff{Equal: true}},
+		{"self", base, RingDiff{Equal: true}},
+		{"missing node", newRing(map[string]int{"a": 2, "b": 2}), RingDiff{OnlyInThis: []string{"c"}}},
+		{"replica counts", newRing(map[string]int{"a": 3, "b": 1, "c": 1}), RingDiff{Changed: []NodeDiff{
+			{Key: "a", OnlyInOther: []uint32{500}},
+			{Key: "b", OnlyInThis: []uint32{400}},
+		}}},
+	}
+	for _, tt := range tests {
+		if got := base.Diff(tt.other); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Diff = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// 反过来比较时两边交换
+	got := newRing(map[string]int{"a": 2, "b": 2}).Diff(base)
+	if !reflect.DeepEqual(got, RingDiff{OnlyInOther: []string{"c"}}) {
+		t.Errorf("reversed Diff = %+v", got)
+	}
+
+	// 下线状态不影响比较
+	down := base.Clone()
+	down.MarkDown("a")
+	if d := base.Diff(down); !d.Equal {
+		t.Errorf("Diff with a down node = %+v", d)
+	}
+
+	// 虚拟结点的生成方式不同
+	x, _ := New(WithSeed(1))
+	y, _ := New(WithSeed(2))
+	x.AddAll(batchTestNodes("node-", 3), 5)
+	y.AddAll(batchTestNodes("node-", 3), 5)
+	if d := x.Diff(y); d.Equal || len(d.Changed) != 3 || len(d.Changed[0].OnlyInThis) != 5 {
+		t.Errorf("Diff with different seeds = %+v", d)
+	}
+}
@@ -0,0 +1,48 @@
+package consistent_hash
+
+import "fmt"
+
+// ShrinkVirtualNodes 删除结点编号最大的 removeCount 个副本，顺序与 SetVirtualNodeCount 相同，
+// 重复执行得到相同的结果。副本全部删除时结点也从环上删除
+func (c *ConsistentHash) ShrinkVirtualNodes(nodeKey string, removeCount int) error {
+	if removeCount < 1 {
+		return fmt.Errorf("%w %d, can't less 1", ErrInvalidReplicas, removeCount)
+	}
+	c.lockWrite()
+	defer c.unlock()
+	return c.shrink(nodeKey, func(int) int { return removeCount })
+}
+
+// Drain 是逐步删除结点的一步：删除当前副本数的 1/steps (向上取整)，steps 为剩余的步数。
+// 按 steps、steps-1、…、1 依次调用 (例如由定时器触发) 即可在 steps 次内均匀地迁走结点的 key，
+// 最后一步删除结点
+func (c *ConsistentHash) Drain(nodeKey string, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps %d can't less 1", steps)
+	}
+	c.lockWrite()
+	defer c.unlock()
+	return c.shrink(nodeKey, func(current int) int {
+		return (current + steps - 1) / steps
+	})
+}
+
+// 调用方需持有写锁
+func (c *ConsistentHash) shrink(nodeKey string, removeCount func(current int) int) error {
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return newNodeError(nodeKey, ErrNodeNotFound)
+	}
+	current := len(cNode.virtualNodes) / c.pointsPerReplica()
+	keep := current - removeCount(current)
+	if keep <= 0 {
+		return c.unlinkNode(nodeKey)
+	}
+
+	keep *= c.pointsPerReplica()
+	c.removeVirtualNodes(c.releasePoints(nodeKey, cNode.virtualNodes[keep:]))
+	cNode.virtualNodes = append([]uint32(nil), cNode.virtualNodes[:keep]...)
+	c.nodes[nodeKey] = cNode
+	c.version++
+	return nil
+}
@@ -0,0 +1,97 @@
+package consistent_hash
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_Drain(t *testing.T) {
+	c := NewConsistentHashXX()
+	if err := c.AddAll(batchTestNodes("node-", 5), 40); err != nil {
+		t.Fatal(err)
+	}
+	removed := c.Clone()
+	if _, err := removed.RemoveByKey("node-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, 5000)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	// 每一步剩余的副本数
+	for steps, want := range []int{32, 24, 16, 8, 0} {
+		before := c.Clone()
+		points := before.nodes["node-2"].virtualNodes
+		if err := c.Drain("node-2", 5-steps); err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := c.VirtualNodeCount("node-2"); n != want {
+			t.Fatalf("step %d: %d replicas left, want %d", steps, n, want)
+		}
+
+		dropped := make(map[uint32]bool)
+		for _, p := range points[want:] {
+			dropped[p] = true
+		}
+		for _, key := range keys {
+			oldOwner, _ := before.GetKey(key)
+			newOwner, _ := c.GetKey(key)
+			// 只有落在被删除的虚拟结点上的 key 会迁移
+			point := before.hashSortedNodes[before.getPosition(before.hashKey(key))]
+			if oldOwner != newOwner && !dropped[point] {
+				t.Fatalf("step %d: key %s moved %s -> %s", steps, key, oldOwner, newOwner)
+			}
+		}
+	}
+
+	if c.HasNode("node-2") {
+		t.Fatal("node still exists after the last Drain step")
+	}
+	if !reflect.DeepEqual(c.hashSortedNodes, removed.hashSortedNodes) || !reflect.DeepEqual(c.circle, removed.circle) {
+		t.Fatal("draining leaves a different ring than Remove")
+	}
+	if err := c.Drain("node-2", 1); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Drain of a removed node = %v", err)
+	}
+	if err := c.Drain("node-1", 0); err == nil {
+		t.Fatal("Drain with 0 steps should fail")
+	}
+}
+
+func TestConsistentHash_ShrinkVirtualNodes(t *testing.T) {
+	a, b := NewConsistentHash(), NewConsistentHash()
+	for _, c := range []*ConsistentHash{a, b} {
+		if err := c.AddKey("a", 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddKey("b", 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.ShrinkVirtualNodes("a", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetVirtualNodeCount("a", 7); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.hashSortedNodes, b.hashSortedNodes) {
+		t.Fatal("ShrinkVirtualNodes and SetVirtualNodeCount remove different points")
+	}
+
+	if err := a.ShrinkVirtualNodes("a", 0); !errors.Is(err, ErrInvalidReplicas) {
+		t.Fatalf("ShrinkVirtualNodes(0) = %v", err)
+	}
+	if err := a.ShrinkVirtualNodes("x", 1); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("ShrinkVirtualNodes(x) = %v", err)
+	}
+	// 超过剩余副本数时删除结点
+	if err := a.ShrinkVirtualNodes("a", 100); err != nil {
+		t.Fatal(err)
+	}
+	if a.HasNode("a") || len(a.hashSortedNodes) != 10 {
+		t.Fatalf("node a left %d points", len(a.hashSortedNodes)-10)
+	}
+}
@@ -0,0 +1,79 @@
+package consistent_hash
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// String 返回环的摘要：结点数、虚拟结点数和每个结点拥有的 hash 空间比例，结点按 key 排序
+func (c *ConsistentHash) String() string {
+	stats := c.Stats()
+	var b strings.Builder
+	fmt.Fprintf(&b, "ConsistentHash(%d nodes, %d points", stats.Nodes, stats.VirtualPoints)
+	for i, k := range sortedShareKeys(stats.Shares) {
+		sep := ", "
+		if i == 0 {
+			sep = ": "
+		}
+		fmt.Fprintf(&b, "%s%s %.2f%%", sep, k, stats.Shares[k]*100)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// Dump 把环的内容写入 w，先按 key 顺序写每个结点的虚拟结点数和比例。
+// verbose 为 true 时再按环上的顺序写所有虚拟结点，同一结点的相邻虚拟结点合并为一行。
+// 输出是确定的，可以直接比较两次 Dump 的结果。写入期间持有读锁
+func (c *ConsistentHash) Dump(w io.Writer, verbose bool) error {
+	c.RLock()
+	defer c.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "nodes %d, points %d\n", len(c.nodes), len(c.hashSortedNodes))
+
+	shares := make(map[string]float64, len(c.nodes))
+	for k := range c.nodes {
+		shares[k] = 0
+	}
+	for i, p := range c.hashSortedNodes {
+		shares[c.circle[p]] += c.pointWidth(i) / (1 << 32)
+	}
+	for _, k := range sortedShareKeys(shares) {
+		cNode := c.nodes[k]
+		fmt.Fprintf(bw, "%s %d points %.2f%%", k, len(cNode.virtualNodes), shares[k]*100)
+		if cNode.down {
+			bw.WriteString(" down")
+		}
+		bw.WriteString("\n")
+	}
+
+	if verbose {
+		points := c.hashSortedNodes
+		for i := 0; i < len(points); {
+			owner := c.circle[points[i]]
+			j := i + 1
+			for j < len(points) && c.circle[points[j]] == owner {
+				j++
+			}
+			if j-i == 1 {
+				fmt.Fprintf(bw, "%d %s\n", points[i], owner)
+			} else {
+				fmt.Fprintf(bw, "%d-%d %s (%d points)\n", points[i], points[j-1], owner, j-i)
+			}
+			i = j
+		}
+	}
+	return bw.Flush()
+}
+
+func sortedShareKeys(shares map[string]float64) []string {
+	keys := make([]string, 0, len(shares))
+	for k := range shares {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package consistent_hash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func dumpTestRing(t *testing.T) *ConsistentHash {
+	t.Helper()
+	const quarter = 1 << 30
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": quarter - 1, "a#1#0": 2*quarter - 1, "a#2#0": 2 * quarter,
+		"b#0#0": 3*quarter - 1, "c#0#0": 3 * quarter,
+	}))
+	for _, n := range []struct {
+		key      string
+		replicas int
+	}{{"b", 1}, {"c", 1}, {"a", 3}} {
+		if err := c.AddKey(n.key, n.replicas); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestConsistentHash_String(t *testing.T) {
+	want := "ConsistentHash(3 nodes, 5 points: a 75.00%, b 25.00%, c 0.00%)"
+	if got := dumpTestRing(t).String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := NewConsistentHash().String(); got != "ConsistentHash(0 nodes, 0 points)" {
+		t.Errorf("empty String() = %q", got)
+	}
+}
+
+func TestConsistentHash_Dump(t *testing.T) {
+	c := dumpTestRing(t)
+	c.MarkDown("c")
+
+	var buf bytes.Buffer
+	if err := c.Dump(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	summary := "nodes 3, points 5\n" +
+		"a 3 points 75.00%\n" +
+		"b 1 points 25.00%\n" +
+		"c 1 points 0.00% down\n"
+	if buf.String() != summary {
+		t.Errorf("Dump(false) =\n%s\nwant\n%s", buf.String(), summary)
+	}
+
+	buf.Reset()
+	if err := c.Dump(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	verbose := summary +
+		"1073741823-2147483648 a (3 points)\n" +
+		"3221225471 b\n" +
+		"3221225472 c\n"
+	if buf.String() != verbose {
+		t.Errorf("Dump(true) =\n%s\nwant\n%s", buf.String(), verbose)
+	}
+
+	// 同样的环两次 Dump 的结果相同
+	var again bytes.Buffer
+	c.Dump(&again, true)
+	if again.String() != verbose {
+		t.Error("Dump is not deterministic")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestConsistentHash_DumpWriteError(t *testing.T) {
+	if err := dumpTestRing(t).Dump(failingWriter{}, true); err == nil {
+		t.Fatal("Dump ignored the write error")
+	}
+}
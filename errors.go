@@ -0,0 +1,74 @@
+package consistent_hash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrEmptyRing       = errors.New("node size is 0")
+	ErrNilNode         = errors.New("node is nil")
+	ErrInvalidReplicas = errors.New("invalid virtualNodeCount")
+	ErrEmptyKey        = errors.New("node key is empty")
+	ErrNodeExists      = errors.New("node already existed")
+	ErrNodeNotFound    = errors.New("node not exist")
+	ErrHashCollision   = errors.New("node hash collision")
+	ErrCorruptData     = errors.New("corrupt ring data")
+	ErrHashMismatch    = errors.New("hash algorithm mismatch")
+	ErrNoNeighbor      = errors.New("node has no neighbor")
+	ErrAllExcluded     = errors.New("all nodes are excluded")
+	ErrNoHealthyNodes  = errors.New("no healthy nodes")
+	ErrInconsistent    = errors.New("ring is inconsistent")
+	ErrInvalidWeight   = errors.New("node weight must be greater than 0")
+	ErrNoTTL           = errors.New("node has no ttl")
+)
+
+var (
+	// PartitionRing 的分区数在创建后不能修改
+	ErrPartitionCountFixed = errors.New("partition count is fixed")
+	// SlotMap 中的 slot 没有分配给任何结点
+	ErrSlotNotAssigned = errors.New("slot is not assigned")
+	// UnpinKey 的 key 没有被固定
+	ErrKeyNotPinned = errors.New("key is not pinned")
+	// key 的 hash 被 ReserveRange 保留，具体的标签见 ReservedError
+	ErrKeyReserved = errors.New("key is reserved")
+	// ReserveRange 的区间与已保留的区间重叠
+	ErrRangeOverlap = errors.New("reserved ranges overlap")
+	// 在线结点不足，具体数量见 InsufficientNodesError
+	ErrInsufficientNodes = errors.New("insufficient nodes")
+	// GetN 等要求的结点数小于 1
+	ErrInvalidCount = errors.New("invalid node count")
+)
+
+// NodeError 记录出错结点的 key，Err 为 ErrNodeExists 等具体原因，可以用 errors.Is 判断
+type NodeError struct {
+	Key string
+	Err error
+}
+
+func newNodeError(key string, err error) error {
+	return &NodeError{Key: key, Err: err}
+}
+
+func (e *NodeError) Error() string {
+	return "node " + e.Key + strings.TrimPrefix(e.Err.Error(), "node")
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// InsufficientNodesError 表示在线结点不足 GetN 等要求的数量，Available 为在线结点数
+type InsufficientNodesError struct {
+	Want      int
+	Available int
+}
+
+func (e *InsufficientNodesError) Error() string {
+	return fmt.Sprintf("insufficient nodes: want %d, available %d", e.Want, e.Available)
+}
+
+func (e *InsufficientNodesError) Is(target error) bool {
+	return target == ErrInsufficientNodes
+}
@@ -0,0 +1,106 @@
+package consistent_hash
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	c := NewConsistentWithCustomHash(func(string) uint32 { return 1 })
+
+	if _, err := c.GetNode("key"); !errors.Is(err, ErrEmptyRing) {
+		t.Errorf("GetNode on empty ring: %v", err)
+	}
+	if err := c.Add(nil); !errors.Is(err, ErrNilNode) {
+		t.Errorf("Add(nil): %v", err)
+	}
+	if err := c.AddWithVirtualNode(testNode{"a"}, 0); !errors.Is(err, ErrInvalidReplicas) {
+		t.Errorf("AddWithVirtualNode with 0 replicas: %v", err)
+	}
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+		key      string
+		msg      string
+	}{
+		{"duplicate", c.Add(testNode{"a"}), ErrNodeExists, "a", "node a already existed"},
+		{"collision", c.UnmarshalJSON([]byte(`{"nodes":[{"key":"b","points":[1]}]}`)), ErrHashCollision, "b", "node b hash collision"},
+		{"remove unknown", c.Remove(testNode{"x"}), ErrNodeNotFound, "x", "node x not exist"},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.sentinel) {
+			t.Errorf("%s: %v isn't %v", tt.name, tt.err, tt.sentinel)
+			continue
+		}
+		var nodeErr *NodeError
+		if !errors.As(tt.err, &nodeErr) || nodeErr.Key != tt.key {
+			t.Errorf("%s: %v doesn't carry key %s", tt.name, tt.err, tt.key)
+		}
+		if tt.err.Error() != tt.msg {
+			t.Errorf("%s: message %q, want %q", tt.name, tt.err.Error(), tt.msg)
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	c, err := New(WithMaxReplicas(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+		msg      string
+	}{
+		{"empty key", c.Add(testNode{""}), ErrEmptyKey, "node key is empty"},
+		{"zero replicas", c.AddWithVirtualNode(testNode{"a"}, 0), ErrInvalidReplicas, "invalid virtualNodeCount 0, can't less 1"},
+		{"zero count", func() error { _, err := c.GetN("a", 0); return err }(), ErrInvalidCount, "invalid node count 0, can't less 1"},
+		{"too many replicas", c.AddWithVirtualNode(testNode{"a"}, 101), ErrInvalidReplicas, "invalid virtualNodeCount 101, can't exceed 100"},
+		{"huge weight", c.AddWithWeight(testNode{"a"}, 1e300), ErrInvalidReplicas, "invalid virtualNodeCount 2147483647, can't exceed 100"},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.sentinel) {
+			t.Errorf("%s: %v isn't %v", tt.name, tt.err, tt.sentinel)
+		} else if tt.err.Error() != tt.msg {
+			t.Errorf("%s: message %q, want %q", tt.name, tt.err.Error(), tt.msg)
+		}
+	}
+	if c.Len() != 0 || len(c.circle) != 0 {
+		t.Fatal("rejected nodes were added")
+	}
+
+	if err := c.AddWithVirtualNode(testNode{"a"}, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetVirtualNodeCount("a", 101); !errors.Is(err, ErrInvalidReplicas) {
+		t.Errorf("SetVirtualNodeCount over max = %v", err)
+	}
+
+	// 默认上限
+	if err := NewConsistentHash().AddWithVirtualNode(testNode{"a"}, 50000000); !errors.Is(err, ErrInvalidReplicas) {
+		t.Errorf("AddWithVirtualNode(50000000) = %v", err)
+	}
+	if _, err := New(WithMaxReplicas(0)); err == nil {
+		t.Error("WithMaxReplicas(0) should fail")
+	}
+}
+
+func TestNewConsistentWithCustomHashNil(t *testing.T) {
+	c := NewConsistentWithCustomHash(nil)
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNode("key"); err != nil {
+		t.Fatal(err)
+	}
+	if c.hashKey("key") != defaultHash("key") {
+		t.Error("nil custom hash doesn't fall back to crc32")
+	}
+}
@@ -0,0 +1,39 @@
+package consistent_hash
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// EstimateDistribution 返回每个结点分到的 key 数量，查找方式与 GetNode 相同 (PinKey 固定的 key、
+// ReserveRange 保留的区间、跳过下线结点)，但不修改环，不统计负载和 Metrics，也不使用查询缓存。
+// 没有所属结点的 key 不计数，没有可用结点时返回空 map。
+func (c *ConsistentHash) EstimateDistribution(keys []string) map[string]int {
+	s := c.loadSnapshot()
+	counts := map[string]int{}
+	for _, key := range keys {
+		if n, err := s.locate(key); err == nil {
+			counts[n.key]++
+		}
+	}
+	return counts
+}
+
+// EstimateDistributionRandom 与 EstimateDistribution 相同，使用 seed 生成的 n 个伪随机 key
+func (c *ConsistentHash) EstimateDistributionRandom(n int, seed int64) map[string]int {
+	s := c.loadSnapshot()
+	counts := map[string]int{}
+	if s == nil || s.nodeCount == 0 {
+		return counts
+	}
+	r := rand.New(rand.NewSource(seed))
+	buf := make([]byte, 0, 20)
+	for i := 0; i < n; i++ {
+		buf = strconv.AppendUint(buf[:0], r.Uint64(), 36)
+		// 可以直接对 []byte 计算 hash 时复用 buf，避免每个 key 分配一个字符串
+		if node, err := s.locateBytes(buf); err == nil {
+			counts[node.key]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,131 @@
+package consistent_hash
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_EstimateDistribution(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddWithVirtualNode(testNode{"heavy"}, 400); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithVirtualNode(testNode{"light"}, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	for name, counts := range map[string]map[string]int{
+		"keys":   c.EstimateDistribution(keys),
+		"random": c.EstimateDistributionRandom(len(keys), 1),
+	} {
+		if total := counts["heavy"] + counts["light"]; total != len(keys) {
+			t.Fatalf("%s: counts = %v, sum %d", name, counts, total)
+		}
+		// 权重为 4:1，允许一定偏差
+		if ratio := float64(counts["heavy"]) / float64(counts["light"]); ratio < 3 || ratio > 5.5 {
+			t.Errorf("%s: heavy/light = %v, counts = %v", name, ratio, counts)
+		}
+	}
+
+	for _, key := range keys[:100] {
+		n, _ := c.GetNode(key)
+		if n.Key() != "heavy" {
+			continue
+		}
+		if got := c.EstimateDistribution([]string{key}); got["heavy"] != 1 {
+			t.Fatalf("EstimateDistribution(%s) = %v", key, got)
+		}
+		// 与 GetNode 一样跳过下线结点
+		c.MarkDown("heavy")
+		if got := c.EstimateDistribution([]string{key}); got["light"] != 1 {
+			t.Fatalf("EstimateDistribution(%s) with heavy down = %v", key, got)
+		}
+		break
+	}
+	if got := c.EstimateDistributionRandom(10, 2); got["light"] != 10 {
+		t.Fatalf("EstimateDistributionRandom with heavy down = %v", got)
+	}
+	if got := NewConsistentHash().EstimateDistribution(keys); len(got) != 0 {
+		t.Fatalf("empty ring = %v", got)
+	}
+}
+
+func TestConsistentHash_EstimateDistributionAllocs(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 4), 50); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	// 只有结果 map 和随机数生成器的分配，与 key 的数量无关
+	if allocs := testing.AllocsPerRun(10, func() { c.EstimateDistribution(keys) }); allocs > 5 {
+		t.Errorf("EstimateDistribution allocs = %v", allocs)
+	}
+	if allocs := testing.AllocsPerRun(10, func() { c.EstimateDistributionRandom(1000, 1) }); allocs > 10 {
+		t.Errorf("EstimateDistributionRandom allocs = %v", allocs)
+	}
+}
+
+// 与 GetNode 一样遵守 PinKey 和 ReserveRange
+func TestConsistentHash_EstimateDistributionPinsAndReserved(t *testing.T) {
+	c, _ := New(WithLookupCache(64))
+	c.AddAll(batchTestNodes("node-", 4), 50)
+	if err := c.ReserveRange(0, math.MaxUint32/2, "half"); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	var pinned string
+	for _, key := range keys {
+		if _, err := c.GetNode(key); errors.Is(err, ErrKeyReserved) {
+			pinned = key
+			break
+		}
+	}
+	if err := c.PinKey(pinned, "node-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{}
+	for _, key := range keys {
+		if n, err := c.GetNode(key); err == nil {
+			want[n.Key()]++
+		}
+	}
+	if got := c.EstimateDistribution(keys); !reflect.DeepEqual(got, want) {
+		t.Fatalf("EstimateDistribution = %v, GetNode gives %v", got, want)
+	}
+	if got := c.EstimateDistribution([]string{pinned}); got["node-3"] != 1 {
+		t.Fatalf("pinned key counted as %v", got)
+	}
+
+	want = map[string]int{}
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 1000; i++ {
+		if n, err := c.GetNode(strconv.FormatUint(r.Uint64(), 36)); err == nil {
+			want[n.Key()]++
+		}
+	}
+	if got := c.EstimateDistributionRandom(1000, 3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("EstimateDistributionRandom = %v, GetNode gives %v", got, want)
+	}
+	total := 0
+	for _, n := range want {
+		total += n
+	}
+	if total < 300 || total > 700 {
+		t.Fatalf("%d of 1000 random keys found an owner with half the ring reserved", total)
+	}
+}
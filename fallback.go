@@ -0,0 +1,92 @@
+package consistent_hash
+
+import "sync/atomic"
+
+// GetNodeExcluding 从 key 所在位置顺时针返回第一个不在 exclude 中的在线结点，不修改环。
+// 所有在线结点都被排除时返回 ErrAllExcluded，没有在线结点时返回 ErrNoHealthyNodes
+func (c *ConsistentHash) GetNodeExcluding(key string, exclude ...string) (Node, error) {
+	excluded := make(map[string]struct{}, len(exclude))
+	for _, k := range exclude {
+		excluded[k] = struct{}{}
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+	if len(c.hashSortedNodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+	// 与 GetN 相同地遍历，CollisionChain 下共用虚拟结点的结点按添加顺序访问
+	candidates, err := c.walkKey(key, len(c.nodes))
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoHealthyNodes
+	}
+	for _, n := range candidates {
+		if _, ok := excluded[n.Key()]; !ok {
+			return n, nil
+		}
+	}
+	return nil, ErrAllExcluded
+}
+
+// GetNodeWithFallback 从 key 所属结点开始顺时针依次询问 ok，返回第一个被接受的在线结点，
+// 每个物理结点最多询问一次，都不接受时返回 ErrAllExcluded。
+// ok 在释放锁之后对快照调用，可以在 ok 中访问环
+func (c *ConsistentHash) GetNodeWithFallback(key string, ok func(Node) bool) (Node, error) {
+	c.RLock()
+	if len(c.hashSortedNodes) == 0 {
+		c.RUnlock()
+		return nil, ErrEmptyRing
+	}
+	candidates, err := c.walkKey(key, len(c.nodes))
+	c.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoHealthyNodes
+	}
+	for _, n := range candidates {
+		if ok(n) {
+			return n, nil
+		}
+	}
+	return nil, ErrAllExcluded
+}
+
+// GetNodePreferring 在 preferredKey 对应的结点仍在环上且在线时返回该结点，第二个返回值为 true；
+// 否则与 GetNode 相同。检查偏好结点与查找在同一个读锁内完成，不会返回已经删除的结点。
+// preferredKey 为空时与 GetNode 相同
+func (c *ConsistentHash) GetNodePreferring(key string, preferredKey string) (Node, bool, error) {
+	if preferredKey == "" {
+		n, err := c.GetNode(key)
+		return n, false, err
+	}
+	c.RLock()
+	if cNode, ok := c.nodes[preferredKey]; ok && !cNode.down {
+		if c.loadTracking {
+			atomic.AddUint64(cNode.hits, 1)
+		}
+		metrics := c.metrics
+		c.RUnlock()
+		if metrics != nil {
+			metrics.ObserveLookup(preferredKey)
+		}
+		return cNode.node, true, nil
+	}
+	// 快照在写锁内发布，持有读锁时与 nodes 一致
+	s := c.loadSnapshot()
+	n, err := s.find(key)
+	c.RUnlock()
+	if err != nil {
+		return nil, false, err
+	}
+	node := s.hit(n)
+	if s.metrics != nil {
+		s.metrics.ObserveLookup(n.key)
+	}
+	return node, false, nil
+}
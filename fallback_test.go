@@ -0,0 +1,156 @@
+package consistent_hash
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_GetNodeExcluding(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 5), 20); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		want, _ := c.GetN(key, 3)
+		if n, err := c.GetNodeExcluding(key); err != nil || n != want[0] {
+			t.Fatalf("GetNodeExcluding(%s) = %v %v, want %s", key, n, err, want[0].Key())
+		}
+		if n, err := c.GetNodeExcluding(key, want[0].Key(), "unknown"); err != nil || n != want[1] {
+			t.Fatalf("GetNodeExcluding(%s, owner) = %v %v, want %s", key, n, err, want[1].Key())
+		}
+		if n, err := c.GetNodeExcluding(key, want[1].Key(), want[0].Key()); err != nil || n != want[2] {
+			t.Fatalf("GetNodeExcluding(%s, first two) = %v %v, want %s", key, n, err, want[2].Key())
+		}
+	}
+
+	all := nodeKeys(c.Members())
+	if _, err := c.GetNodeExcluding("key", all...); !errors.Is(err, ErrAllExcluded) {
+		t.Fatalf("excluding every node = %v, want ErrAllExcluded", err)
+	}
+	if _, err := NewConsistentHash().GetNodeExcluding("key"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("empty ring = %v, want ErrEmptyRing", err)
+	}
+}
+
+// CollisionChain 下与 GetN 的顺序相同，共用虚拟结点的结点按添加顺序访问
+func TestConsistentHash_GetNodeExcludingChain(t *testing.T) {
+	c := collisionRing(t, CollisionChain)
+	if err := c.AddAll([]Node{testNode{"b"}, testNode{"c"}, testNode{"d"}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	want, err := c.GetN("5", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if n, err := c.GetNodeExcluding("5", nodeKeys(want[:i])...); err != nil || n != want[i] {
+			t.Fatalf("GetNodeExcluding(5, %v) = %v %v, want %s", nodeKeys(want[:i]), n, err, want[i].Key())
+		}
+	}
+	c.MarkDown("b")
+	if n, err := c.GetNodeExcluding("5", "a"); err != nil || n.Key() != "c" {
+		t.Fatalf("GetNodeExcluding(5, a) with b down = %v %v, want c", n, err)
+	}
+}
+
+func TestConsistentHash_GetNodeWithFallback(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 5), 20); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := c.GetN("key", 5)
+
+	var asked []string
+	ask := func(accept func(Node) bool) func(Node) bool {
+		asked = asked[:0]
+		return func(n Node) bool {
+			asked = append(asked, n.Key())
+			// 在 ok 中访问环不会死锁
+			c.HasNode(n.Key())
+			return accept(n)
+		}
+	}
+
+	n, err := c.GetNodeWithFallback("key", ask(func(Node) bool { return true }))
+	if err != nil || n != order[0] || len(asked) != 1 {
+		t.Fatalf("accept first = %v %v, asked %v", n, err, asked)
+	}
+
+	last := order[4]
+	n, err = c.GetNodeWithFallback("key", ask(func(n Node) bool { return n == last }))
+	if err != nil || n != last || !equalStrings(asked, nodeKeys(order)) {
+		t.Fatalf("accept last = %v %v, asked %v", n, err, asked)
+	}
+
+	n, err = c.GetNodeWithFallback("key", ask(func(Node) bool { return false }))
+	if !errors.Is(err, ErrAllExcluded) || n != nil || len(asked) != 5 {
+		t.Fatalf("reject all = %v %v, asked %v", n, err, asked)
+	}
+
+	if _, err := NewConsistentHash().GetNodeWithFallback("key", func(Node) bool { return true }); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("empty ring = %v", err)
+	}
+}
+
+func TestConsistentHash_GetNodePreferring(t *testing.T) {
+	c := NewConsistentHash()
+	if _, _, err := c.GetNodePreferring("k", "a"); err != ErrEmptyRing {
+		t.Fatalf("empty ring err = %v", err)
+	}
+	if err := c.AddAll(batchTestNodes("node-", 5), 20); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		owner, _ := c.GetNode(key)
+		if n, ok, err := c.GetNodePreferring(key, ""); err != nil || ok || n != owner {
+			t.Fatalf("GetNodePreferring(%s, \"\") = %v, %v, %v", key, n, ok, err)
+		}
+		if n, ok, err := c.GetNodePreferring(key, "node-3"); err != nil || !ok || n.Key() != "node-3" {
+			t.Fatalf("GetNodePreferring(%s, node-3) = %v, %v, %v", key, n, ok, err)
+		}
+		if n, ok, err := c.GetNodePreferring(key, "missing"); err != nil || ok || n != owner {
+			t.Fatalf("GetNodePreferring(%s, missing) = %v, %v, %v", key, n, ok, err)
+		}
+	}
+
+	c.MarkDown("node-3")
+	if n, ok, _ := c.GetNodePreferring("k", "node-3"); ok || n.Key() == "node-3" {
+		t.Fatalf("preferred node is down but got %v, %v", n.Key(), ok)
+	}
+}
+
+func TestConsistentHash_GetNodePreferringConcurrentRemove(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 3), 20)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			c.RemoveByKey("sticky")
+			c.AddWithVirtualNode(testNode{"sticky"}, 20)
+		}
+		c.RemoveByKey("sticky")
+	}()
+	for {
+		select {
+		case <-done:
+			if _, ok, _ := c.GetNodePreferring("k", "sticky"); ok {
+				t.Fatal("removed node was honored")
+			}
+			return
+		default:
+		}
+		n, ok, err := c.GetNodePreferring("k", "sticky")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != (n.Key() == "sticky") {
+			t.Fatalf("honored = %v for %s", ok, n.Key())
+		}
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/Tsai-ilin/consistent-hash
 
-go 1.16
+go 1.18
@@ -0,0 +1,15 @@
+package consistent_hash
+
+import "strconv"
+
+// WithGroupcacheCompatibility 使虚拟结点与 golang/groupcache 的 consistenthash.Map 完全一致：
+// 第 i 个副本为 hash(strconv.Itoa(i) + key)，不做冲突重试，冲突时后添加的结点覆盖该虚拟结点，即 CollisionOverwrite。
+func WithGroupcacheCompatibility() Option {
+	return func(c *ConsistentHash) error {
+		c.collision = CollisionOverwrite
+		c.virtualKeyFunc = func(nodeKey string, replica int) string {
+			return strconv.Itoa(replica) + nodeKey
+		}
+		return nil
+	}
+}
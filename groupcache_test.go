@@ -0,0 +1,109 @@
+package consistent_hash
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// groupcacheMap 照搬 github.com/golang/groupcache/consistenthash 的实现 (Apache License 2.0)，
+// 作为对照
+type groupcacheMap struct {
+	hash     func(data []byte) uint32
+	replicas int
+	keys     []int
+	hashMap  map[int]string
+}
+
+func newGroupcacheMap(replicas int, fn func(data []byte) uint32) *groupcacheMap {
+	m := &groupcacheMap{
+		replicas: replicas,
+		hash:     fn,
+		hashMap:  make(map[int]string),
+	}
+	if m.hash == nil {
+		m.hash = crc32.ChecksumIEEE
+	}
+	return m
+}
+
+func (m *groupcacheMap) Add(keys ...string) {
+	for _, key := range keys {
+		for i := 0; i < m.replicas; i++ {
+			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+			m.keys = append(m.keys, hash)
+			m.hashMap[hash] = key
+		}
+	}
+	sort.Ints(m.keys)
+}
+
+func (m *groupcacheMap) Get(key string) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
+
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool { return m.keys[i] >= hash })
+	if idx == len(m.keys) {
+		idx = 0
+	}
+	return m.hashMap[m.keys[idx]]
+}
+
+func TestGroupcacheCompatibility(t *testing.T) {
+	c, err := New(WithGroupcacheCompatibility())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newGroupcacheMap(50, nil)
+	for i := 0; i < 20; i++ {
+		key := "10.0.0." + strconv.Itoa(i) + ":8080"
+		if err := c.AddWithVirtualNode(testNode{key}, 50); err != nil {
+			t.Fatal(err)
+		}
+		m.Add(key)
+	}
+
+	for i := 0; i < 100000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		n, err := c.GetNode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := m.Get(key); n.Key() != want {
+			t.Fatalf("GetNode(%s) = %s, groupcache = %s", key, n.Key(), want)
+		}
+	}
+}
+
+func TestGroupcacheCompatibility_Collision(t *testing.T) {
+	// a 的第 1 个副本与 b 的第 0 个副本冲突，hash 超过最大的虚拟结点时回到起点
+	table := map[string]uint32{"0a": 10, "1a": 20, "0b": 20, "1b": 30}
+	h := tableHash(table)
+	c, err := New(WithHash(h), WithGroupcacheCompatibility())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newGroupcacheMap(2, func(data []byte) uint32 { return h(string(data)) })
+	for _, key := range []string{"a", "b"} {
+		if err := c.AddWithVirtualNode(testNode{key}, 2); err != nil {
+			t.Fatal(err)
+		}
+		m.Add(key)
+	}
+
+	for _, key := range []string{"5", "10", "15", "20", "25", "30", "35"} {
+		n, err := c.GetNode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := m.Get(key); n.Key() != want {
+			t.Errorf("GetNode(%s) = %s, groupcache = %s", key, n.Key(), want)
+		}
+	}
+	if len(c.hashSortedNodes) != 3 || len(c.nodes["a"].virtualNodes) != 1 || len(c.nodes["b"].virtualNodes) != 2 {
+		t.Errorf("points = %v, a = %v, b = %v", c.hashSortedNodes, c.nodes["a"].virtualNodes, c.nodes["b"].virtualNodes)
+	}
+}
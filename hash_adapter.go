@@ -0,0 +1,61 @@
+package consistent_hash
+
+import (
+	"errors"
+	"hash"
+	"sync"
+)
+
+// WithHash32 使用标准库 hash.Hash32 的实现作为环的 hash，例如 WithHash32(fnv.New32a)。
+// hash.Hash32 不能并发使用，newHash 创建的实例放在池中复用，每次使用前 Reset
+func WithHash32(newHash func() hash.Hash32) Option {
+	return func(c *ConsistentHash) error {
+		if newHash == nil {
+			return errors.New("hash is nil")
+		}
+		pool := &sync.Pool{New: func() interface{} { return newHash() }}
+		return WithBytesHash(func(key []byte) uint32 {
+			h := pool.Get().(hash.Hash32)
+			h.Reset()
+			h.Write(key)
+			sum := h.Sum32()
+			pool.Put(h)
+			return sum
+		})(c)
+	}
+}
+
+// WithHash64 与 WithHash32 相同，64 位的结果高低 32 位异或后用于环
+func WithHash64(newHash func() hash.Hash64) Option {
+	return func(c *ConsistentHash) error {
+		if newHash == nil {
+			return errors.New("hash is nil")
+		}
+		h := hash64Bytes(newHash)
+		return WithBytesHash(func(key []byte) uint32 {
+			sum := h(key)
+			return uint32(sum ^ sum>>32)
+		})(c)
+	}
+}
+
+// Hash64Func 把 hash.Hash64 的实现转换为 NewConsistentWithCustomHash64 使用的 hash，
+// 例如 NewConsistentWithCustomHash64(Hash64Func(fnv.New64a))，可以并发使用
+func Hash64Func(newHash func() hash.Hash64) func(key string) uint64 {
+	h := hash64Bytes(newHash)
+	return func(key string) uint64 {
+		return h([]byte(key))
+	}
+}
+
+func hash64Bytes(newHash func() hash.Hash64) func([]byte) uint64 {
+	pool := &sync.Pool{New: func() interface{} { return newHash() }}
+	return func(key []byte) uint64 {
+		h := pool.Get().(hash.Hash64)
+		h.Reset()
+		h.Write(key)
+		sum := h.Sum64()
+		pool.Put(h)
+		return sum
+	}
+}
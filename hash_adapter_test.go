@@ -0,0 +1,107 @@
+package consistent_hash
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestWithHash32(t *testing.T) {
+	c, err := New(WithHash32(fnv.New32a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "a", "node-1", "a much longer key that spans several blocks"} {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		// 池中的实例重复使用，每次都要 Reset
+		for i := 0; i < 3; i++ {
+			if got := c.hash(key); got != h.Sum32() {
+				t.Fatalf("hash(%q) = %d, want %d", key, got, h.Sum32())
+			}
+		}
+		if got := c.bytesHash([]byte(key)); got != h.Sum32() {
+			t.Fatalf("bytesHash(%q) = %d, want %d", key, got, h.Sum32())
+		}
+	}
+
+	c.AddAll(batchTestNodes("node-", 5), 20)
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		a, _ := c.GetNode(key)
+		b, _ := c.GetNodeBytes([]byte(key))
+		if a != b {
+			t.Fatalf("GetNode(%s) = %s, GetNodeBytes = %s", key, a.Key(), b.Key())
+		}
+	}
+
+	if _, err := New(WithHash32(nil)); err == nil {
+		t.Fatal("nil factory accepted")
+	}
+}
+
+func TestWithHash64(t *testing.T) {
+	c, err := New(WithHash64(fnv.New64a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h64 := Hash64Func(fnv.New64a)
+	for _, key := range []string{"", "a", "node-1"} {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		sum := h.Sum64()
+		if got := c.hash(key); got != uint32(sum^sum>>32) {
+			t.Fatalf("hash(%q) = %d, want %d", key, got, uint32(sum^sum>>32))
+		}
+		if got := h64(key); got != sum {
+			t.Fatalf("Hash64Func(%q) = %d, want %d", key, got, sum)
+		}
+	}
+
+	ring := NewConsistentWithCustomHash64(h64)
+	ring.AddWithVirtualNode(testNode{"a"}, 10)
+	if n, err := ring.GetNode("k"); err != nil || n.Key() != "a" {
+		t.Fatalf("ConsistentHash64 GetNode = %v, %v", n, err)
+	}
+	if _, err := New(WithHash64(nil)); err == nil {
+		t.Fatal("nil factory accepted")
+	}
+}
+
+func TestWithHash32_Concurrent(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"Hash32": WithHash32(fnv.New32a),
+		"Hash64": WithHash64(fnv.New64a),
+	} {
+		c, _ := New(opt)
+		c.AddAll(batchTestNodes("node-", 10), 50)
+		want := map[string]string{}
+		for i := 0; i < 200; i++ {
+			key := "key-" + strconv.Itoa(i)
+			n, _ := c.GetNode(key)
+			want[key] = n.Key()
+		}
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					key := "key-" + strconv.Itoa(i%200)
+					var n Node
+					if i%2 == 0 {
+						n, _ = c.GetNode(key)
+					} else {
+						n, _ = c.GetNodeBytes([]byte(key))
+					}
+					if n.Key() != want[key] {
+						t.Errorf("%s: GetNode(%s) = %s, want %s", name, key, n.Key(), want[key])
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,65 @@
+package consistent_hash
+
+import "sort"
+
+// MarkDown 把结点标记为下线，结点的虚拟结点保留在环上，查询时跳过该结点继续顺时针查找。
+// 与 Remove 不同，MarkUp 之后分布与下线前完全相同
+func (c *ConsistentHash) MarkDown(nodeKey string) error {
+	return c.setDown(nodeKey, true)
+}
+
+// MarkUp 把 MarkDown 下线的结点重新上线
+func (c *ConsistentHash) MarkUp(nodeKey string) error {
+	return c.setDown(nodeKey, false)
+}
+
+func (c *ConsistentHash) setDown(nodeKey string, down bool) error {
+	c.lockWrite()
+	defer c.unlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return newNodeError(nodeKey, ErrNodeNotFound)
+	}
+	if cNode.down == down {
+		// 状态没有变化，不重建快照
+		return nil
+	}
+	cNode.down = down
+	c.nodes[nodeKey] = cNode
+	c.version++
+	return nil
+}
+
+// Healthy 返回按 key 排序的在线结点
+func (c *ConsistentHash) Healthy() []Node {
+	c.RLock()
+	defer c.RUnlock()
+
+	healthy := make([]Node, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		if !n.down {
+			healthy = append(healthy, n.node)
+		}
+	}
+	sort.Slice(healthy, func(i, j int) bool {
+		return healthy[i].Key() < healthy[j].Key()
+	})
+	return healthy
+}
+
+// 从第 i 个虚拟结点开始顺时针返回第一个在线结点，调用方需持有锁
+func (c *ConsistentHash) healthyFrom(i int) (Node, error) {
+	for j := 0; j < len(c.hashSortedNodes); j++ {
+		p := c.hashSortedNodes[(i+j)%len(c.hashSortedNodes)]
+		if cNode := c.nodes[c.circle[p]]; !cNode.down {
+			return cNode.node, nil
+		}
+		// CollisionChain 下依次尝试共用该虚拟结点的其他结点
+		for _, owner := range c.chains[p] {
+			if cNode := c.nodes[owner]; !cNode.down {
+				return cNode.node, nil
+			}
+		}
+	}
+	return nil, ErrNoHealthyNodes
+}
@@ -0,0 +1,159 @@
+package consistent_hash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type clock interface {
+	After(d time.Duration) <-chan time.Time
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// StartHealthCheck 启动后台协程，每隔 interval 并发探测所有结点。连续失败 failThreshold 次的结点被标记为下线，
+// 下线结点连续成功 passThreshold 次后重新上线，阈值小于 1 时按 1 处理。
+// 探测时不持有环的锁，探测期间被 Remove 的结点不会被重新加回。ctx 取消后协程退出。
+// check 为 nil 或 interval 不大于 0 时返回错误，不启动协程
+func (c *ConsistentHash) StartHealthCheck(ctx context.Context, check func(Node) error, interval time.Duration, failThreshold, passThreshold int) error {
+	if check == nil {
+		return errors.New("health check func is nil")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("health check interval %v must be positive", interval)
+	}
+	if failThreshold < 1 {
+		failThreshold = 1
+	}
+	if passThreshold < 1 {
+		passThreshold = 1
+	}
+	c.RLock()
+	clk := c.clock
+	c.RUnlock()
+	if clk == nil {
+		clk = realClock{}
+	}
+
+	h := &healthChecker{
+		c:             c,
+		check:         check,
+		failThreshold: failThreshold,
+		passThreshold: passThreshold,
+		states:        map[string]*probeState{},
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-clk.After(interval):
+				if ctx.Err() != nil {
+					return
+				}
+				h.round()
+			}
+		}
+	}()
+	return nil
+}
+
+// Unhealthy 返回按 key 排序的下线结点
+func (c *ConsistentHash) Unhealthy() []Node {
+	c.RLock()
+	defer c.RUnlock()
+
+	var unhealthy []Node
+	for _, n := range c.nodes {
+		if n.down {
+			unhealthy = append(unhealthy, n.node)
+		}
+	}
+	sort.Slice(unhealthy, func(i, j int) bool {
+		return unhealthy[i].Key() < unhealthy[j].Key()
+	})
+	return unhealthy
+}
+
+type healthChecker struct {
+	c             *ConsistentHash
+	check         func(Node) error
+	failThreshold int
+	passThreshold int
+	// 只在后台协程中访问
+	states map[string]*probeState
+}
+
+// 结点连续失败、成功的次数
+type probeState struct {
+	fails  int
+	passes int
+}
+
+func (h *healthChecker) round() {
+	c := h.c
+	c.RLock()
+	nodes := make([]Node, 0, len(c.nodes))
+	down := make(map[string]bool, len(c.nodes))
+	for k, n := range c.nodes {
+		nodes = append(nodes, n.node)
+		down[k] = n.down
+	}
+	c.RUnlock()
+
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node Node) {
+			defer wg.Done()
+			errs[i] = h.check(node)
+		}(i, node)
+	}
+	wg.Wait()
+
+	for k := range h.states {
+		if _, ok := down[k]; !ok {
+			delete(h.states, k)
+		}
+	}
+	for i, node := range nodes {
+		k := node.Key()
+		st := h.states[k]
+		if st == nil {
+			st = &probeState{}
+			h.states[k] = st
+		}
+		if errs[i] != nil {
+			st.fails++
+			st.passes = 0
+			if !down[k] && st.fails >= h.failThreshold {
+				// 结点已被删除时 setDown 返回 ErrNodeNotFound，不会把它加回环上
+				if c.setDown(k, true) != nil {
+					delete(h.states, k)
+				}
+			}
+			continue
+		}
+		st.passes++
+		st.fails = 0
+		if down[k] && st.passes >= h.passThreshold {
+			if c.setDown(k, false) != nil {
+				delete(h.states, k)
+			}
+		}
+	}
+}
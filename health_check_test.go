@@ -0,0 +1,196 @@
+package consistent_hash
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 的 After 每次调用都会在 waiting 中放入一个信号，说明上一轮探测已经结束。
+// Now 返回的时间只由 advance 推进
+type fakeClock struct {
+	waiting chan struct{}
+	ticks   chan time.Time
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{waiting: make(chan struct{}, 1), ticks: make(chan time.Time), now: time.Unix(0, 0)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+func (f *fakeClock) After(time.Duration) <-chan time.Time {
+	f.waiting <- struct{}{}
+	return f.ticks
+}
+
+// 触发一轮探测并等待它结束
+func (f *fakeClock) tick(t *testing.T) {
+	t.Helper()
+	f.ticks <- time.Now()
+	select {
+	case <-f.waiting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("health check round did not finish")
+	}
+}
+
+// scriptedCheck 按轮次返回每个结点的探测结果，脚本用完之后探测成功
+type scriptedCheck struct {
+	mu     sync.Mutex
+	script map[string][]bool
+	calls  int
+}
+
+func (s *scriptedCheck) check(n Node) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	results := s.script[n.Key()]
+	if len(results) == 0 {
+		return nil
+	}
+	ok := results[0]
+	s.script[n.Key()] = results[1:]
+	if !ok {
+		return errors.New("probe failed")
+	}
+	return nil
+}
+
+func (s *scriptedCheck) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func startFakeHealthCheck(t *testing.T, c *ConsistentHash, check func(Node) error) (*fakeClock, context.CancelFunc) {
+	clk := newFakeClock()
+	c.clock = clk
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := c.StartHealthCheck(ctx, check, time.Second, 2, 2); err != nil {
+		t.Fatal(err)
+	}
+	<-clk.waiting
+	return clk, cancel
+}
+
+func healthKeys(nodes []Node) []string {
+	keys := []string{}
+	for _, n := range nodes {
+		keys = append(keys, n.Key())
+	}
+	return keys
+}
+
+func TestConsistentHash_StartHealthCheck(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll([]Node{testNode{"a"}, testNode{"b"}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	s := &scriptedCheck{script: map[string][]bool{
+		// 交替失败不会下线；连续两次失败下线；连续两次成功才重新上线
+		"a": {false, true, false, true, false, false, true, false, true, true},
+	}}
+	clk, cancel := startFakeHealthCheck(t, c, s.check)
+	defer cancel()
+
+	wantDown := []bool{false, false, false, false, false, true, true, true, true, false}
+	for i, down := range wantDown {
+		clk.tick(t)
+		want := []string{}
+		if down {
+			want = []string{"a"}
+		}
+		if got := healthKeys(c.Unhealthy()); !reflect.DeepEqual(got, want) {
+			t.Fatalf("round %d: Unhealthy = %v, want %v", i, got, want)
+		}
+		if down {
+			if n, _ := c.GetNode("x"); n.Key() != "b" {
+				t.Fatalf("round %d: lookup returned ejected node %s", i, n.Key())
+			}
+		}
+	}
+	if got := healthKeys(c.Healthy()); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Healthy = %v", got)
+	}
+}
+
+func TestConsistentHash_StartHealthCheckInvalid(t *testing.T) {
+	c := NewConsistentHash()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.StartHealthCheck(ctx, nil, time.Second, 1, 1); err == nil {
+		t.Error("nil check accepted")
+	}
+	if err := c.StartHealthCheck(ctx, func(Node) error { return nil }, 0, 1, 1); err == nil {
+		t.Error("zero interval accepted")
+	}
+}
+
+func TestConsistentHash_StartHealthCheckRemovedMidProbe(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll([]Node{testNode{"a"}, testNode{"b"}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	check := func(n Node) error {
+		if n.Key() == "b" {
+			c.RemoveByKey("b")
+			return errors.New("gone")
+		}
+		return nil
+	}
+	clk, cancel := startFakeHealthCheck(t, c, check)
+	defer cancel()
+	clk.tick(t)
+	clk.tick(t)
+	if c.HasNode("b") || c.Len() != 1 {
+		t.Fatalf("removed node came back: members = %v", healthKeys(c.Members()))
+	}
+	if len(c.Unhealthy()) != 0 {
+		t.Fatalf("Unhealthy = %v", healthKeys(c.Unhealthy()))
+	}
+}
+
+func TestConsistentHash_StartHealthCheckShutdown(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	s := &scriptedCheck{script: map[string][]bool{}}
+	clk, cancel := startFakeHealthCheck(t, c, s.check)
+	clk.tick(t)
+	if s.callCount() != 1 {
+		t.Fatalf("calls = %d, want 1", s.callCount())
+	}
+
+	cancel()
+	select {
+	case clk.ticks <- time.Now():
+	case <-time.After(50 * time.Millisecond):
+	}
+	select {
+	case <-clk.waiting:
+		t.Fatal("health check still running after cancel")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if s.callCount() != 1 {
+		t.Fatalf("probed after cancel: calls = %d", s.callCount())
+	}
+}
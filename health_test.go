@@ -0,0 +1,119 @@
+package consistent_hash
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_MarkDown(t *testing.T) {
+	c := NewConsistentHashXX()
+	if err := c.AddAll(batchTestNodes("node-", 5), 50); err != nil {
+		t.Fatal(err)
+	}
+	owners := make(map[string][]Node)
+	for i := 0; i < 2000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		owners[key], _ = c.GetN(key, 5)
+	}
+
+	if err := c.MarkDown("node-2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(c.Healthy()); !equalStrings(got, []string{"node-0", "node-1", "node-3", "node-4"}) {
+		t.Fatalf("Healthy = %v", got)
+	}
+	if c.Len() != 5 {
+		t.Fatalf("Len = %d, MarkDown shouldn't remove the node", c.Len())
+	}
+	for key, want := range owners {
+		// 只有 node-2 的 key 迁移到它顺时针方向的下一个结点
+		next := want[0]
+		if next.Key() == "node-2" {
+			next = want[1]
+		}
+		if n, err := c.GetNode(key); err != nil || n != next {
+			t.Fatalf("GetNode(%s) = %v %v, want %s", key, n, err, next.Key())
+		}
+		got, _ := c.GetN(key, 4)
+		if len(got) != 4 || got[0] != next {
+			t.Fatalf("GetN(%s) = %v", key, nodeKeys(got))
+		}
+		for _, n := range got {
+			if n.Key() == "node-2" {
+				t.Fatalf("GetN(%s) returned a down node", key)
+			}
+		}
+	}
+
+	if err := c.MarkUp("node-2"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range owners {
+		if got, _ := c.GetN(key, 5); !reflect.DeepEqual(got, want) {
+			t.Fatalf("GetN(%s) = %v after MarkUp, want %v", key, nodeKeys(got), nodeKeys(want))
+		}
+	}
+
+	// 状态没有变化时不修改环
+	c.MarkDown("node-3")
+	version, snapshot := c.Version(), c.loadSnapshot()
+	if err := c.MarkUp("node-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.MarkDown("node-3"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Version() != version || c.loadSnapshot() != snapshot {
+		t.Fatal("repeated MarkUp or MarkDown changed the ring")
+	}
+
+	if err := c.MarkDown("x"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("MarkDown(x) = %v", err)
+	}
+	if err := c.MarkUp("x"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("MarkUp(x) = %v", err)
+	}
+}
+
+func TestConsistentHash_NoHealthyNodes(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 3), 10); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"node-0", "node-1", "node-2"} {
+		if err := c.MarkDown(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.GetNode("key"); !errors.Is(err, ErrNoHealthyNodes) {
+		t.Errorf("GetNode = %v, want ErrNoHealthyNodes", err)
+	}
+	if _, err := c.GetN("key", 2); !errors.Is(err, ErrNoHealthyNodes) {
+		t.Errorf("GetN = %v, want ErrNoHealthyNodes", err)
+	}
+	if _, err := c.GetLeast("key"); !errors.Is(err, ErrNoHealthyNodes) {
+		t.Errorf("GetLeast = %v, want ErrNoHealthyNodes", err)
+	}
+	if _, err := c.GetNodeExcluding("key"); !errors.Is(err, ErrNoHealthyNodes) {
+		t.Errorf("GetNodeExcluding = %v, want ErrNoHealthyNodes", err)
+	}
+	if _, ok := c.Iterate("key").Next(); ok {
+		t.Error("Iterate returned a down node")
+	}
+	if len(c.Healthy()) != 0 {
+		t.Errorf("Healthy = %v", c.Healthy())
+	}
+
+	// 下线的结点仍然可以删除
+	if _, err := c.RemoveByKey("node-0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.MarkUp("node-1"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.GetNode("key"); err != nil || n.Key() != "node-1" {
+		t.Errorf("GetNode = %v %v, want node-1", n, err)
+	}
+}
@@ -0,0 +1,115 @@
+package consistent_hash
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+type httpRing struct {
+	Version  uint64       `json:"version"`
+	Checksum string       `json:"checksum"`
+	Nodes    int          `json:"nodes"`
+	Points   int          `json:"points"`
+	Members  []httpMember `json:"members"`
+}
+
+type httpMember struct {
+	Key      string `json:"key"`
+	Replicas int    `json:"replicas"`
+	Points   int    `json:"points"`
+	// 拥有的 hash 空间的百分比
+	Ownership float64 `json:"ownership_percent"`
+	Down      bool    `json:"down"`
+}
+
+type httpLookup struct {
+	Version uint64 `json:"version"`
+	Key     string `json:"key"`
+	Hash    uint32 `json:"hash"`
+	Node    string `json:"node"`
+	Point   uint32 `json:"point"`
+}
+
+type httpError struct {
+	Error string `json:"error"`
+}
+
+// Handler 返回以 JSON 输出环的状态的调试接口，挂载在任意路径下：
+// GET 返回版本、Checksum 和按 key 排序的结点及其副本数、拥有的 hash 空间百分比；
+// GET ?key=foo 返回 foo 所属的在线结点和匹配的虚拟结点，不计入负载统计。
+// 结果在读锁内一次生成，写给客户端时不持有锁
+func (c *ConsistentHash) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, httpError{Error: "method not allowed"})
+			return
+		}
+		if keys, ok := r.URL.Query()["key"]; ok {
+			lookup, err := c.httpLookup(keys[0])
+			if err != nil {
+				writeJSON(w, http.StatusServiceUnavailable, httpError{Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, lookup)
+			return
+		}
+		writeJSON(w, http.StatusOK, c.httpRing())
+	})
+}
+
+func (c *ConsistentHash) httpRing() httpRing {
+	c.RLock()
+	defer c.RUnlock()
+	ring := httpRing{
+		Version:  c.version,
+		Checksum: fmt.Sprintf("%016x", c.checksumLocked()),
+		Nodes:    len(c.nodes),
+		Points:   len(c.hashSortedNodes),
+		Members:  make([]httpMember, 0, len(c.nodes)),
+	}
+	shares := make(map[string]float64, len(c.nodes))
+	points := make(map[string]int, len(c.nodes))
+	for i, p := range c.hashSortedNodes {
+		shares[c.circle[p]] += c.pointWidth(i)
+		points[c.circle[p]]++
+	}
+	for k, n := range c.nodes {
+		ring.Members = append(ring.Members, httpMember{
+			Key:       k,
+			Replicas:  len(n.virtualNodes) / c.pointsPerReplica(),
+			Points:    points[k],
+			Ownership: shares[k] / (1 << 32) * 100,
+			Down:      n.down,
+		})
+	}
+	sort.Slice(ring.Members, func(i, j int) bool { return ring.Members[i].Key < ring.Members[j].Key })
+	return ring
+}
+
+func (c *ConsistentHash) httpLookup(key string) (httpLookup, error) {
+	// 快照在写锁内替换，持有读锁时与环的状态一致
+	c.RLock()
+	defer c.RUnlock()
+	s := c.loadSnapshot()
+	if s == nil || s.nodeCount == 0 {
+		return httpLookup{}, ErrEmptyRing
+	}
+	h := s.hash(key)
+	if err := checkReserved(s.reserved, h); err != nil {
+		return httpLookup{}, err
+	}
+	i, n := s.ownerAt(h)
+	if n == nil {
+		return httpLookup{}, ErrNoHealthyNodes
+	}
+	return httpLookup{Version: c.version, Key: key, Hash: h, Node: n.key, Point: s.points[i]}, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,82 @@
+package consistent_hash
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func getJSON(t *testing.T, h http.Handler, target string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("GET %s Content-Type = %q", target, ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: %v: %s", target, err, rec.Body)
+	}
+	return rec.Code
+}
+
+func TestConsistentHash_Handler(t *testing.T) {
+	// 环: 1<<30(a) 1<<31(b) 3<<30(a)
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 1 << 30, "a#1#0": 3 << 30, "b#0#0": 1 << 31, "k": 1<<30 + 5,
+	}))
+	c.AddKey("a", 2)
+	c.AddKey("b", 1)
+	c.MarkDown("b")
+	h := c.Handler()
+
+	var ring httpRing
+	if code := getJSON(t, h, "/debug/ring", &ring); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	want := httpRing{
+		Version:  c.Version(),
+		Nodes:    2,
+		Points:   3,
+		Checksum: ring.Checksum,
+		Members: []httpMember{
+			{Key: "a", Replicas: 2, Points: 2, Ownership: 75},
+			{Key: "b", Replicas: 1, Points: 1, Ownership: 25, Down: true},
+		},
+	}
+	if !reflect.DeepEqual(ring, want) {
+		t.Fatalf("ring = %+v, want %+v", ring, want)
+	}
+	if want := fmt.Sprintf("%016x", c.Checksum()); ring.Checksum != want {
+		t.Fatalf("checksum = %s, want %s", ring.Checksum, want)
+	}
+
+	// b 下线，顺时针跳到 a 的第二个虚拟结点
+	var lookup httpLookup
+	if code := getJSON(t, h, "/debug/ring?key=k", &lookup); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if lookup != (httpLookup{Version: c.Version(), Key: "k", Hash: 1<<30 + 5, Node: "a", Point: 3 << 30}) {
+		t.Fatalf("lookup = %+v", lookup)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status = %d", rec.Code)
+	}
+}
+
+func TestConsistentHash_HandlerEmptyRing(t *testing.T) {
+	h := NewConsistentHash().Handler()
+	var ring httpRing
+	if code := getJSON(t, h, "/", &ring); code != http.StatusOK || ring.Nodes != 0 || ring.Members == nil {
+		t.Fatalf("empty ring = %d %+v", code, ring)
+	}
+	var e httpError
+	if code := getJSON(t, h, "/?key=k", &e); code != http.StatusServiceUnavailable || e.Error != ErrEmptyRing.Error() {
+		t.Fatalf("lookup on empty ring = %d %+v", code, e)
+	}
+}
@@ -0,0 +1,131 @@
+package consistent_hash
+
+import "sync/atomic"
+
+// RingIterator 从 key 所在位置顺时针依次返回不同的物理结点，见 Iterate
+type RingIterator struct {
+	nodes []Node
+	next  int
+	err   error
+}
+
+// Iterate 返回从 key 所属结点开始顺时针遍历在线物理结点的迭代器，每个结点只返回一次。
+// 迭代器使用创建时的快照，之后对环的修改不影响遍历。key 落在保留区间时迭代器为空，Err 返回 ReservedError
+func (c *ConsistentHash) Iterate(key string) *RingIterator {
+	c.RLock()
+	defer c.RUnlock()
+	if len(c.hashSortedNodes) == 0 {
+		return &RingIterator{}
+	}
+	nodes, err := c.walkKey(key, len(c.nodes))
+	return &RingIterator{nodes: nodes, err: err}
+}
+
+// Err 返回创建迭代器时的错误，目前只有 key 落在保留区间时不为 nil
+func (it *RingIterator) Err() error {
+	return it.err
+}
+
+// Next 返回下一个结点，所有结点都返回过后返回 false
+func (it *RingIterator) Next() (Node, bool) {
+	if it.next >= len(it.nodes) {
+		return nil, false
+	}
+	it.next++
+	return it.nodes[it.next-1], true
+}
+
+// Range 按 key 的顺序对每个物理结点 (包括下线的结点) 调用 fn，fn 返回 false 时停止。
+// 遍历的是调用时的快照，不持有锁也不复制结点列表，fn 中可以修改环，修改不影响本次遍历，每个结点最多访问一次
+func (c *ConsistentHash) Range(fn func(Node) bool) {
+	s := c.loadSnapshot()
+	if s == nil {
+		return
+	}
+	for _, n := range s.members {
+		if atomic.LoadUint32(&n.removed) != 0 {
+			continue
+		}
+		if !fn(n.node) {
+			return
+		}
+	}
+}
+
+// RangePoints 按 hash 从小到大对每个虚拟结点及其所属的物理结点调用 fn，fn 返回 false 时停止。
+// 与 Range 一样遍历调用时的快照，CollisionChain 下共用的虚拟结点只返回 chain 中的第一个结点
+func (c *ConsistentHash) RangePoints(fn func(point uint32, owner Node) bool) {
+	s := c.loadSnapshot()
+	if s == nil {
+		return
+	}
+	for i, p := range s.points {
+		n := s.owners[i]
+		if atomic.LoadUint32(&n.removed) != 0 {
+			// 延迟删除的虚拟结点，CollisionChain 下由 chain 中下一个没有删除的结点接替
+			n = nil
+			for _, next := range s.chains[i] {
+				if atomic.LoadUint32(&next.removed) == 0 {
+					n = next
+					break
+				}
+			}
+			if n == nil {
+				continue
+			}
+		}
+		if !fn(p, n.node) {
+			return
+		}
+	}
+}
+
+// 从第 i 个虚拟结点开始顺时针返回最多 n 个不同的在线物理结点，CollisionChain 下共用的虚拟结点按添加顺序
+// 依次访问其中的结点，调用方需持有锁
+func (c *ConsistentHash) walk(i, n int) []Node {
+	seen := make(map[string]struct{}, n)
+	nodes := make([]Node, 0, n)
+	visit := func(k string) {
+		if _, ok := seen[k]; ok || len(nodes) == n {
+			return
+		}
+		seen[k] = struct{}{}
+		if cNode := c.nodes[k]; !cNode.down {
+			nodes = append(nodes, cNode.node)
+		}
+	}
+	for j := 0; j < len(c.hashSortedNodes) && len(nodes) < n; j++ {
+		p := c.hashSortedNodes[(i+j)%len(c.hashSortedNodes)]
+		if owners, ok := c.chains[p]; ok {
+			for _, k := range owners {
+				visit(k)
+			}
+			continue
+		}
+		visit(c.circle[p])
+	}
+	return nodes
+}
+
+// 与 GetN 相同，从 key 所在位置开始 walk，PinKey 固定的在线结点排在最前面。
+// key 没有固定到在线结点且落在保留区间时返回 ReservedError，调用方需持有锁
+func (c *ConsistentHash) walkKey(key string, n int) ([]Node, error) {
+	h := c.hashKey(key)
+	if _, pinned := c.pinnedNode(key); !pinned {
+		if err := checkReserved(c.reserved, h); err != nil {
+			return nil, err
+		}
+	}
+	return c.pinFirst(key, c.walk(c.getPosition(h), n)), nil
+}
+
+// 检查 walk 找到的在线结点数，没有在线结点时返回 ErrNoHealthyNodes，调用方需持有锁
+func (c *ConsistentHash) checkReplicaCount(available, want int) error {
+	if available == 0 {
+		return ErrNoHealthyNodes
+	}
+	if available < want && !c.degradedReplicas {
+		return &InsufficientNodesError{Want: want, Available: available}
+	}
+	return nil
+}
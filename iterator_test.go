@@ -0,0 +1,155 @@
+package consistent_hash
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestConsistentHash_Iterate(t *testing.T) {
+	c := NewConsistentHashXX()
+	if err := c.AddAll(batchTestNodes("node-", 8), 20); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		it := c.Iterate(key)
+		var got []Node
+		for n, ok := it.Next(); ok; n, ok = it.Next() {
+			got = append(got, n)
+			want, _ := c.GetN(key, len(got))
+			if want[len(want)-1] != n {
+				t.Fatalf("Iterate(%s) #%d = %s, want %s", key, len(got), n.Key(), want[len(want)-1].Key())
+			}
+		}
+		if len(got) != 8 {
+			t.Fatalf("Iterate(%s) yielded %d nodes", key, len(got))
+		}
+		if n, ok := it.Next(); ok || n != nil {
+			t.Fatalf("exhausted iterator returned %v %v", n, ok)
+		}
+	}
+
+	if it := c.Iterate("key"); it.Err() != nil {
+		t.Fatalf("Iterate err = %v", it.Err())
+	}
+	if _, ok := NewConsistentHash().Iterate("key").Next(); ok {
+		t.Fatal("iterator over an empty ring returned a node")
+	}
+}
+
+func TestConsistentHash_IterateSnapshot(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 5), 20); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := c.GetN("key", 5)
+
+	it := c.Iterate("key")
+	first, _ := it.Next()
+	// 遍历过程中修改环不影响迭代器
+	for _, n := range want[1:3] {
+		if err := c.Remove(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.AddAll(batchTestNodes("new-", 5), 20); err != nil {
+		t.Fatal(err)
+	}
+	got := []Node{first}
+	for n, ok := it.Next(); ok; n, ok = it.Next() {
+		got = append(got, n)
+	}
+	if !equalStrings(nodeKeys(got), nodeKeys(want)) {
+		t.Fatalf("Iterate = %v, want %v", nodeKeys(got), nodeKeys(want))
+	}
+}
+
+func TestConsistentHash_Range(t *testing.T) {
+	c := NewConsistentHash()
+	c.Range(func(Node) bool {
+		t.Fatal("fn called on an empty ring")
+		return true
+	})
+	c.RangePoints(func(uint32, Node) bool {
+		t.Fatal("fn called on an empty ring")
+		return true
+	})
+
+	c.AddAll(batchTestNodes("node-", 5), 10)
+	c.MarkDown("node-3")
+	var keys []string
+	c.Range(func(n Node) bool {
+		keys = append(keys, n.Key())
+		return true
+	})
+	if !equalStrings(keys, nodeKeys(c.Members())) {
+		t.Fatalf("Range visited %v", keys)
+	}
+
+	// fn 返回 false 时停止
+	visits := 0
+	c.Range(func(Node) bool {
+		visits++
+		return visits < 2
+	})
+	if visits != 2 {
+		t.Fatalf("Range visited %d nodes after stopping", visits)
+	}
+
+	var points []uint32
+	c.RangePoints(func(p uint32, owner Node) bool {
+		if owner.Key() != c.circle[p] {
+			t.Fatalf("point %d owner = %s", p, owner.Key())
+		}
+		points = append(points, p)
+		return true
+	})
+	if !reflect.DeepEqual(points, c.hashSortedNodes) {
+		t.Fatal("RangePoints did not visit the points in ring order")
+	}
+	visits = 0
+	c.RangePoints(func(uint32, Node) bool {
+		visits++
+		return false
+	})
+	if visits != 1 {
+		t.Fatalf("RangePoints visited %d points after stopping", visits)
+	}
+}
+
+func TestConsistentHash_RangeMutate(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 5), 10)
+	// fn 中删除和添加结点不会死锁，遍历的仍然是调用时的结点
+	seen := map[string]int{}
+	c.Range(func(n Node) bool {
+		seen[n.Key()]++
+		c.RemoveByKey(n.Key())
+		c.Add(testNode{n.Key() + "-new"})
+		return true
+	})
+	if len(seen) != 5 {
+		t.Fatalf("Range visited %v", seen)
+	}
+	for k, v := range seen {
+		if v != 1 || !strings.HasPrefix(k, "node-") || strings.HasSuffix(k, "-new") {
+			t.Fatalf("Range visited %v", seen)
+		}
+	}
+	if c.HasNode("node-0") || !c.HasNode("node-0-new") {
+		t.Fatal("mutations in fn were lost")
+	}
+
+	points := 0
+	c.RangePoints(func(_ uint32, owner Node) bool {
+		points++
+		c.RemoveByKey(owner.Key())
+		return true
+	})
+	if points != 5*c.pointsPerReplica() || c.Len() != 0 {
+		t.Fatalf("RangePoints visited %d points, %d nodes left", points, c.Len())
+	}
+}
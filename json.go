@@ -0,0 +1,129 @@
+package consistent_hash
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+type jsonRing struct {
+	Nodes  []jsonNode        `json:"nodes"`
+	Pins   map[string]string `json:"pins,omitempty"`
+	Chains []jsonChain       `json:"chains,omitempty"`
+}
+
+// CollisionChain 下被多个结点共用的虚拟结点，Nodes 按添加顺序排列
+type jsonChain struct {
+	Point uint32   `json:"point"`
+	Nodes []string `json:"nodes"`
+}
+
+type jsonNode struct {
+	Key    string   `json:"key"`
+	Points []uint32 `json:"points"`
+}
+
+// MarshalJSON 输出按 key 排序的结点及其虚拟结点，有 PinKey 固定的 key 时还输出 pins，
+// 有被多个结点共用的虚拟结点时还输出按位置排序的 chains，格式为
+// {"nodes":[{"key":"a","points":[1,2]}],"pins":{"k":"a"},"chains":[{"point":2,"nodes":["a","b"]}]}
+func (c *ConsistentHash) MarshalJSON() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	ring := jsonRing{Nodes: make([]jsonNode, 0, len(c.nodes)), Pins: c.pins}
+	for k, n := range c.nodes {
+		ring.Nodes = append(ring.Nodes, jsonNode{Key: k, Points: n.virtualNodes})
+	}
+	sort.Slice(ring.Nodes, func(i, j int) bool {
+		return ring.Nodes[i].Key < ring.Nodes[j].Key
+	})
+	for _, p := range c.sortedChainPoints() {
+		ring.Chains = append(ring.Chains, jsonChain{Point: p, Nodes: c.chains[p]})
+	}
+	return json.Marshal(ring)
+}
+
+// UnmarshalJSON 把 MarshalJSON 输出的结点合并到环中，circle 和排序数组由各结点的虚拟结点重建。
+// 结点由 WithNodeFactory 创建，默认为 StringNode。pins 覆盖环上相同 key 的固定。
+// chains 中的虚拟结点可以由多个结点共用，包括环上已有的结点，chain 必须恰好包含共用它的全部结点。
+// 结点已存在或虚拟结点与环上已有的冲突时返回错误，环保持不变
+func (c *ConsistentHash) UnmarshalJSON(data []byte) error {
+	var ring jsonRing
+	if err := json.Unmarshal(data, &ring); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptData, err)
+	}
+
+	points := 0
+	for _, n := range ring.Nodes {
+		points += len(n.Points)
+	}
+	return c.update(len(ring.Nodes), points, func(staged *ConsistentHash) error {
+		return staged.mergeJSON(ring)
+	})
+}
+
+// 调用方需持有写锁
+func (c *ConsistentHash) mergeJSON(ring jsonRing) error {
+	chains := make(map[uint32][]string, len(ring.Chains))
+	for _, ch := range ring.Chains {
+		if _, ok := chains[ch.Point]; ok {
+			return fmt.Errorf("%w: duplicate chain of point %d", ErrCorruptData, ch.Point)
+		}
+		chains[ch.Point] = ch.Nodes
+	}
+
+	var added []uint32
+	shared := map[uint32][]string{}
+	for _, n := range ring.Nodes {
+		if n.Key == "" {
+			return ErrEmptyKey
+		}
+		if _, ok := c.nodes[n.Key]; ok {
+			return newNodeError(n.Key, ErrNodeExists)
+		}
+		for _, p := range n.Points {
+			if _, ok := c.circle[p]; !ok {
+				c.circle[p] = n.Key
+				added = append(added, p)
+				continue
+			}
+			if _, chained := chains[p]; !chained {
+				return newNodeError(n.Key, ErrHashCollision)
+			}
+			if shared[p] == nil {
+				shared[p] = c.ownersAt(p)
+			}
+			if containsKey(shared[p], n.Key) {
+				return newNodeError(n.Key, ErrHashCollision)
+			}
+			shared[p] = append(shared[p], n.Key)
+		}
+		node := c.newNode(n.Key)
+		c.nodes[n.Key] = newConsistentNode(node, append([]uint32(nil), n.Points...))
+		c.recordAdded(node)
+	}
+	if err := checkChains(shared, chains); err != nil {
+		return err
+	}
+	if len(chains) > 0 && c.chains == nil {
+		c.chains = make(map[uint32][]string, len(chains))
+	}
+	for p, chain := range chains {
+		c.chains[p] = append([]string(nil), chain...)
+		c.circle[p] = chain[0]
+	}
+	c.insertSorted(added)
+	if len(ring.Pins) > 0 {
+		// 与环共用，不能原地修改
+		pins := copyPins(c.pins)
+		if pins == nil {
+			pins = make(map[string]string, len(ring.Pins))
+		}
+		for k, v := range ring.Pins {
+			pins[k] = v
+		}
+		c.pins = pins
+		c.version++
+	}
+	return nil
+}
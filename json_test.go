@@ -0,0 +1,148 @@
+package consistent_hash
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_MarshalJSON(t *testing.T) {
+	src := NewConsistentHash()
+	if err := src.AddAll(batchTestNodes("node-", 10), 20); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewConsistentHash()
+	if err := json.Unmarshal(data, dst); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst.hashSortedNodes, src.hashSortedNodes) || !reflect.DeepEqual(dst.circle, src.circle) {
+		t.Fatal("ring differs after JSON round trip")
+	}
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		a, _ := src.GetNode(key)
+		b, _ := dst.GetNode(key)
+		if a.Key() != b.Key() {
+			t.Fatalf("GetNode(%s) = %s, want %s", key, b.Key(), a.Key())
+		}
+	}
+	if _, ok := dst.Members()[0].(StringNode); !ok {
+		t.Fatalf("node type = %T, want StringNode", dst.Members()[0])
+	}
+
+	empty, _ := json.Marshal(NewConsistentHash())
+	if string(empty) != `{"nodes":[]}` {
+		t.Fatalf("empty ring = %s", empty)
+	}
+}
+
+func TestConsistentHash_MarshalJSONStable(t *testing.T) {
+	nodes := batchTestNodes("node-", 10)
+	forward := NewConsistentHash()
+	backward := NewConsistentHash()
+	for i := range nodes {
+		if err := forward.AddWithVirtualNode(nodes[i], 5); err != nil {
+			t.Fatal(err)
+		}
+		if err := backward.AddWithVirtualNode(nodes[len(nodes)-1-i], 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, _ := json.Marshal(forward)
+	b, _ := json.Marshal(backward)
+	if !bytes.Equal(a, b) {
+		t.Fatalf("output depends on insertion order:\n%s\n%s", a, b)
+	}
+
+	var ring jsonRing
+	if err := json.Unmarshal(a, &ring); err != nil {
+		t.Fatal(err)
+	}
+	if !sort.SliceIsSorted(ring.Nodes, func(i, j int) bool { return ring.Nodes[i].Key < ring.Nodes[j].Key }) {
+		t.Fatalf("nodes aren't sorted by key: %s", a)
+	}
+}
+
+func TestConsistentHash_UnmarshalJSONMerge(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	point := c.hashSortedNodes[0]
+
+	// circle 和排序数组由 points 重建
+	if err := json.Unmarshal([]byte(`{"nodes":[{"key":"b","points":[1,4000000000]}]}`), c); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(c.Members()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("Members = %v", got)
+	}
+	want := []uint32{1, point, 4000000000}
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+	if !reflect.DeepEqual(c.hashSortedNodes, want) {
+		t.Fatalf("hashSortedNodes = %v, want %v", c.hashSortedNodes, want)
+	}
+
+	circle := make(map[uint32]string)
+	for k, v := range c.circle {
+		circle[k] = v
+	}
+	tests := []struct {
+		name     string
+		data     string
+		sentinel error
+	}{
+		{"existing node", `{"nodes":[{"key":"c","points":[2]},{"key":"a","points":[3]}]}`, ErrNodeExists},
+		{"point collision", `{"nodes":[{"key":"c","points":[2,1]}]}`, ErrHashCollision},
+		{"duplicate point", `{"nodes":[{"key":"c","points":[2,2]}]}`, ErrHashCollision},
+		{"empty key", `{"nodes":[{"key":"","points":[2]}]}`, ErrEmptyKey},
+		{"negative point", `{"nodes":[{"key":"c","points":[-1]}]}`, ErrCorruptData},
+		{"syntax", `{"nodes":[`, ErrCorruptData},
+	}
+	for _, tt := range tests {
+		if err := c.UnmarshalJSON([]byte(tt.data)); !errors.Is(err, tt.sentinel) {
+			t.Errorf("%s: UnmarshalJSON = %v, want %v", tt.name, err, tt.sentinel)
+		}
+		if !reflect.DeepEqual(c.circle, circle) || !reflect.DeepEqual(c.hashSortedNodes, want) {
+			t.Fatalf("%s: failed UnmarshalJSON changed the ring", tt.name)
+		}
+	}
+}
+
+func TestWithNodeFactory(t *testing.T) {
+	src := NewConsistentHash()
+	if err := src.AddAll(batchTestNodes("node-", 3), 5); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := json.Marshal(src)
+	bin, _ := src.MarshalBinary()
+
+	factory := WithNodeFactory(func(key string) Node { return testNode{key} })
+	fromJSON, _ := New(factory)
+	if err := json.Unmarshal(text, fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	fromBinary, _ := New(factory)
+	if err := fromBinary.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*ConsistentHash{fromJSON, fromBinary} {
+		if !reflect.DeepEqual(c.Members(), src.Members()) {
+			t.Fatalf("Members = %v, want %v", c.Members(), src.Members())
+		}
+	}
+
+	if _, err := New(WithNodeFactory(nil)); err == nil {
+		t.Fatal("WithNodeFactory(nil) should fail")
+	}
+}
@@ -0,0 +1,68 @@
+package consistent_hash
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// JumpHash 实现 Lamping & Veach 的 Jump Consistent Hash (https://arxiv.org/abs/1406.2294)。
+// 结点按添加顺序编号为桶，只能在末尾增删；删除中间的桶会让其后所有桶的编号变化，
+// 大量 key 随之迁移，所以这里只提供 RemoveLast。
+type JumpHash struct {
+	nodes []Node
+	sync.RWMutex
+}
+
+func NewJumpHash(nodes []Node) *JumpHash {
+	return &JumpHash{nodes: append([]Node(nil), nodes...)}
+}
+
+func (j *JumpHash) AddNode(node Node) error {
+	if node == nil {
+		return ErrNilNode
+	}
+	j.Lock()
+	defer j.Unlock()
+	j.nodes = append(j.nodes, node)
+	return nil
+}
+
+// RemoveLast 删除最后添加的结点并返回它
+func (j *JumpHash) RemoveLast() (Node, error) {
+	j.Lock()
+	defer j.Unlock()
+	if len(j.nodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+	node := j.nodes[len(j.nodes)-1]
+	j.nodes[len(j.nodes)-1] = nil
+	j.nodes = j.nodes[:len(j.nodes)-1]
+	return node, nil
+}
+
+func (j *JumpHash) GetNode(key string) (Node, error) {
+	j.RLock()
+	defer j.RUnlock()
+	if len(j.nodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return j.nodes[jump(h.Sum64(), len(j.nodes))], nil
+}
+
+func (j *JumpHash) Len() int {
+	j.RLock()
+	defer j.RUnlock()
+	return len(j.nodes)
+}
+
+func jump(key uint64, buckets int) int {
+	var b, k int64 = -1, 0
+	for k < int64(buckets) {
+		b = k
+		key = key*2862933555777941757 + 1
+		k = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,118 @@
+package consistent_hash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func jumpTestNodes(n int) []Node {
+	nodes := make([]Node, n)
+	for i := range nodes {
+		nodes[i] = testNode{"node-" + strconv.Itoa(i)}
+	}
+	return nodes
+}
+
+func jumpOwners(t *testing.T, j *JumpHash, keys []string) []string {
+	owners := make([]string, len(keys))
+	for i, k := range keys {
+		n, err := j.GetNode(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[i] = n.Key()
+	}
+	return owners
+}
+
+func TestJumpHash_AddRemoveLast(t *testing.T) {
+	j := NewJumpHash(nil)
+	if _, err := j.GetNode("key"); err == nil {
+		t.Fatal("GetNode on empty JumpHash should fail")
+	}
+	if _, err := j.RemoveLast(); err == nil {
+		t.Fatal("RemoveLast on empty JumpHash should fail")
+	}
+
+	j = NewJumpHash(jumpTestNodes(10))
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	before := jumpOwners(t, j, keys)
+
+	if err := j.AddNode(testNode{"node-10"}); err != nil {
+		t.Fatal(err)
+	}
+	after := jumpOwners(t, j, keys)
+	moved := 0
+	for i := range keys {
+		if before[i] == after[i] {
+			continue
+		}
+		moved++
+		if after[i] != "node-10" {
+			t.Fatalf("key %s moved from %s to %s", keys[i], before[i], after[i])
+		}
+	}
+	// 理想情况下迁移 1/11
+	if frac := float64(moved) / float64(len(keys)); frac < 0.07 || frac > 0.11 {
+		t.Errorf("moved fraction %.3f after AddNode, want about 1/11", frac)
+	}
+
+	n, err := j.RemoveLast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Key() != "node-10" || j.Len() != 10 {
+		t.Fatalf("RemoveLast = %s, Len = %d", n.Key(), j.Len())
+	}
+	for i, owner := range jumpOwners(t, j, keys) {
+		if owner != before[i] {
+			t.Fatalf("key %s owned by %s after RemoveLast, want %s", keys[i], owner, before[i])
+		}
+	}
+}
+
+func TestJumpHash_RemoveMiddleRemapsHeavily(t *testing.T) {
+	nodes := jumpTestNodes(10)
+	j := NewJumpHash(nodes)
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	before := jumpOwners(t, j, keys)
+
+	// 删除第一个桶后，其余结点的编号整体前移
+	after := jumpOwners(t, NewJumpHash(nodes[1:]), keys)
+	moved := 0
+	for i := range keys {
+		if before[i] != after[i] {
+			moved++
+		}
+	}
+	if frac := float64(moved) / float64(len(keys)); frac < 0.5 {
+		t.Errorf("moved fraction %.3f after removing the first bucket, want most keys to move", frac)
+	}
+}
+
+func BenchmarkJumpHash_GetNode(b *testing.B) {
+	j := NewJumpHash(jumpTestNodes(1000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j.GetNode("key-" + strconv.Itoa(i))
+	}
+}
+
+func BenchmarkConsistentHash_GetNode1kNodes(b *testing.B) {
+	c := NewConsistentHash()
+	for _, n := range jumpTestNodes(1000) {
+		if err := c.AddWithVirtualNode(n, 20); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetNode("key-" + strconv.Itoa(i))
+	}
+}
@@ -0,0 +1,55 @@
+package consistent_hash
+
+import (
+	"crypto/md5"
+	"strconv"
+)
+
+const (
+	ketamaDefaultReplicas  = 40
+	ketamaPointsPerReplica = 4
+)
+
+// NewKetama 创建与 libketama 兼容的环，见 WithKetama
+func NewKetama() *ConsistentHash {
+	c, _ := New(WithKetama())
+	return c
+}
+
+// WithKetama 以 libketama 的方式计算虚拟结点和 key 的 hash，使分布与基于 libketama 的
+// memcached 客户端完全一致：每个副本取 md5("<key>-<i>") 的 16 字节生成 4 个虚拟结点，
+// Add 默认 40 个副本。ketama 模式固定使用 md5，会忽略 WithHash 等自定义 hash。
+func WithKetama() Option {
+	return func(c *ConsistentHash) error {
+		c.ketama = true
+		c.defaultReplicas = ketamaDefaultReplicas
+		return nil
+	}
+}
+
+func ketamaHash(key string) uint32 {
+	digest := md5.Sum([]byte(key))
+	return ketamaPoint(digest, 0)
+}
+
+func ketamaPoint(digest [md5.Size]byte, h int) uint32 {
+	return uint32(digest[3+h*4])<<24 | uint32(digest[2+h*4])<<16 | uint32(digest[1+h*4])<<8 | uint32(digest[h*4])
+}
+
+// 生成编号 [from, to) 的副本并记录到 staged 中，冲突时返回 ErrHashCollision，只用于 CollisionError。调用方需持有锁
+func (c *ConsistentHash) stageKetamaPoints(nodeKey string, from, to int, staged map[uint32]string) ([]uint32, error) {
+	virtualNodes := make([]uint32, 0, (to-from)*ketamaPointsPerReplica)
+	for i := from; i < to; i++ {
+		digest := md5.Sum([]byte(nodeKey + "-" + strconv.Itoa(i)))
+		for h := 0; h < ketamaPointsPerReplica; h++ {
+			k := ketamaPoint(digest, h)
+			_, ok := c.circle[k]
+			if _, dup := staged[k]; ok || dup {
+				return nil, newNodeError(nodeKey, ErrHashCollision)
+			}
+			staged[k] = nodeKey
+			virtualNodes = append(virtualNodes, k)
+		}
+	}
+	return virtualNodes, nil
+}
@@ -0,0 +1,150 @@
+package consistent_hash
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testdata/ketama.golden 由 testdata/ketama_golden.py 生成，该程序按 libketama 的 ketama.c 编写，
+// 不是 libketama 本身的输出，只能发现与该程序不一致的错误。另一个独立的实现得到相同的结果
+func TestKetama_Golden(t *testing.T) {
+	c := NewKetama()
+	for i := 1; i <= 5; i++ {
+		if err := c.Add(testNode{"10.0.1." + strconv.Itoa(i) + ":11211"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.hashSortedNodes) != 5*ketamaDefaultReplicas*ketamaPointsPerReplica {
+		t.Fatalf("ring has %d points", len(c.hashSortedNodes))
+	}
+	if n, _ := c.VirtualNodeCount("10.0.1.1:11211"); n != ketamaDefaultReplicas {
+		t.Errorf("VirtualNodeCount = %d, want %d", n, ketamaDefaultReplicas)
+	}
+
+	f, err := os.Open("testdata/ketama.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lines := 0
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "#") {
+			continue
+		}
+		fields := strings.Fields(s.Text())
+		key := fields[0]
+		if key == `""` {
+			key = ""
+		}
+		hash, _ := strconv.ParseUint(fields[1], 10, 32)
+		if got := c.hashKey(key); got != uint32(hash) {
+			t.Errorf("hash(%q) = %d, want %s", key, got, fields[1])
+		}
+		n, err := c.GetNode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Key() != fields[2] {
+			t.Errorf("GetNode(%q) = %s, want %s", key, n.Key(), fields[2])
+		}
+		lines++
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if lines == 0 {
+		t.Fatal("golden file is empty")
+	}
+}
+
+func TestKetama_Position(t *testing.T) {
+	c := NewKetama()
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	points := c.hashSortedNodes
+	last := len(points) - 1
+	tests := []struct {
+		hash uint32
+		want int
+	}{
+		{0, 0},
+		{points[0], 0},
+		{points[0] + 1, 1},
+		{points[last-1] + 1, last},
+		{points[last], last},
+		{points[last] + 1, 0},
+	}
+	for _, tt := range tests {
+		if got := c.getPosition(tt.hash); got != tt.want {
+			t.Errorf("getPosition(%d) = %d, want %d", tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestKetama_IgnoresCustomHash(t *testing.T) {
+	c, err := New(WithHash(func(string) uint32 { return 1 }), WithKetama())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.hashKey("foo") != ketamaHash("foo") {
+		t.Error("ketama mode uses the custom hash")
+	}
+	for i := 0; i < 3; i++ {
+		if err := c.Add(testNode{"node-" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.SetVirtualNodeCount("node-0", 10); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.VirtualNodeCount("node-0"); n != 10 || len(c.hashSortedNodes) != 90*ketamaPointsPerReplica {
+		t.Errorf("VirtualNodeCount = %d, points = %d", n, len(c.hashSortedNodes))
+	}
+}
+
+// ketama 的虚拟结点不能重试，默认与 libketama 一样由后添加的结点覆盖，CollisionError 时返回错误
+func TestKetama_Collision(t *testing.T) {
+	// a 已经占用了 b 的第一个虚拟结点
+	taken := ketamaPoint(md5.Sum([]byte("b-0")), 0)
+	data, _ := json.Marshal(jsonRing{Nodes: []jsonNode{{Key: "a", Points: []uint32{taken, taken + 1}}}})
+	for _, tt := range []struct {
+		policy CollisionPolicy
+		owner  string
+		err    error
+	}{
+		{CollisionProbe, "b", nil},
+		{CollisionOverwrite, "b", nil},
+		{CollisionChain, "a", nil},
+		{CollisionError, "a", ErrHashCollision},
+	} {
+		c, err := New(WithKetama(), WithCollisionPolicy(tt.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.UnmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Add(testNode{"b"}); !errors.Is(err, tt.err) {
+			t.Fatalf("%v: Add err = %v, want %v", tt.policy, err, tt.err)
+		}
+		if got := ownerOfHash(c, taken); got != tt.owner {
+			t.Errorf("%v: owner of the shared point = %s, want %s", tt.policy, got, tt.owner)
+		}
+		if err := c.VerifyRing(); err != nil {
+			t.Errorf("%v: %v", tt.policy, err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package consistent_hash
+
+import "sync/atomic"
+
+// WithLoadTracking 统计 GetNode、GetN 返回每个结点的次数，计数使用原子操作，不影响读锁的并发
+func WithLoadTracking() Option {
+	return func(c *ConsistentHash) error {
+		c.loadTracking = true
+		return nil
+	}
+}
+
+// LoadReport 返回每个结点被 GetNode、GetN 返回的次数，需要 WithLoadTracking
+func (c *ConsistentHash) LoadReport() map[string]uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	report := make(map[string]uint64, len(c.nodes))
+	for k, n := range c.nodes {
+		report[k] = atomic.LoadUint64(n.hits)
+	}
+	return report
+}
+
+// ResetLoad 把 LoadReport 的计数清零
+func (c *ConsistentHash) ResetLoad() {
+	c.RLock()
+	defer c.RUnlock()
+	for _, n := range c.nodes {
+		atomic.StoreUint64(n.hits, 0)
+	}
+}
+
+// 调用方需持有锁
+func (c *ConsistentHash) recordHit(node Node) {
+	atomic.AddUint64(c.nodes[node.Key()].hits, 1)
+}
@@ -0,0 +1,109 @@
+package consistent_hash
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConsistentHash_LoadTracking(t *testing.T) {
+	c, _ := New(WithLoadTracking())
+	if err := c.AddAll(batchTestNodes("node-", 5), 20); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa(g) + "-" + strconv.Itoa(i)
+				if _, err := c.GetNode(key); err != nil {
+					t.Error(err)
+				}
+				if _, err := c.GetN(key, 2); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	report := c.LoadReport()
+	var sum uint64
+	for _, hits := range report {
+		sum += hits
+	}
+	// 每次 GetNode 计 1 次，每次 GetN 计 2 次
+	if want := uint64(8 * 1000 * 3); sum != want {
+		t.Fatalf("sum of LoadReport = %d, want %d", sum, want)
+	}
+	if len(report) != 5 || report["node-0"] == 0 {
+		t.Fatalf("LoadReport = %v", report)
+	}
+
+	if _, err := c.RemoveByKey("node-0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.LoadReport()["node-0"]; ok {
+		t.Fatal("Remove didn't drop the node's counter")
+	}
+	c.ResetLoad()
+	for k, hits := range c.LoadReport() {
+		if hits != 0 {
+			t.Fatalf("%s has %d hits after ResetLoad", k, hits)
+		}
+	}
+
+	// Clone 复制计数，之后互不影响
+	if _, err := c.GetNode("key"); err != nil {
+		t.Fatal(err)
+	}
+	clone := c.Clone()
+	if _, err := clone.GetNode("key"); err != nil {
+		t.Fatal(err)
+	}
+	owner, _ := c.GetKey("key")
+	if c.LoadReport()[owner] != 2 || clone.LoadReport()[owner] != 2 {
+		t.Fatalf("LoadReport after Clone = %v %v", c.LoadReport(), clone.LoadReport())
+	}
+}
+
+func TestConsistentHash_LoadTrackingDisabled(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNode("key"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := c.LoadReport()["a"]; hits != 0 {
+		t.Fatalf("untracked ring counted %d hits", hits)
+	}
+}
+
+func BenchmarkConsistentHash_GetNodeLoadTracking(b *testing.B) {
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{"off", nil},
+		{"on", []Option{WithLoadTracking()}},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			c, _ := New(tt.opts...)
+			if err := c.AddAll(batchTestNodes("node-", 100), 100); err != nil {
+				b.Fatal(err)
+			}
+			keys := make([]string, 1024)
+			for i := range keys {
+				keys[i] = "key-" + strconv.Itoa(i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.GetNode(keys[i%len(keys)])
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package consistent_hash
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// WithLookupCache 缓存最近查询的 key 的 GetNode 结果，适合少量热点 key 占大部分查询的场景。
+// 缓存属于快照，环的每次修改都会换成新的空缓存，不会返回过期的结点
+func WithLookupCache(size int) Option {
+	return func(c *ConsistentHash) error {
+		if size < 1 {
+			return errors.New("lookup cache size can't less 1")
+		}
+		c.lookupCacheSize = size
+		return nil
+	}
+}
+
+// 直接映射的缓存，每个 key 只能放在一个槽里，新的 key 覆盖槽里原来的 key。
+// 读写都是原子操作，不加锁，并发查询不会在缓存上竞争
+type lookupCache struct {
+	slots []atomic.Value
+	mask  uint32
+}
+
+type lookupCacheEntry struct {
+	key  string
+	node *snapshotNode
+}
+
+// 槽的数量取不小于 size 的 2 的幂
+func newLookupCache(size int) *lookupCache {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	return &lookupCache{slots: make([]atomic.Value, n), mask: uint32(n - 1)}
+}
+
+// 用 FNV-1a 选择槽
+func (lc *lookupCache) slot(key string) *atomic.Value {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return &lc.slots[h&lc.mask]
+}
+
+func (lc *lookupCache) get(key string) *snapshotNode {
+	if e, ok := lc.slot(key).Load().(*lookupCacheEntry); ok && e.key == key {
+		return e.node
+	}
+	return nil
+}
+
+func (lc *lookupCache) put(key string, n *snapshotNode) {
+	lc.slot(key).Store(&lookupCacheEntry{key: key, node: n})
+}
@@ -0,0 +1,154 @@
+package consistent_hash
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestLookupCache(t *testing.T) {
+	lc := newLookupCache(3)
+	if len(lc.slots) != 4 {
+		t.Fatalf("slots = %d", len(lc.slots))
+	}
+	a, b := &snapshotNode{}, &snapshotNode{}
+	// 找到落在同一个槽的两个 key
+	var keys []string
+	slot := lc.slot("k0")
+	for i := 0; len(keys) < 2; i++ {
+		if k := "k" + strconv.Itoa(i); lc.slot(k) == slot {
+			keys = append(keys, k)
+		}
+	}
+	if lc.get(keys[0]) != nil {
+		t.Fatal("empty cache hit")
+	}
+	lc.put(keys[0], a)
+	if lc.get(keys[0]) != a || lc.get(keys[1]) != nil {
+		t.Fatal("wrong entry returned")
+	}
+	lc.put(keys[1], b)
+	if lc.get(keys[0]) != nil || lc.get(keys[1]) != b {
+		t.Fatal("colliding key not replaced")
+	}
+}
+
+func TestConsistentHash_LookupCacheInvalidation(t *testing.T) {
+	if _, err := New(WithLookupCache(0)); err == nil {
+		t.Fatal("WithLookupCache(0) succeeded")
+	}
+	table := map[string]uint32{"a#0#0": 100, "b#0#0": 200, "c#0#0": 150}
+	c, _ := New(WithHash(tableHash(table)), WithLookupCache(16))
+	if _, err := c.GetNode("120"); err != ErrEmptyRing {
+		t.Fatalf("empty ring err = %v", err)
+	}
+	c.AddKey("a", 1)
+	c.AddKey("b", 1)
+
+	steps := []struct {
+		name string
+		op   func() error
+		want string
+	}{
+		{"initial", func() error { return nil }, "b"},
+		{"add", func() error { return c.AddKey("c", 1) }, "c"},
+		{"mark down", func() error { return c.MarkDown("c") }, "b"},
+		{"mark up", func() error { return c.MarkUp("c") }, "c"},
+		{"remove", func() error { _, err := c.RemoveByKey("c"); return err }, "b"},
+		{"sync", func() error { _, _, err := c.Sync([]Node{testNode{"a"}}, 1); return err }, "a"},
+	}
+	for _, s := range steps {
+		if err := s.op(); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		// 第二次查询命中缓存
+		for i := 0; i < 2; i++ {
+			if n, err := c.GetNode("120"); err != nil || n.Key() != s.want {
+				t.Fatalf("%s: GetNode = %v, %v, want %s", s.name, n, err, s.want)
+			}
+		}
+	}
+
+	c.MarkDown("a")
+	if _, err := c.GetNode("120"); err != ErrNoHealthyNodes {
+		t.Fatalf("all down err = %v", err)
+	}
+}
+
+func TestConsistentHash_LookupCacheConcurrent(t *testing.T) {
+	c, _ := New(WithLookupCache(64), WithLoadTracking())
+	if err := c.AddAll(batchTestNodes("node-", 5), 20); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				if _, err := c.GetNode(strconv.Itoa((g + i) % 100)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	for i := 0; i < 50; i++ {
+		c.AddWithVirtualNode(testNode{"churn"}, 20)
+		c.RemoveByKey("churn")
+	}
+	wg.Wait()
+
+	// 写操作结束之后，缓存的结果与不使用缓存的查找相同
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		n, _ := c.GetNode(key)
+		c.RLock()
+		want, _ := c.lookup(key)
+		c.RUnlock()
+		if n.Key() != want.Key() {
+			t.Fatalf("GetNode(%s) = %s, want %s", key, n.Key(), want.Key())
+		}
+	}
+	var total uint64
+	for _, hits := range c.LoadReport() {
+		total += hits
+	}
+	if total != 4*2000+100 {
+		t.Fatalf("counted %d lookups", total)
+	}
+}
+
+func benchmarkGetNodeZipf(b *testing.B, opts ...Option) {
+	c, err := New(opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := c.AddAll(batchTestNodes("node-", 1000), 160); err != nil {
+		b.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.3, 1, 99999)
+	keys := make([]string, 1<<12)
+	for i := range keys {
+		keys[i] = "key-" + strconv.FormatUint(zipf.Uint64(), 10)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.GetNode(keys[i&(len(keys)-1)])
+			i++
+		}
+	})
+}
+
+func BenchmarkConsistentHash_GetNodeZipf(b *testing.B) {
+	benchmarkGetNodeZipf(b)
+}
+
+func BenchmarkConsistentHash_GetNodeZipfCached(b *testing.B) {
+	benchmarkGetNodeZipf(b, WithLookupCache(1024))
+}
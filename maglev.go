@@ -0,0 +1,146 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultMaglevTableSize = 65537
+
+// Maglev 实现 Google Maglev 论文中的查找表 (https://research.google/pubs/pub44824/)。
+// 每次 Add/Remove 在写锁下重建查找表并原子替换，查找时不加锁，只做一次取模和数组下标访问。
+type Maglev struct {
+	nodes map[string]Node
+	sync.Mutex
+	size uint64
+	// 当前的 *maglevTable
+	table atomic.Value
+}
+
+type maglevTable struct {
+	entries []Node
+}
+
+func NewMaglev() *Maglev {
+	m, _ := NewMaglevWithSize(defaultMaglevTableSize)
+	return m
+}
+
+// NewMaglevWithSize 指定查找表大小，大小必须是质数且应远大于结点数
+func NewMaglevWithSize(size int) (*Maglev, error) {
+	if !isPrime(size) {
+		return nil, fmt.Errorf("table size %d isn't a prime", size)
+	}
+	m := &Maglev{nodes: map[string]Node{}, size: uint64(size)}
+	m.table.Store(&maglevTable{})
+	return m, nil
+}
+
+func (m *Maglev) Add(node Node) error {
+	if node == nil {
+		return ErrNilNode
+	}
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.nodes[node.Key()]; ok {
+		return newNodeError(node.Key(), ErrNodeExists)
+	}
+	if uint64(len(m.nodes)) >= m.size {
+		return fmt.Errorf("node size can't exceed table size %d", m.size)
+	}
+	m.nodes[node.Key()] = node
+	m.rebuild()
+	return nil
+}
+
+func (m *Maglev) Remove(key string) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.nodes[key]; !ok {
+		return newNodeError(key, ErrNodeNotFound)
+	}
+	delete(m.nodes, key)
+	m.rebuild()
+	return nil
+}
+
+func (m *Maglev) Len() int {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.nodes)
+}
+
+func (m *Maglev) GetNode(key string) (Node, error) {
+	t := m.table.Load().(*maglevTable)
+	if len(t.entries) == 0 {
+		return nil, ErrEmptyRing
+	}
+	return t.entries[maglevHash(key, 0)%uint64(len(t.entries))], nil
+}
+
+// 按论文中的算法填充查找表，调用方需持有锁
+func (m *Maglev) rebuild() {
+	if len(m.nodes) == 0 {
+		m.table.Store(&maglevTable{})
+		return
+	}
+
+	// 按 key 排序保证相同的结点集合得到相同的查找表
+	nodes := make([]Node, 0, len(m.nodes))
+	for _, n := range m.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Key() < nodes[j].Key()
+	})
+
+	offsets := make([]uint64, len(nodes))
+	skips := make([]uint64, len(nodes))
+	for i, n := range nodes {
+		offsets[i] = maglevHash(n.Key(), 1) % m.size
+		skips[i] = maglevHash(n.Key(), 2)%(m.size-1) + 1
+	}
+
+	entries := make([]Node, m.size)
+	next := make([]uint64, len(nodes))
+	for filled := uint64(0); ; {
+		for i := range nodes {
+			c := (offsets[i] + next[i]*skips[i]) % m.size
+			for entries[c] != nil {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % m.size
+			}
+			entries[c] = nodes[i]
+			next[i]++
+			filled++
+			if filled == m.size {
+				m.table.Store(&maglevTable{entries: entries})
+				return
+			}
+		}
+	}
+}
+
+func maglevHash(key string, seed byte) uint64 {
+	h := fnv.New64a()
+	if seed != 0 {
+		h.Write([]byte{seed})
+	}
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for i := 2; i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package consistent_hash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMaglev_Remove(t *testing.T) {
+	m := NewMaglev()
+	if _, err := m.GetNode("key"); err == nil {
+		t.Fatal("GetNode on empty Maglev should fail")
+	}
+	for i := 0; i < 10; i++ {
+		if err := m.Add(testNode{"node-" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add(testNode{"node-0"}); err == nil {
+		t.Fatal("add duplicate node should fail")
+	}
+
+	keys := make([]string, 100000)
+	before := make([]string, len(keys))
+	counts := map[string]int{}
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+		n, err := m.GetNode(keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[i] = n.Key()
+		counts[n.Key()]++
+	}
+	for k, v := range counts {
+		if v < 9000 || v > 11000 {
+			t.Errorf("node %s owns %d keys, want about 10000", k, v)
+		}
+	}
+
+	if err := m.Remove("node-3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("node-3"); err == nil {
+		t.Fatal("remove unknown node should fail")
+	}
+	// Maglev 不保证完全最小迁移，但其余结点上的 key 只应有极少量移动
+	moved, others := 0, 0
+	for i, k := range keys {
+		n, _ := m.GetNode(k)
+		if n.Key() == "node-3" {
+			t.Fatalf("key %s still owned by removed node", k)
+		}
+		if before[i] == "node-3" {
+			continue
+		}
+		others++
+		if n.Key() != before[i] {
+			moved++
+		}
+	}
+	if frac := float64(moved) / float64(others); frac > 0.02 {
+		t.Errorf("%.3f of keys on surviving nodes moved, want < 0.02", frac)
+	}
+}
+
+func TestMaglev_TableSize(t *testing.T) {
+	if _, err := NewMaglevWithSize(100); err == nil {
+		t.Error("non-prime table size should fail")
+	}
+	m, err := NewMaglevWithSize(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		if err := m.Add(testNode{"node-" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add(testNode{"node-7"}); err == nil {
+		t.Error("node size larger than table size should fail")
+	}
+	// 每个结点恰好占据一个表项
+	seen := map[string]bool{}
+	for _, n := range m.table.Load().(*maglevTable).entries {
+		seen[n.Key()] = true
+	}
+	if len(seen) != 7 {
+		t.Errorf("table holds %d distinct nodes, want 7", len(seen))
+	}
+}
+
+func BenchmarkMaglev_GetNode(b *testing.B) {
+	m := NewMaglev()
+	for _, n := range jumpTestNodes(1000) {
+		m.Add(n)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.GetNode("key-" + strconv.Itoa(i))
+	}
+}
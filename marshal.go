@@ -0,0 +1,295 @@
+package consistent_hash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// 二进制格式:
+//
+//	version      byte
+//	node count   uvarint
+//	每个结点按 key 排序:
+//	  key        uvarint 长度 + 字节
+//	  points     uvarint 个数 + 每个 4 字节大端 uint32，保持添加时的顺序
+//	sorted       uvarint 个数 + 每个 4 字节大端 uint32
+//	pins         版本 2、3 有，uvarint 个数 + 按 key 排序的 (key, 结点 key)，均为 uvarint 长度 + 字节
+//	chains       只有版本 3 有，uvarint 个数 + 按位置排序的 (4 字节大端位置, uvarint 个数 + 每个结点 key)，
+//	             CollisionChain 下被多个结点共用的虚拟结点，结点按添加顺序排列
+//
+// 没有 PinKey 固定的 key 时输出版本 1，与旧版本兼容，有共用的虚拟结点时输出版本 3
+const (
+	binaryVersion       = 1
+	binaryVersionPins   = 2
+	binaryVersionChains = 3
+)
+
+// MarshalBinary 序列化结点 key、每个结点的虚拟结点以及排序后的虚拟结点数组。
+// hash 函数和其他配置不会被序列化
+func (c *ConsistentHash) MarshalBinary() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	keys := make([]string, 0, len(c.nodes))
+	size := 1 + binary.MaxVarintLen64*2 + 4*len(c.hashSortedNodes)
+	for k, n := range c.nodes {
+		keys = append(keys, k)
+		size += binary.MaxVarintLen64*2 + len(k) + 4*len(n.virtualNodes)
+	}
+	sort.Strings(keys)
+
+	pinKeys := make([]string, 0, len(c.pins))
+	for k, v := range c.pins {
+		pinKeys = append(pinKeys, k)
+		size += binary.MaxVarintLen64*2 + len(k) + len(v)
+	}
+	sort.Strings(pinKeys)
+
+	chainPoints := c.sortedChainPoints()
+	for _, p := range chainPoints {
+		size += 4 + binary.MaxVarintLen64
+		for _, k := range c.chains[p] {
+			size += binary.MaxVarintLen64 + len(k)
+		}
+	}
+
+	version := byte(binaryVersion)
+	switch {
+	case len(chainPoints) > 0:
+		version = binaryVersionChains
+	case len(pinKeys) > 0:
+		version = binaryVersionPins
+	}
+	buf := make([]byte, 0, size)
+	buf = append(buf, version)
+	buf = appendUvarint(buf, uint64(len(keys)))
+	for _, k := range keys {
+		buf = appendString(buf, k)
+		buf = appendPoints(buf, c.nodes[k].virtualNodes)
+	}
+	buf = appendPoints(buf, c.hashSortedNodes)
+	if version >= binaryVersionPins {
+		buf = appendUvarint(buf, uint64(len(pinKeys)))
+		for _, k := range pinKeys {
+			buf = appendString(buf, k)
+			buf = appendString(buf, c.pins[k])
+		}
+	}
+	if version == binaryVersionChains {
+		buf = appendUvarint(buf, uint64(len(chainPoints)))
+		var tmp [4]byte
+		for _, p := range chainPoints {
+			binary.BigEndian.PutUint32(tmp[:], p)
+			buf = append(buf, tmp[:]...)
+			buf = appendUvarint(buf, uint64(len(c.chains[p])))
+			for _, k := range c.chains[p] {
+				buf = appendString(buf, k)
+			}
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 用 MarshalBinary 的结果替换环上的全部结点，结点由 WithNodeFactory 创建，默认为 StringNode。
+// 接收者的 hash 函数和配置保持不变，数据不完整或不一致时返回 ErrCorruptData，环保持不变
+func (c *ConsistentHash) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty input", ErrCorruptData)
+	}
+	if data[0] < binaryVersion || data[0] > binaryVersionChains {
+		return fmt.Errorf("%w: unknown version %d", ErrCorruptData, data[0])
+	}
+	d := binaryDecoder{data: data[1:]}
+
+	count := d.length(1)
+	keys := make([]string, 0, count)
+	points := make([][]uint32, 0, count)
+	for i := 0; i < count && d.err == nil; i++ {
+		keys = append(keys, string(d.bytes(d.length(1))))
+		points = append(points, d.points())
+	}
+	sorted := d.points()
+	var pins map[string]string
+	if data[0] >= binaryVersionPins {
+		// 每个固定至少有两个长度
+		count := d.length(2)
+		pins = make(map[string]string, count)
+		for i := 0; i < count && d.err == nil; i++ {
+			key := string(d.bytes(d.length(1)))
+			pins[key] = string(d.bytes(d.length(1)))
+		}
+	}
+	var chains map[uint32][]string
+	if data[0] == binaryVersionChains {
+		// 每个 chain 至少有位置和结点数
+		count := d.length(5)
+		chains = make(map[uint32][]string, count)
+		for i := 0; i < count && d.err == nil; i++ {
+			p := d.uint32()
+			owners := make([]string, d.length(1))
+			for j := range owners {
+				owners[j] = string(d.bytes(d.length(1)))
+			}
+			if _, ok := chains[p]; ok && d.err == nil {
+				d.err = fmt.Errorf("%w: duplicate chain of point %d", ErrCorruptData, p)
+			}
+			chains[p] = owners
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrCorruptData, len(d.data))
+	}
+	return c.loadPoints(keys, points, sorted, pins, chains)
+}
+
+// 用 keys[i] 及其虚拟结点 points[i] 替换环上的全部结点，sorted 必须是全部虚拟结点的升序排列，
+// pins 替换全部固定，可以指向不在环上的结点。chains 为被多个结点共用的虚拟结点，必须与 points 一致。
+// 数据不一致时返回 ErrCorruptData，环保持不变
+func (c *ConsistentHash) loadPoints(keys []string, points [][]uint32, sorted []uint32, pins map[string]string, chains map[uint32][]string) error {
+	nodes := make(map[string]consistentNode, len(keys))
+	circle := make(map[uint32]string, len(sorted))
+	var shared map[uint32][]string
+	for i, key := range keys {
+		if key == "" {
+			return fmt.Errorf("%w: %v", ErrCorruptData, ErrEmptyKey)
+		}
+		if _, ok := nodes[key]; ok {
+			return fmt.Errorf("%w: duplicate node %s", ErrCorruptData, key)
+		}
+		for _, p := range points[i] {
+			owner, ok := circle[p]
+			if !ok {
+				circle[p] = key
+				continue
+			}
+			if _, chained := chains[p]; !chained || owner == key || containsKey(shared[p], key) {
+				return fmt.Errorf("%w: point %d owned by both %s and %s", ErrCorruptData, p, owner, key)
+			}
+			if shared == nil {
+				shared = make(map[uint32][]string, len(chains))
+			}
+			if shared[p] == nil {
+				shared[p] = []string{owner}
+			}
+			shared[p] = append(shared[p], key)
+		}
+		nodes[key] = newConsistentNode(nil, points[i])
+	}
+	if err := checkChains(shared, chains); err != nil {
+		return err
+	}
+	for p, chain := range chains {
+		circle[p] = chain[0]
+	}
+	if len(chains) == 0 {
+		chains = nil
+	}
+
+	// 排序数组必须与各结点的虚拟结点一一对应
+	if len(sorted) != len(circle) {
+		return fmt.Errorf("%w: %d sorted points for %d virtual nodes", ErrCorruptData, len(sorted), len(circle))
+	}
+	for i, p := range sorted {
+		if i > 0 && sorted[i-1] >= p {
+			return fmt.Errorf("%w: sorted points out of order", ErrCorruptData)
+		}
+		if _, ok := circle[p]; !ok {
+			return fmt.Errorf("%w: point %d has no owner", ErrCorruptData, p)
+		}
+	}
+
+	c.lockWrite()
+	defer c.unlock()
+	for k, n := range nodes {
+		n.node = c.newNode(k)
+		nodes[k] = n
+	}
+	c.lazyInit()
+	c.recordAllRemoved()
+	c.replaceState(&ConsistentHash{hashSortedNodes: sorted, circle: circle, nodes: nodes, chains: chains, pins: copyPins(pins), reserved: c.reserved})
+	for _, k := range keys {
+		c.recordAdded(nodes[k].node)
+	}
+	return nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = appendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendPoints(buf []byte, points []uint32) []byte {
+	buf = appendUvarint(buf, uint64(len(points)))
+	var tmp [4]byte
+	for _, p := range points {
+		binary.BigEndian.PutUint32(tmp[:], p)
+		buf = append(buf, tmp[:]...)
+	}
+	return buf
+}
+
+// 记录第一个错误，之后的读取都返回零值
+type binaryDecoder struct {
+	data []byte
+	err  error
+}
+
+// 读取一个长度，每个元素至少占 size 字节，长度超过剩余数据时视为损坏
+func (d *binaryDecoder) length(size int) int {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = fmt.Errorf("%w: bad length", ErrCorruptData)
+		return 0
+	}
+	d.data = d.data[n:]
+	if v > uint64(len(d.data)/size) {
+		d.err = fmt.Errorf("%w: length %d exceeds input", ErrCorruptData, v)
+		return 0
+	}
+	return int(v)
+}
+
+func (d *binaryDecoder) uint32() uint32 {
+	if d.err == nil && len(d.data) < 4 {
+		d.err = fmt.Errorf("%w: truncated point", ErrCorruptData)
+	}
+	b := d.bytes(4)
+	if d.err != nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *binaryDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *binaryDecoder) points() []uint32 {
+	b := d.bytes(4 * d.length(4))
+	if d.err != nil {
+		return nil
+	}
+	points := make([]uint32, len(b)/4)
+	for i := range points {
+		points[i] = binary.BigEndian.Uint32(b[4*i:])
+	}
+	return points
+}
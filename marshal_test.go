@@ -0,0 +1,204 @@
+package consistent_hash
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*ConsistentHash)(nil)
+	_ encoding.BinaryUnmarshaler = (*ConsistentHash)(nil)
+)
+
+func TestConsistentHash_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		ring *ConsistentHash
+		load func() *ConsistentHash
+	}{
+		{"default", NewConsistentHash(), NewConsistentHash},
+		{"xxhash", NewConsistentHashXX(), NewConsistentHashXX},
+		{"ketama", NewKetama(), NewKetama},
+	}
+	for _, tt := range tests {
+		if err := tt.ring.AddAll(batchTestNodes("node-", 20), 30); err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.ring.SetVirtualNodeCount("node-3", 5); err != nil {
+			t.Fatal(err)
+		}
+		data, err := tt.ring.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := tt.load()
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got.hashSortedNodes, tt.ring.hashSortedNodes) || !reflect.DeepEqual(got.circle, tt.ring.circle) {
+			t.Fatalf("%s: ring differs after round trip", tt.name)
+		}
+		for k, n := range tt.ring.nodes {
+			if !reflect.DeepEqual(got.nodes[k].virtualNodes, n.virtualNodes) {
+				t.Fatalf("%s: virtual nodes of %s differ", tt.name, k)
+			}
+		}
+		for i := 0; i < 1000; i++ {
+			key := "key-" + strconv.Itoa(i)
+			a, _ := tt.ring.GetNode(key)
+			b, _ := got.GetNode(key)
+			if a.Key() != b.Key() {
+				t.Fatalf("%s: GetNode(%s) = %s, want %s", tt.name, key, b.Key(), a.Key())
+			}
+		}
+
+		// 还原后的环可以继续修改
+		if n, _ := got.VirtualNodeCount("node-3"); n != 5 {
+			t.Fatalf("%s: VirtualNodeCount = %d, want 5", tt.name, n)
+		}
+		if _, err := got.RemoveByKey("node-0"); err != nil {
+			t.Fatal(err)
+		}
+		if err := got.AddWithVirtualNode(testNode{"node-0"}, 30); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.hashSortedNodes, tt.ring.hashSortedNodes) {
+			t.Fatalf("%s: re-adding a node after Unmarshal changed the ring", tt.name)
+		}
+	}
+}
+
+func TestConsistentHash_UnmarshalBinaryReplaces(t *testing.T) {
+	src := NewConsistentHash()
+	if err := src.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := src.MarshalBinary()
+
+	dst := NewConsistentHash()
+	if err := dst.AddAll(batchTestNodes("old-", 3), 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(dst.Members()); !equalStrings(got, []string{"a"}) {
+		t.Fatalf("Members = %v", got)
+	}
+	if _, ok := dst.Members()[0].(StringNode); !ok {
+		t.Fatalf("node type = %T, want StringNode", dst.Members()[0])
+	}
+
+	empty, _ := NewConsistentHash().MarshalBinary()
+	if err := dst.UnmarshalBinary(empty); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dst.GetNode("x"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("GetNode = %v, want ErrEmptyRing", err)
+	}
+}
+
+func TestConsistentHash_UnmarshalBinaryCorrupt(t *testing.T) {
+	src := NewConsistentHash()
+	if err := src.AddAll(batchTestNodes("node-", 3), 4); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := src.MarshalBinary()
+
+	dst := NewConsistentHash()
+	if err := dst.Add(testNode{"keep"}); err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, b []byte) {
+		t.Helper()
+		if err := dst.UnmarshalBinary(b); !errors.Is(err, ErrCorruptData) {
+			t.Fatalf("%s: UnmarshalBinary = %v, want ErrCorruptData", name, err)
+		}
+		if got := nodeKeys(dst.Members()); !equalStrings(got, []string{"keep"}) {
+			t.Fatalf("%s: failed UnmarshalBinary changed the ring: %v", name, got)
+		}
+	}
+
+	for i := 0; i < len(data); i++ {
+		check("truncated at "+strconv.Itoa(i), data[:i])
+	}
+	check("trailing bytes", append(append([]byte(nil), data...), 0))
+
+	version := append([]byte(nil), data...)
+	version[0] = binaryVersionPins + 1
+	check("unknown version", version)
+
+	// 交换排序数组的最后两个虚拟结点
+	unsorted := append([]byte(nil), data...)
+	n := len(unsorted)
+	last := binary.BigEndian.Uint32(unsorted[n-4:])
+	binary.BigEndian.PutUint32(unsorted[n-4:], binary.BigEndian.Uint32(unsorted[n-8:]))
+	binary.BigEndian.PutUint32(unsorted[n-8:], last)
+	check("unsorted points", unsorted)
+
+	// 排序数组中出现不属于任何结点的虚拟结点
+	orphan := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(orphan[n-4:], last+1)
+	check("orphan point", orphan)
+
+	huge := []byte{binaryVersion}
+	huge = appendUvarint(huge, 1<<40)
+	check("huge length", huge)
+}
+
+func TestConsistentHash_UnmarshalBinaryKeepsHash(t *testing.T) {
+	src := NewConsistentHashXX()
+	if err := src.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := src.MarshalBinary()
+
+	// 接收者使用不同的 hash 时，虚拟结点位置照搬，查询使用接收者的 hash
+	dst := NewConsistentWithCustomHash(func(string) uint32 { return 7 })
+	if err := dst.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if dst.hashKey("x") != 7 {
+		t.Fatal("UnmarshalBinary replaced the receiver's hash")
+	}
+	if !reflect.DeepEqual(dst.hashSortedNodes, src.hashSortedNodes) {
+		t.Fatal("points differ after round trip")
+	}
+}
+
+func benchmarkRing(b *testing.B) *ConsistentHash {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 1000), 100); err != nil {
+		b.Fatal(err)
+	}
+	return c
+}
+
+func BenchmarkConsistentHash_UnmarshalBinary(b *testing.B) {
+	data, _ := benchmarkRing(b).MarshalBinary()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := NewConsistentHash()
+		if err := c.UnmarshalBinary(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConsistentHash_RebuildFromAdds(b *testing.B) {
+	nodes := batchTestNodes("node-", 1000)
+	for i := 0; i < b.N; i++ {
+		c := NewConsistentHash()
+		for _, n := range nodes {
+			if err := c.AddWithVirtualNode(n, 100); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package consistent_hash
+
+// SetMeta 设置结点的元数据，覆盖之前的值。元数据随结点删除，Clone 时复制引用，不影响查找和 Version
+func (c *ConsistentHash) SetMeta(nodeKey string, meta interface{}) error {
+	c.lockWrite()
+	defer c.unlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return newNodeError(nodeKey, ErrNodeNotFound)
+	}
+	cNode.meta = meta
+	c.nodes[nodeKey] = cNode
+	return nil
+}
+
+// GetMeta 返回结点的元数据，结点不存在或没有设置时第二个返回值为 false
+func (c *ConsistentHash) GetMeta(nodeKey string) (interface{}, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok || cNode.meta == nil {
+		return nil, false
+	}
+	return cNode.meta, true
+}
+
+// GetNodeWithMeta 与 GetNode 相同，同时返回结点的元数据
+func (c *ConsistentHash) GetNodeWithMeta(key string) (Node, interface{}, error) {
+	c.RLock()
+	// 快照在写锁内发布，持有读锁时与 nodes 一致
+	s := c.loadSnapshot()
+	n, err := s.find(key)
+	if err != nil {
+		c.RUnlock()
+		return nil, nil, err
+	}
+	meta := c.nodes[n.key].meta
+	c.RUnlock()
+
+	node := s.hit(n)
+	if s.metrics != nil {
+		s.metrics.ObserveLookup(n.key)
+	}
+	return node, meta, nil
+}
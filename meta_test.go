@@ -0,0 +1,55 @@
+package consistent_hash
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConsistentHash_Meta(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.SetMeta("a", "dc1"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("SetMeta on unknown node err = %v", err)
+	}
+	if _, _, err := c.GetNodeWithMeta("k"); err != ErrEmptyRing {
+		t.Fatalf("GetNodeWithMeta on empty ring err = %v", err)
+	}
+	c.AddWithVirtualNode(testNode{"a"}, 10)
+	c.AddWithVirtualNode(testNode{"b"}, 10)
+	if _, ok := c.GetMeta("a"); ok {
+		t.Fatal("meta set before SetMeta")
+	}
+
+	version := c.Version()
+	c.SetMeta("a", "dc1")
+	c.SetMeta("a", "dc2")
+	c.SetMeta("b", 3)
+	if m, ok := c.GetMeta("a"); !ok || m != "dc2" {
+		t.Fatalf("GetMeta(a) = %v, %v", m, ok)
+	}
+	if c.Version() != version {
+		t.Fatal("SetMeta changed the ring version")
+	}
+	n, m, err := c.GetNodeWithMeta("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := c.GetMeta(n.Key()); m != want {
+		t.Fatalf("GetNodeWithMeta = %v, %v, want meta %v", n.Key(), m, want)
+	}
+
+	clone := c.Clone()
+	c.RemoveByKey("a")
+	if _, ok := c.GetMeta("a"); ok {
+		t.Fatal("meta survived Remove")
+	}
+	c.AddWithVirtualNode(testNode{"a"}, 10)
+	if _, ok := c.GetMeta("a"); ok {
+		t.Fatal("re-added node inherited old meta")
+	}
+	if m, ok := clone.GetMeta("a"); !ok || m != "dc2" {
+		t.Fatalf("clone GetMeta(a) = %v, %v", m, ok)
+	}
+	if m, ok := clone.GetMeta("b"); !ok || m != 3 {
+		t.Fatalf("clone GetMeta(b) = %v, %v", m, ok)
+	}
+}
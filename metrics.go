@@ -0,0 +1,93 @@
+package consistent_hash
+
+import "math"
+
+// Metrics 接收环的监控数据，用于对接 Prometheus 等监控系统而不引入依赖。
+// 方法在释放环的锁之后调用，可能被并发调用
+type Metrics interface {
+	// GetNode 返回了 nodeKey
+	ObserveLookup(nodeKey string)
+	// 添加或删除了结点，remappedFraction 为归属发生变化的 hash 空间的比例
+	ObserveTopologyChange(added, removed int, remappedFraction float64)
+	SetNodeCount(n int)
+	SetVirtualPointCount(n int)
+}
+
+// NopMetrics 忽略所有监控数据
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveLookup(string)                    {}
+func (NopMetrics) ObserveTopologyChange(int, int, float64) {}
+func (NopMetrics) SetNodeCount(int)                        {}
+func (NopMetrics) SetVirtualPointCount(int)                {}
+
+// WithMetrics 在 GetNode 时调用 m.ObserveLookup，在 Add、AddWithVirtualNode、Remove、RemoveByKey 时调用
+// m.ObserveTopologyChange。任何修改 (Sync、AddAll、Reset、Drain 等) 改变了结点数或虚拟结点数之后调用
+// SetNodeCount 和 SetVirtualPointCount
+func WithMetrics(m Metrics) Option {
+	return func(c *ConsistentHash) error {
+		c.metrics = m
+		return nil
+	}
+}
+
+// 在锁内记录拓扑变化，返回的函数在释放锁之后调用，调用方需持有锁
+func (c *ConsistentHash) observeTopology(added, removed int, fraction float64) func() {
+	m := c.metrics
+	if m == nil {
+		return nil
+	}
+	return func() {
+		m.ObserveTopologyChange(added, removed, fraction)
+	}
+}
+
+// 结点数或虚拟结点数与上一次上报的不同时返回上报的函数，在释放锁之后调用，调用方需持有写锁
+func (c *ConsistentHash) observeGauges() func() {
+	m := c.metrics
+	gauges := [2]int{len(c.nodes), len(c.hashSortedNodes)}
+	if m == nil || gauges == c.gauges {
+		return nil
+	}
+	c.gauges = gauges
+	return func() {
+		m.SetNodeCount(gauges[0])
+		m.SetVirtualPointCount(gauges[1])
+	}
+}
+
+// 结点拥有的 hash 空间的比例，调用方需持有锁
+func (c *ConsistentHash) ownedFraction(nodeKey string) float64 {
+	var width float64
+	for i, p := range c.hashSortedNodes {
+		if c.circle[p] == nodeKey {
+			width += c.pointWidth(i)
+		}
+	}
+	return width / (1 << 32)
+}
+
+// 第 i 个虚拟结点拥有的区间宽度，不包含 ReserveRange 保留的部分，调用方需持有锁
+func (c *ConsistentHash) pointWidth(i int) float64 {
+	return pointWidthIn(c.hashSortedNodes, c.reserved, i)
+}
+
+// 有序的虚拟结点 points 中第 i 个拥有的区间宽度，不包含 reserved
+func pointWidthIn(points []uint32, reserved []reservedRange, i int) float64 {
+	if i > 0 {
+		width := float64(points[i] - points[i-1])
+		if len(reserved) > 0 {
+			width -= reservedWidth(reserved, points[i-1]+1, points[i])
+		}
+		return width
+	}
+	// 第一个虚拟结点拥有跨越环的起点的区间
+	width := float64(points[0]) + float64(math.MaxUint32-points[len(points)-1]) + 1
+	if len(reserved) > 0 {
+		width -= reservedWidth(reserved, 0, points[0])
+		if last := points[len(points)-1]; last != math.MaxUint32 {
+			width -= reservedWidth(reserved, last+1, math.MaxUint32)
+		}
+	}
+	return width
+}
@@ -0,0 +1,167 @@
+package consistent_hash
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type recordingMetrics struct {
+	sync.Mutex
+	ring    *ConsistentHash
+	lookups []string
+	changes [][3]float64
+	nodes   []int
+	points  []int
+}
+
+func (m *recordingMetrics) ObserveLookup(nodeKey string) {
+	m.Lock()
+	defer m.Unlock()
+	m.lookups = append(m.lookups, nodeKey)
+}
+
+func (m *recordingMetrics) ObserveTopologyChange(added, removed int, fraction float64) {
+	// 在锁外调用，这里访问环不会死锁
+	m.ring.Len()
+	m.Lock()
+	defer m.Unlock()
+	m.changes = append(m.changes, [3]float64{float64(added), float64(removed), fraction})
+}
+
+func (m *recordingMetrics) SetNodeCount(n int) {
+	m.Lock()
+	defer m.Unlock()
+	m.nodes = append(m.nodes, n)
+}
+
+func (m *recordingMetrics) SetVirtualPointCount(n int) {
+	m.Lock()
+	defer m.Unlock()
+	m.points = append(m.points, n)
+}
+
+func TestWithMetrics(t *testing.T) {
+	m := &recordingMetrics{}
+	c, _ := New(WithMetrics(m), WithHash(tableHash(map[string]uint32{
+		"a#0#0": 1 << 30, "b#0#0": 1 << 31, "b#1#0": 3 << 30,
+	})))
+	m.ring = c
+
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithVirtualNode(testNode{"b"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	// 失败的操作不上报
+	if err := c.Add(testNode{"a"}); err == nil {
+		t.Fatal("duplicate Add should fail")
+	}
+	if _, err := c.GetNode("1073741824"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Remove(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RemoveByKey("x"); err == nil {
+		t.Fatal("RemoveByKey(x) should fail")
+	}
+
+	wantChanges := [][3]float64{
+		{1, 0, 1},   // 第一个结点接管全部 hash 空间
+		{1, 0, 0.5}, // b 拥有 (1<<30, 1<<31] 和 (1<<31, 3<<30]
+		{0, 1, 0.5},
+	}
+	if !reflect.DeepEqual(m.changes, wantChanges) {
+		t.Errorf("ObserveTopologyChange = %v, want %v", m.changes, wantChanges)
+	}
+	if !reflect.DeepEqual(m.nodes, []int{1, 2, 1}) || !reflect.DeepEqual(m.points, []int{1, 3, 1}) {
+		t.Errorf("SetNodeCount = %v, SetVirtualPointCount = %v", m.nodes, m.points)
+	}
+	if !reflect.DeepEqual(m.lookups, []string{"a"}) {
+		t.Errorf("ObserveLookup = %v", m.lookups)
+	}
+}
+
+func TestNopMetrics(t *testing.T) {
+	var _ Metrics = NopMetrics{}
+	c, _ := New(WithMetrics(NopMetrics{}))
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNode("key"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 所有修改结点数或虚拟结点数的操作都更新 gauge，不修改的操作不上报
+func TestWithMetrics_Gauges(t *testing.T) {
+	m := &recordingMetrics{}
+	c, _ := New(WithMetrics(m))
+	m.ring = c
+	last := func() (int, int) {
+		m.Lock()
+		defer m.Unlock()
+		if len(m.nodes) == 0 {
+			return -1, -1
+		}
+		return m.nodes[len(m.nodes)-1], m.points[len(m.points)-1]
+	}
+	check := func(op string) {
+		t.Helper()
+		if nodes, points := last(); nodes != c.Len() || points != c.TotalPoints() {
+			t.Errorf("after %s gauges = %d nodes, %d points, ring has %d, %d", op, nodes, points, c.Len(), c.TotalPoints())
+		}
+	}
+
+	c.Sync(batchTestNodes("node-", 4), 10)
+	check("Sync")
+	c.AddAll(batchTestNodes("more-", 2), 10)
+	check("AddAll")
+	c.Drain("more-0", 2)
+	check("Drain")
+	c.ShrinkVirtualNodes("more-1", 5)
+	check("ShrinkVirtualNodes")
+	c.RemoveAll([]string{"more-0", "more-1"})
+	check("RemoveAll")
+	c.ApplyPlan(map[string]int{"node-0": 20})
+	check("ApplyPlan")
+	if err := c.Batch().Add(testNode{"batch"}, 10).Commit(); err != nil {
+		t.Fatal(err)
+	}
+	check("Batch.Commit")
+
+	m.Lock()
+	reported := len(m.nodes)
+	m.Unlock()
+	c.MarkDown("node-1")
+	c.Add(testNode{"node-0"})
+	if len(m.nodes) != reported {
+		t.Error("gauges reported without a change in node or point count")
+	}
+	c.Reset()
+	check("Reset")
+}
+
+// 预测修改结果的方法不上报
+func TestWithMetrics_Previews(t *testing.T) {
+	m := &recordingMetrics{}
+	c, _ := New(WithMetrics(m))
+	m.ring = c
+	c.AddAll(batchTestNodes("node-", 3), 10)
+	m.Lock()
+	reported := len(m.nodes) + len(m.changes)
+	m.Unlock()
+
+	c.RemapOnAdd(testNode{"x"}, 10)
+	c.RemapOnRemove("node-0")
+	c.WhatIfAdd(testNode{"x"}, 10)
+	c.WhatIfRemove("node-0")
+	c.PlanReplicas(map[string]float64{"node-0": 0.5, "node-1": 0.25, "node-2": 0.25}, 100)
+	m.Lock()
+	defer m.Unlock()
+	if len(m.nodes)+len(m.changes) != reported {
+		t.Errorf("previews reported metrics: nodes %v, changes %v", m.nodes, m.changes)
+	}
+}
@@ -0,0 +1,52 @@
+package consistent_hash
+
+// Migration 用于扩缩容期间的读修复：先读新环上的结点，没有时再读旧环上的结点
+type Migration struct {
+	old, new *ConsistentHash
+}
+
+// NewMigration 创建迁移助手，oldRing 或 newRing 为 nil 时视为空环
+func NewMigration(oldRing, newRing *ConsistentHash) *Migration {
+	if oldRing == nil {
+		oldRing = NewConsistentHash()
+	}
+	if newRing == nil {
+		newRing = NewConsistentHash()
+	}
+	return &Migration{old: oldRing, new: newRing}
+}
+
+// GetNodes 返回 key 在新环和旧环上所属的结点，环为空时对应的结点为 nil。
+// moved 表示两个环上都有结点且 key 不同，即 key 需要从旧结点迁移到新结点。
+// 两个环都为空时返回 ErrEmptyRing
+func (m *Migration) GetNodes(key string) (newOwner, oldOwner Node, moved bool, err error) {
+	unlock := rlockBoth(m.old, m.new)
+	defer unlock()
+
+	newOwner = m.new.ownerNode(m.new.hashKey(key))
+	oldOwner = m.old.ownerNode(m.old.hashKey(key))
+	if newOwner == nil && oldOwner == nil {
+		return nil, nil, false, ErrEmptyRing
+	}
+	moved = newOwner != nil && oldOwner != nil && newOwner.Key() != oldOwner.Key()
+	return newOwner, oldOwner, moved, nil
+}
+
+// MovedFraction 返回 sample 中需要迁移的 key 的比例
+func (m *Migration) MovedFraction(sample []string) float64 {
+	if len(sample) == 0 {
+		return 0
+	}
+	unlock := rlockBoth(m.old, m.new)
+	defer unlock()
+
+	moved := 0
+	for _, key := range sample {
+		newOwner := m.new.ownerOf(m.new.hashKey(key))
+		oldOwner := m.old.ownerOf(m.old.hashKey(key))
+		if newOwner != "" && oldOwner != "" && newOwner != oldOwner {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(sample))
+}
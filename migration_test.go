@@ -0,0 +1,87 @@
+package consistent_hash
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestMigration_GetNodes(t *testing.T) {
+	oldRing := NewConsistentHashXX()
+	if err := oldRing.AddAll(batchTestNodes("node-", 4), 100); err != nil {
+		t.Fatal(err)
+	}
+	newRing := oldRing.Clone()
+	// node-0 只在旧环上，node-4 只在新环上
+	if _, err := newRing.RemoveByKey("node-0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := newRing.AddWithVirtualNode(testNode{"node-4"}, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMigration(oldRing, newRing)
+	movedKeys, stayed := 0, 0
+	sample := make([]string, 10000)
+	for i := range sample {
+		sample[i] = "key-" + strconv.Itoa(i)
+		newOwner, oldOwner, moved, err := m.GetNodes(sample[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantNew, _ := newRing.GetNode(sample[i])
+		wantOld, _ := oldRing.GetNode(sample[i])
+		if newOwner.Key() != wantNew.Key() || oldOwner.Key() != wantOld.Key() {
+			t.Fatalf("GetNodes(%s) = %s %s, want %s %s", sample[i], newOwner.Key(), oldOwner.Key(), wantNew.Key(), wantOld.Key())
+		}
+		if moved != (wantNew.Key() != wantOld.Key()) {
+			t.Fatalf("GetNodes(%s) moved = %v", sample[i], moved)
+		}
+		if oldOwner.Key() == "node-0" && !moved {
+			t.Fatalf("key %s of the removed node didn't move", sample[i])
+		}
+		if moved {
+			movedKeys++
+		} else {
+			stayed++
+		}
+	}
+	if movedKeys == 0 || stayed == 0 {
+		t.Fatalf("moved %d, stayed %d", movedKeys, stayed)
+	}
+	if got, want := m.MovedFraction(sample), float64(movedKeys)/float64(len(sample)); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("MovedFraction = %v, want %v", got, want)
+	}
+	if got := m.MovedFraction(nil); got != 0 {
+		t.Fatalf("MovedFraction(nil) = %v", got)
+	}
+}
+
+func TestMigration_EmptyRings(t *testing.T) {
+	ring := NewConsistentHash()
+	if err := ring.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 冷启动，旧环为空
+	newOwner, oldOwner, moved, err := NewMigration(nil, ring).GetNodes("key")
+	if err != nil || newOwner.Key() != "a" || oldOwner != nil || moved {
+		t.Fatalf("cold start: %v %v %v %v", newOwner, oldOwner, moved, err)
+	}
+	newOwner, oldOwner, moved, err = NewMigration(ring, NewConsistentHash()).GetNodes("key")
+	if err != nil || newOwner != nil || oldOwner.Key() != "a" || moved {
+		t.Fatalf("empty new ring: %v %v %v %v", newOwner, oldOwner, moved, err)
+	}
+	if _, _, _, err := NewMigration(nil, nil).GetNodes("key"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("both empty: %v", err)
+	}
+	if got := NewMigration(nil, ring).MovedFraction([]string{"a", "b"}); got != 0 {
+		t.Fatalf("cold start MovedFraction = %v", got)
+	}
+
+	// 新旧环相同时不会重复加锁
+	if _, _, moved, err := NewMigration(ring, ring).GetNodes("key"); err != nil || moved {
+		t.Fatalf("same ring: %v %v", moved, err)
+	}
+}
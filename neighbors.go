@@ -0,0 +1,87 @@
+package consistent_hash
+
+// PointNeighbors 记录结点的一个虚拟结点两侧最近的其他物理结点及其虚拟结点
+type PointNeighbors struct {
+	Point uint32
+	// 逆时针方向最近的其他结点
+	Predecessor      Node
+	PredecessorPoint uint32
+	// 顺时针方向最近的其他结点，删除结点时该虚拟结点的 key 迁移到这里
+	Successor      Node
+	SuccessorPoint uint32
+}
+
+// NeighborsByPoint 按 hash 从小到大返回结点每个虚拟结点的邻居。
+// 结点不存在时返回 nil，环上只有这一个结点时 Predecessor 和 Successor 为 nil
+func (c *ConsistentHash) NeighborsByPoint(nodeKey string) []PointNeighbors {
+	c.RLock()
+	defer c.RUnlock()
+	return c.neighbors(nodeKey)
+}
+
+// Successor 返回结点顺时针方向最近的其他物理结点，结点有多个虚拟结点时返回出现次数最多的，
+// 次数相同时取 key 较小的。环上只有这一个结点时返回 ErrNoNeighbor
+func (c *ConsistentHash) Successor(nodeKey string) (Node, error) {
+	return c.nearest(nodeKey, func(n PointNeighbors) Node { return n.Successor })
+}
+
+// Predecessor 返回结点逆时针方向最近的其他物理结点，规则与 Successor 相同
+func (c *ConsistentHash) Predecessor(nodeKey string) (Node, error) {
+	return c.nearest(nodeKey, func(n PointNeighbors) Node { return n.Predecessor })
+}
+
+func (c *ConsistentHash) nearest(nodeKey string, pick func(PointNeighbors) Node) (Node, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if _, ok := c.nodes[nodeKey]; !ok {
+		return nil, newNodeError(nodeKey, ErrNodeNotFound)
+	}
+
+	counts := make(map[string]int)
+	var best Node
+	for _, n := range c.neighbors(nodeKey) {
+		node := pick(n)
+		if node == nil {
+			continue
+		}
+		k := node.Key()
+		counts[k]++
+		if best == nil || counts[k] > counts[best.Key()] || counts[k] == counts[best.Key()] && k < best.Key() {
+			best = node
+		}
+	}
+	if best == nil {
+		return nil, newNodeError(nodeKey, ErrNoNeighbor)
+	}
+	return best, nil
+}
+
+// 调用方需持有锁
+func (c *ConsistentHash) neighbors(nodeKey string) []PointNeighbors {
+	if _, ok := c.nodes[nodeKey]; !ok {
+		return nil
+	}
+	points := c.hashSortedNodes
+	n := len(points)
+	var result []PointNeighbors
+	for i, p := range points {
+		if c.circle[p] != nodeKey {
+			continue
+		}
+		neighbor := PointNeighbors{Point: p}
+		for j := 1; j < n; j++ {
+			if k := c.circle[points[(i+j)%n]]; k != nodeKey {
+				neighbor.Successor, neighbor.SuccessorPoint = c.nodes[k].node, points[(i+j)%n]
+				break
+			}
+		}
+		for j := 1; j < n; j++ {
+			if k := c.circle[points[(i-j+n)%n]]; k != nodeKey {
+				neighbor.Predecessor, neighbor.PredecessorPoint = c.nodes[k].node, points[(i-j+n)%n]
+				break
+			}
+		}
+		result = append(result, neighbor)
+	}
+	return result
+}
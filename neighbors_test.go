@@ -0,0 +1,85 @@
+package consistent_hash
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConsistentHash_Neighbors(t *testing.T) {
+	// 环上的顺序为 a(100) b(200) a(300) c(400) c(500) b(600)
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 100, "a#1#0": 300,
+		"b#0#0": 200, "b#1#0": 600,
+		"c#0#0": 400, "c#1#0": 500,
+	}))
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.AddKey(k, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []PointNeighbors{
+		// 逆时针越过环的起点
+		{Point: 100, Predecessor: StringNode("b"), PredecessorPoint: 600, Successor: StringNode("b"), SuccessorPoint: 200},
+		{Point: 300, Predecessor: StringNode("b"), PredecessorPoint: 200, Successor: StringNode("c"), SuccessorPoint: 400},
+	}
+	if got := c.NeighborsByPoint("a"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("NeighborsByPoint(a) = %+v", got)
+	}
+	// c 的两个虚拟结点相邻，跳过自己
+	want = []PointNeighbors{
+		{Point: 400, Predecessor: StringNode("a"), PredecessorPoint: 300, Successor: StringNode("b"), SuccessorPoint: 600},
+		{Point: 500, Predecessor: StringNode("a"), PredecessorPoint: 300, Successor: StringNode("b"), SuccessorPoint: 600},
+	}
+	if got := c.NeighborsByPoint("c"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("NeighborsByPoint(c) = %+v", got)
+	}
+	// 顺时针越过环的起点
+	if got := c.NeighborsByPoint("b")[1]; got.Successor != StringNode("a") || got.SuccessorPoint != 100 {
+		t.Fatalf("NeighborsByPoint(b)[1] = %+v", got)
+	}
+
+	tests := []struct {
+		key         string
+		successor   string
+		predecessor string
+	}{
+		{"a", "b", "b"},
+		{"b", "a", "a"}, // a、c 各出现一次时取 key 较小的
+		{"c", "b", "a"},
+	}
+	for _, tt := range tests {
+		if n, err := c.Successor(tt.key); err != nil || n.Key() != tt.successor {
+			t.Errorf("Successor(%s) = %v %v, want %s", tt.key, n, err, tt.successor)
+		}
+		if n, err := c.Predecessor(tt.key); err != nil || n.Key() != tt.predecessor {
+			t.Errorf("Predecessor(%s) = %v %v, want %s", tt.key, n, err, tt.predecessor)
+		}
+	}
+
+	if _, err := c.Successor("x"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Successor(x) = %v", err)
+	}
+	if got := c.NeighborsByPoint("x"); got != nil {
+		t.Errorf("NeighborsByPoint(x) = %v", got)
+	}
+}
+
+func TestConsistentHash_NeighborsSingleNode(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddKey("a", 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Successor("a"); !errors.Is(err, ErrNoNeighbor) {
+		t.Errorf("Successor = %v, want ErrNoNeighbor", err)
+	}
+	if _, err := c.Predecessor("a"); !errors.Is(err, ErrNoNeighbor) {
+		t.Errorf("Predecessor = %v, want ErrNoNeighbor", err)
+	}
+	for _, n := range c.NeighborsByPoint("a") {
+		if n.Successor != nil || n.Predecessor != nil {
+			t.Errorf("NeighborsByPoint = %+v", n)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package consistent_hash
+
+import (
+	"sort"
+	"sync"
+)
+
+// 结点的加入或离开，在写锁内记录，释放写锁之后通知
+type ringEvent struct {
+	typ     EventType
+	node    Node
+	version uint64
+}
+
+// 按提交顺序通知回调。回调期间不持有环的锁，回调中修改环产生的事件排在队列后面，
+// 由正在通知的 goroutine 继续处理
+type notifier struct {
+	mu         sync.Mutex
+	onAdded    []func(Node)
+	onRemoved  []func(Node)
+	queue      []ringEvent
+	delivering bool
+	// Watch 的订阅
+	watchers map[<-chan Event]*watcher
+	// OnPinDangling 的回调
+	onDangling []func([]DanglingPin)
+}
+
+// OnNodeAdded 注册结点加入环之后的回调，可以注册多个，按注册顺序调用。
+// 回调在释放写锁之后调用，可以在回调中访问和修改环，失败的操作不会触发回调
+func (c *ConsistentHash) OnNodeAdded(f func(Node)) {
+	c.notifier.mu.Lock()
+	defer c.notifier.mu.Unlock()
+	c.notifier.onAdded = append(c.notifier.onAdded, f)
+}
+
+// OnNodeRemoved 注册结点离开环之后的回调，规则与 OnNodeAdded 相同。
+// 所有回调按变更提交的顺序调用，先提交的删除一定先于后提交的添加通知
+func (c *ConsistentHash) OnNodeRemoved(f func(Node)) {
+	c.notifier.mu.Lock()
+	defer c.notifier.mu.Unlock()
+	c.notifier.onRemoved = append(c.notifier.onRemoved, f)
+}
+
+// 调用方需持有写锁
+func (c *ConsistentHash) recordAdded(node Node) {
+	c.version++
+	c.pending = append(c.pending, ringEvent{typ: EventAdded, node: node, version: c.version})
+}
+
+// 调用方需持有写锁
+func (c *ConsistentHash) recordRemoved(node Node) {
+	c.version++
+	c.pending = append(c.pending, ringEvent{typ: EventRemoved, node: node, version: c.version})
+}
+
+// 只推送给 Watch，不调用 OnNodeAdded 和 OnNodeRemoved 的回调，调用方需持有写锁
+func (c *ConsistentHash) recordReplaced(node Node) {
+	c.version++
+	c.pending = append(c.pending, ringEvent{typ: EventReplaced, node: node, version: c.version})
+}
+
+// 按 key 的顺序记录全部结点的离开，调用方需持有写锁
+func (c *ConsistentHash) recordAllRemoved() {
+	keys := make([]string, 0, len(c.nodes))
+	for k := range c.nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		c.recordRemoved(c.nodes[k].node)
+	}
+}
+
+// 修改环的方法用 unlock 释放 lockWrite 获取的锁，发布新的快照，并在释放锁之后通知本次记录的事件
+func (c *ConsistentHash) unlock() {
+	c.publish()
+	gauges := c.observeGauges()
+	events := c.pending
+	c.pending = nil
+	var dangling []DanglingPin
+	if len(events) > 0 {
+		// 在写锁内入队，保证通知顺序与提交顺序一致
+		c.notifier.push(events)
+		if len(c.pins) > 0 {
+			dangling = c.danglingPins(events)
+		}
+	}
+	c.Unlock()
+	c.writeMu.Unlock()
+	if gauges != nil {
+		gauges()
+	}
+	if len(events) > 0 {
+		c.notifier.deliver()
+	}
+	if len(dangling) > 0 {
+		c.notifier.warnDangling(dangling)
+	}
+}
+
+func (n *notifier) push(events []ringEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.queue = append(n.queue, events...)
+}
+
+func (n *notifier) deliver() {
+	n.mu.Lock()
+	if n.delivering {
+		n.mu.Unlock()
+		return
+	}
+	n.delivering = true
+	for len(n.queue) > 0 {
+		e := n.queue[0]
+		n.queue = n.queue[1:]
+		n.publish(e)
+		var callbacks []func(Node)
+		switch e.typ {
+		case EventAdded:
+			callbacks = n.onAdded
+		case EventRemoved:
+			callbacks = n.onRemoved
+		}
+		n.mu.Unlock()
+		for _, f := range callbacks {
+			f(e.node)
+		}
+		n.mu.Lock()
+	}
+	n.queue = nil
+	n.delivering = false
+	n.mu.Unlock()
+}
@@ -0,0 +1,133 @@
+package consistent_hash
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type eventRecorder struct {
+	sync.Mutex
+	events []string
+}
+
+func (r *eventRecorder) watch(c *ConsistentHash) {
+	c.OnNodeAdded(func(n Node) { r.record("+" + n.Key()) })
+	c.OnNodeRemoved(func(n Node) { r.record("-" + n.Key()) })
+}
+
+func (r *eventRecorder) record(e string) {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) take() []string {
+	r.Lock()
+	defer r.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func TestConsistentHash_OnNodeAdded(t *testing.T) {
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 10, "b#0#0": 20,
+	}))
+	r := &eventRecorder{}
+	r.watch(c)
+	var second []string
+	c.OnNodeAdded(func(n Node) { second = append(second, n.Key()) })
+
+	steps := []struct {
+		name string
+		do   func() error
+		want []string
+	}{
+		{"add", func() error { return c.Add(testNode{"a"}) }, []string{"+a"}},
+		{"duplicate add", func() error { c.Add(testNode{"a"}); return nil }, nil},
+		{"collision", func() error { c.UnmarshalJSON([]byte(`{"nodes":[{"key":"c","points":[10]}]}`)); return nil }, nil},
+		{"unknown remove", func() error { c.RemoveByKey("x"); return nil }, nil},
+		{"remove", func() error { _, err := c.RemoveByKey("a"); return err }, []string{"-a"}},
+		{"add all", func() error { return c.AddAll([]Node{testNode{"a"}, testNode{"b"}}, 1) }, []string{"+a", "+b"}},
+		{"failed batch", func() error {
+			c.Batch().Remove("a").Add(testNode{"b"}, 1).Commit()
+			return nil
+		}, nil},
+		{"batch", func() error { return c.Batch().Remove("a").Add(testNode{"a"}, 1).Commit() }, []string{"-a", "+a"}},
+		{"remove all", func() error { c.RemoveAll([]string{"b", "x"}); return nil }, []string{"-b"}},
+		{"reset", func() error { c.Reset(); return nil }, []string{"-a"}},
+	}
+	for _, s := range steps {
+		if err := s.do(); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if got := r.take(); !reflect.DeepEqual(got, s.want) {
+			t.Fatalf("%s: events = %v, want %v", s.name, got, s.want)
+		}
+	}
+	if !reflect.DeepEqual(second, []string{"a", "a", "b", "a"}) {
+		t.Fatalf("second callback saw %v", second)
+	}
+}
+
+func TestConsistentHash_OnNodeRemovedReentrant(t *testing.T) {
+	c := NewConsistentHash()
+	r := &eventRecorder{}
+	// 回调在写锁之外调用，可以修改环，新的事件排在当前事件之后
+	c.OnNodeRemoved(func(n Node) {
+		if n.Key() == "old" {
+			if err := c.Add(testNode{"new"}); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	r.watch(c)
+
+	if err := c.Add(testNode{"old"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RemoveByKey("old"); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.take(); !reflect.DeepEqual(got, []string{"+old", "-old", "+new"}) {
+		t.Fatalf("events = %v", got)
+	}
+	if !c.HasNode("new") {
+		t.Fatal("callback's Add was lost")
+	}
+}
+
+func TestConsistentHash_NotifyOrder(t *testing.T) {
+	c := NewConsistentHash()
+	r := &eventRecorder{}
+	r.watch(c)
+
+	// 并发地反复添加和删除同一个结点，通知顺序必须与提交顺序一致
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				c.Add(testNode{"x"})
+				c.RemoveByKey("x")
+			}
+		}()
+	}
+	wg.Wait()
+
+	events := r.take()
+	for i, e := range events {
+		want := "+x"
+		if i%2 == 1 {
+			want = "-x"
+		}
+		if e != want {
+			t.Fatalf("event %d = %s, want %s: %v", i, e, want, events)
+		}
+	}
+	if len(events)%2 != 0 || len(events) == 0 {
+		t.Fatalf("%d events", len(events))
+	}
+}
@@ -0,0 +1,215 @@
+package consistent_hash
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+type Option func(c *ConsistentHash) error
+
+// New 创建 ConsistentHash，所有选项在这里校验，非法配置直接返回错误
+func New(opts ...Option) (*ConsistentHash, error) {
+	c := &ConsistentHash{
+		circle: map[uint32]string{},
+		nodes:  map[string]consistentNode{},
+		config: config{
+			hash:              defaultHash,
+			hashName:          hashCRC32,
+			defaultReplicas:   1,
+			maxReplicas:       defaultMaxReplicas,
+			weightBase:        defaultWeightBase,
+			loadFactor:        defaultLoadFactor,
+			replicationFactor: 1,
+		},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.twemproxyHash != "" && c.hashName != twemproxyHashName(c.twemproxyHash) {
+		// WithTwemproxy 之后又设置了 hash
+		return nil, errors.New("twemproxy mode can't use a custom hash")
+	}
+	return c, nil
+}
+
+func WithHash(h func(key string) uint32) Option {
+	return func(c *ConsistentHash) error {
+		if h == nil {
+			return errors.New("hash is nil")
+		}
+		c.hash = h
+		c.bytesHash = nil
+		c.hashName = ""
+		return nil
+	}
+}
+
+// WithBytesHash 与 WithHash 相同，hash 的参数为 []byte，GetNodeBytes 直接使用 h 而不需要转换为字符串
+func WithBytesHash(h func(key []byte) uint32) Option {
+	return func(c *ConsistentHash) error {
+		if h == nil {
+			return errors.New("hash is nil")
+		}
+		c.hash = func(key string) uint32 {
+			return h([]byte(key))
+		}
+		c.bytesHash = h
+		c.hashName = ""
+		return nil
+	}
+}
+
+// WithDefaultReplicas 设置 Add 使用的虚拟结点数，默认为 1
+func WithDefaultReplicas(replicas int) Option {
+	return func(c *ConsistentHash) error {
+		if replicas < 1 {
+			return errors.New("default replicas can't less 1")
+		}
+		c.defaultReplicas = replicas
+		return nil
+	}
+}
+
+// WithMaxReplicas 设置单个结点允许的最大虚拟结点数，默认为 100000
+func WithMaxReplicas(max int) Option {
+	return func(c *ConsistentHash) error {
+		if max < 1 {
+			return fmt.Errorf("max replicas %d can't less 1", max)
+		}
+		c.maxReplicas = max
+		return nil
+	}
+}
+
+// WithSortedCapacity 预分配虚拟结点排序数组的容量，在副本上修改的操作也按该容量分配副本
+func WithSortedCapacity(capacity int) Option {
+	return func(c *ConsistentHash) error {
+		if capacity < 0 {
+			return errors.New("sorted capacity can't less 0")
+		}
+		c.sortedCapacity = capacity
+		c.hashSortedNodes = make([]uint32, 0, capacity)
+		return nil
+	}
+}
+
+// WithExpectedNodes 按预计的结点数和每个结点的副本数预分配排序数组和 map 的容量。原地修改环的操作
+// (AddWithRamp、GrowVirtualNodes 等) 不会反复扩容，AddAll 等在副本上修改的操作按提示与实际数量中较大的分配副本。
+// 只是容量提示，与实际数量不符时行为不变
+func WithExpectedNodes(nodes, replicasPerNode int) Option {
+	return func(c *ConsistentHash) error {
+		if nodes < 0 || replicasPerNode < 0 {
+			return fmt.Errorf("expected nodes %d and replicas %d can't less 0", nodes, replicasPerNode)
+		}
+		if replicasPerNode > 0 && nodes > math.MaxInt32/replicasPerNode {
+			return fmt.Errorf("expected %d nodes with %d replicas is too large", nodes, replicasPerNode)
+		}
+		c.expectedNodes, c.expectedReplicas = nodes, replicasPerNode
+		points := nodes * replicasPerNode * c.pointsPerReplica()
+		c.hashSortedNodes = make([]uint32, 0, points)
+		c.circle = make(map[uint32]string, points)
+		c.nodes = make(map[string]consistentNode, nodes)
+		return nil
+	}
+}
+
+// WithWeightBase 设置 AddWithWeight 中权重 1 对应的虚拟结点数
+func WithWeightBase(base int) Option {
+	return func(c *ConsistentHash) error {
+		if base < 1 {
+			return errors.New("weight base can't less 1")
+		}
+		c.weightBase = base
+		return nil
+	}
+}
+
+// WithLoadFactor 设置 GetLeast 使用的负载上限系数
+func WithLoadFactor(factor float64) Option {
+	return func(c *ConsistentHash) error {
+		if !(factor > 1) || math.IsInf(factor, 1) {
+			return errors.New("load factor must be greater than 1")
+		}
+		c.loadFactor = factor
+		return nil
+	}
+}
+
+// WithReplicationFactor 设置 GetReplicas 返回的结点数，默认为 1
+func WithReplicationFactor(n int) Option {
+	return func(c *ConsistentHash) error {
+		if n < 1 {
+			return fmt.Errorf("replication factor %d can't less 1", n)
+		}
+		c.replicationFactor = n
+		return nil
+	}
+}
+
+// WithDegradedReplicas 设置在线结点不足 n 个时 GetN、GetReplicas、GetNSpread 的行为：
+// allow 为 true 时返回全部在线结点，默认为 false，返回 *InsufficientNodesError
+func WithDegradedReplicas(allow bool) Option {
+	return func(c *ConsistentHash) error {
+		c.degradedReplicas = allow
+		return nil
+	}
+}
+
+// WithSeed 使用以 seed 为密钥的 SipHash 计算虚拟结点和 key 的 hash，会替换 WithHash 设置的 hash。
+// 相同的 seed 在不同进程中得到相同的分布。
+func WithSeed(seed uint64) Option {
+	return func(c *ConsistentHash) error {
+		c.hash = seededHash(seed)
+		c.bytesHash = nil
+		c.hashName, c.hashSeed = hashSipHash24, seed
+		return nil
+	}
+}
+
+// WithRandomSeed 与 WithSeed 相同，seed 随机生成
+func WithRandomSeed() Option {
+	return func(c *ConsistentHash) error {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return err
+		}
+		return WithSeed(binary.LittleEndian.Uint64(b[:]))(c)
+	}
+}
+
+// WithVirtualKeyFunc 自定义虚拟结点参与 hash 的字符串，用于与其他系统保持一致。
+// 第 replica 个虚拟结点首先使用 f(nodeKey, replica)，冲突重试时在后面追加 "#" 和重试次数。
+func WithVirtualKeyFunc(f func(nodeKey string, replica int) string) Option {
+	return func(c *ConsistentHash) error {
+		if f == nil {
+			return errors.New("virtual key func is nil")
+		}
+		c.virtualKeyFunc = f
+		return nil
+	}
+}
+
+// WithLegacyVirtualKeys 使用旧版本的虚拟结点 key 即 nodeKey + replica + probe，
+// 与旧版本的分布保持一致。这种拼接有歧义，不同结点可能生成相同的虚拟结点。
+func WithLegacyVirtualKeys() Option {
+	return func(c *ConsistentHash) error {
+		c.legacyVirtualKeys = true
+		return nil
+	}
+}
+
+// WithNodeFactory 设置 UnmarshalBinary、UnmarshalJSON 还原结点的方式，默认为 StringNode
+func WithNodeFactory(f func(key string) Node) Option {
+	return func(c *ConsistentHash) error {
+		if f == nil {
+			return errors.New("node factory is nil")
+		}
+		c.nodeFactory = f
+		return nil
+	}
+}
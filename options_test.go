@@ -0,0 +1,230 @@
+package consistent_hash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestNew_Defaults(t *testing.T) {
+	c, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.hash == nil || c.circle == nil || c.nodes == nil {
+		t.Fatal("New doesn't initialize the ring")
+	}
+	if c.defaultReplicas != 1 || c.weightBase != defaultWeightBase || c.loadFactor != defaultLoadFactor {
+		t.Errorf("defaults = %d %d %v", c.defaultReplicas, c.weightBase, c.loadFactor)
+	}
+	if c.hashKey("key") != defaultHash("key") {
+		t.Error("default hash isn't crc32")
+	}
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.VirtualNodeCount("a"); n != 1 {
+		t.Errorf("Add created %d points, want 1", n)
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	h := func(key string) uint32 { return 42 }
+	c, err := New(
+		WithHash(h),
+		WithDefaultReplicas(3),
+		WithSortedCapacity(64),
+		WithWeightBase(7),
+		WithLoadFactor(1.5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.hashKey("anything") != 42 {
+		t.Error("WithHash isn't applied")
+	}
+	if cap(c.hashSortedNodes) != 64 {
+		t.Errorf("sorted capacity = %d, want 64", cap(c.hashSortedNodes))
+	}
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if cap(c.hashSortedNodes) < 64 {
+		t.Errorf("sorted capacity after Add = %d, want at least 64", cap(c.hashSortedNodes))
+	}
+	if c.weightBase != 7 || c.loadFactor != 1.5 {
+		t.Errorf("weightBase = %d, loadFactor = %v", c.weightBase, c.loadFactor)
+	}
+
+	c, err = New(WithDefaultReplicas(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.VirtualNodeCount("a"); n != 3 {
+		t.Errorf("Add created %d points, want 3", n)
+	}
+
+	c, err = New(WithWeightBase(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithWeight(testNode{"a"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.VirtualNodeCount("a"); n != 14 {
+		t.Errorf("AddWithWeight created %d points, want 14", n)
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"nil hash", WithHash(nil)},
+		{"zero replicas", WithDefaultReplicas(0)},
+		{"negative capacity", WithSortedCapacity(-1)},
+		{"zero weight base", WithWeightBase(0)},
+		{"load factor 1", WithLoadFactor(1)},
+	}
+	for _, tt := range tests {
+		if c, err := New(tt.opt); err == nil || c != nil {
+			t.Errorf("%s: New should fail", tt.name)
+		}
+	}
+}
+
+func TestNewConsistentWithCustomHash(t *testing.T) {
+	c := NewConsistentWithCustomHash(func(key string) uint32 { return 42 })
+	if c.hashKey("anything") != 42 {
+		t.Error("custom hash isn't applied")
+	}
+	if c.defaultReplicas != 1 {
+		t.Errorf("defaultReplicas = %d, want 1", c.defaultReplicas)
+	}
+}
+
+func TestVirtualKey_NoCrossNodeCollision(t *testing.T) {
+	pairs := [][2]string{{"a", "a1"}, {"node1", "node11"}, {"server1", "server11"}, {"a#1", "a"}}
+	c := NewConsistentHash()
+	for _, p := range pairs {
+		seen := map[string]string{}
+		for _, nodeKey := range p {
+			for i := 0; i < 200; i++ {
+				for j := 0; j < 3; j++ {
+					k := c.virtualKey(nodeKey, i, j)
+					if owner, ok := seen[k]; ok && owner != nodeKey {
+						t.Fatalf("%s and %s both derive %q", owner, nodeKey, k)
+					}
+					seen[k] = nodeKey
+				}
+			}
+		}
+	}
+
+	// 旧的拼接方式下 "node1" 的第 1x 个副本与 "node11" 的第 x 个副本完全相同
+	legacy, err := New(WithLegacyVirtualKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.virtualKey("node1", 11, 0) != legacy.virtualKey("node11", 1, 0) {
+		t.Fatal("legacy virtual keys aren't ambiguous")
+	}
+	if err := legacy.AddWithVirtualNode(testNode{"node1"}, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := legacy.AddWithVirtualNode(testNode{"node11"}, 20); err != nil {
+		t.Fatal(err)
+	}
+	// node11 的第 1 个副本被 node1 占据，只能靠重试换到别的位置
+	if legacy.nodes["node11"].virtualNodes[1] == legacy.hashKey(legacy.virtualKey("node11", 1, 0)) {
+		t.Fatal("legacy node11 replica 1 didn't collide")
+	}
+
+	for _, p := range pairs {
+		c := NewConsistentHash()
+		for _, nodeKey := range p {
+			if err := c.AddWithVirtualNode(testNode{nodeKey}, 200); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestWithVirtualKeyFunc(t *testing.T) {
+	var got []string
+	c, err := New(
+		WithHash(func(key string) uint32 {
+			got = append(got, key)
+			return uint32(len(got))
+		}),
+		WithVirtualKeyFunc(func(nodeKey string, replica int) string {
+			return nodeKey + "-" + strconv.Itoa(replica)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithVirtualNode(testNode{"a"}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(got, []string{"a-0", "a-1", "a-2"}) {
+		t.Errorf("hashed virtual keys = %v", got)
+	}
+	if c.virtualKey("a", 1, 2) != "a-1#2" {
+		t.Errorf("retry key = %s, want a-1#2", c.virtualKey("a", 1, 2))
+	}
+
+	if _, err := New(WithVirtualKeyFunc(nil)); err == nil {
+		t.Error("nil virtual key func should fail")
+	}
+}
+
+func TestWithExpectedNodes(t *testing.T) {
+	build := func(opts ...Option) *ConsistentHash {
+		c, err := New(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddAll(batchTestNodes("node-", 20), 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddWithRamp(testNode{"ramp"}, 30); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.GrowVirtualNodes("node-3", 5); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.RemoveByKey("node-7"); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	want := build()
+	for _, hint := range []Option{WithExpectedNodes(1, 1), WithExpectedNodes(0, 0), WithExpectedNodes(1000, 500)} {
+		c := build(hint)
+		if c.Checksum() != want.Checksum() || c.Version() != want.Version() {
+			t.Fatal("capacity hint changed the ring")
+		}
+		if err := c.VerifyRing(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, _ := New(WithExpectedNodes(100, 50))
+	if cap(c.hashSortedNodes) != 5000 {
+		t.Errorf("sorted capacity = %d, want 5000", cap(c.hashSortedNodes))
+	}
+	// 在副本上修改之后仍按提示的容量分配
+	c.AddAll(batchTestNodes("node-", 10), 10)
+	if cap(c.hashSortedNodes) < 5000 {
+		t.Errorf("sorted capacity after AddAll = %d, want at least 5000", cap(c.hashSortedNodes))
+	}
+	for _, opt := range []Option{WithExpectedNodes(-1, 1), WithExpectedNodes(1, -1), WithExpectedNodes(1<<30, 1<<10)} {
+		if _, err := New(opt); err == nil {
+			t.Error("invalid hint accepted")
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package consistent_hash
+
+import "math"
+
+// Range 表示 hash 区间 [Start, End]
+type Range struct {
+	Start, End uint32
+}
+
+// OwnershipRanges 按 hash 从小到大返回结点拥有的区间，每个虚拟结点拥有从上一个虚拟结点 (不含) 到它自己 (含) 的区间。
+// 第一个虚拟结点的区间跨越环的起点，拆分为 [0, 第一个虚拟结点] 和 [最后一个虚拟结点 + 1, math.MaxUint32] 两段。
+// ReserveRange 保留的部分不属于任何结点，区间在保留处断开
+func (c *ConsistentHash) OwnershipRanges(nodeKey string) ([]Range, error) {
+	return c.ownershipRanges(nodeKey, false)
+}
+
+// OwnershipRangesCoalesced 与 OwnershipRanges 相同，相邻的区间合并为一个
+func (c *ConsistentHash) OwnershipRangesCoalesced(nodeKey string) ([]Range, error) {
+	return c.ownershipRanges(nodeKey, true)
+}
+
+func (c *ConsistentHash) ownershipRanges(nodeKey string, coalesce bool) ([]Range, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if _, ok := c.nodes[nodeKey]; !ok {
+		return nil, newNodeError(nodeKey, ErrNodeNotFound)
+	}
+
+	var ranges []Range
+	free := func(start, end uint32) {
+		if n := len(ranges); coalesce && n > 0 && ranges[n-1].End+1 == start {
+			ranges[n-1].End = end
+			return
+		}
+		ranges = append(ranges, Range{Start: start, End: end})
+	}
+	add := func(r Range) {
+		if len(c.reserved) == 0 {
+			free(r.Start, r.End)
+			return
+		}
+		splitReserved(c.reserved, r.Start, r.End, free, func(uint32, uint32) {})
+	}
+	points := c.hashSortedNodes
+	for i, p := range points {
+		if c.circle[p] != nodeKey {
+			continue
+		}
+		if i > 0 {
+			add(Range{Start: points[i-1] + 1, End: p})
+		} else {
+			add(Range{Start: 0, End: p})
+		}
+	}
+	if len(points) == 0 {
+		return ranges, nil
+	}
+	if last := points[len(points)-1]; c.circle[points[0]] == nodeKey && last != math.MaxUint32 {
+		add(Range{Start: last + 1, End: math.MaxUint32})
+	}
+	return ranges, nil
+}
+
+// OwnershipFraction 返回结点拥有的 hash 空间的比例，结点不存在时返回 0
+func (c *ConsistentHash) OwnershipFraction(nodeKey string) float64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.ownedFraction(nodeKey)
+}
+
+// PointOwner 是环上的一个虚拟结点及其所属的物理结点
+type PointOwner struct {
+	Point uint32
+	Node  Node
+}
+
+// PointsInRange 按环的顺序从 start 开始返回所有拥有的区间与 [start, end] 相交的虚拟结点，
+// 最后一个是 end 所属的虚拟结点。start > end 时区间跨越环的起点，即 [start, math.MaxUint32] 和 [0, end]。
+// CollisionChain 下共用的虚拟结点只返回 chain 中的第一个结点
+func (c *ConsistentHash) PointsInRange(start, end uint32) []PointOwner {
+	c.RLock()
+	defer c.RUnlock()
+	var points []PointOwner
+	c.pointsInRange(start, end, func(p uint32) {
+		points = append(points, PointOwner{Point: p, Node: c.nodes[c.circle[p]].node})
+	})
+	return points
+}
+
+// NodesInRange 按环的顺序从 start 开始返回在 [start, end] 中拥有区间的物理结点，每个结点只出现一次，
+// 区间的规则与 PointsInRange 相同。下线的结点也会返回
+func (c *ConsistentHash) NodesInRange(start, end uint32) []Node {
+	c.RLock()
+	defer c.RUnlock()
+	var nodes []Node
+	seen := map[string]struct{}{}
+	visit := func(k string) {
+		if _, ok := seen[k]; !ok {
+			seen[k] = struct{}{}
+			nodes = append(nodes, c.nodes[k].node)
+		}
+	}
+	c.pointsInRange(start, end, func(p uint32) {
+		if owners, ok := c.chains[p]; ok {
+			for _, k := range owners {
+				visit(k)
+			}
+			return
+		}
+		visit(c.circle[p])
+	})
+	return nodes
+}
+
+// 调用方需持有读锁
+func (c *ConsistentHash) pointsInRange(start, end uint32, fn func(p uint32)) {
+	points := c.hashSortedNodes
+	if len(points) == 0 {
+		return
+	}
+	// 无符号减法自然处理跨越起点的情况
+	length := end - start
+	i := c.getPosition(start)
+	for j := 0; j < len(points); j++ {
+		p := points[(i+j)%len(points)]
+		fn(p)
+		if p-start >= length {
+			// p 拥有 end
+			return
+		}
+	}
+}
+
+// BucketOwnership 是 Histogram 中的一个桶，覆盖 hash 区间 [Start, End]，Owners 为每个结点拥有的部分占桶的比例，
+// Reserved 为 ReserveRange 保留的部分占桶的比例
+type BucketOwnership struct {
+	Start, End uint32
+	Owners     map[string]float64
+	Reserved   float64
+}
+
+// Histogram 把 hash 空间等分为 buckets 个桶，按区间的实际交集计算每个结点在每个桶中拥有的比例，
+// 只包含比例大于 0 的结点。不考虑结点的上下线状态，buckets 小于 1 时返回 nil
+func (c *ConsistentHash) Histogram(buckets int) []BucketOwnership {
+	if buckets < 1 {
+		return nil
+	}
+	c.RLock()
+	defer c.RUnlock()
+
+	hist := make([]BucketOwnership, buckets)
+	for i := range hist {
+		hist[i] = BucketOwnership{
+			Start:  uint32(uint64(i) << 32 / uint64(buckets)),
+			End:    uint32(uint64(i+1)<<32/uint64(buckets) - 1),
+			Owners: map[string]float64{},
+		}
+	}
+	points := c.hashSortedNodes
+	if len(points) == 0 {
+		return hist
+	}
+	// 按 hash 从小到大依次处理每个归属区间，最后一段属于第一个虚拟结点
+	b := 0
+	fill := func(start, end uint64, add func(bucket *BucketOwnership, fraction float64)) {
+		for start <= end {
+			bucket := &hist[b]
+			stop := end
+			if uint64(bucket.End) < stop {
+				stop = uint64(bucket.End)
+			}
+			add(bucket, float64(stop-start+1)/float64(uint64(bucket.End)-uint64(bucket.Start)+1))
+			if stop == uint64(bucket.End) && b < len(hist)-1 {
+				b++
+			}
+			start = stop + 1
+		}
+	}
+	reserve := func(start, end uint32) {
+		fill(uint64(start), uint64(end), func(bucket *BucketOwnership, fraction float64) {
+			bucket.Reserved += fraction
+		})
+	}
+	own := func(start, end uint64, owner string) {
+		free := func(start, end uint32) {
+			fill(uint64(start), uint64(end), func(bucket *BucketOwnership, fraction float64) {
+				bucket.Owners[owner] += fraction
+			})
+		}
+		if start <= end {
+			splitReserved(c.reserved, uint32(start), uint32(end), free, reserve)
+		}
+	}
+	start := uint64(0)
+	for _, p := range points {
+		own(start, uint64(p), c.circle[p])
+		start = uint64(p) + 1
+	}
+	if start <= math.MaxUint32 {
+		own(start, math.MaxUint32, c.circle[points[0]])
+	}
+	return hist
+}
@@ -0,0 +1,185 @@
+package consistent_hash
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestConsistentHash_OwnershipRanges(t *testing.T) {
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 100, "a#1#0": 300, "a#2#0": 400,
+		"b#0#0": 200, "b#1#0": 500,
+	}))
+	if err := c.AddKey("a", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddKey("b", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key      string
+		coalesce bool
+		want     []Range
+	}{
+		{"a", false, []Range{{0, 100}, {201, 300}, {301, 400}, {501, math.MaxUint32}}},
+		{"a", true, []Range{{0, 100}, {201, 400}, {501, math.MaxUint32}}},
+		{"b", false, []Range{{101, 200}, {401, 500}}},
+	}
+	for _, tt := range tests {
+		got, err := c.ownershipRanges(tt.key, tt.coalesce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ownershipRanges(%s, %v) = %v, want %v", tt.key, tt.coalesce, got, tt.want)
+		}
+	}
+
+	if got, want := c.OwnershipFraction("b"), 200.0/(1<<32); got != want {
+		t.Errorf("OwnershipFraction(b) = %v, want %v", got, want)
+	}
+	if got := c.OwnershipFraction("a") + c.OwnershipFraction("b"); got != 1 {
+		t.Errorf("fractions sum to %v", got)
+	}
+	if _, err := c.OwnershipRanges("x"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("OwnershipRanges(x) = %v", err)
+	}
+	if got := c.OwnershipFraction("x"); got != 0 {
+		t.Errorf("OwnershipFraction(x) = %v", got)
+	}
+}
+
+func TestConsistentHash_OwnershipRangesTop(t *testing.T) {
+	// 最后一个虚拟结点在环的终点时没有跨越起点的区间
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": math.MaxUint32, "b#0#0": 10,
+	}))
+	if err := c.AddKey("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.OwnershipRanges("a"); !reflect.DeepEqual(got, []Range{{0, math.MaxUint32}}) {
+		t.Errorf("single node = %v", got)
+	}
+	if err := c.AddKey("b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.OwnershipRanges("a"); !reflect.DeepEqual(got, []Range{{11, math.MaxUint32}}) {
+		t.Errorf("a = %v", got)
+	}
+	if got, _ := c.OwnershipRanges("b"); !reflect.DeepEqual(got, []Range{{0, 10}}) {
+		t.Errorf("b = %v", got)
+	}
+}
+
+func TestConsistentHash_PointsInRange(t *testing.T) {
+	// 环: 100(a) 200(b) 300(a) 400(c) 500(b)
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 100, "a#1#0": 300,
+		"b#0#0": 200, "b#1#0": 500,
+		"c#0#0": 400,
+	}))
+	c.AddKey("a", 2)
+	c.AddKey("b", 2)
+	c.AddKey("c", 1)
+	if got := NewConsistentHash().PointsInRange(0, math.MaxUint32); got != nil {
+		t.Fatalf("empty ring PointsInRange = %v", got)
+	}
+
+	tests := []struct {
+		start, end uint32
+		points     []uint32
+		nodes      []string
+	}{
+		// 虚拟结点拥有 (上一个虚拟结点, 自己]
+		{100, 100, []uint32{100}, []string{"a"}},
+		{101, 200, []uint32{200}, []string{"b"}},
+		{100, 101, []uint32{100, 200}, []string{"a", "b"}},
+		{150, 350, []uint32{200, 300, 400}, []string{"b", "a", "c"}},
+		{250, 250, []uint32{300}, []string{"a"}},
+		// 最后一个虚拟结点之后的区间属于第一个虚拟结点
+		{501, 600, []uint32{100}, []string{"a"}},
+		{450, 550, []uint32{500, 100}, []string{"b", "a"}},
+		// 跨越起点
+		{math.MaxUint32, 0, []uint32{100}, []string{"a"}},
+		{450, 150, []uint32{500, 100, 200}, []string{"b", "a"}},
+		{301, 300, []uint32{400, 500, 100, 200, 300}, []string{"c", "b", "a"}},
+		{0, math.MaxUint32, []uint32{100, 200, 300, 400, 500}, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		var points []uint32
+		for _, p := range c.PointsInRange(tt.start, tt.end) {
+			if p.Node.Key() != c.circle[p.Point] {
+				t.Fatalf("point %d owner = %s", p.Point, p.Node.Key())
+			}
+			points = append(points, p.Point)
+		}
+		if !reflect.DeepEqual(points, tt.points) {
+			t.Errorf("PointsInRange(%d, %d) = %v, want %v", tt.start, tt.end, points, tt.points)
+		}
+		if got := nodeKeys(c.NodesInRange(tt.start, tt.end)); !reflect.DeepEqual(got, tt.nodes) {
+			t.Errorf("NodesInRange(%d, %d) = %v, want %v", tt.start, tt.end, got, tt.nodes)
+		}
+	}
+}
+
+func TestConsistentHash_Histogram(t *testing.T) {
+	// 环: 100(a) 1<<31(b) 3<<30(a)
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 100, "a#1#0": 3 << 30, "b#0#0": 1 << 31,
+	}))
+	c.AddKey("a", 2)
+	c.AddKey("b", 1)
+
+	const q = 1 << 30
+	want := []BucketOwnership{
+		{0, q - 1, map[string]float64{"a": 101.0 / q, "b": (q - 101.0) / q}, 0},
+		// 桶比两个虚拟结点之间的间隔小，整个桶属于 b
+		{q, 2*q - 1, map[string]float64{"b": 1}, 0},
+		{2 * q, 3*q - 1, map[string]float64{"b": 1.0 / q, "a": (q - 1.0) / q}, 0},
+		// 3<<30 属于 a 的第二个虚拟结点，之后跨越起点的区间属于 100
+		{3 * q, math.MaxUint32, map[string]float64{"a": 1}, 0},
+	}
+	if got := c.Histogram(4); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Histogram(4) = %v, want %v", got, want)
+	}
+	if got := c.Histogram(1); !reflect.DeepEqual(got, []BucketOwnership{
+		{0, math.MaxUint32, map[string]float64{"a": (2*q + 100.0) / (4 * q), "b": (2*q - 100.0) / (4 * q)}, 0},
+	}) {
+		t.Fatalf("Histogram(1) = %v", got)
+	}
+	if c.Histogram(0) != nil {
+		t.Fatal("Histogram(0) != nil")
+	}
+
+	empty := NewConsistentHash().Histogram(2)
+	if len(empty) != 2 || len(empty[0].Owners) != 0 || empty[1].End != math.MaxUint32 {
+		t.Fatalf("empty ring Histogram = %v", empty)
+	}
+
+	// 桶的大小不能整除 hash 空间，各结点的比例按桶的大小加权后与 OwnershipFraction 相同
+	r := NewConsistentHash()
+	r.AddAll(batchTestNodes("node-", 7), 13)
+	hist := r.Histogram(3)
+	shares := map[string]float64{}
+	for i, b := range hist {
+		if i > 0 && b.Start != hist[i-1].End+1 {
+			t.Fatalf("bucket %d starts at %d after %d", i, b.Start, hist[i-1].End)
+		}
+		var sum float64
+		for k, f := range b.Owners {
+			sum += f
+			shares[k] += f * (float64(b.End) - float64(b.Start) + 1) / (1 << 32)
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Fatalf("bucket %d fractions sum to %v", i, sum)
+		}
+	}
+	for k, s := range shares {
+		if want := r.OwnershipFraction(k); math.Abs(s-want) > 1e-9 {
+			t.Errorf("%s owns %v in the histogram, %v in total", k, s, want)
+		}
+	}
+}
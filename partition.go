@@ -0,0 +1,82 @@
+package consistent_hash
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// PartitionRing 把 key 映射到固定数量的分区，再把分区映射到环上的结点。
+// 分区编号按 "partition-<编号>" 放到环上，结点变化时只有整个分区迁移，迁移的分区数与普通的 key 相同。
+// 结点通过 Ring 返回的环增删
+type PartitionRing struct {
+	ring       *ConsistentHash
+	partitions int
+	// 每个分区在环上的 key，避免每次查询时拼接
+	keys []string
+}
+
+// NewPartitionRing 在 ring 上创建有 partitions 个分区的 PartitionRing
+func NewPartitionRing(ring *ConsistentHash, partitions int) (*PartitionRing, error) {
+	if ring == nil {
+		return nil, errors.New("ring is nil")
+	}
+	if partitions < 1 {
+		return nil, fmt.Errorf("partitions %d can't less 1", partitions)
+	}
+	keys := make([]string, partitions)
+	for i := range keys {
+		keys[i] = "partition-" + strconv.Itoa(i)
+	}
+	return &PartitionRing{ring: ring, partitions: partitions, keys: keys}, nil
+}
+
+// Ring 返回分区所在的环
+func (p *PartitionRing) Ring() *ConsistentHash {
+	return p.ring
+}
+
+// PartitionCount 返回分区数
+func (p *PartitionRing) PartitionCount() int {
+	return p.partitions
+}
+
+// SetPartitionCount 分区数不能修改，n 与当前分区数不同时返回 ErrPartitionCountFixed
+func (p *PartitionRing) SetPartitionCount(n int) error {
+	if n != p.partitions {
+		return fmt.Errorf("%w: %d, can't change to %d", ErrPartitionCountFixed, p.partitions, n)
+	}
+	return nil
+}
+
+// Partition 返回 key 所属的分区，即 key 的 hash 对分区数取模，与环上的结点无关
+func (p *PartitionRing) Partition(key string) int {
+	return int(p.ring.hashKey(key) % uint32(p.partitions))
+}
+
+// PartitionOwner 返回分区所属的结点，分区编号不在 [0, PartitionCount) 内时返回错误
+func (p *PartitionRing) PartitionOwner(partition int) (Node, error) {
+	if partition < 0 || partition >= p.partitions {
+		return nil, fmt.Errorf("partition %d out of range [0, %d)", partition, p.partitions)
+	}
+	return p.ring.GetNode(p.keys[partition])
+}
+
+// GetNode 返回 key 所在分区所属的结点
+func (p *PartitionRing) GetNode(key string) (Node, error) {
+	return p.ring.GetNode(p.keys[p.Partition(key)])
+}
+
+// Assignments 返回每个分区所属结点的 key，所有分区来自同一个快照，不计入负载统计。环为空时返回空 map
+func (p *PartitionRing) Assignments() map[int]string {
+	s := p.ring.loadSnapshot()
+	assignments := make(map[int]string, p.partitions)
+	for i, key := range p.keys {
+		n, err := s.find(key)
+		if err != nil {
+			break
+		}
+		assignments[i] = n.key
+	}
+	return assignments
+}
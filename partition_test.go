@@ -0,0 +1,74 @@
+package consistent_hash
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestPartitionRing(t *testing.T) {
+	c := NewConsistentHash()
+	p, err := NewPartitionRing(c, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Assignments()) != 0 {
+		t.Fatal("empty ring has assignments")
+	}
+	if _, err := p.PartitionOwner(0); err != ErrEmptyRing {
+		t.Fatalf("PartitionOwner on empty ring err = %v", err)
+	}
+	if err := c.AddAll(batchTestNodes("node-", 10), 100); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		part := p.Partition(key)
+		if part < 0 || part >= 1024 || part != p.Partition(key) {
+			t.Fatalf("Partition(%q) = %d", key, part)
+		}
+		owner, _ := p.PartitionOwner(part)
+		n, _ := p.GetNode(key)
+		if n != owner {
+			t.Fatalf("GetNode(%q) = %s, partition owner = %s", key, n.Key(), owner.Key())
+		}
+	}
+
+	before := p.Assignments()
+	if len(before) != 1024 {
+		t.Fatalf("%d partitions assigned", len(before))
+	}
+	c.AddWithVirtualNode(testNode{"new"}, 100)
+	after := p.Assignments()
+	moved := 0
+	for part, owner := range after {
+		if owner != before[part] {
+			if owner != "new" {
+				t.Fatalf("partition %d moved from %s to %s", part, before[part], owner)
+			}
+			moved++
+		}
+	}
+	if want := 1024 / 11; moved < want/2 || moved > want*2 {
+		t.Fatalf("%d partitions moved, want about %d", moved, want)
+	}
+
+	if err := p.SetPartitionCount(2048); !errors.Is(err, ErrPartitionCountFixed) {
+		t.Fatalf("SetPartitionCount err = %v", err)
+	}
+	if err := p.SetPartitionCount(1024); err != nil || p.PartitionCount() != 1024 {
+		t.Fatal("SetPartitionCount with the same count failed")
+	}
+	for _, part := range []int{-1, 1024} {
+		if _, err := p.PartitionOwner(part); err == nil {
+			t.Fatalf("PartitionOwner(%d) succeeded", part)
+		}
+	}
+	if _, err := NewPartitionRing(c, 0); err == nil {
+		t.Fatal("0 partitions accepted")
+	}
+	if _, err := NewPartitionRing(nil, 1); err == nil {
+		t.Fatal("nil ring accepted")
+	}
+}
@@ -0,0 +1,142 @@
+package consistent_hash
+
+import "sort"
+
+// PinKey 把 key 固定到结点 nodeKey，GetNode、GetNodeBytes、GetNodeWithMeta、GetTwo、GetN 和 GetReplicas
+// 优先返回该结点，GetN 中它是第一个结点，其余结点保持环上的顺序。Lookup、GetOwnerByHash 等按 hash 查询的方法不受影响。
+// 结点不在环上时返回 ErrNodeNotFound。
+// 固定的结点下线或离开环时 key 按 hash 分配，固定保留，结点重新上线或以相同的 key 加回后恢复。
+// 结点离开环时调用 OnPinDangling 注册的回调
+func (c *ConsistentHash) PinKey(key, nodeKey string) error {
+	c.lockWrite()
+	defer c.unlock()
+	if _, ok := c.nodes[nodeKey]; !ok {
+		return newNodeError(nodeKey, ErrNodeNotFound)
+	}
+	if old, ok := c.pins[key]; ok && old == nodeKey {
+		return nil
+	}
+	if c.pins == nil {
+		c.pins = map[string]string{}
+	}
+	c.pins[key] = nodeKey
+	c.version++
+	return nil
+}
+
+// UnpinKey 取消 PinKey，key 没有固定时返回 ErrKeyNotPinned
+func (c *ConsistentHash) UnpinKey(key string) error {
+	c.lockWrite()
+	defer c.unlock()
+	if _, ok := c.pins[key]; !ok {
+		return ErrKeyNotPinned
+	}
+	delete(c.pins, key)
+	c.version++
+	return nil
+}
+
+// Pins 返回全部固定的 key 到结点 key 的副本，包括结点已经离开环的
+func (c *ConsistentHash) Pins() map[string]string {
+	c.RLock()
+	defer c.RUnlock()
+	pins := copyPins(c.pins)
+	if pins == nil {
+		pins = map[string]string{}
+	}
+	return pins
+}
+
+// DanglingPin 是结点已经离开环的固定，见 OnPinDangling
+type DanglingPin struct {
+	Key     string
+	NodeKey string
+}
+
+// OnPinDangling 注册固定的结点离开环之后的回调，每次变更按 key 的顺序传入新出现的 DanglingPin。
+// 与 OnNodeRemoved 相同，回调在释放写锁之后调用
+func (c *ConsistentHash) OnPinDangling(f func([]DanglingPin)) {
+	c.notifier.mu.Lock()
+	defer c.notifier.mu.Unlock()
+	c.notifier.onDangling = append(c.notifier.onDangling, f)
+}
+
+// 本次离开环的结点上的固定，调用方需持有写锁
+func (c *ConsistentHash) danglingPins(events []ringEvent) []DanglingPin {
+	removed := map[string]bool{}
+	for _, e := range events {
+		if e.typ == EventRemoved {
+			removed[e.node.Key()] = true
+		}
+	}
+	var dangling []DanglingPin
+	for key, nodeKey := range c.pins {
+		if _, ok := c.nodes[nodeKey]; !ok && removed[nodeKey] {
+			dangling = append(dangling, DanglingPin{Key: key, NodeKey: nodeKey})
+		}
+	}
+	sort.Slice(dangling, func(i, j int) bool { return dangling[i].Key < dangling[j].Key })
+	return dangling
+}
+
+func (n *notifier) warnDangling(dangling []DanglingPin) {
+	n.mu.Lock()
+	callbacks := n.onDangling
+	n.mu.Unlock()
+	for _, f := range callbacks {
+		f(dangling)
+	}
+}
+
+// 返回 key 固定的在线结点，调用方需持有锁
+func (c *ConsistentHash) pinnedNode(key string) (Node, bool) {
+	nodeKey, ok := c.pins[key]
+	if !ok {
+		return nil, false
+	}
+	cNode, ok := c.nodes[nodeKey]
+	if !ok || cNode.down {
+		return nil, false
+	}
+	return cNode.node, true
+}
+
+// 把 key 固定的在线结点移到最前面，长度不变，调用方需持有锁
+func (c *ConsistentHash) pinFirst(key string, nodes []Node) []Node {
+	pinned, ok := c.pinnedNode(key)
+	if !ok || len(nodes) == 0 {
+		return nodes
+	}
+	i := len(nodes) - 1
+	for j, n := range nodes {
+		if n.Key() == pinned.Key() {
+			i = j
+			break
+		}
+	}
+	copy(nodes[1:i+1], nodes[:i])
+	nodes[0] = pinned
+	return nodes
+}
+
+// 返回 key 固定的在线结点，没有时返回 nil
+func (s *ringSnapshot) pinned(key string) *snapshotNode {
+	if s.pins == nil {
+		return nil
+	}
+	if n := s.pins[key]; n != nil && n.live() {
+		return n
+	}
+	return nil
+}
+
+func copyPins(pins map[string]string) map[string]string {
+	if len(pins) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(pins))
+	for k, v := range pins {
+		copied[k] = v
+	}
+	return copied
+}
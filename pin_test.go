@@ -0,0 +1,270 @@
+package consistent_hash
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 返回一个 hash 不属于 nodeKey 的 key
+func keyNotOwnedBy(t *testing.T, c *ConsistentHash, nodeKey string) string {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		key := "tenant-" + strconv.Itoa(i)
+		if n, _ := c.GetNode(key); n.Key() != nodeKey {
+			return key
+		}
+	}
+	t.Fatalf("all keys belong to %s", nodeKey)
+	return ""
+}
+
+func TestConsistentHash_PinKey(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithLookupCache(16)}} {
+		c, _ := New(opts...)
+		c.AddAll(batchTestNodes("node-", 5), 20)
+		key := keyNotOwnedBy(t, c, "node-4")
+		hashed, _ := c.GetN(key, 3)
+
+		version := c.Version()
+		if err := c.PinKey(key, "node-4"); err != nil {
+			t.Fatal(err)
+		}
+		if c.Version() == version {
+			t.Fatal("PinKey didn't change the version")
+		}
+		if n, _ := c.GetNode(key); n.Key() != "node-4" {
+			t.Fatalf("GetNode(pinned) = %s", n.Key())
+		}
+		if n, _ := c.GetNodeBytes([]byte(key)); n.Key() != "node-4" {
+			t.Fatalf("GetNodeBytes(pinned) = %s", n.Key())
+		}
+		// 固定的结点排在最前面，其余结点保持环上的顺序
+		want := []string{"node-4"}
+		for _, n := range hashed {
+			if n.Key() != "node-4" && len(want) < 3 {
+				want = append(want, n.Key())
+			}
+		}
+		got, _ := c.GetN(key, 3)
+		if !equalStrings(nodeKeys(got), want) {
+			t.Fatalf("GetN(pinned) = %v, want %v", nodeKeys(got), want)
+		}
+		checkGetTwo(t, "pinned", c, key)
+		if n, err := c.GetNodeExcluding(key); err != nil || n.Key() != "node-4" {
+			t.Fatalf("GetNodeExcluding(pinned) = %v, %v", n, err)
+		}
+		if n, err := c.GetNodeExcluding(key, "node-4"); err != nil || n.Key() != want[1] {
+			t.Fatalf("GetNodeExcluding(pinned, node-4) = %v, %v, want %s", n, err, want[1])
+		}
+		if n, err := c.GetNodeWithFallback(key, func(Node) bool { return true }); err != nil || n.Key() != "node-4" {
+			t.Fatalf("GetNodeWithFallback(pinned) = %v, %v", n, err)
+		}
+		if n, err := c.GetLeast(key); err != nil || n.Key() != "node-4" {
+			t.Fatalf("GetLeast(pinned) = %v, %v", n, err)
+		}
+		if n, ok := c.Iterate(key).Next(); !ok || n.Key() != "node-4" {
+			t.Fatalf("Iterate(pinned) = %v, %v", n, ok)
+		}
+		if spread, err := c.GetNSpread(key, 3); err != nil || !equalStrings(nodeKeys(spread), want) {
+			t.Fatalf("GetNSpread(pinned) = %v, %v, want %v", nodeKeys(spread), err, want)
+		}
+
+		// 其他 key 不受影响，按 hash 查询的方法也不受影响
+		for i := 0; i < 100; i++ {
+			other := "other-" + strconv.Itoa(i)
+			n, _ := c.GetNode(other)
+			if want, _ := c.GetOwnerByHash(defaultHash(other)); n != want {
+				t.Fatalf("GetNode(%s) = %s, want %s", other, n.Key(), want.Key())
+			}
+		}
+		if r, _ := c.Lookup(key); r.Node.Key() != hashed[0].Key() {
+			t.Fatalf("Lookup(pinned) = %s, want %s", r.Node.Key(), hashed[0].Key())
+		}
+
+		if err := c.PinKey(key, "missing"); !errors.Is(err, ErrNodeNotFound) {
+			t.Fatalf("PinKey to a missing node err = %v", err)
+		}
+		if !reflect.DeepEqual(c.Pins(), map[string]string{key: "node-4"}) {
+			t.Fatalf("Pins = %v", c.Pins())
+		}
+		if err := c.UnpinKey(key); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.UnpinKey(key); !errors.Is(err, ErrKeyNotPinned) {
+			t.Fatalf("UnpinKey twice err = %v", err)
+		}
+		if n, _ := c.GetNode(key); n.Key() != hashed[0].Key() {
+			t.Fatalf("GetNode after UnpinKey = %s, want %s", n.Key(), hashed[0].Key())
+		}
+	}
+}
+
+func TestConsistentHash_PinKeyRemoved(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 3), 20)
+	key := keyNotOwnedBy(t, c, "node-2")
+	c.PinKey(key, "node-2")
+	c.PinKey("other", "node-2")
+	c.PinKey("kept", "node-1")
+	var mu sync.Mutex
+	var warned []DanglingPin
+	c.OnPinDangling(func(pins []DanglingPin) {
+		mu.Lock()
+		warned = append(warned, pins...)
+		mu.Unlock()
+	})
+
+	// 下线时按 hash 分配，上线后恢复
+	c.MarkDown("node-2")
+	if n, _ := c.GetNode(key); n.Key() == "node-2" {
+		t.Fatal("GetNode returned a down pinned node")
+	}
+	c.MarkUp("node-2")
+	if n, _ := c.GetNode(key); n.Key() != "node-2" {
+		t.Fatalf("GetNode after MarkUp = %s", n.Key())
+	}
+
+	c.RemoveByKey("node-2")
+	want, _ := c.GetOwnerByHash(defaultHash(key))
+	if n, err := c.GetNode(key); err != nil || n != want {
+		t.Fatalf("GetNode with a dangling pin = %v, %v, want %s", n, err, want.Key())
+	}
+	if nodes, err := c.GetN(key, 2); err != nil || len(nodes) != 2 {
+		t.Fatalf("GetN with a dangling pin = %v, %v", nodeKeys(nodes), err)
+	}
+	if c.Pins()[key] != "node-2" {
+		t.Fatal("removing the node dropped the pin")
+	}
+	mu.Lock()
+	// 按 key 排序
+	if !reflect.DeepEqual(warned, []DanglingPin{{"other", "node-2"}, {key, "node-2"}}) {
+		t.Fatalf("OnPinDangling = %v", warned)
+	}
+	warned = nil
+	mu.Unlock()
+
+	// 以相同的 key 加回后恢复
+	c.AddWithVirtualNode(testNode{"node-2"}, 20)
+	if n, _ := c.GetNode(key); n.Key() != "node-2" {
+		t.Fatalf("GetNode after re-adding = %s", n.Key())
+	}
+	c.RemoveByKey("node-0")
+	mu.Lock()
+	if len(warned) != 0 {
+		t.Fatalf("removing an unpinned node warned %v", warned)
+	}
+	mu.Unlock()
+
+	c.Reset()
+	if len(c.Pins()) != 0 {
+		t.Fatalf("Pins after Reset = %v", c.Pins())
+	}
+}
+
+func TestConsistentHash_PinKeyPersist(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 3), 20)
+	c.PinKey("k1", "node-1")
+	c.PinKey("k2", "node-2")
+	c.RemoveByKey("node-2")
+	want := c.Pins()
+
+	check := func(name string, r *ConsistentHash) {
+		t.Helper()
+		if !reflect.DeepEqual(r.Pins(), want) {
+			t.Fatalf("%s: Pins = %v, want %v", name, r.Pins(), want)
+		}
+		if n, _ := r.GetNode("k1"); n.Key() != "node-1" {
+			t.Fatalf("%s: GetNode(k1) = %s", name, n.Key())
+		}
+	}
+
+	clone := c.Clone()
+	check("Clone", clone)
+	clone.UnpinKey("k1")
+	if _, ok := c.Pins()["k1"]; !ok {
+		t.Fatal("UnpinKey on the clone changed the original")
+	}
+
+	data, _ := c.MarshalBinary()
+	if data[0] != binaryVersionPins {
+		t.Fatalf("binary version = %d", data[0])
+	}
+	r := NewConsistentHash()
+	if err := r.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	check("UnmarshalBinary", r)
+	// 截断的固定
+	if err := r.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrCorruptData) {
+		t.Fatalf("truncated pins err = %v", err)
+	}
+
+	js, _ := json.Marshal(c)
+	r = NewConsistentHash()
+	if err := json.Unmarshal(js, r); err != nil {
+		t.Fatal(err)
+	}
+	check("UnmarshalJSON", r)
+
+	r = NewConsistentHash()
+	if err := r.ImportProto(c.ExportProto()); err != nil {
+		t.Fatal(err)
+	}
+	check("ImportProto", r)
+
+	// 没有固定时的输出与之前的版本相同
+	c.UnpinKey("k1")
+	c.UnpinKey("k2")
+	if data, _ := c.MarshalBinary(); data[0] != binaryVersion {
+		t.Fatalf("binary version without pins = %d", data[0])
+	}
+	if js, _ := json.Marshal(c); strings.Contains(string(js), "pins") {
+		t.Fatalf("MarshalJSON without pins = %s", js)
+	}
+}
+
+func TestConsistentHash_PinKeyConcurrent(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 4), 20)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			nodeKey := "node-" + strconv.Itoa(g)
+			for i := 0; i < 200; i++ {
+				key := "tenant-" + strconv.Itoa(i%10)
+				if err := c.PinKey(key, nodeKey); err != nil {
+					t.Error(err)
+					return
+				}
+				c.UnpinKey(key)
+				c.Pins()
+			}
+		}(g)
+	}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := "tenant-" + strconv.Itoa(i%10)
+				if _, err := c.GetNode(key); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := c.GetN(key, 2); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 目标比例之和与 1 允许的误差
+const planTolerance = 0.01
+
+// PlanReplicas 根据每个结点期望的 hash 空间比例 targets 计算副本数，虚拟结点总数约为 totalPoints。
+// 结点拥有的 hash 空间的期望与副本数成正比，副本数按最大余数法取整，每个结点至少 1 个。
+// targets 必须包含环上的全部结点，比例都大于 0 且和约为 1。取整带来的误差见 PlanResidual，
+// 实际的分布还有虚拟结点位置随机带来的偏差，虚拟结点越多偏差越小
+func (c *ConsistentHash) PlanReplicas(targets map[string]float64, totalPoints int) (map[string]int, error) {
+	c.RLock()
+	keys := make([]string, 0, len(c.nodes))
+	for k := range c.nodes {
+		keys = append(keys, k)
+	}
+	for k := range targets {
+		if _, ok := c.nodes[k]; !ok {
+			c.RUnlock()
+			return nil, newNodeError(k, ErrNodeNotFound)
+		}
+	}
+	c.RUnlock()
+	if len(keys) == 0 {
+		return nil, ErrEmptyRing
+	}
+	var sum float64
+	for _, k := range keys {
+		share, ok := targets[k]
+		if !ok {
+			return nil, fmt.Errorf("node %s has no target share", k)
+		}
+		if !(share > 0) || math.IsInf(share, 0) {
+			return nil, fmt.Errorf("target share %v of node %s must be positive", share, k)
+		}
+		sum += share
+	}
+	if math.Abs(sum-1) > planTolerance {
+		return nil, fmt.Errorf("target shares sum to %v, want 1", sum)
+	}
+	total := totalPoints / c.pointsPerReplica()
+	if total < len(keys) {
+		return nil, fmt.Errorf("total points %d can't less %d nodes", totalPoints, len(keys))
+	}
+
+	// 先取整数部分，剩余的副本 (少于结点数) 依次分给小数部分最大的结点
+	sort.Strings(keys)
+	plan := make(map[string]int, len(keys))
+	rest := total
+	for _, k := range keys {
+		plan[k] = int(targets[k] / sum * float64(total))
+		rest -= plan[k]
+	}
+	byRemainder := append([]string(nil), keys...)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		qi, qj := targets[byRemainder[i]]/sum*float64(total), targets[byRemainder[j]]/sum*float64(total)
+		return qi-math.Floor(qi) > qj-math.Floor(qj)
+	})
+	for i := 0; i < rest; i++ {
+		plan[byRemainder[i]]++
+	}
+	for _, k := range keys {
+		if plan[k] < 1 {
+			plan[k] = 1
+		}
+		if err := c.checkReplicas(plan[k]); err != nil {
+			return nil, newNodeError(k, err)
+		}
+	}
+	return plan, nil
+}
+
+// PlanResidual 返回按 plan 的副本数期望的比例与 targets 的最大偏差，targets 先归一化
+func PlanResidual(targets map[string]float64, plan map[string]int) float64 {
+	var sum float64
+	for _, share := range targets {
+		sum += share
+	}
+	total := 0
+	for _, n := range plan {
+		total += n
+	}
+	if sum == 0 || total == 0 {
+		return 0
+	}
+	var residual float64
+	for k, share := range targets {
+		residual = math.Max(residual, math.Abs(float64(plan[k])/float64(total)-share/sum))
+	}
+	for k, n := range plan {
+		if _, ok := targets[k]; !ok {
+			residual = math.Max(residual, float64(n)/float64(total))
+		}
+	}
+	return residual
+}
+
+// ApplyPlan 把 plan 中每个结点的副本数原地调整为计划的数目，与 SetVirtualNodeCount 相同，
+// 只有增加或删除的虚拟结点上的 key 会迁移。不在 plan 中的结点不变，任何一个结点失败时环保持不变
+func (c *ConsistentHash) ApplyPlan(plan map[string]int) error {
+	keys := make([]string, 0, len(plan))
+	for k, n := range plan {
+		if err := c.checkReplicas(n); err != nil {
+			return newNodeError(k, err)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return c.update(0, 0, func(staged *ConsistentHash) error {
+		for _, k := range keys {
+			cNode, ok := staged.nodes[k]
+			if !ok {
+				return newNodeError(k, ErrNodeNotFound)
+			}
+			current, want := len(cNode.virtualNodes)/staged.pointsPerReplica(), plan[k]
+			var err error
+			switch {
+			case want > current:
+				err = staged.grow(k, want-current)
+			case want < current:
+				err = staged.shrink(k, func(current int) int { return current - want })
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package consistent_hash
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_PlanReplicas(t *testing.T) {
+	c := NewConsistentHashXX()
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.AddWithVirtualNode(testNode{k}, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	targets := map[string]float64{"a": 3.0 / 6, "b": 2.0 / 6, "c": 1.0 / 6}
+	plan, err := c.PlanReplicas(targets, 12000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan["a"] != 6000 || plan["b"] != 4000 || plan["c"] != 2000 {
+		t.Fatalf("PlanReplicas = %v", plan)
+	}
+	if r := PlanResidual(targets, plan); r > 1e-9 {
+		t.Errorf("PlanResidual = %v", r)
+	}
+	if err := c.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	// 原地调整后的环与直接按计划添加的环相同，只迁移增加或删除的虚拟结点上的 key
+	fresh := NewConsistentHashXX()
+	for k, n := range plan {
+		fresh.AddWithVirtualNode(testNode{k}, n)
+	}
+	if c.Checksum() != fresh.Checksum() {
+		t.Error("ApplyPlan differs from adding the nodes with the planned replicas")
+	}
+
+	shares := c.Stats().Shares
+	counts := map[string]int{}
+	const samples = 60000
+	for i := 0; i < samples; i++ {
+		k, _ := c.GetKey("key-" + strconv.Itoa(i))
+		counts[k]++
+	}
+	for k, want := range targets {
+		if math.Abs(shares[k]-want) > 0.01 {
+			t.Errorf("share of %s = %.3f, want about %.3f", k, shares[k], want)
+		}
+		if got := float64(counts[k]) / samples; math.Abs(got-want) > 0.015 {
+			t.Errorf("%s received %.3f of keys, want about %.3f", k, got, want)
+		}
+	}
+}
+
+func TestConsistentHash_PlanReplicasRounding(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 3), 10)
+	// 每个结点 33.33 个副本，剩余的 1 个给小数部分最大的结点
+	targets := map[string]float64{"node-0": 0.3334, "node-1": 0.3333, "node-2": 0.3333}
+	plan, err := c.PlanReplicas(targets, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan["node-0"] != 34 || plan["node-1"] != 33 || plan["node-2"] != 33 {
+		t.Fatalf("PlanReplicas = %v", plan)
+	}
+	if r := PlanResidual(targets, plan); r < 0.006 || r > 0.007 {
+		t.Errorf("PlanResidual = %v", r)
+	}
+
+	// 比例很小的结点至少 1 个副本
+	plan, err = c.PlanReplicas(map[string]float64{"node-0": 0.995, "node-1": 0.004, "node-2": 0.001}, 100)
+	if err != nil || plan["node-2"] != 1 {
+		t.Fatalf("PlanReplicas = %v, %v", plan, err)
+	}
+}
+
+func TestConsistentHash_PlanReplicasInvalid(t *testing.T) {
+	c := NewConsistentHash()
+	if _, err := c.PlanReplicas(map[string]float64{}, 100); !errors.Is(err, ErrEmptyRing) {
+		t.Errorf("empty ring err = %v", err)
+	}
+	c.AddAll(batchTestNodes("node-", 2), 10)
+	for name, targets := range map[string]map[string]float64{
+		"sum":      {"node-0": 0.5, "node-1": 0.3},
+		"missing":  {"node-0": 1},
+		"zero":     {"node-0": 1, "node-1": 0},
+		"negative": {"node-0": 1.5, "node-1": -0.5},
+		"nan":      {"node-0": 1, "node-1": math.NaN()},
+	} {
+		if _, err := c.PlanReplicas(targets, 100); err == nil {
+			t.Errorf("%s: targets %v accepted", name, targets)
+		}
+	}
+	if _, err := c.PlanReplicas(map[string]float64{"node-0": 0.5, "node-1": 0.25, "x": 0.25}, 100); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("unknown node err = %v", err)
+	}
+	if _, err := c.PlanReplicas(map[string]float64{"node-0": 0.5, "node-1": 0.5}, 1); err == nil {
+		t.Error("fewer points than nodes accepted")
+	}
+	if _, err := c.PlanReplicas(map[string]float64{"node-0": 0.5, "node-1": 0.5}, 2*defaultMaxReplicas+2); !errors.Is(err, ErrInvalidReplicas) {
+		t.Errorf("too many replicas err = %v", err)
+	}
+
+	checksum := c.Checksum()
+	for _, plan := range []map[string]int{
+		{"node-0": 20, "x": 5},
+		{"node-0": 20, "node-1": 0},
+	} {
+		if err := c.ApplyPlan(plan); err == nil {
+			t.Errorf("ApplyPlan(%v) accepted", plan)
+		}
+	}
+	if c.Checksum() != checksum {
+		t.Fatal("failed ApplyPlan changed the ring")
+	}
+}
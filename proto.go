@@ -0,0 +1,126 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Tsai-ilin/consistent-hash/ringpb"
+)
+
+const protoFormatVersion = 1
+
+// 查询 key 时使用的 hash 算法，调用方需持有锁
+func (c *ConsistentHash) protoHashAlgorithm() (ringpb.HashAlgorithm, uint64) {
+	if c.ketama && (c.twemproxyHash == "" || c.twemproxyHash == "md5") {
+		return ringpb.HashAlgorithm_HASH_ALGORITHM_KETAMA_MD5, 0
+	}
+	if c.hash == nil {
+		return ringpb.HashAlgorithm_HASH_ALGORITHM_CRC32_IEEE, 0
+	}
+	switch c.hashName {
+	case hashCRC32:
+		return ringpb.HashAlgorithm_HASH_ALGORITHM_CRC32_IEEE, 0
+	case hashXXHash32:
+		return ringpb.HashAlgorithm_HASH_ALGORITHM_XXHASH32, 0
+	case hashSipHash24:
+		return ringpb.HashAlgorithm_HASH_ALGORITHM_SIPHASH24, c.hashSeed
+	}
+	return ringpb.HashAlgorithm_HASH_ALGORITHM_UNSPECIFIED, 0
+}
+
+// ExportProto 导出环的状态，包含每个虚拟结点的值，其他语言只需实现相同的 key hash 即可查询。
+// 使用 WithHash 等自定义 hash 时 HashAlgorithm 为 HASH_ALGORITHM_UNSPECIFIED
+func (c *ConsistentHash) ExportProto() *ringpb.Ring {
+	c.RLock()
+	defer c.RUnlock()
+
+	r := &ringpb.Ring{
+		FormatVersion: protoFormatVersion,
+		Nodes:         make([]*ringpb.Node, 0, len(c.nodes)),
+		SortedPoints:  append([]uint32(nil), c.hashSortedNodes...),
+	}
+	r.HashAlgorithm, r.HashSeed = c.protoHashAlgorithm()
+	for k, n := range c.nodes {
+		r.Nodes = append(r.Nodes, &ringpb.Node{
+			Key:      k,
+			Replicas: uint32(len(n.virtualNodes) / c.pointsPerReplica()),
+			Points:   append([]uint32(nil), n.virtualNodes...),
+		})
+	}
+	sort.Slice(r.Nodes, func(i, j int) bool {
+		return r.Nodes[i].Key < r.Nodes[j].Key
+	})
+	for k, v := range c.pins {
+		r.Pins = append(r.Pins, &ringpb.Pin{Key: k, NodeKey: v})
+	}
+	sort.Slice(r.Pins, func(i, j int) bool {
+		return r.Pins[i].Key < r.Pins[j].Key
+	})
+	for _, p := range c.sortedChainPoints() {
+		r.Chains = append(r.Chains, &ringpb.Chain{Point: p, NodeKeys: append([]string(nil), c.chains[p]...)})
+	}
+	return r
+}
+
+// ImportProto 用 ExportProto 的结果替换环上的全部结点，结点由 WithNodeFactory 创建。
+// r 的 hash 算法必须与接收者相同，除 Chains 记录的共用虚拟结点外虚拟结点必须唯一，SortedPoints 严格升序，
+// 否则返回错误，环保持不变
+func (c *ConsistentHash) ImportProto(r *ringpb.Ring) error {
+	if r == nil {
+		return fmt.Errorf("%w: ring is nil", ErrCorruptData)
+	}
+	if r.FormatVersion != protoFormatVersion {
+		return fmt.Errorf("%w: unknown format version %d", ErrCorruptData, r.FormatVersion)
+	}
+	if !r.HashAlgorithm.Known() {
+		return fmt.Errorf("%w: unknown hash algorithm %v", ErrCorruptData, r.HashAlgorithm)
+	}
+
+	c.RLock()
+	algorithm, seed := c.protoHashAlgorithm()
+	perReplica := c.pointsPerReplica()
+	shares := c.sharesPoints()
+	c.RUnlock()
+	if r.HashAlgorithm != algorithm || r.HashSeed != seed {
+		return fmt.Errorf("%w: ring uses %v, receiver uses %v", ErrHashMismatch, r.HashAlgorithm, algorithm)
+	}
+
+	keys := make([]string, len(r.Nodes))
+	points := make([][]uint32, len(r.Nodes))
+	for i, n := range r.Nodes {
+		if n == nil {
+			return fmt.Errorf("%w: node %d is nil", ErrCorruptData, i)
+		}
+		// CollisionOverwrite 下虚拟结点可能被后添加的结点覆盖，CollisionChain 下同一结点的副本冲突时只保留一个，
+		// 个数少于副本数
+		if !shares && int(n.Replicas)*perReplica != len(n.Points) {
+			return fmt.Errorf("%w: node %s has %d points for %d replicas", ErrCorruptData, n.Key, len(n.Points), n.Replicas)
+		}
+		keys[i] = n.Key
+		points[i] = append([]uint32(nil), n.Points...)
+	}
+	var pins map[string]string
+	for i, p := range r.Pins {
+		if p == nil {
+			return fmt.Errorf("%w: pin %d is nil", ErrCorruptData, i)
+		}
+		if pins == nil {
+			pins = make(map[string]string, len(r.Pins))
+		}
+		pins[p.Key] = p.NodeKey
+	}
+	var chains map[uint32][]string
+	for i, ch := range r.Chains {
+		if ch == nil {
+			return fmt.Errorf("%w: chain %d is nil", ErrCorruptData, i)
+		}
+		if _, ok := chains[ch.Point]; ok {
+			return fmt.Errorf("%w: duplicate chain of point %d", ErrCorruptData, ch.Point)
+		}
+		if chains == nil {
+			chains = make(map[uint32][]string, len(r.Chains))
+		}
+		chains[ch.Point] = append([]string(nil), ch.NodeKeys...)
+	}
+	return c.loadPoints(keys, points, append([]uint32(nil), r.SortedPoints...), pins, chains)
+}
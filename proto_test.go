@@ -0,0 +1,122 @@
+package consistent_hash
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Tsai-ilin/consistent-hash/ringpb"
+)
+
+func TestConsistentHash_ExportProto(t *testing.T) {
+	seeded := func() *ConsistentHash {
+		c, _ := New(WithSeed(42))
+		return c
+	}
+	tests := []struct {
+		name      string
+		new       func() *ConsistentHash
+		algorithm ringpb.HashAlgorithm
+	}{
+		{"crc32", NewConsistentHash, ringpb.HashAlgorithm_HASH_ALGORITHM_CRC32_IEEE},
+		{"xxhash", NewConsistentHashXX, ringpb.HashAlgorithm_HASH_ALGORITHM_XXHASH32},
+		{"ketama", NewKetama, ringpb.HashAlgorithm_HASH_ALGORITHM_KETAMA_MD5},
+		{"siphash", seeded, ringpb.HashAlgorithm_HASH_ALGORITHM_SIPHASH24},
+	}
+	for _, tt := range tests {
+		src := tt.new()
+		if err := src.AddAll(batchTestNodes("node-", 10), 20); err != nil {
+			t.Fatal(err)
+		}
+		exported := src.ExportProto()
+		if exported.HashAlgorithm != tt.algorithm || len(exported.Nodes) != 10 || exported.Nodes[0].Replicas != 20 {
+			t.Fatalf("%s: ExportProto = %v %d nodes", tt.name, exported.HashAlgorithm, len(exported.Nodes))
+		}
+
+		data, err := exported.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r ringpb.Ring
+		if err := r.Unmarshal(data); err != nil {
+			t.Fatal(err)
+		}
+		dst := tt.new()
+		if err := dst.ImportProto(&r); err != nil {
+			t.Fatalf("%s: ImportProto = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(dst.ExportProto(), exported) {
+			t.Fatalf("%s: ring differs after round trip", tt.name)
+		}
+		for i := 0; i < 1000; i++ {
+			key := "key-" + strconv.Itoa(i)
+			a, _ := src.GetNode(key)
+			b, _ := dst.GetNode(key)
+			if a.Key() != b.Key() {
+				t.Fatalf("%s: GetNode(%s) = %s, want %s", tt.name, key, b.Key(), a.Key())
+			}
+		}
+	}
+}
+
+func TestConsistentHash_ImportProtoValidation(t *testing.T) {
+	src := NewConsistentHash()
+	if err := src.AddAll(batchTestNodes("node-", 3), 2); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewConsistentHash()
+	if err := dst.Add(testNode{"keep"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(r *ringpb.Ring)
+		sentinel error
+	}{
+		{"format version", func(r *ringpb.Ring) { r.FormatVersion = 2 }, ErrCorruptData},
+		{"unknown hash", func(r *ringpb.Ring) { r.HashAlgorithm = 99 }, ErrCorruptData},
+		{"other hash", func(r *ringpb.Ring) { r.HashAlgorithm = ringpb.HashAlgorithm_HASH_ALGORITHM_XXHASH32 }, ErrHashMismatch},
+		{"unsorted", func(r *ringpb.Ring) {
+			r.SortedPoints[0], r.SortedPoints[1] = r.SortedPoints[1], r.SortedPoints[0]
+		}, ErrCorruptData},
+		{"duplicate point", func(r *ringpb.Ring) { r.Nodes[1].Points[0] = r.Nodes[0].Points[0] }, ErrCorruptData},
+		{"missing sorted point", func(r *ringpb.Ring) { r.SortedPoints = r.SortedPoints[1:] }, ErrCorruptData},
+		{"replicas", func(r *ringpb.Ring) { r.Nodes[0].Replicas = 3 }, ErrCorruptData},
+		{"duplicate node", func(r *ringpb.Ring) { r.Nodes[1].Key = r.Nodes[0].Key }, ErrCorruptData},
+		{"nil node", func(r *ringpb.Ring) { r.Nodes[0] = nil }, ErrCorruptData},
+	}
+	for _, tt := range tests {
+		r := src.ExportProto()
+		tt.modify(r)
+		if err := dst.ImportProto(r); !errors.Is(err, tt.sentinel) {
+			t.Errorf("%s: ImportProto = %v, want %v", tt.name, err, tt.sentinel)
+		}
+		if got := nodeKeys(dst.Members()); !equalStrings(got, []string{"keep"}) {
+			t.Fatalf("%s: failed ImportProto changed the ring: %v", tt.name, got)
+		}
+	}
+	if err := dst.ImportProto(nil); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("ImportProto(nil) = %v", err)
+	}
+
+	// 自定义 hash 无法识别，只能导入到同样使用自定义 hash 的环
+	custom := NewConsistentWithCustomHash(xxhash32)
+	r := custom.ExportProto()
+	if r.HashAlgorithm != ringpb.HashAlgorithm_HASH_ALGORITHM_UNSPECIFIED {
+		t.Fatalf("custom hash exported as %v", r.HashAlgorithm)
+	}
+	if err := dst.ImportProto(r); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("ImportProto = %v, want ErrHashMismatch", err)
+	}
+	if err := NewConsistentWithCustomHash(xxhash32).ImportProto(r); err != nil {
+		t.Error(err)
+	}
+
+	other, _ := New(WithSeed(1))
+	seeded, _ := New(WithSeed(2))
+	if err := seeded.ImportProto(other.ExportProto()); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("ImportProto with another seed = %v, want ErrHashMismatch", err)
+	}
+}
@@ -0,0 +1,83 @@
+package consistent_hash
+
+import "fmt"
+
+// AddWithRamp 初始副本数占目标副本数的比例
+const rampInitialFraction = 10
+
+// AddWithRamp 以 targetReplicas 的 1/10 (向上取整) 添加结点，之后由 Ramp 或 GrowVirtualNodes
+// 逐步增加到 targetReplicas，避免冷启动的结点立即承担全部流量
+func (c *ConsistentHash) AddWithRamp(node Node, targetReplicas int) error {
+	if err := checkNode(node); err != nil {
+		return err
+	}
+	if err := c.checkReplicas(targetReplicas); err != nil {
+		return err
+	}
+	c.lockWrite()
+	defer c.unlock()
+	initial := (targetReplicas + rampInitialFraction - 1) / rampInitialFraction
+	if err := c.addNode(node, initial); err != nil {
+		return err
+	}
+	if initial < targetReplicas {
+		cNode := c.nodes[node.Key()]
+		cNode.rampTarget = targetReplicas
+		c.nodes[node.Key()] = cNode
+	}
+	return nil
+}
+
+// Ramp 是 AddWithRamp 之后逐步增加副本的一步：增加与目标副本数差值的 1/steps (向上取整)，
+// steps 为剩余的步数。按 steps、steps-1、…、1 依次调用即可在 steps 次内达到目标副本数，
+// 已经达到目标时不做任何事
+func (c *ConsistentHash) Ramp(nodeKey string, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps %d can't less 1", steps)
+	}
+	c.lockWrite()
+	defer c.unlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return newNodeError(nodeKey, ErrNodeNotFound)
+	}
+	remaining := cNode.rampTarget - len(cNode.virtualNodes)/c.pointsPerReplica()
+	if remaining <= 0 {
+		return nil
+	}
+	return c.grow(nodeKey, (remaining+steps-1)/steps)
+}
+
+// GrowVirtualNodes 按原有规则为结点增加 addCount 个后续编号的副本，已有的虚拟结点保持不变，
+// 只有新虚拟结点上的 key 迁移到该结点。增加到 n 个副本后的环与直接以 n 个副本添加的环相同
+func (c *ConsistentHash) GrowVirtualNodes(nodeKey string, addCount int) error {
+	if addCount < 1 {
+		return fmt.Errorf("%w %d, can't less 1", ErrInvalidReplicas, addCount)
+	}
+	c.lockWrite()
+	defer c.unlock()
+	return c.grow(nodeKey, addCount)
+}
+
+// 调用方需持有写锁
+func (c *ConsistentHash) grow(nodeKey string, addCount int) error {
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return newNodeError(nodeKey, ErrNodeNotFound)
+	}
+	current := len(cNode.virtualNodes) / c.pointsPerReplica()
+	if err := c.checkReplicas(current + addCount); err != nil {
+		return err
+	}
+	virtualNodes, err := c.addVirtualNodes(nodeKey, current, current+addCount)
+	if err != nil {
+		return err
+	}
+	cNode.virtualNodes = append(cNode.virtualNodes, virtualNodes...)
+	if current+addCount >= cNode.rampTarget {
+		cNode.rampTarget = 0
+	}
+	c.nodes[nodeKey] = cNode
+	c.version++
+	return nil
+}
@@ -0,0 +1,90 @@
+package consistent_hash
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_AddWithRamp(t *testing.T) {
+	c := NewConsistentHashXX()
+	if err := c.AddAll(batchTestNodes("node-", 5), 100); err != nil {
+		t.Fatal(err)
+	}
+	direct := c.Clone()
+	if err := direct.AddWithVirtualNode(testNode{"new"}, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	before := c.Clone()
+	if err := c.AddWithRamp(testNode{"new"}, 100); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 5000)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	for steps, want := range []int{10, 33, 56, 78, 100} {
+		if steps > 0 {
+			before = c.Clone()
+			if err := c.Ramp("new", 5-steps); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n, _ := c.VirtualNodeCount("new"); n != want {
+			t.Fatalf("step %d: %d replicas, want %d", steps, n, want)
+		}
+		// 只会有 key 迁移到新结点
+		for _, key := range keys {
+			oldOwner, _ := before.GetKey(key)
+			newOwner, _ := c.GetKey(key)
+			if oldOwner != newOwner && newOwner != "new" {
+				t.Fatalf("step %d: key %s moved %s -> %s", steps, key, oldOwner, newOwner)
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(c.hashSortedNodes, direct.hashSortedNodes) || !reflect.DeepEqual(c.circle, direct.circle) ||
+		!reflect.DeepEqual(c.nodes["new"].virtualNodes, direct.nodes["new"].virtualNodes) {
+		t.Fatal("ramping to the target differs from adding with the target directly")
+	}
+	if err := c.Ramp("new", 1); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.VirtualNodeCount("new"); n != 100 {
+		t.Fatalf("Ramp past the target grew the node to %d", n)
+	}
+	if err := c.Ramp("x", 1); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Ramp(x) = %v", err)
+	}
+}
+
+func TestConsistentHash_GrowVirtualNodes(t *testing.T) {
+	grown, direct := NewConsistentHash(), NewConsistentHash()
+	if err := grown.AddKey("a", 3); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{5, 1, 11} {
+		if err := grown.GrowVirtualNodes("a", n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := direct.AddKey("a", 20); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(grown.hashSortedNodes, direct.hashSortedNodes) ||
+		!reflect.DeepEqual(grown.nodes["a"].virtualNodes, direct.nodes["a"].virtualNodes) {
+		t.Fatal("GrowVirtualNodes differs from adding with the final count")
+	}
+
+	if err := grown.GrowVirtualNodes("a", 0); !errors.Is(err, ErrInvalidReplicas) {
+		t.Fatalf("GrowVirtualNodes(0) = %v", err)
+	}
+	if err := grown.GrowVirtualNodes("a", defaultMaxReplicas); !errors.Is(err, ErrInvalidReplicas) {
+		t.Fatalf("GrowVirtualNodes past the limit = %v", err)
+	}
+	if err := grown.GrowVirtualNodes("x", 1); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("GrowVirtualNodes(x) = %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+)
+
+// RebalanceReport 是 Rebalance 的结果，Changes 按 key 排序，Churn 为归属发生变化的 hash 空间比例
+type RebalanceReport struct {
+	Changes []ReplicaChange
+	Churn   float64
+}
+
+// ReplicaChange 记录一个结点的副本数从 From 调整为 To
+type ReplicaChange struct {
+	Key      string
+	From, To int
+}
+
+// Rebalance 根据 WithLoadTracking 统计的负载调整在线结点的副本数，使负载趋于均匀：
+// 负载比例高于平均的结点减少副本，低于平均的增加副本。每次向目标调整一半，每个结点最多调整 maxAdjust 个，
+// 至少保留 1 个。
+// 调整之后负载计数清零，下一次调用使用新的分布下的负载。没有开启负载统计或者计数为 0 时不修改环
+func (c *ConsistentHash) Rebalance(maxAdjust int) (RebalanceReport, error) {
+	if maxAdjust < 1 {
+		return RebalanceReport{}, fmt.Errorf("max adjust %d can't less 1", maxAdjust)
+	}
+	var report RebalanceReport
+	err := c.update(0, 0, func(staged *ConsistentHash) error {
+		if !staged.loadTracking {
+			return nil
+		}
+		report.Changes = staged.rebalanceTargets(maxAdjust)
+		if len(report.Changes) == 0 {
+			return nil
+		}
+		for _, ch := range report.Changes {
+			var err error
+			if ch.To > ch.From {
+				err = staged.grow(ch.Key, ch.To-ch.From)
+			} else {
+				err = staged.shrink(ch.Key, func(int) int { return ch.From - ch.To })
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for _, n := range staged.nodes {
+			atomic.StoreUint64(n.hits, 0)
+		}
+		// 持有 writeMu 时可以读取 c
+		_, report.Churn = remapRanges(c, staged)
+		return nil
+	})
+	if err != nil {
+		return RebalanceReport{}, err
+	}
+	return report, nil
+}
+
+// 计算每个在线结点调整后的副本数，调用方需持有写锁
+func (c *ConsistentHash) rebalanceTargets(maxAdjust int) []ReplicaChange {
+	shares := make(map[string]float64, len(c.nodes))
+	for i, p := range c.hashSortedNodes {
+		shares[c.circle[p]] += c.pointWidth(i)
+	}
+
+	var total uint64
+	keys := make([]string, 0, len(c.nodes))
+	for k, n := range c.nodes {
+		if n.down {
+			continue
+		}
+		total += atomic.LoadUint64(n.hits)
+		keys = append(keys, k)
+	}
+	if total == 0 || len(keys) < 2 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	// 结点的 key 密度为负载比例除以 hash 空间比例，负载均匀时每个结点拥有的 hash 空间与密度成反比，
+	// 副本数按 hash 空间的比例缩放: target = replicas * (1/load) / sum(share/load)
+	load := func(k string) float64 {
+		// 没有命中的结点按半次计算，避免除以 0
+		return math.Max(float64(atomic.LoadUint64(c.nodes[k].hits)), 0.5) / float64(total)
+	}
+	var space, sum float64
+	for _, k := range keys {
+		space += shares[k]
+	}
+	for _, k := range keys {
+		sum += shares[k] / space / load(k)
+	}
+
+	max := c.maxReplicas
+	if max == 0 {
+		max = defaultMaxReplicas
+	}
+	var changes []ReplicaChange
+	for _, k := range keys {
+		from := len(c.nodes[k].virtualNodes) / c.pointsPerReplica()
+		// 只调整差距的一半，新增的虚拟结点位置随机，一次调整到位容易越过目标
+		delta := int(math.Round((float64(from)/load(k)/sum - float64(from)) / 2))
+		if delta > maxAdjust {
+			delta = maxAdjust
+		} else if delta < -maxAdjust {
+			delta = -maxAdjust
+		}
+		to := from + delta
+		if to < 1 {
+			to = 1
+		} else if to > max {
+			to = max
+		}
+		if to != from {
+			changes = append(changes, ReplicaChange{Key: k, From: from, To: to})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,111 @@
+package consistent_hash
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// 用集中在环的起点附近的 hash 模拟倾斜的 key 分布，返回负载最大与最小的结点之比
+func skewedLoadRatio(c *ConsistentHash, r *rand.Rand, lookups int) float64 {
+	for i := 0; i < lookups; i++ {
+		u := r.Float64()
+		c.GetOwnerByHash(uint32(u * u * math.MaxUint32))
+	}
+	min, max := uint64(math.MaxUint64), uint64(0)
+	for _, n := range c.LoadReport() {
+		if n < min {
+			min = n
+		}
+		if n > max {
+			max = n
+		}
+	}
+	return float64(max) / math.Max(float64(min), 1)
+}
+
+func TestConsistentHash_Rebalance(t *testing.T) {
+	c, _ := New(WithLoadTracking())
+	if err := c.AddAll(batchTestNodes("node-", 8), 100); err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(1))
+
+	ratios := []float64{skewedLoadRatio(c, r, 200000)}
+	for round := 0; round < 8; round++ {
+		before := c.ReplicaCounts()
+		report, err := c.Rebalance(20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sort.SliceIsSorted(report.Changes, func(i, j int) bool { return report.Changes[i].Key < report.Changes[j].Key }) {
+			t.Fatal("changes are not sorted")
+		}
+		after := c.ReplicaCounts()
+		for _, ch := range report.Changes {
+			if before[ch.Key] != ch.From || after[ch.Key] != ch.To || ch.To < 1 {
+				t.Fatalf("change %+v, replicas before %d after %d", ch, before[ch.Key], after[ch.Key])
+			}
+			if d := ch.To - ch.From; d > 20 || d < -20 || d == 0 {
+				t.Fatalf("change %+v exceeds maxAdjust", ch)
+			}
+			delete(before, ch.Key)
+			delete(after, ch.Key)
+		}
+		if !reflect.DeepEqual(before, after) {
+			t.Fatal("nodes changed without being reported")
+		}
+		if len(report.Changes) > 0 && !(report.Churn > 0 && report.Churn < 1) {
+			t.Fatalf("churn = %v", report.Churn)
+		}
+		for _, n := range c.LoadReport() {
+			if n != 0 {
+				t.Fatal("load counters were not reset")
+			}
+		}
+		if err := c.VerifyRing(); err != nil {
+			t.Fatal(err)
+		}
+		ratios = append(ratios, skewedLoadRatio(c, r, 200000))
+	}
+	t.Logf("max/min load ratio: %.2f", ratios)
+	if last := ratios[len(ratios)-1]; last > ratios[0]*0.6 || last > 1.3 {
+		t.Fatalf("Rebalance did not even out the load: %.2f", ratios)
+	}
+}
+
+func TestConsistentHash_RebalanceNoop(t *testing.T) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 4), 10)
+	c.GetNode("k")
+	version := c.Version()
+	if report, err := c.Rebalance(5); err != nil || report.Changes != nil || c.Version() != version {
+		t.Fatalf("Rebalance without load tracking = %+v, %v", report, err)
+	}
+
+	c, _ = New(WithLoadTracking())
+	c.AddAll(batchTestNodes("node-", 4), 10)
+	version = c.Version()
+	if report, err := c.Rebalance(5); err != nil || report.Changes != nil || c.Version() != version {
+		t.Fatalf("Rebalance without hits = %+v, %v", report, err)
+	}
+	if _, err := c.Rebalance(0); err == nil {
+		t.Fatal("maxAdjust 0 accepted")
+	}
+
+	// 副本数不会低于 1
+	c, _ = New(WithLoadTracking())
+	c.AddWithVirtualNode(testNode{"hot"}, 2)
+	c.AddWithVirtualNode(testNode{"cold"}, 2)
+	for i := 0; i < 1000; i++ {
+		c.GetOwnerByHash(c.nodes["hot"].virtualNodes[0])
+	}
+	if _, err := c.Rebalance(100); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.VirtualNodeCount("hot"); n != 1 {
+		t.Fatalf("hot node has %d replicas", n)
+	}
+}
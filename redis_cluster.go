@@ -0,0 +1,79 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// RedisSlots 是 Redis Cluster 的 slot 数
+const RedisSlots = 16384
+
+// Slot 按 Redis Cluster 的规则计算 key 的 slot: 对 key 的 hash tag 计算 CRC16 (XMODEM) 后对 16384 取模。
+// key 中第一个 '{' 与其后第一个 '}' 之间的内容非空时为 hash tag，否则使用整个 key
+func Slot(key string) uint16 {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			key = key[i+1 : i+1+j]
+		}
+	}
+	return crc16(key) & (RedisSlots - 1)
+}
+
+// CRC16 XMODEM: 多项式 0x1021，初始值 0
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// SlotMap 记录 Redis Cluster 每个 slot 所属结点的 key，可以并发使用
+type SlotMap struct {
+	mu     sync.RWMutex
+	owners [RedisSlots]string
+}
+
+func NewSlotMap() *SlotMap {
+	return &SlotMap{}
+}
+
+// AssignSlots 把 [from, to] 的 slot 分配给 nodeKey，覆盖原来的分配。nodeKey 为空时取消分配
+func (m *SlotMap) AssignSlots(nodeKey string, from, to uint16) error {
+	if from > to || to >= RedisSlots {
+		return fmt.Errorf("invalid slot range [%d, %d]", from, to)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for s := int(from); s <= int(to); s++ {
+		m.owners[s] = nodeKey
+	}
+	return nil
+}
+
+// OwnerOfSlot 返回 slot 所属结点的 key，没有分配时返回 ErrSlotNotAssigned
+func (m *SlotMap) OwnerOfSlot(slot uint16) (string, error) {
+	if slot >= RedisSlots {
+		return "", fmt.Errorf("slot %d out of range [0, %d)", slot, RedisSlots)
+	}
+	m.mu.RLock()
+	owner := m.owners[slot]
+	m.mu.RUnlock()
+	if owner == "" {
+		return "", fmt.Errorf("%w: %d", ErrSlotNotAssigned, slot)
+	}
+	return owner, nil
+}
+
+// GetNode 返回 key 所在 slot 所属结点的 key
+func (m *SlotMap) GetNode(key string) (string, error) {
+	return m.OwnerOfSlot(Slot(key))
+}
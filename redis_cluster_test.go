@@ -0,0 +1,88 @@
+package consistent_hash
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		want uint16
+	}{
+		// CLUSTER KEYSLOT 的结果
+		{"foo", 12182},
+		{"bar", 5061},
+		{"hello", 866},
+		{"123456789", 0x31c3},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := Slot(tt.key); got != tt.want {
+			t.Errorf("Slot(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+
+	tags := []struct {
+		key, same string
+	}{
+		{"{user1000}.following", "user1000"},
+		{"{user1000}.followers", "user1000"},
+		{"foo{bar}{zap}", "bar"},
+		{"foo{{bar}}zap", "{bar"},
+		{"foo{bar", "foo{bar"},
+		// 空的 hash tag 使用整个 key
+		{"foo{}{bar}", "foo{}{bar}"},
+		{"{}", "{}"},
+		{"}foo{bar}", "bar"},
+	}
+	for _, tt := range tags {
+		if got, want := Slot(tt.key), crc16(tt.same)%RedisSlots; got != want {
+			t.Errorf("Slot(%q) = %d, want slot of %q = %d", tt.key, got, tt.same, want)
+		}
+	}
+	if Slot("{}") == Slot("") {
+		t.Error("empty hash tag hashed as empty key")
+	}
+}
+
+func TestSlotMap(t *testing.T) {
+	m := NewSlotMap()
+	if _, err := m.GetNode("foo"); !errors.Is(err, ErrSlotNotAssigned) {
+		t.Fatalf("unassigned err = %v", err)
+	}
+	if err := m.AssignSlots("a", 0, 8191); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AssignSlots("b", 8192, RedisSlots-1); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := m.GetNode("foo"); err != nil || n != "b" {
+		t.Fatalf("GetNode(foo) = %v, %v", n, err)
+	}
+	if n, err := m.GetNode("hello"); err != nil || n != "a" {
+		t.Fatalf("GetNode(hello) = %v, %v", n, err)
+	}
+
+	// 迁移一个 slot
+	m.AssignSlots("c", 12182, 12182)
+	for slot, want := range map[uint16]string{12181: "b", 12182: "c", 12183: "b"} {
+		if n, _ := m.OwnerOfSlot(slot); n != want {
+			t.Fatalf("OwnerOfSlot(%d) = %s, want %s", slot, n, want)
+		}
+	}
+	m.AssignSlots("", 12182, 12182)
+	if _, err := m.OwnerOfSlot(12182); !errors.Is(err, ErrSlotNotAssigned) {
+		t.Fatalf("unassigned slot err = %v", err)
+	}
+
+	if err := m.AssignSlots("a", 10, 9); err == nil {
+		t.Fatal("reversed range accepted")
+	}
+	if err := m.AssignSlots("a", 0, RedisSlots); err == nil {
+		t.Fatal("out of range slot accepted")
+	}
+	if _, err := m.OwnerOfSlot(RedisSlots); err == nil {
+		t.Fatal("out of range slot accepted")
+	}
+}
@@ -0,0 +1,153 @@
+package consistent_hash
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"unsafe"
+)
+
+// RangeMove 表示 hash 区间 [Start, End] 的归属从 OldOwner 变为 NewOwner。
+// 区间不跨越环的起点，跨越起点的区间拆分为 [x, math.MaxUint32] 和 [0, y] 两段。
+// 环上原本没有结点时 OldOwner 为空，删除后没有结点时 NewOwner 为空
+type RangeMove struct {
+	Start, End uint32
+	OldOwner   string
+	NewOwner   string
+}
+
+// RemapOnAdd 返回添加 node 后归属发生变化的 hash 区间和它们占 hash 空间的比例，不修改环。
+// node 无法添加时返回 nil 和 0
+func (c *ConsistentHash) RemapOnAdd(node Node, replicas int) ([]RangeMove, float64) {
+	c.RLock()
+	defer c.RUnlock()
+	after := c.previewLocked()
+	if err := after.AddWithVirtualNode(node, replicas); err != nil {
+		return nil, 0
+	}
+	return remapRanges(c, after)
+}
+
+// RemapOnRemove 返回删除结点后归属发生变化的 hash 区间和它们占 hash 空间的比例，不修改环。
+// 结点不存在时返回 nil 和 0
+func (c *ConsistentHash) RemapOnRemove(nodeKey string) ([]RangeMove, float64) {
+	c.RLock()
+	defer c.RUnlock()
+	after := c.previewLocked()
+	if err := after.unlinkNode(nodeKey); err != nil {
+		return nil, 0
+	}
+	return remapRanges(c, after)
+}
+
+// 用于预测修改结果的副本，修改它不会上报 Metrics，调用方需持有锁
+func (c *ConsistentHash) previewLocked() *ConsistentHash {
+	after := c.cloneLocked()
+	after.metrics = nil
+	return after
+}
+
+// 比较两个环每个 hash 区间的归属，调用方需持有两个环的锁
+func remapRanges(before, after *ConsistentHash) ([]RangeMove, float64) {
+	return remapPoints(before.hashSortedNodes, after.hashSortedNodes, before.ownerOf, after.ownerOf)
+}
+
+// 比较修改前后每个 hash 区间的归属，beforeOwner、afterOwner 返回 hash 在修改前后所属结点的 key
+func remapPoints(beforePoints, afterPoints []uint32, beforeOwner, afterOwner func(uint32) string) ([]RangeMove, float64) {
+	// 两个环的虚拟结点把 hash 空间分成若干个区间，每个区间在两个环中各只有一个归属
+	bounds := mergePoints(beforePoints, afterPoints)
+	var moves []RangeMove
+	var width float64
+	start := uint64(0)
+	for i := 0; i <= len(bounds); i++ {
+		end := uint64(math.MaxUint32)
+		if i < len(bounds) {
+			end = uint64(bounds[i])
+		}
+		if start > end {
+			// 最后一个虚拟结点是 math.MaxUint32
+			break
+		}
+
+		oldOwner := beforeOwner(uint32(start))
+		newOwner := afterOwner(uint32(start))
+		if oldOwner != newOwner {
+			if n := len(moves); n > 0 && moves[n-1].End+1 == uint32(start) &&
+				moves[n-1].OldOwner == oldOwner && moves[n-1].NewOwner == newOwner {
+				moves[n-1].End = uint32(end)
+			} else {
+				moves = append(moves, RangeMove{Start: uint32(start), End: uint32(end), OldOwner: oldOwner, NewOwner: newOwner})
+			}
+			width += float64(end - start + 1)
+		}
+		start = end + 1
+	}
+	return moves, width / (1 << 32)
+}
+
+// ChurnRate 返回 sampleKeys 中在 before 和 after 两个环上归属不同的 key 的比例，只比较结点的 key，
+// 两个环可以使用不同的 hash
+func ChurnRate(before, after *ConsistentHash, sampleKeys []string) float64 {
+	if len(sampleKeys) == 0 || before == after {
+		return 0
+	}
+	unlock := rlockBoth(before, after)
+	defer unlock()
+
+	moved := 0
+	for _, key := range sampleKeys {
+		if before.ownerOf(before.hashKey(key)) != after.ownerOf(after.hashKey(key)) {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(sampleKeys))
+}
+
+// SampleChurnRate 与 ChurnRate 相同，使用 n 个随机 key
+func SampleChurnRate(before, after *ConsistentHash, n int) float64 {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = strconv.FormatUint(rand.Uint64(), 36)
+	}
+	return ChurnRate(before, after, keys)
+}
+
+// 按地址顺序对两个环加读锁，避免与写锁交错时死锁
+func rlockBoth(a, b *ConsistentHash) (unlock func()) {
+	if a == b {
+		a.RLock()
+		return a.RUnlock
+	}
+	if uintptr(unsafe.Pointer(a)) > uintptr(unsafe.Pointer(b)) {
+		a, b = b, a
+	}
+	a.RLock()
+	b.RLock()
+	return func() {
+		b.RUnlock()
+		a.RUnlock()
+	}
+}
+
+// 合并两个有序数组并去重
+func mergePoints(a, b []uint32) []uint32 {
+	merged := make([]uint32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		var v uint32
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			v = a[i]
+			i++
+		case i == len(a) || b[j] < a[i]:
+			v = b[j]
+			j++
+		default:
+			v = a[i]
+			i++
+			j++
+		}
+		merged = append(merged, v)
+	}
+	return merged
+}
@@ -0,0 +1,168 @@
+package consistent_hash
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_RemapOnAdd(t *testing.T) {
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 100, "b#0#0": 200, "c#0#0": 150, "d#0#0": 4000000000,
+	}))
+
+	// 第一个结点接管全部 hash 空间
+	moves, fraction := c.RemapOnAdd(testNode{"a"}, 1)
+	if want := []RangeMove{{0, math.MaxUint32, "", "a"}}; !reflect.DeepEqual(moves, want) || fraction != 1 {
+		t.Fatalf("first node: %v %v", moves, fraction)
+	}
+	if c.Len() != 0 {
+		t.Fatal("RemapOnAdd changed the ring")
+	}
+
+	if err := c.AddKey("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddKey("b", 1); err != nil {
+		t.Fatal(err)
+	}
+	moves, fraction = c.RemapOnAdd(testNode{"c"}, 1)
+	if want := []RangeMove{{101, 150, "b", "c"}}; !reflect.DeepEqual(moves, want) || fraction != 50.0/(1<<32) {
+		t.Fatalf("middle node: %v %v", moves, fraction)
+	}
+
+	// 超过最后一个虚拟结点的 hash 回到 a
+	moves, _ = c.RemapOnAdd(testNode{"d"}, 1)
+	if want := []RangeMove{{201, 4000000000, "a", "d"}}; !reflect.DeepEqual(moves, want) {
+		t.Fatalf("top node: %v", moves)
+	}
+
+	if moves, fraction := c.RemapOnAdd(testNode{"a"}, 1); moves != nil || fraction != 0 {
+		t.Fatalf("existing node: %v %v", moves, fraction)
+	}
+}
+
+func TestConsistentHash_RemapOnRemove(t *testing.T) {
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 100, "b#0#0": 200,
+	}))
+	if err := c.AddKey("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddKey("b", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// a 的区间跨越环的起点，拆分为两段
+	moves, fraction := c.RemapOnRemove("a")
+	want := []RangeMove{{0, 100, "a", "b"}, {201, math.MaxUint32, "a", "b"}}
+	if !reflect.DeepEqual(moves, want) || fraction != float64(1<<32-100)/(1<<32) {
+		t.Fatalf("wrap node: %v %v", moves, fraction)
+	}
+	moves, _ = c.RemapOnRemove("b")
+	if want := []RangeMove{{101, 200, "b", "a"}}; !reflect.DeepEqual(moves, want) {
+		t.Fatalf("middle node: %v", moves)
+	}
+	if moves, _ := c.RemapOnRemove("x"); moves != nil {
+		t.Fatalf("unknown node: %v", moves)
+	}
+
+	if err := c.RemoveKey("b"); err != nil {
+		t.Fatal(err)
+	}
+	moves, fraction = c.RemapOnRemove("a")
+	if want := []RangeMove{{0, math.MaxUint32, "a", ""}}; !reflect.DeepEqual(moves, want) || fraction != 1 {
+		t.Fatalf("last node: %v %v", moves, fraction)
+	}
+}
+
+func TestConsistentHash_RemapSampled(t *testing.T) {
+	samples := 1000000
+	if testing.Short() {
+		samples = 100000
+	}
+	rnd := rand.New(rand.NewSource(1))
+	keys := make([]string, samples)
+	for i := range keys {
+		keys[i] = strconv.FormatUint(rnd.Uint64(), 36)
+	}
+
+	c := NewConsistentHashXX()
+	if err := c.AddAll(batchTestNodes("node-", 10), 100); err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, moves []RangeMove, fraction float64, change func(c *ConsistentHash) error) {
+		t.Helper()
+		after := c.Clone()
+		if err := change(after); err != nil {
+			t.Fatal(err)
+		}
+		moved := 0
+		for _, key := range keys {
+			oldOwner, _ := c.GetKey(key)
+			newOwner, _ := after.GetKey(key)
+			if oldOwner == newOwner {
+				continue
+			}
+			moved++
+			h := c.hashKey(key)
+			i := sort.Search(len(moves), func(i int) bool { return moves[i].End >= h })
+			if i == len(moves) || moves[i].Start > h || moves[i].OldOwner != oldOwner || moves[i].NewOwner != newOwner {
+				t.Fatalf("%s: key %s moved %s -> %s outside the reported ranges", name, key, oldOwner, newOwner)
+			}
+		}
+		if got := float64(moved) / float64(len(keys)); math.Abs(got-fraction) > 0.005 {
+			t.Fatalf("%s: sampled %.4f of keys moved, reported %.4f", name, got, fraction)
+		}
+	}
+
+	moves, fraction := c.RemapOnAdd(testNode{"new"}, 100)
+	check("add", moves, fraction, func(c *ConsistentHash) error { return c.AddWithVirtualNode(testNode{"new"}, 100) })
+	moves, fraction = c.RemapOnRemove("node-3")
+	check("remove", moves, fraction, func(c *ConsistentHash) error { _, err := c.RemoveByKey("node-3"); return err })
+}
+
+func TestChurnRate(t *testing.T) {
+	before := NewConsistentHashXX()
+	if err := before.AddAll(batchTestNodes("node-", 10), 200); err != nil {
+		t.Fatal(err)
+	}
+	after := before.Clone()
+	if err := after.AddWithVirtualNode(testNode{"node-10"}, 200); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, 100000)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	// 一致性哈希只迁移新结点接管的约 1/11 的 key
+	if churn := ChurnRate(before, after, keys); math.Abs(churn-1.0/11) > 0.02 {
+		t.Fatalf("ChurnRate = %.4f, want about %.4f", churn, 1.0/11)
+	}
+	if churn := SampleChurnRate(before, after, 100000); math.Abs(churn-1.0/11) > 0.02 {
+		t.Fatalf("SampleChurnRate = %.4f, want about %.4f", churn, 1.0/11)
+	}
+
+	if churn := ChurnRate(before, before, keys); churn != 0 {
+		t.Fatalf("ChurnRate with the same ring = %v", churn)
+	}
+	if churn := ChurnRate(before, after, nil); churn != 0 {
+		t.Fatalf("ChurnRate without keys = %v", churn)
+	}
+	if churn := ChurnRate(before, NewConsistentHash(), keys); churn != 1 {
+		t.Fatalf("ChurnRate to an empty ring = %v", churn)
+	}
+
+	// 只比较归属，hash 不同的两个环也可以比较
+	crc := NewConsistentHash()
+	if err := crc.AddAll(batchTestNodes("node-", 10), 200); err != nil {
+		t.Fatal(err)
+	}
+	if churn := ChurnRate(before, crc, keys); churn < 0.5 {
+		t.Fatalf("ChurnRate between different hashes = %.4f", churn)
+	}
+}
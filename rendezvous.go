@@ -0,0 +1,222 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"hash/crc32"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Rendezvous 实现最高随机权重 (HRW) 哈希：对每个结点计算 hash(nodeKey, key)，
+// 分数最高的结点即为 key 的归属。不需要虚拟结点，查找开销与结点数成正比。
+type Rendezvous struct {
+	nodes map[string]Node
+	// AddWeighted、SetWeight 设置的权重，为空时所有结点权重相同
+	weights map[string]float64
+	sync.RWMutex
+	hash func(string) uint32
+}
+
+func NewRendezvous() *Rendezvous {
+	return NewRendezvousWithCustomHash(func(key string) uint32 {
+		return crc32.ChecksumIEEE([]byte(key))
+	})
+}
+
+// NewRendezvousWithCustomHash 使用自定义 hash 创建，h 为 nil 时使用默认的 crc32
+func NewRendezvousWithCustomHash(h func(key string) uint32) *Rendezvous {
+	if h == nil {
+		return NewRendezvous()
+	}
+	return &Rendezvous{nodes: map[string]Node{}, hash: h}
+}
+
+func (r *Rendezvous) Add(node Node) error {
+	if node == nil {
+		return ErrNilNode
+	}
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.nodes[node.Key()]; ok {
+		return newNodeError(node.Key(), ErrNodeExists)
+	}
+	r.nodes[node.Key()] = node
+	return nil
+}
+
+func (r *Rendezvous) Remove(key string) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.nodes[key]; !ok {
+		return newNodeError(key, ErrNodeNotFound)
+	}
+	delete(r.nodes, key)
+	delete(r.weights, key)
+	return nil
+}
+
+func (r *Rendezvous) Len() int {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.nodes)
+}
+
+func (r *Rendezvous) GetNode(key string) (Node, error) {
+	r.RLock()
+	defer r.RUnlock()
+	if len(r.nodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+
+	if len(r.weights) > 0 {
+		var best Node
+		var bestScore float64
+		for k, n := range r.nodes {
+			s := r.weightedScore(k, key)
+			if best == nil || s > bestScore || (s == bestScore && k < best.Key()) {
+				best, bestScore = n, s
+			}
+		}
+		return best, nil
+	}
+
+	var best Node
+	var bestScore uint32
+	for k, n := range r.nodes {
+		s := r.score(k, key)
+		if best == nil || s > bestScore || (s == bestScore && k < best.Key()) {
+			best, bestScore = n, s
+		}
+	}
+	return best, nil
+}
+
+// GetN 返回分数最高的 n 个结点，按分数从高到低排列，结点不足 n 个时返回全部结点
+func (r *Rendezvous) GetN(key string, n int) ([]Node, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%w %d, can't less 1", ErrInvalidCount, n)
+	}
+	r.RLock()
+	defer r.RUnlock()
+	if len(r.nodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+
+	type scored struct {
+		node  Node
+		score float64
+	}
+	all := make([]scored, 0, len(r.nodes))
+	for k, node := range r.nodes {
+		s := float64(r.score(k, key))
+		if len(r.weights) > 0 {
+			s = r.weightedScore(k, key)
+		}
+		all = append(all, scored{node: node, score: s})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].score != all[j].score {
+			return all[i].score > all[j].score
+		}
+		return all[i].node.Key() < all[j].node.Key()
+	})
+
+	if n > len(all) {
+		n = len(all)
+	}
+	nodes := make([]Node, n)
+	for i := range nodes {
+		nodes[i] = all[i].node
+	}
+	return nodes, nil
+}
+
+func (r *Rendezvous) score(nodeKey, key string) uint32 {
+	// crc32 是线性的，再做一次 murmur3 的 fmix32 打散
+	return fmix32(r.hash(nodeKey + key))
+}
+
+// AddWeighted 添加权重为 weight 的结点，key 落到结点的概率与权重成正比。
+// 没有设置权重的结点权重为 1，所有结点权重都为 1 时与 Add 的结果相同
+func (r *Rendezvous) AddWeighted(node Node, weight float64) error {
+	if node == nil {
+		return ErrNilNode
+	}
+	if err := checkWeight(node.Key(), weight); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.nodes[node.Key()]; ok {
+		return newNodeError(node.Key(), ErrNodeExists)
+	}
+	r.nodes[node.Key()] = node
+	r.setWeight(node.Key(), weight)
+	return nil
+}
+
+// SetWeight 修改结点的权重，只有该结点与其他结点之间的 key 会迁移，其他结点之间的归属不变
+func (r *Rendezvous) SetWeight(key string, weight float64) error {
+	if err := checkWeight(key, weight); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.nodes[key]; !ok {
+		return newNodeError(key, ErrNodeNotFound)
+	}
+	r.setWeight(key, weight)
+	return nil
+}
+
+// Weight 返回结点的权重
+func (r *Rendezvous) Weight(key string) (float64, error) {
+	r.RLock()
+	defer r.RUnlock()
+	if _, ok := r.nodes[key]; !ok {
+		return 0, newNodeError(key, ErrNodeNotFound)
+	}
+	return r.weight(key), nil
+}
+
+func checkWeight(key string, weight float64) error {
+	if !(weight > 0) || math.IsInf(weight, 1) {
+		return newNodeError(key, fmt.Errorf("%w, got %v", ErrInvalidWeight, weight))
+	}
+	return nil
+}
+
+// 调用方需持有写锁
+func (r *Rendezvous) setWeight(key string, weight float64) {
+	if r.weights == nil {
+		r.weights = map[string]float64{}
+	}
+	r.weights[key] = weight
+}
+
+// 调用方需持有锁
+func (r *Rendezvous) weight(key string) float64 {
+	if w, ok := r.weights[key]; ok {
+		return w
+	}
+	return 1
+}
+
+// 加权 HRW 的分数 -w/ln(u)，u 为 (0, 1) 内均匀分布的 64 位 hash。
+// u 的高 32 位为 score，所有权重相同时排序与 score 一致
+func (r *Rendezvous) weightedScore(nodeKey, key string) float64 {
+	h := uint64(r.score(nodeKey, key))<<32 | uint64(fmix32(r.hash(key+"#"+nodeKey)))
+	u := (float64(h>>11) + 0.5) / (1 << 53)
+	return -r.weight(nodeKey) / math.Log(u)
+}
+
+// murmur3 的 fmix32
+func fmix32(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,225 @@
+package consistent_hash
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestRendezvous_Remove(t *testing.T) {
+	r := NewRendezvous()
+	if _, err := r.GetNode("key"); err == nil {
+		t.Fatal("GetNode on empty Rendezvous should fail")
+	}
+	for i := 0; i < 10; i++ {
+		if err := r.Add(testNode{"node-" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Add(testNode{"node-0"}); err == nil {
+		t.Fatal("add duplicate node should fail")
+	}
+
+	keys := make([]string, 50000)
+	before := make([]string, len(keys))
+	counts := map[string]int{}
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+		n, err := r.GetNode(keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[i] = n.Key()
+		counts[n.Key()]++
+	}
+	for k, v := range counts {
+		if v < 4000 || v > 6000 {
+			t.Errorf("node %s owns %d keys, want about 5000", k, v)
+		}
+	}
+
+	if err := r.Remove("node-3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Remove("node-3"); err == nil {
+		t.Fatal("remove unknown node should fail")
+	}
+	for i, k := range keys {
+		n, _ := r.GetNode(k)
+		if before[i] != "node-3" && n.Key() != before[i] {
+			t.Fatalf("key %s moved from %s to %s", k, before[i], n.Key())
+		}
+		if n.Key() == "node-3" {
+			t.Fatalf("key %s still owned by removed node", k)
+		}
+	}
+}
+
+func TestNewRendezvousWithCustomHash(t *testing.T) {
+	// nil 时与 NewRendezvous 相同
+	r, want := NewRendezvousWithCustomHash(nil), NewRendezvous()
+	for _, x := range []*Rendezvous{r, want} {
+		x.Add(testNode{"a"})
+		x.Add(testNode{"b"})
+		x.Add(testNode{"c"})
+	}
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		n, err := r.GetNode(key)
+		if w, _ := want.GetNode(key); err != nil || n != w {
+			t.Fatalf("GetNode(%s) = %v, %v, want %v", key, n, err, w)
+		}
+	}
+}
+
+func TestRendezvous_GetN(t *testing.T) {
+	r := NewRendezvousWithCustomHash(tableHash(nil))
+	for i := 0; i < 5; i++ {
+		if err := r.Add(testNode{"node-" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		nodes, err := r.GetN(key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) != 3 {
+			t.Fatalf("GetN returned %d nodes", len(nodes))
+		}
+		first, _ := r.GetNode(key)
+		if nodes[0].Key() != first.Key() {
+			t.Fatalf("GetN(%s)[0] = %s, GetNode = %s", key, nodes[0].Key(), first.Key())
+		}
+		seen := map[string]bool{}
+		for _, n := range nodes {
+			if seen[n.Key()] {
+				t.Fatalf("GetN returned duplicate node %s", n.Key())
+			}
+			seen[n.Key()] = true
+		}
+	}
+
+	all, err := r.GetN("key", 10)
+	if err != nil || len(all) != 5 {
+		t.Fatalf("GetN(10) = %d nodes, %v", len(all), err)
+	}
+	if _, err := r.GetN("key", 0); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("GetN with n=0 err = %v", err)
+	}
+}
+
+func BenchmarkRendezvous_GetNode(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			r := NewRendezvous()
+			for i := 0; i < size; i++ {
+				r.Add(testNode{"node-" + strconv.Itoa(i)})
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.GetNode("key-" + strconv.Itoa(i))
+			}
+		})
+	}
+}
+
+func TestRendezvous_Weighted(t *testing.T) {
+	r := NewRendezvous()
+	plain := NewRendezvous()
+	for i := 0; i < 5; i++ {
+		n := testNode{"node-" + strconv.Itoa(i)}
+		if err := r.AddWeighted(n, 1); err != nil {
+			t.Fatal(err)
+		}
+		plain.Add(n)
+	}
+	keys := make([]string, 60000)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	// 权重都为 1 时与 Add 的结果相同
+	for _, key := range keys[:5000] {
+		a, _ := r.GetNode(key)
+		b, _ := plain.GetNode(key)
+		if a != b {
+			t.Fatalf("GetNode(%s) = %s with unit weights, %s without", key, a.Key(), b.Key())
+		}
+	}
+
+	if err := r.AddWeighted(testNode{"heavy"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	owners := func() map[string]string {
+		m := make(map[string]string, len(keys))
+		for _, key := range keys {
+			n, _ := r.GetNode(key)
+			m[key] = n.Key()
+		}
+		return m
+	}
+	before := owners()
+	counts := map[string]int{}
+	for _, owner := range before {
+		counts[owner]++
+	}
+	// 总权重为 7，heavy 约为 2/7，其他结点约为 1/7
+	for k, v := range counts {
+		want := len(keys) / 7
+		if k == "heavy" {
+			want *= 2
+		}
+		if v < want*85/100 || v > want*115/100 {
+			t.Errorf("%s owns %d keys, want about %d", k, v, want)
+		}
+	}
+
+	if err := r.SetWeight("node-2", 3); err != nil {
+		t.Fatal(err)
+	}
+	after := owners()
+	moved := 0
+	for _, key := range keys {
+		if before[key] != after[key] {
+			moved++
+			if after[key] != "node-2" {
+				t.Fatalf("%s moved from %s to %s when only node-2 got heavier", key, before[key], after[key])
+			}
+		}
+	}
+	if moved == 0 {
+		t.Fatal("no key moved to node-2")
+	}
+	r.SetWeight("node-2", 0.5)
+	for key, owner := range owners() {
+		if owner != after[key] && after[key] != "node-2" {
+			t.Fatalf("%s moved from %s to %s when only node-2 got lighter", key, after[key], owner)
+		}
+	}
+
+	nodes, _ := r.GetN("key", 3)
+	if n, _ := r.GetNode("key"); nodes[0] != n {
+		t.Fatalf("GetN[0] = %s, GetNode = %s", nodes[0].Key(), n.Key())
+	}
+	if w, err := r.Weight("node-2"); err != nil || w != 0.5 {
+		t.Fatalf("Weight = %v, %v", w, err)
+	}
+	r.Remove("heavy")
+	if _, err := r.Weight("heavy"); err == nil {
+		t.Fatal("removed node has a weight")
+	}
+	for _, w := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if err := r.AddWeighted(testNode{"bad"}, w); !errors.Is(err, ErrInvalidWeight) {
+			t.Errorf("AddWeighted(%v) err = %v", w, err)
+		}
+		if err := r.SetWeight("node-0", w); !errors.Is(err, ErrInvalidWeight) {
+			t.Errorf("SetWeight(%v) err = %v", w, err)
+		}
+	}
+	if err := r.SetWeight("missing", 1); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("SetWeight on unknown node err = %v", err)
+	}
+}
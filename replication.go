@@ -0,0 +1,23 @@
+package consistent_hash
+
+// SetReplicationFactor 设置 GetReplicas 返回的结点数，只影响查询，不会重建环
+func (c *ConsistentHash) SetReplicationFactor(n int) error {
+	c.lockWrite()
+	defer c.unlock()
+	return WithReplicationFactor(n)(c)
+}
+
+func (c *ConsistentHash) ReplicationFactor() int {
+	c.RLock()
+	defer c.RUnlock()
+	if c.replicationFactor == 0 {
+		return 1
+	}
+	return c.replicationFactor
+}
+
+// GetReplicas 按环上的顺序返回 key 的 ReplicationFactor 个不同的结点，第一个即 GetNode 的结果。
+// 在线结点不足时的行为与 GetN 相同
+func (c *ConsistentHash) GetReplicas(key string) ([]Node, error) {
+	return c.GetN(key, c.ReplicationFactor())
+}
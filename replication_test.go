@@ -0,0 +1,66 @@
+package consistent_hash
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_GetReplicas(t *testing.T) {
+	c, err := New(WithReplicationFactor(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddAll(batchTestNodes("node-", 5), 20); err != nil {
+		t.Fatal(err)
+	}
+	if c.ReplicationFactor() != 3 {
+		t.Fatalf("ReplicationFactor = %d", c.ReplicationFactor())
+	}
+	sorted := append([]uint32(nil), c.hashSortedNodes...)
+
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		got, err := c.GetReplicas(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := c.GetN(key, 3)
+		again, _ := c.GetReplicas(key)
+		if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(got, again) {
+			t.Fatalf("GetReplicas(%s) = %v, want %v", key, nodeKeys(got), nodeKeys(want))
+		}
+	}
+
+	if err := c.SetReplicationFactor(1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		got, _ := c.GetReplicas(key)
+		owner, _ := c.GetNode(key)
+		if len(got) != 1 || got[0] != owner {
+			t.Fatalf("GetReplicas(%s) with factor 1 = %v, want %s", key, nodeKeys(got), owner.Key())
+		}
+	}
+
+	// 结点不足
+	if err := c.SetReplicationFactor(10); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.GetReplicas("key"); !errors.Is(err, ErrInsufficientNodes) {
+		t.Fatalf("GetReplicas with factor 10 = %v, %v", nodeKeys(got), err)
+	}
+	if !reflect.DeepEqual(c.hashSortedNodes, sorted) {
+		t.Fatal("SetReplicationFactor changed the ring")
+	}
+
+	if err := c.SetReplicationFactor(0); err == nil {
+		t.Fatal("SetReplicationFactor(0) should fail")
+	}
+	var zero ConsistentHash
+	if zero.ReplicationFactor() != 1 {
+		t.Fatalf("zero value ReplicationFactor = %d", zero.ReplicationFactor())
+	}
+}
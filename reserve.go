@@ -0,0 +1,130 @@
+package consistent_hash
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ReservedError 表示 key 的 hash 落在 ReserveRange 保留的区间中，Tag 为保留时的标签
+type ReservedError struct {
+	Tag  string
+	Hash uint32
+}
+
+func (e *ReservedError) Error() string {
+	return fmt.Sprintf("key hash %d is reserved by %s", e.Hash, e.Tag)
+}
+
+func (e *ReservedError) Is(target error) bool {
+	return target == ErrKeyReserved
+}
+
+// 保留的区间 [start, end]，不跨越环的起点
+type reservedRange struct {
+	start, end uint32
+	tag        string
+}
+
+// ReserveRange 保留 hash 区间 [start, end]，start > end 时区间跨越环的起点。
+// key 的 hash 落在保留区间中时 GetNode、GetN、GetOwnerByHash 等查询返回 *ReservedError，
+// 调用方可以据此把 key 转发到其他系统。PinKey 固定的 key 不受影响。
+// 同一个 tag 可以保留多个区间，与已有的区间重叠时返回 ErrRangeOverlap。
+// OwnershipRanges、OwnershipFraction、Stats 和 Histogram 中结点拥有的 hash 空间不包含保留区间
+func (c *ConsistentHash) ReserveRange(start, end uint32, tag string) error {
+	if tag == "" {
+		return errors.New("reserve tag is empty")
+	}
+	added := []reservedRange{{start: start, end: end, tag: tag}}
+	if start > end {
+		added = []reservedRange{{start: 0, end: end, tag: tag}, {start: start, end: math.MaxUint32, tag: tag}}
+	}
+
+	c.lockWrite()
+	defer c.unlock()
+	for _, r := range added {
+		if conflict, ok := firstReserved(c.reserved, r.start, r.end); ok {
+			return fmt.Errorf("%w: [%d, %d] overlaps [%d, %d] reserved by %s",
+				ErrRangeOverlap, start, end, conflict.start, conflict.end, conflict.tag)
+		}
+	}
+	// 快照与 c 共用切片，只能整体替换
+	reserved := append(append(make([]reservedRange, 0, len(c.reserved)+len(added)), c.reserved...), added...)
+	sort.Slice(reserved, func(i, j int) bool { return reserved[i].start < reserved[j].start })
+	c.reserved = reserved
+	c.version++
+	return nil
+}
+
+// UnreserveRange 取消 tag 保留的全部区间，返回是否存在
+func (c *ConsistentHash) UnreserveRange(tag string) bool {
+	c.lockWrite()
+	defer c.unlock()
+	var reserved []reservedRange
+	for _, r := range c.reserved {
+		if r.tag != tag {
+			reserved = append(reserved, r)
+		}
+	}
+	if len(reserved) == len(c.reserved) {
+		return false
+	}
+	c.reserved = reserved
+	c.version++
+	return true
+}
+
+// 返回包含 h 的保留区间，reserved 按 start 排序且互不重叠
+func reservedAt(reserved []reservedRange, h uint32) (reservedRange, bool) {
+	return firstReserved(reserved, h, h)
+}
+
+// 返回第一个与 [start, end] 相交的保留区间
+func firstReserved(reserved []reservedRange, start, end uint32) (reservedRange, bool) {
+	i := sort.Search(len(reserved), func(i int) bool { return reserved[i].end >= start })
+	if i < len(reserved) && reserved[i].start <= end {
+		return reserved[i], true
+	}
+	return reservedRange{}, false
+}
+
+// h 被保留时返回 *ReservedError
+func checkReserved(reserved []reservedRange, h uint32) error {
+	if len(reserved) == 0 {
+		return nil
+	}
+	if r, ok := reservedAt(reserved, h); ok {
+		return &ReservedError{Tag: r.tag, Hash: h}
+	}
+	return nil
+}
+
+// 把 [start, end] 按保留区间拆分，按顺序对没有保留的部分调用 free，对保留的部分调用 reserved
+func splitReserved(ranges []reservedRange, start, end uint32, free, reserved func(start, end uint32)) {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].end >= start })
+	for ; i < len(ranges) && ranges[i].start <= end; i++ {
+		r := ranges[i]
+		if r.start > start {
+			free(start, r.start-1)
+		} else {
+			r.start = start
+		}
+		if r.end >= end {
+			reserved(r.start, end)
+			return
+		}
+		reserved(r.start, r.end)
+		start = r.end + 1
+	}
+	free(start, end)
+}
+
+// [start, end] 中保留的宽度
+func reservedWidth(ranges []reservedRange, start, end uint32) float64 {
+	var width float64
+	splitReserved(ranges, start, end, func(uint32, uint32) {}, func(s, e uint32) {
+		width += float64(e-s) + 1
+	})
+	return width
+}
@@ -0,0 +1,184 @@
+package consistent_hash
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func checkReservedKey(t *testing.T, c *ConsistentHash, h uint32, tag string) {
+	t.Helper()
+	key := strconv.FormatUint(uint64(h), 10)
+	n, err := c.GetNode(key)
+	if tag == "" {
+		if err != nil {
+			t.Fatalf("GetNode(%s) err = %v", key, err)
+		}
+		return
+	}
+	var reserved *ReservedError
+	if !errors.Is(err, ErrKeyReserved) || !errors.As(err, &reserved) || reserved.Tag != tag || reserved.Hash != h {
+		t.Fatalf("GetNode(%s) = %v, %v, want reserved by %s", key, n, err, tag)
+	}
+}
+
+func TestConsistentHash_ReserveRange(t *testing.T) {
+	// 环: 100(a) 1<<31(b)，数字 key 的 hash 为其本身
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{"a#0#0": 100, "b#0#0": 1 << 31}))
+	c.AddKey("a", 1)
+	c.AddKey("b", 1)
+
+	if err := c.ReserveRange(math.MaxUint32-9, 9, "legacy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ReserveRange(200, 300, "move"); err != nil {
+		t.Fatal(err)
+	}
+	// 两端都包含在内，跨越起点
+	for h, tag := range map[uint32]string{
+		math.MaxUint32 - 10: "", math.MaxUint32 - 9: "legacy", math.MaxUint32: "legacy",
+		0: "legacy", 9: "legacy", 10: "",
+		199: "", 200: "move", 300: "move", 301: "",
+	} {
+		checkReservedKey(t, c, h, tag)
+	}
+	if n, _ := c.GetNode("10"); n.Key() != "a" {
+		t.Fatalf("GetNode(10) = %s", n.Key())
+	}
+
+	// 其他查询方法
+	if _, err := c.GetNodeBytes([]byte("250")); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("GetNodeBytes err = %v", err)
+	}
+	if _, err := c.GetOwnerByHash(0); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("GetOwnerByHash err = %v", err)
+	}
+	if _, err := c.Lookup("250"); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("Lookup err = %v", err)
+	}
+	if _, _, err := c.GetTwo("250"); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("GetTwo err = %v", err)
+	}
+	if _, err := c.GetN("250", 2); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("GetN err = %v", err)
+	}
+	if _, err := c.GetNSpread("250", 2); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("GetNSpread err = %v", err)
+	}
+	if _, err := c.GetNodeExcluding("250", "b"); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("GetNodeExcluding err = %v", err)
+	}
+	if _, err := c.GetNodeWithFallback("250", func(Node) bool { return true }); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("GetNodeWithFallback err = %v", err)
+	}
+	if _, err := c.GetLeast("250"); !errors.Is(err, ErrKeyReserved) {
+		t.Fatalf("GetLeast err = %v", err)
+	}
+	if it := c.Iterate("250"); !errors.Is(it.Err(), ErrKeyReserved) {
+		t.Fatalf("Iterate err = %v", it.Err())
+	} else if n, ok := it.Next(); ok {
+		t.Fatalf("Iterate over a reserved key returned %s", n.Key())
+	}
+
+	// 固定的 key 不受保留区间影响
+	c.PinKey("250", "a")
+	if n, err := c.GetNode("250"); err != nil || n.Key() != "a" {
+		t.Fatalf("GetNode(pinned) = %v, %v", n, err)
+	}
+	if nodes, err := c.GetN("250", 2); err != nil || nodes[0].Key() != "a" {
+		t.Fatalf("GetN(pinned) = %v, %v", nodeKeys(nodes), err)
+	}
+	if nodes, err := c.GetNSpread("250", 2); err != nil || nodes[0].Key() != "a" {
+		t.Fatalf("GetNSpread(pinned) = %v, %v", nodeKeys(nodes), err)
+	}
+	if n, err := c.GetNodeExcluding("250"); err != nil || n.Key() != "a" {
+		t.Fatalf("GetNodeExcluding(pinned) = %v, %v", n, err)
+	}
+	if n, err := c.GetNodeWithFallback("250", func(Node) bool { return true }); err != nil || n.Key() != "a" {
+		t.Fatalf("GetNodeWithFallback(pinned) = %v, %v", n, err)
+	}
+	if n, err := c.GetLeast("250"); err != nil || n.Key() != "a" {
+		t.Fatalf("GetLeast(pinned) = %v, %v", n, err)
+	}
+	if it := c.Iterate("250"); it.Err() != nil {
+		t.Fatalf("Iterate(pinned) err = %v", it.Err())
+	}
+	c.UnpinKey("250")
+
+	for _, r := range [][2]uint32{{5, 20}, {300, 400}, {math.MaxUint32, 0}, {100, 99}, {0, math.MaxUint32}} {
+		if err := c.ReserveRange(r[0], r[1], "other"); !errors.Is(err, ErrRangeOverlap) {
+			t.Fatalf("ReserveRange(%d, %d) err = %v", r[0], r[1], err)
+		}
+	}
+	if err := c.ReserveRange(1, 2, ""); err == nil {
+		t.Fatal("empty tag accepted")
+	}
+
+	version := c.Version()
+	if c.UnreserveRange("missing") || c.Version() != version {
+		t.Fatal("UnreserveRange of a missing tag changed the ring")
+	}
+	if !c.UnreserveRange("legacy") || c.Version() == version {
+		t.Fatal("UnreserveRange failed")
+	}
+	for h, tag := range map[uint32]string{math.MaxUint32: "", 0: "", 9: "", 250: "move"} {
+		checkReservedKey(t, c, h, tag)
+	}
+	if n, _ := c.GetNode("0"); n.Key() != "a" {
+		t.Fatalf("GetNode(0) after UnreserveRange = %s", n.Key())
+	}
+	// 取消的区间可以重新保留
+	if err := c.ReserveRange(math.MaxUint32, 0, "wrap"); err != nil {
+		t.Fatal(err)
+	}
+	checkReservedKey(t, c, 0, "wrap")
+
+	clone := c.Clone()
+	c.Reset()
+	checkReservedKey(t, clone, 250, "move")
+	c.AddKey("a", 1)
+	checkReservedKey(t, c, 250, "")
+}
+
+func TestConsistentHash_ReserveRangeOwnership(t *testing.T) {
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{"a#0#0": 100, "b#0#0": 1 << 31}))
+	c.AddKey("a", 1)
+	c.AddKey("b", 1)
+	c.ReserveRange(math.MaxUint32-9, 9, "legacy")
+	c.ReserveRange(200, 300, "move")
+
+	const half = 1 << 31
+	if got, _ := c.OwnershipRanges("a"); !reflect.DeepEqual(got, []Range{{10, 100}, {half + 1, math.MaxUint32 - 10}}) {
+		t.Fatalf("OwnershipRanges(a) = %v", got)
+	}
+	if got, _ := c.OwnershipRangesCoalesced("b"); !reflect.DeepEqual(got, []Range{{101, 199}, {301, half}}) {
+		t.Fatalf("OwnershipRanges(b) = %v", got)
+	}
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-12 }
+	if got := c.OwnershipFraction("a"); !near(got, (half+80.0)/(1<<32)) {
+		t.Fatalf("OwnershipFraction(a) = %v", got)
+	}
+	stats := c.Stats()
+	if !near(stats.Reserved, 121.0/(1<<32)) || !near(stats.Shares["a"]+stats.Shares["b"]+stats.Reserved, 1) {
+		t.Fatalf("Stats = %+v", stats)
+	}
+
+	hist := c.Histogram(2)
+	want := []BucketOwnership{
+		{0, half - 1, map[string]float64{"a": 91.0 / half, "b": (half - 202.0) / half}, 111.0 / half},
+		{half, math.MaxUint32, map[string]float64{"a": (half - 11.0) / half, "b": 1.0 / half}, 10.0 / half},
+	}
+	for i, b := range hist {
+		w := want[i]
+		if b.Start != w.Start || b.End != w.End || !near(b.Reserved, w.Reserved) || len(b.Owners) != len(w.Owners) {
+			t.Fatalf("Histogram(2)[%d] = %+v, want %+v", i, b, w)
+		}
+		for k, v := range w.Owners {
+			if !near(b.Owners[k], v) {
+				t.Fatalf("Histogram(2)[%d] = %+v, want %+v", i, b, w)
+			}
+		}
+	}
+}
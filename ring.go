@@ -0,0 +1,65 @@
+package consistent_hash
+
+import "errors"
+
+// Ring 是 ConsistentHash 的泛型封装，结点为任意类型 T，key 由 keyFn 得到，查找结果不需要类型断言
+type Ring[T any] struct {
+	keyFn func(T) string
+	c     *ConsistentHash
+}
+
+// 把 T 包装为 Node
+type ringNode[T any] struct {
+	key   string
+	value T
+}
+
+func (n ringNode[T]) Key() string {
+	return n.key
+}
+
+// NewRing 创建 Ring，opts 与 New 相同
+func NewRing[T any](keyFn func(T) string, opts ...Option) (*Ring[T], error) {
+	if keyFn == nil {
+		return nil, errors.New("key func is nil")
+	}
+	c, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Ring[T]{keyFn: keyFn, c: c}, nil
+}
+
+// Add 以 keyFn(value) 为 key 添加结点
+func (r *Ring[T]) Add(value T, replicas int) error {
+	return r.c.AddWithVirtualNode(ringNode[T]{key: r.keyFn(value), value: value}, replicas)
+}
+
+// Remove 按 key 删除结点
+func (r *Ring[T]) Remove(key string) error {
+	_, err := r.c.RemoveByKey(key)
+	return err
+}
+
+// Get 返回 key 所属的结点，出错时返回 T 的零值
+func (r *Ring[T]) Get(key string) (T, error) {
+	n, err := r.c.GetNode(key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return n.(ringNode[T]).value, nil
+}
+
+// GetN 与 ConsistentHash.GetN 相同
+func (r *Ring[T]) GetN(key string, n int) ([]T, error) {
+	nodes, err := r.c.GetN(key, n)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]T, len(nodes))
+	for i, node := range nodes {
+		values[i] = node.(ringNode[T]).value
+	}
+	return values, nil
+}
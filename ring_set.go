@@ -0,0 +1,82 @@
+package consistent_hash
+
+import (
+	"sort"
+	"sync"
+)
+
+// RingSet 按名字管理多个互相独立的环，可以并发使用
+type RingSet struct {
+	opts []Option
+
+	mu    sync.RWMutex
+	rings map[string]*ConsistentHash
+}
+
+// NewRingSet 创建 RingSet，opts 应用于它创建的每一个环，在这里校验一次
+func NewRingSet(opts ...Option) (*RingSet, error) {
+	if _, err := New(opts...); err != nil {
+		return nil, err
+	}
+	return &RingSet{
+		opts:  append([]Option(nil), opts...),
+		rings: map[string]*ConsistentHash{},
+	}, nil
+}
+
+// Ring 返回名字为 name 的环，不存在时用 RingSet 的选项创建
+func (s *RingSet) Ring(name string) *ConsistentHash {
+	s.mu.RLock()
+	c, ok := s.rings[name]
+	s.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.rings[name]; ok {
+		return c
+	}
+	// 选项在 NewRingSet 中已经校验过
+	c, _ = New(s.opts...)
+	s.rings[name] = c
+	return c
+}
+
+// AddTo 向名字为 name 的环添加结点，环不存在时先创建
+func (s *RingSet) AddTo(name string, node Node, replicas int) error {
+	return s.Ring(name).AddWithVirtualNode(node, replicas)
+}
+
+// GetNode 在名字为 name 的环上查找 key 所属的结点，环不存在时返回 ErrEmptyRing 且不会创建环
+func (s *RingSet) GetNode(name, key string) (Node, error) {
+	s.mu.RLock()
+	c, ok := s.rings[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrEmptyRing
+	}
+	return c.GetNode(key)
+}
+
+// Delete 删除名字为 name 的环，返回是否存在。已经通过 Ring 取得的环仍然可以使用，但不再属于 RingSet
+func (s *RingSet) Delete(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.rings[name]
+	delete(s.rings, name)
+	return ok
+}
+
+// Names 按字典序返回所有环的名字
+func (s *RingSet) Names() []string {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.rings))
+	for name := range s.rings {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
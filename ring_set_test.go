@@ -0,0 +1,107 @@
+package consistent_hash
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRingSet(t *testing.T) {
+	s, err := NewRingSet(WithSeed(7), WithDefaultReplicas(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTo("sessions", testNode{"a"}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ring("blobs").Add(testNode{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Names(); !reflect.DeepEqual(got, []string{"blobs", "sessions"}) {
+		t.Fatalf("Names = %v", got)
+	}
+
+	want, _ := New(WithSeed(7))
+	want.AddWithVirtualNode(testNode{"b"}, 20)
+	if s.Ring("blobs").Checksum() != want.Checksum() {
+		t.Fatal("RingSet options were not applied to the created ring")
+	}
+
+	if n, err := s.GetNode("sessions", "k"); err != nil || n.Key() != "a" {
+		t.Fatalf("GetNode = %v, %v", n, err)
+	}
+	if _, err := s.GetNode("queues", "k"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("GetNode on a missing ring err = %v", err)
+	}
+	if len(s.Names()) != 2 {
+		t.Fatal("GetNode created a ring")
+	}
+
+	if !s.Delete("sessions") || s.Delete("sessions") {
+		t.Fatal("Delete reported the wrong result")
+	}
+	if _, err := s.GetNode("sessions", "k"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("GetNode after Delete err = %v", err)
+	}
+
+	if _, err := NewRingSet(WithDefaultReplicas(0)); err == nil {
+		t.Fatal("invalid option accepted")
+	}
+}
+
+func TestRingSet_ConcurrentCreate(t *testing.T) {
+	s, _ := NewRingSet()
+	const n = 64
+	rings := make([]*ConsistentHash, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rings[i] = s.Ring("shared")
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range rings {
+		if c != rings[0] {
+			t.Fatal("concurrent Ring calls created different rings")
+		}
+	}
+}
+
+func TestRingSet_DeleteDuringLookup(t *testing.T) {
+	s, _ := NewRingSet()
+	s.AddTo("r", testNode{"a"}, 10)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				n, err := s.GetNode("r", "key")
+				if err != nil && !errors.Is(err, ErrEmptyRing) {
+					t.Error(err)
+					return
+				}
+				if err == nil && n.Key() != "a" {
+					t.Errorf("GetNode = %v", n.Key())
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		s.Delete("r")
+		s.AddTo("r", testNode{"a"}, 10)
+	}
+	close(stop)
+	wg.Wait()
+}
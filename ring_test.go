@@ -0,0 +1,68 @@
+package consistent_hash
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type backend struct {
+	Addr   string
+	Weight int
+}
+
+func TestRing(t *testing.T) {
+	r, err := NewRing(func(b backend) string { return b.Addr })
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Get("k")
+	if !errors.Is(err, ErrEmptyRing) || got != (backend{}) {
+		t.Fatalf("Get on empty ring = %v, %v", got, err)
+	}
+
+	c := NewConsistentHash()
+	for i := 0; i < 5; i++ {
+		b := backend{Addr: "10.0.0." + strconv.Itoa(i), Weight: i}
+		if err := r.Add(b, 50); err != nil {
+			t.Fatal(err)
+		}
+		c.AddWithVirtualNode(StringNode(b.Addr), 50)
+	}
+	if err := r.Add(backend{Addr: "10.0.0.1"}, 50); !errors.Is(err, ErrNodeExists) {
+		t.Fatalf("duplicate Add err = %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		b, err := r.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := c.GetKey(key)
+		if b.Addr != want || b.Addr != "10.0.0."+strconv.Itoa(b.Weight) {
+			t.Fatalf("Get(%q) = %+v, want %s", key, b, want)
+		}
+
+		bs, err := r.GetN(key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantN, _ := c.GetN(key, 3)
+		for j := range bs {
+			if bs[j].Addr != wantN[j].Key() {
+				t.Fatalf("GetN(%q)[%d] = %s, want %s", key, j, bs[j].Addr, wantN[j].Key())
+			}
+		}
+	}
+
+	if err := r.Remove("10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Remove("10.0.0.1"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("second Remove err = %v", err)
+	}
+	if _, err := NewRing[backend](nil); err == nil {
+		t.Fatal("nil key func accepted")
+	}
+}
@@ -0,0 +1,320 @@
+// Package ringpb 是 ring.proto 的 Go 实现，用于与其他语言交换环的状态。
+//
+// 为了不引入 google.golang.org/protobuf 依赖，编解码是手写的，
+// 与 protoc 生成的代码在线路格式上完全兼容，未知字段在解码时跳过。
+package ringpb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+type HashAlgorithm int32
+
+const (
+	HashAlgorithm_HASH_ALGORITHM_UNSPECIFIED HashAlgorithm = 0
+	HashAlgorithm_HASH_ALGORITHM_CRC32_IEEE  HashAlgorithm = 1
+	HashAlgorithm_HASH_ALGORITHM_XXHASH32    HashAlgorithm = 2
+	HashAlgorithm_HASH_ALGORITHM_KETAMA_MD5  HashAlgorithm = 3
+	HashAlgorithm_HASH_ALGORITHM_SIPHASH24   HashAlgorithm = 4
+)
+
+var hashAlgorithmNames = map[HashAlgorithm]string{
+	HashAlgorithm_HASH_ALGORITHM_UNSPECIFIED: "HASH_ALGORITHM_UNSPECIFIED",
+	HashAlgorithm_HASH_ALGORITHM_CRC32_IEEE:  "HASH_ALGORITHM_CRC32_IEEE",
+	HashAlgorithm_HASH_ALGORITHM_XXHASH32:    "HASH_ALGORITHM_XXHASH32",
+	HashAlgorithm_HASH_ALGORITHM_KETAMA_MD5:  "HASH_ALGORITHM_KETAMA_MD5",
+	HashAlgorithm_HASH_ALGORITHM_SIPHASH24:   "HASH_ALGORITHM_SIPHASH24",
+}
+
+func (x HashAlgorithm) String() string {
+	if name, ok := hashAlgorithmNames[x]; ok {
+		return name
+	}
+	return fmt.Sprintf("HashAlgorithm(%d)", int32(x))
+}
+
+// Known 报告 x 是否为 ring.proto 中定义的值
+func (x HashAlgorithm) Known() bool {
+	_, ok := hashAlgorithmNames[x]
+	return ok
+}
+
+type Ring struct {
+	FormatVersion uint32
+	HashAlgorithm HashAlgorithm
+	HashSeed      uint64
+	Nodes         []*Node
+	SortedPoints  []uint32
+	Pins          []*Pin
+	Chains        []*Chain
+}
+
+type Node struct {
+	Key      string
+	Replicas uint32
+	Points   []uint32
+}
+
+type Pin struct {
+	Key     string
+	NodeKey string
+}
+
+type Chain struct {
+	Point    uint32
+	NodeKeys []string
+}
+
+var errTruncated = errors.New("ringpb: truncated message")
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func (r *Ring) Marshal() ([]byte, error) {
+	var buf []byte
+	if r.FormatVersion != 0 {
+		buf = appendVarintField(buf, 1, uint64(r.FormatVersion))
+	}
+	if r.HashAlgorithm != 0 {
+		buf = appendVarintField(buf, 2, uint64(r.HashAlgorithm))
+	}
+	if r.HashSeed != 0 {
+		buf = appendVarintField(buf, 3, r.HashSeed)
+	}
+	for _, n := range r.Nodes {
+		buf = appendBytesField(buf, 4, n.marshal())
+	}
+	if len(r.SortedPoints) > 0 {
+		buf = appendFixed32sField(buf, 5, r.SortedPoints)
+	}
+	for _, p := range r.Pins {
+		buf = appendBytesField(buf, 6, p.marshal())
+	}
+	for _, ch := range r.Chains {
+		buf = appendBytesField(buf, 7, ch.marshal())
+	}
+	return buf, nil
+}
+
+func (r *Ring) Unmarshal(data []byte) error {
+	*r = Ring{}
+	return forEachField(data, func(num int, wire int, v uint64, b []byte) error {
+		switch {
+		case num == 1 && wire == wireVarint:
+			r.FormatVersion = uint32(v)
+		case num == 2 && wire == wireVarint:
+			r.HashAlgorithm = HashAlgorithm(int32(v))
+		case num == 3 && wire == wireVarint:
+			r.HashSeed = v
+		case num == 4 && wire == wireBytes:
+			n := &Node{}
+			if err := n.unmarshal(b); err != nil {
+				return err
+			}
+			r.Nodes = append(r.Nodes, n)
+		case num == 5:
+			points, err := appendFixed32s(r.SortedPoints, wire, v, b)
+			if err != nil {
+				return err
+			}
+			r.SortedPoints = points
+		case num == 6 && wire == wireBytes:
+			p := &Pin{}
+			if err := p.unmarshal(b); err != nil {
+				return err
+			}
+			r.Pins = append(r.Pins, p)
+		case num == 7 && wire == wireBytes:
+			ch := &Chain{}
+			if err := ch.unmarshal(b); err != nil {
+				return err
+			}
+			r.Chains = append(r.Chains, ch)
+		}
+		return nil
+	})
+}
+
+func (n *Node) marshal() []byte {
+	var buf []byte
+	if n.Key != "" {
+		buf = appendBytesField(buf, 1, []byte(n.Key))
+	}
+	if n.Replicas != 0 {
+		buf = appendVarintField(buf, 2, uint64(n.Replicas))
+	}
+	if len(n.Points) > 0 {
+		buf = appendFixed32sField(buf, 3, n.Points)
+	}
+	return buf
+}
+
+func (n *Node) unmarshal(data []byte) error {
+	return forEachField(data, func(num int, wire int, v uint64, b []byte) error {
+		switch {
+		case num == 1 && wire == wireBytes:
+			n.Key = string(b)
+		case num == 2 && wire == wireVarint:
+			n.Replicas = uint32(v)
+		case num == 3:
+			points, err := appendFixed32s(n.Points, wire, v, b)
+			if err != nil {
+				return err
+			}
+			n.Points = points
+		}
+		return nil
+	})
+}
+
+func (p *Pin) marshal() []byte {
+	var buf []byte
+	if p.Key != "" {
+		buf = appendBytesField(buf, 1, []byte(p.Key))
+	}
+	if p.NodeKey != "" {
+		buf = appendBytesField(buf, 2, []byte(p.NodeKey))
+	}
+	return buf
+}
+
+func (p *Pin) unmarshal(data []byte) error {
+	return forEachField(data, func(num int, wire int, v uint64, b []byte) error {
+		switch {
+		case num == 1 && wire == wireBytes:
+			p.Key = string(b)
+		case num == 2 && wire == wireBytes:
+			p.NodeKey = string(b)
+		}
+		return nil
+	})
+}
+
+func (ch *Chain) marshal() []byte {
+	var buf []byte
+	if ch.Point != 0 {
+		buf = appendVarint(buf, 1<<3|wireFixed32)
+		var tmp [4]byte
+		binary.LittleEndian.PutUint32(tmp[:], ch.Point)
+		buf = append(buf, tmp[:]...)
+	}
+	for _, k := range ch.NodeKeys {
+		buf = appendBytesField(buf, 2, []byte(k))
+	}
+	return buf
+}
+
+func (ch *Chain) unmarshal(data []byte) error {
+	return forEachField(data, func(num int, wire int, v uint64, b []byte) error {
+		switch {
+		case num == 1 && wire == wireFixed32:
+			ch.Point = uint32(v)
+		case num == 2 && wire == wireBytes:
+			ch.NodeKeys = append(ch.NodeKeys, string(b))
+		}
+		return nil
+	})
+}
+
+func appendVarintField(buf []byte, num int, v uint64) []byte {
+	buf = appendVarint(buf, uint64(num)<<3|wireVarint)
+	return appendVarint(buf, v)
+}
+
+func appendBytesField(buf []byte, num int, b []byte) []byte {
+	buf = appendVarint(buf, uint64(num)<<3|wireBytes)
+	buf = appendVarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// repeated fixed32 使用 packed 编码
+func appendFixed32sField(buf []byte, num int, points []uint32) []byte {
+	buf = appendVarint(buf, uint64(num)<<3|wireBytes)
+	buf = appendVarint(buf, uint64(4*len(points)))
+	var tmp [4]byte
+	for _, p := range points {
+		binary.LittleEndian.PutUint32(tmp[:], p)
+		buf = append(buf, tmp[:]...)
+	}
+	return buf
+}
+
+// 解码 repeated fixed32，同时接受 packed 和非 packed 编码
+func appendFixed32s(points []uint32, wire int, v uint64, b []byte) ([]uint32, error) {
+	switch wire {
+	case wireFixed32:
+		return append(points, uint32(v)), nil
+	case wireBytes:
+		if len(b)%4 != 0 {
+			return nil, errors.New("ringpb: packed fixed32 length isn't a multiple of 4")
+		}
+		for i := 0; i < len(b); i += 4 {
+			points = append(points, binary.LittleEndian.Uint32(b[i:]))
+		}
+		return points, nil
+	}
+	return nil, fmt.Errorf("ringpb: unexpected wire type %d for fixed32", wire)
+}
+
+func appendVarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+// 依次解析每个字段，varint、fixed32、fixed64 的值在 v 中，length-delimited 的内容在 b 中
+func forEachField(data []byte, f func(num int, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		num, wire := int(tag>>3), int(tag&7)
+		if num == 0 {
+			return errors.New("ringpb: invalid field number 0")
+		}
+
+		var v uint64
+		var b []byte
+		switch wire {
+		case wireVarint:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || l > uint64(len(data)-n) {
+				return errTruncated
+			}
+			b = data[n : n+int(l)]
+			data = data[n+int(l):]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return fmt.Errorf("ringpb: unsupported wire type %d", wire)
+		}
+		if err := f(num, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
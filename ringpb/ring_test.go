@@ -0,0 +1,97 @@
+package ringpb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRing_Marshal(t *testing.T) {
+	r := &Ring{
+		FormatVersion: 1,
+		HashAlgorithm: HashAlgorithm_HASH_ALGORITHM_CRC32_IEEE,
+		Nodes:         []*Node{{Key: "a", Replicas: 1, Points: []uint32{1}}},
+		SortedPoints:  []uint32{1},
+	}
+	data, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与 protoc 生成的代码的输出一致
+	want := []byte{
+		0x08, 0x01,
+		0x10, 0x01,
+		0x22, 0x0b, 0x0a, 0x01, 'a', 0x10, 0x01, 0x1a, 0x04, 0x01, 0x00, 0x00, 0x00,
+		0x2a, 0x04, 0x01, 0x00, 0x00, 0x00,
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("Marshal = % x, want % x", data, want)
+	}
+
+	var got Ring
+	if err := got.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, r) {
+		t.Fatalf("Unmarshal = %+v, want %+v", got, r)
+	}
+}
+
+func TestRing_MarshalPins(t *testing.T) {
+	r := &Ring{Pins: []*Pin{{Key: "k", NodeKey: "a"}, {Key: "", NodeKey: "b"}}}
+	data, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x32, 0x06, 0x0a, 0x01, 'k', 0x12, 0x01, 'a',
+		0x32, 0x03, 0x12, 0x01, 'b',
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("Marshal = % x, want % x", data, want)
+	}
+	var got Ring
+	if err := got.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, r) {
+		t.Fatalf("Unmarshal = %+v, want %+v", got, r)
+	}
+}
+
+func TestRing_UnmarshalCompat(t *testing.T) {
+	data := []byte{
+		// 未知的 varint、fixed64、bytes、fixed32 字段
+		0x30, 0x96, 0x01,
+		0x39, 1, 2, 3, 4, 5, 6, 7, 8,
+		0x42, 0x02, 'x', 'y',
+		0x4d, 1, 2, 3, 4,
+		// 非 packed 的 repeated fixed32
+		0x2d, 0x02, 0x00, 0x00, 0x00,
+		0x2d, 0x03, 0x00, 0x00, 0x00,
+		0x18, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01,
+	}
+	var r Ring
+	if err := r.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.SortedPoints, []uint32{2, 3}) || r.HashSeed != 1<<64-1 {
+		t.Fatalf("Unmarshal = %+v", r)
+	}
+
+	full, _ := (&Ring{Nodes: []*Node{{Key: "a", Points: []uint32{1, 2}}}}).Marshal()
+	for i := 1; i < len(full); i++ {
+		if err := r.Unmarshal(full[:i]); err == nil {
+			t.Fatalf("Unmarshal of %d/%d bytes should fail", i, len(full))
+		}
+	}
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	if !HashAlgorithm_HASH_ALGORITHM_SIPHASH24.Known() || HashAlgorithm(5).Known() {
+		t.Fatal("Known")
+	}
+	if s := HashAlgorithm(5).String(); s != "HashAlgorithm(5)" {
+		t.Fatalf("String = %s", s)
+	}
+}
@@ -0,0 +1,70 @@
+package consistent_hash
+
+import "math/bits"
+
+// SipHash-2-4 (https://www.aumasson.jp/siphash/siphash.pdf)，带密钥的 hash，
+// 不知道密钥时无法构造大量落在同一结点上的 key
+
+func siphash24(k0, k1 uint64, key string) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(key)
+	for ; len(key) >= 8; key = key[8:] {
+		m := sipRead64(key)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+
+	// 最后不足 8 字节的部分，最高字节为长度
+	m := uint64(n) << 56
+	for i := 0; i < len(key); i++ {
+		m |= uint64(key[i]) << (8 * uint(i))
+	}
+	v3 ^= m
+	round()
+	round()
+	v0 ^= m
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+func sipRead64(s string) uint64 {
+	return uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 | uint64(s[3])<<24 |
+		uint64(s[4])<<32 | uint64(s[5])<<40 | uint64(s[6])<<48 | uint64(s[7])<<56
+}
+
+// 以 seed 为密钥的 32 位 hash
+func seededHash(seed uint64) func(string) uint32 {
+	k0, k1 := seed, seed^0x9e3779b97f4a7c15
+	return func(key string) uint32 {
+		h := siphash24(k0, k1, key)
+		return uint32(h ^ h>>32)
+	}
+}
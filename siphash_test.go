@@ -0,0 +1,67 @@
+package consistent_hash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSiphash24(t *testing.T) {
+	// 论文附录中的测试向量：key 为 00..0f
+	const k0, k1 = 0x0706050403020100, 0x0f0e0d0c0b0a0908
+	msg := make([]byte, 15)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	if got := siphash24(k0, k1, string(msg)); got != 0xa129ca6149be45e5 {
+		t.Errorf("siphash24(00..0e) = %#016x, want 0xa129ca6149be45e5", got)
+	}
+	if got := siphash24(k0, k1, ""); got != 0x726fdb47dd0e0e31 {
+		t.Errorf("siphash24(\"\") = %#016x, want 0x726fdb47dd0e0e31", got)
+	}
+}
+
+func seededOwners(t *testing.T, opts ...Option) []string {
+	c, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := c.AddWithVirtualNode(testNode{"node-" + strconv.Itoa(i)}, 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	owners := make([]string, 1000)
+	for i := range owners {
+		n, err := c.GetNode("key-" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[i] = n.Key()
+	}
+	return owners
+}
+
+func TestWithSeed(t *testing.T) {
+	a := seededOwners(t, WithSeed(1))
+	if !equalStrings(a, seededOwners(t, WithSeed(1))) {
+		t.Error("same seed produced different placements")
+	}
+
+	b := seededOwners(t, WithSeed(2))
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	// 10 个结点时随机重合的比例约为 1/10
+	if same > 300 {
+		t.Errorf("%d of %d keys have the same owner across seeds", same, len(a))
+	}
+
+	r1 := seededOwners(t, WithRandomSeed())
+	r2 := seededOwners(t, WithRandomSeed())
+	if equalStrings(r1, r2) {
+		t.Error("random seeds produced identical placements")
+	}
+}
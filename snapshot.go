@@ -0,0 +1,253 @@
+package consistent_hash
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// 查询路径使用的只读快照，写操作在写锁内生成新的快照替换，GetNode 不加锁也不查 map
+type ringSnapshot struct {
+	points []uint32
+	// 与 points 一一对应的结点，同一结点的虚拟结点共享一个 snapshotNode
+	owners []*snapshotNode
+	// CollisionChain 下被多个结点共用的虚拟结点的其余结点，以在 points 中的下标为 key，没有时为 nil
+	chains map[int][]*snapshotNode
+	// 按 key 排序的全部物理结点
+	members []*snapshotNode
+	// PinKey 固定的 key 到结点，只包含环上的结点，没有时为 nil
+	pins map[string]*snapshotNode
+	// ReserveRange 保留的区间，与环共用
+	reserved  []reservedRange
+	nodeCount int
+	// 延迟删除的结点留在 points 中的虚拟结点数，见 WithLazyRemoval
+	tombstones int
+	version    uint64
+
+	hash         func(string) uint32
+	bytesHash    func([]byte) uint32
+	loadTracking bool
+	metrics      Metrics
+	// 为 nil 时不缓存，见 WithLookupCache
+	cache *lookupCache
+}
+
+type snapshotNode struct {
+	node Node
+	key  string
+	down bool
+	hits *uint64
+	// 延迟删除后置为 1，原子访问，同一快照的读者都能看到
+	removed uint32
+}
+
+// 在线且没有被删除
+func (n *snapshotNode) live() bool {
+	return !n.down && atomic.LoadUint32(&n.removed) == 0
+}
+
+// 环的版本变化之后重新生成快照，调用方需持有写锁
+func (c *ConsistentHash) publish() {
+	if s := c.loadSnapshot(); s != nil && s.version == c.version {
+		return
+	}
+	s := &ringSnapshot{
+		points:       append([]uint32(nil), c.hashSortedNodes...),
+		owners:       make([]*snapshotNode, len(c.hashSortedNodes)),
+		nodeCount:    len(c.nodes),
+		version:      c.version,
+		reserved:     c.reserved,
+		loadTracking: c.loadTracking,
+		metrics:      c.metrics,
+	}
+	if c.lookupCacheSize > 0 {
+		s.cache = newLookupCache(c.lookupCacheSize)
+	}
+	switch {
+	case c.ketama && c.twemproxyHash == "":
+		s.hash = ketamaHash
+	case c.hash != nil:
+		s.hash = c.hash
+	default:
+		s.hash = defaultHash
+	}
+	s.bytesHash = c.bytesHashFunc()
+	nodes := make(map[string]*snapshotNode, len(c.nodes))
+	s.members = make([]*snapshotNode, 0, len(c.nodes))
+	for k, n := range c.nodes {
+		nodes[k] = &snapshotNode{node: n.node, key: k, down: n.down, hits: n.hits}
+		s.members = append(s.members, nodes[k])
+	}
+	sort.Slice(s.members, func(i, j int) bool { return s.members[i].key < s.members[j].key })
+	for i, p := range s.points {
+		s.owners[i] = nodes[c.circle[p]]
+	}
+	for key, nodeKey := range c.pins {
+		if n, ok := nodes[nodeKey]; ok {
+			if s.pins == nil {
+				s.pins = make(map[string]*snapshotNode, len(c.pins))
+			}
+			s.pins[key] = n
+		}
+	}
+	if len(c.chains) > 0 {
+		s.chains = make(map[int][]*snapshotNode, len(c.chains))
+		for i, p := range s.points {
+			owners, ok := c.chains[p]
+			if !ok {
+				continue
+			}
+			rest := make([]*snapshotNode, len(owners)-1)
+			for j, owner := range owners[1:] {
+				rest[j] = nodes[owner]
+			}
+			s.chains[i] = rest
+		}
+	}
+	c.snapshot.Store(s)
+}
+
+// 还没有发布过快照时返回 nil，相当于空环
+func (c *ConsistentHash) loadSnapshot() *ringSnapshot {
+	s, _ := c.snapshot.Load().(*ringSnapshot)
+	return s
+}
+
+func (s *ringSnapshot) getNode(key string) (Node, error) {
+	n, err := s.find(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.hit(n), nil
+}
+
+// 返回 key 所属的在线结点，不统计负载
+func (s *ringSnapshot) find(key string) (*snapshotNode, error) {
+	if s == nil || s.cache == nil {
+		return s.locate(key)
+	}
+	// 缓存属于快照，固定的 key、保留区间和结点状态修改后使用新的快照和缓存
+	if n := s.cache.get(key); n != nil {
+		return n, nil
+	}
+	n, err := s.locate(key)
+	if err == nil {
+		s.cache.put(key, n)
+	}
+	return n, err
+}
+
+// 查找规则与 find 相同，但不使用查询缓存，批量查询时缓存只会被冲掉
+func (s *ringSnapshot) locate(key string) (*snapshotNode, error) {
+	if s == nil || s.nodeCount == 0 {
+		return nil, ErrEmptyRing
+	}
+	if n := s.pinned(key); n != nil {
+		return n, nil
+	}
+	return s.locateHash(s.hash(key))
+}
+
+// 与 locate 相同，key 为 []byte，默认的 crc32 和 WithBytesHash 设置的 hash 不需要转换为字符串
+func (s *ringSnapshot) locateBytes(key []byte) (*snapshotNode, error) {
+	if s == nil || s.nodeCount == 0 {
+		return nil, ErrEmptyRing
+	}
+	if s.pins != nil {
+		// 直接用 string(key) 查 map 不分配内存
+		if n := s.pins[string(key)]; n != nil && n.live() {
+			return n, nil
+		}
+	}
+	if s.bytesHash == nil {
+		// 只有字符串参数的 hash，只能转换
+		return s.locateHash(s.hash(string(key)))
+	}
+	return s.locateHash(s.bytesHash(key))
+}
+
+func (s *ringSnapshot) getNodeBytes(key []byte) (Node, error) {
+	n, err := s.locateBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.hit(n), nil
+}
+
+// 从 hash 所在位置顺时针找到第一个在线结点
+func (s *ringSnapshot) owner(hash uint32) (Node, error) {
+	n, err := s.locateHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return s.hit(n), nil
+}
+
+// 与 owner 相同但不统计负载
+func (s *ringSnapshot) locateHash(hash uint32) (*snapshotNode, error) {
+	if err := checkReserved(s.reserved, hash); err != nil {
+		return nil, err
+	}
+	n := s.ownerNode(hash)
+	if n == nil {
+		return nil, ErrNoHealthyNodes
+	}
+	return n, nil
+}
+
+// 没有在线结点时返回 nil
+func (s *ringSnapshot) ownerNode(hash uint32) *snapshotNode {
+	_, n := s.ownerAt(hash)
+	return n
+}
+
+// 返回 hash 所属的在线结点及其虚拟结点在 points 中的下标，没有在线结点时返回 -1 和 nil
+func (s *ringSnapshot) ownerAt(hash uint32) (int, *snapshotNode) {
+	return s.next(s.search(hash), nil)
+}
+
+// 从下标 i 开始顺时针找到第一个不是 skip 的在线结点，i 可以等于 len(points)，越过末尾时回到开头
+func (s *ringSnapshot) next(i int, skip *snapshotNode) (int, *snapshotNode) {
+	for j := 0; j < len(s.points); j++ {
+		k := (i + j) % len(s.points)
+		if n := s.owners[k]; n.live() && n != skip {
+			return k, n
+		}
+		if s.chains != nil {
+			for _, n := range s.chains[k] {
+				if n.live() && n != skip {
+					return k, n
+				}
+			}
+		}
+	}
+	return -1, nil
+}
+
+func (s *ringSnapshot) hit(n *snapshotNode) Node {
+	if s.loadTracking {
+		atomic.AddUint64(n.hits, 1)
+	}
+	return n.node
+}
+
+func (s *ringSnapshot) search(hash uint32) int {
+	return searchPoints(s.points, hash)
+}
+
+// 返回第一个不小于 hash 的下标，都小于 hash 时返回 len(points)，与 sort.Search 的结果相同。
+// 每一轮不调用函数，比较的结果用减法的符号位表示，循环中没有难以预测的分支
+func searchPoints(points []uint32, hash uint32) int {
+	n := len(points)
+	if n == 0 {
+		return 0
+	}
+	base := 0
+	for n > 1 {
+		half := n >> 1
+		// points[base+half-1] < hash 时 less 为 1
+		less := int((uint64(points[base+half-1]) - uint64(hash)) >> 63)
+		base += half & -less
+		n -= half
+	}
+	return base + int((uint64(points[base])-uint64(hash))>>63)
+}
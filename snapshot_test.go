@@ -0,0 +1,286 @@
+package consistent_hash
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 每种修改之后 GetNode 读到的快照都与加锁查询的结果相同
+func TestConsistentHash_SnapshotFollowsMutations(t *testing.T) {
+	c := NewConsistentHash()
+	keys := make([]string, 200)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	check := func(step string) {
+		t.Helper()
+		for _, k := range keys {
+			got, err := c.GetNode(k)
+			c.RLock()
+			want, wantErr := c.lookup(k)
+			c.RUnlock()
+			if err != wantErr || (err == nil && got.Key() != want.Key()) {
+				t.Fatalf("%s: GetNode(%s) = %v, %v, want %v, %v", step, k, got, err, want, wantErr)
+			}
+		}
+	}
+
+	check("empty")
+	steps := []struct {
+		name string
+		op   func() error
+	}{
+		{"add", func() error { return c.AddWithVirtualNode(testNode{"a"}, 10) }},
+		{"add all", func() error { return c.AddAll(batchTestNodes("node-", 3), 10) }},
+		{"mark down", func() error { return c.MarkDown("a") }},
+		{"grow", func() error { return c.GrowVirtualNodes("node-0", 10) }},
+		{"ramp", func() error { return c.AddWithRamp(testNode{"r"}, 20) }},
+		{"set count", func() error { return c.SetVirtualNodeCount("node-1", 2) }},
+		{"batch", func() error { return c.Batch().Remove("node-2").Add(testNode{"b"}, 5).Commit() }},
+		{"sync", func() error { _, _, err := c.Sync([]Node{testNode{"a"}, testNode{"b"}}, 5); return err }},
+		{"mark up", func() error { return c.MarkUp("a") }},
+		{"remove", func() error { _, err := c.RemoveByKey("b"); return err }},
+		{"unmarshal", func() error {
+			data, _ := c.Clone().MarshalBinary()
+			c.Reset()
+			return c.UnmarshalBinary(data)
+		}},
+		{"reset", func() error { c.Reset(); return nil }},
+	}
+	for _, s := range steps {
+		if err := s.op(); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		check(s.name)
+	}
+
+	c.AddWithVirtualNode(testNode{"a"}, 10)
+	c.MarkDown("a")
+	if _, err := c.GetNode("k"); err != ErrNoHealthyNodes {
+		t.Fatalf("all down: err = %v", err)
+	}
+	clone := c.Clone()
+	clone.MarkUp("a")
+	if n, err := clone.GetNode("k"); err != nil || n.Key() != "a" {
+		t.Fatalf("clone GetNode = %v, %v", n, err)
+	}
+}
+
+// 写操作停在暂存的副本上时，GetNode 仍然可以完成，读到的是修改之前的环
+func TestConsistentHash_GetNodeDuringStagedWrite(t *testing.T) {
+	c := NewConsistentHash()
+	a, b := batchTestNodes("a-", 500), batchTestNodes("b-", 500)
+	if _, _, err := c.Sync(a, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	// 与 Sync 相同地在副本上替换全部结点，在 fn 中等待 release
+	entered, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- c.update(len(b), len(b)*20, func(staged *ConsistentHash) error {
+			close(entered)
+			<-release
+			staged.removeNodes(nodeKeys(a))
+			for _, node := range b {
+				if err := staged.addNode(node, 20); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}()
+	<-entered
+
+	const lookups = 1000
+	completed := make(chan int, 1)
+	go func() {
+		n := 0
+		for i := 0; i < lookups; i++ {
+			node, err := c.GetNode(strconv.Itoa(i))
+			if err != nil || !strings.HasPrefix(node.Key(), "a-") {
+				t.Errorf("GetNode during staged write = %v, %v", node, err)
+				break
+			}
+			n++
+		}
+		completed <- n
+	}()
+	select {
+	case n := <-completed:
+		if n != lookups {
+			t.Fatalf("%d of %d lookups completed while the write was staged", n, lookups)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("GetNode blocked while a write was staged")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if node, err := c.GetNode("k"); err != nil || !strings.HasPrefix(node.Key(), "b-") {
+		t.Fatalf("GetNode after the write = %v, %v", node, err)
+	}
+}
+
+// 多个 goroutine 并发 GetNode，同时后台不断添加、删除结点
+func benchmarkGetNodeWithWriter(b *testing.B, readers int) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 100), 100); err != nil {
+		b.Fatal(err)
+	}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+
+	stop := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		node := testNode{"churn"}
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			c.AddWithVirtualNode(node, 100)
+			c.Remove(node)
+		}
+	}()
+
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := r; i < b.N; i += readers {
+				if _, err := c.GetNode(keys[i%len(keys)]); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+	b.StopTimer()
+	close(stop)
+	<-writerDone
+}
+
+func BenchmarkConsistentHash_GetNodeWithWriter1(b *testing.B) {
+	benchmarkGetNodeWithWriter(b, 1)
+}
+
+func BenchmarkConsistentHash_GetNodeWithWriter8(b *testing.B) {
+	benchmarkGetNodeWithWriter(b, 8)
+}
+
+func BenchmarkConsistentHash_GetNodeWithWriter64(b *testing.B) {
+	benchmarkGetNodeWithWriter(b, 64)
+}
+
+func BenchmarkConsistentHash_GetNode(b *testing.B) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 100), 100); err != nil {
+		b.Fatal(err)
+	}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetNode(keys[i%len(keys)])
+	}
+}
+
+func TestConsistentHash_GetNodeAllocs(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 10), 50); err != nil {
+		t.Fatal(err)
+	}
+	c.MarkDown("node-3")
+	if allocs := testing.AllocsPerRun(100, func() { c.GetNode("some-key") }); allocs != 0 {
+		t.Errorf("GetNode allocs = %v", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { c.GetNodeVersioned("some-key") }); allocs != 0 {
+		t.Errorf("GetNodeVersioned allocs = %v", allocs)
+	}
+}
+
+// 与 sort.Search 对比，包括空切片、重复的值、needle 在两端之外和等于边界的情况
+func TestSearchPoints(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	pairs := 2000000
+	if testing.Short() {
+		pairs = 200000
+	}
+	var points []uint32
+	for i := 0; i < pairs; i++ {
+		if i%200 == 0 {
+			points = make([]uint32, r.Intn(300))
+			// 值域较小时出现重复的值
+			max := uint32(1 + r.Intn(1000))
+			if r.Intn(2) == 0 {
+				max = math.MaxUint32
+			}
+			for j := range points {
+				points[j] = r.Uint32() % max
+			}
+			sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+		}
+		var needle uint32
+		switch {
+		case len(points) > 0 && i%3 == 0:
+			needle = points[r.Intn(len(points))] + uint32(r.Intn(3)) - 1
+		case i%7 == 0:
+			needle = [...]uint32{0, math.MaxUint32}[r.Intn(2)]
+		default:
+			needle = r.Uint32() % 1200
+			if i%2 == 0 {
+				needle = r.Uint32()
+			}
+		}
+		want := sort.Search(len(points), func(i int) bool { return points[i] >= needle })
+		if got := searchPoints(points, needle); got != want {
+			t.Fatalf("searchPoints(%v, %d) = %d, want %d", points, needle, got, want)
+		}
+	}
+}
+
+func BenchmarkSearchPoints(b *testing.B) {
+	for _, n := range []int{1000, 100000, 2000000} {
+		r := rand.New(rand.NewSource(1))
+		points := make([]uint32, n)
+		for i := range points {
+			points[i] = r.Uint32()
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+		needles := make([]uint32, 4096)
+		for i := range needles {
+			needles[i] = r.Uint32()
+		}
+		b.Run(strconv.Itoa(n)+"/sort.Search", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h := needles[i%len(needles)]
+				sort.Search(len(points), func(i int) bool { return points[i] >= h })
+			}
+		})
+		b.Run(strconv.Itoa(n)+"/searchPoints", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				searchPoints(points, needles[i%len(needles)])
+			}
+		})
+	}
+}
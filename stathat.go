@@ -0,0 +1,23 @@
+package consistent_hash
+
+import "strconv"
+
+// stathat/consistent 的 NumberOfReplicas 默认值
+const stathatDefaultReplicas = 20
+
+// WithStathatCompatibility 使查询结果与 stathat.com/c/consistent 完全一致，迁移时 key 的归属不变：
+// 第 i 个副本为 crc32(strconv.Itoa(i) + key)，Add 默认 20 个副本，冲突时后添加的结点覆盖该虚拟结点，
+// key 属于第一个 hash 严格大于它的虚拟结点。为此虚拟结点的位置比 stathat 中的小 1，
+// 查找第一个不小于 key hash 的虚拟结点时结果相同，OwnershipRanges 等返回的也是移动后的位置。
+// 与 stathat 的区别：删除被覆盖的结点时不会删除覆盖它的结点的虚拟结点
+func WithStathatCompatibility() Option {
+	return func(c *ConsistentHash) error {
+		c.collision = CollisionOverwrite
+		c.defaultReplicas = stathatDefaultReplicas
+		c.virtualKeyFunc = func(nodeKey string, replica int) string {
+			return strconv.Itoa(replica) + nodeKey
+		}
+		c.stathat = true
+		return nil
+	}
+}
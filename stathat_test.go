@@ -0,0 +1,98 @@
+package consistent_hash
+
+import "testing"
+
+// 由 stathat.com/c/consistent 的 add 和 Get 逐行翻译的 Python 程序生成 (zlib.crc32 和 bisect_right)，
+// 不是 stathat 本身的输出，只能发现与翻译不一致的错误。testdata/stathat_vectors.go 用 stathat 本身输出每个 key 的结点，
+// 可以用它核对或重新生成向量。每组最后三个 key 的 hash 恰好等于某个虚拟结点，stathat 把它们分给下一个虚拟结点
+var stathatVectors = []struct {
+	members []string
+	want    map[string]string
+}{
+	{[]string{"cacheA", "cacheB", "cacheC"}, map[string]string{
+		"user:0":        "cacheC",
+		"user:1":        "cacheA",
+		"user:2":        "cacheC",
+		"user:3":        "cacheA",
+		"user:4":        "cacheC",
+		"user:5":        "cacheA",
+		"user:6":        "cacheB",
+		"user:7":        "cacheB",
+		"user:8":        "cacheB",
+		"user:9":        "cacheA",
+		"user:10":       "cacheA",
+		"user:11":       "cacheB",
+		"user:12":       "cacheA",
+		"user:13":       "cacheB",
+		"user:14":       "cacheC",
+		"session-0":     "cacheC",
+		"session-7919":  "cacheA",
+		"session-15838": "cacheA",
+		"session-23757": "cacheA",
+		"session-31676": "cacheC",
+		"session-39595": "cacheA",
+		"session-47514": "cacheA",
+		"session-55433": "cacheA",
+		"session-63352": "cacheC",
+		"session-71271": "cacheB",
+		"0cacheA":       "cacheB",
+		"7cacheB":       "cacheC",
+		"19cacheC":      "cacheA",
+	}},
+	{[]string{"10.0.0.1:6379", "10.0.0.2:6379", "10.0.0.3:6379", "10.0.0.4:6379", "10.0.0.5:6379"}, map[string]string{
+		"user:0":          "10.0.0.3:6379",
+		"user:1":          "10.0.0.3:6379",
+		"user:2":          "10.0.0.3:6379",
+		"user:3":          "10.0.0.4:6379",
+		"user:4":          "10.0.0.3:6379",
+		"user:5":          "10.0.0.3:6379",
+		"user:6":          "10.0.0.4:6379",
+		"user:7":          "10.0.0.4:6379",
+		"user:8":          "10.0.0.3:6379",
+		"user:9":          "10.0.0.5:6379",
+		"user:10":         "10.0.0.3:6379",
+		"user:11":         "10.0.0.4:6379",
+		"user:12":         "10.0.0.3:6379",
+		"user:13":         "10.0.0.3:6379",
+		"user:14":         "10.0.0.4:6379",
+		"session-0":       "10.0.0.2:6379",
+		"session-7919":    "10.0.0.5:6379",
+		"session-15838":   "10.0.0.3:6379",
+		"session-23757":   "10.0.0.1:6379",
+		"session-31676":   "10.0.0.4:6379",
+		"session-39595":   "10.0.0.2:6379",
+		"session-47514":   "10.0.0.5:6379",
+		"session-55433":   "10.0.0.1:6379",
+		"session-63352":   "10.0.0.5:6379",
+		"session-71271":   "10.0.0.4:6379",
+		"010.0.0.1:6379":  "10.0.0.2:6379",
+		"710.0.0.2:6379":  "10.0.0.3:6379",
+		"1910.0.0.5:6379": "10.0.0.4:6379",
+	}},
+}
+
+func TestWithStathatCompatibility(t *testing.T) {
+	for _, tt := range stathatVectors {
+		c, err := New(WithStathatCompatibility())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range tt.members {
+			if err := c.Add(StringNode(m)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c.TotalPoints() != stathatDefaultReplicas*len(tt.members) {
+			t.Fatalf("%d points", c.TotalPoints())
+		}
+		for key, want := range tt.want {
+			if n, _ := c.GetNode(key); n.Key() != want {
+				t.Errorf("%v: GetNode(%q) = %s, want %s", tt.members, key, n.Key(), want)
+			}
+			// 按 hash 查询的路径也一样
+			if p, _, _ := c.GetTwo(key); p.Key() != want {
+				t.Errorf("%v: GetTwo(%q) = %s, want %s", tt.members, key, p.Key(), want)
+			}
+		}
+	}
+}
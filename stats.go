@@ -0,0 +1,59 @@
+package consistent_hash
+
+import "math"
+
+// RingStats 描述 hash 空间在结点之间的分布，Share 为结点拥有的 hash 空间比例
+type RingStats struct {
+	Nodes         int
+	VirtualPoints int
+	Shares        map[string]float64
+
+	MinShare  float64
+	MaxShare  float64
+	MeanShare float64
+	StdDev    float64
+	// 最大比例与平均比例之比，没有保留区间时平均比例为 1/Nodes，完全均匀时为 1
+	Imbalance float64
+	// ReserveRange 保留的 hash 空间比例，不属于任何结点
+	Reserved float64
+}
+
+// Stats 根据虚拟结点的位置计算分布统计，不对样本 key 做 hash，复杂度 O(虚拟结点数)
+func (c *ConsistentHash) Stats() RingStats {
+	c.RLock()
+	defer c.RUnlock()
+
+	stats := RingStats{
+		Nodes:         len(c.nodes),
+		VirtualPoints: len(c.hashSortedNodes),
+		Shares:        make(map[string]float64, len(c.nodes)),
+	}
+	for _, r := range c.reserved {
+		stats.Reserved += (float64(r.end-r.start) + 1) / (1 << 32)
+	}
+	if stats.Nodes == 0 {
+		return stats
+	}
+	for k := range c.nodes {
+		stats.Shares[k] = 0
+	}
+	for i, p := range c.hashSortedNodes {
+		stats.Shares[c.circle[p]] += c.pointWidth(i) / (1 << 32)
+	}
+
+	stats.MinShare = math.Inf(1)
+	var sum float64
+	for _, s := range stats.Shares {
+		sum += s
+		stats.MinShare = math.Min(stats.MinShare, s)
+		stats.MaxShare = math.Max(stats.MaxShare, s)
+	}
+	stats.MeanShare = sum / float64(stats.Nodes)
+	var variance float64
+	for _, s := range stats.Shares {
+		variance += (s - stats.MeanShare) * (s - stats.MeanShare)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(stats.Nodes))
+	stats.Imbalance = stats.MaxShare / stats.MeanShare
+	return stats
+}
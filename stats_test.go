@@ -0,0 +1,69 @@
+package consistent_hash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_Stats(t *testing.T) {
+	const quarter = 1 << 30
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": quarter - 1, "a#1#0": 2*quarter - 1,
+		"b#0#0": 3*quarter - 1, "c#0#0": math.MaxUint32,
+	}))
+	if stats := c.Stats(); stats.Nodes != 0 || stats.Imbalance != 0 {
+		t.Fatalf("empty Stats = %+v", stats)
+	}
+	if err := c.AddKey("a", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddKey("b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddKey("c", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := c.Stats()
+	if stats.Nodes != 3 || stats.VirtualPoints != 4 {
+		t.Fatalf("Nodes = %d, VirtualPoints = %d", stats.Nodes, stats.VirtualPoints)
+	}
+	want := map[string]float64{"a": 0.5, "b": 0.25, "c": 0.25}
+	for k, w := range want {
+		if stats.Shares[k] != w {
+			t.Errorf("Shares[%s] = %v, want %v", k, stats.Shares[k], w)
+		}
+	}
+	if stats.MinShare != 0.25 || stats.MaxShare != 0.5 || stats.Imbalance != 1.5 {
+		t.Errorf("min = %v, max = %v, imbalance = %v", stats.MinShare, stats.MaxShare, stats.Imbalance)
+	}
+	if math.Abs(stats.MeanShare-1.0/3) > 1e-12 {
+		t.Errorf("MeanShare = %v", stats.MeanShare)
+	}
+	if want := math.Sqrt(2) / 12; math.Abs(stats.StdDev-want) > 1e-12 {
+		t.Errorf("StdDev = %v, want %v", stats.StdDev, want)
+	}
+
+	// 起点之前的区间属于第一个虚拟结点
+	c.RemoveByKey("c")
+	if got := c.Stats().Shares["a"]; got != 0.75 {
+		t.Errorf("Shares[a] with wrap interval = %v, want 0.75", got)
+	}
+}
+
+func TestConsistentHash_StatsMoreReplicas(t *testing.T) {
+	stddev := func(replicas int) float64 {
+		c := NewConsistentHash()
+		for i := 0; i < 10; i++ {
+			if err := c.AddWithVirtualNode(testNode{"node-" + strconv.Itoa(i)}, replicas); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return c.Stats().StdDev
+	}
+	low, high := stddev(10), stddev(500)
+	if high >= low {
+		t.Fatalf("stddev with 500 replicas = %v, with 10 = %v", high, low)
+	}
+}
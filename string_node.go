@@ -0,0 +1,26 @@
+package consistent_hash
+
+// StringNode 以字符串本身作为 key 的结点，例如 "10.0.0.1:6379"
+type StringNode string
+
+func (s StringNode) Key() string {
+	return string(s)
+}
+
+func (c *ConsistentHash) AddKey(key string, replicas int) error {
+	return c.AddWithVirtualNode(StringNode(key), replicas)
+}
+
+func (c *ConsistentHash) RemoveKey(key string) error {
+	_, err := c.RemoveByKey(key)
+	return err
+}
+
+// GetKey 返回 key 所属结点的 key
+func (c *ConsistentHash) GetKey(key string) (string, error) {
+	n, err := c.GetNode(key)
+	if err != nil {
+		return "", err
+	}
+	return n.Key(), nil
+}
@@ -0,0 +1,61 @@
+package consistent_hash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_StringKeys(t *testing.T) {
+	c := NewConsistentHash()
+	if _, err := c.GetKey("key"); err == nil {
+		t.Fatal("GetKey on empty ring should fail")
+	}
+
+	if err := c.AddKey("10.0.0.1:6379", 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddWithVirtualNode(testNode{"10.0.0.2:6379"}, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddKey("10.0.0.3:6379", 20); err != nil {
+		t.Fatal(err)
+	}
+	// Node 和字符串添加的结点共用同一个 key 空间
+	if err := c.AddKey("10.0.0.2:6379", 20); err == nil {
+		t.Fatal("AddKey of existing node should fail")
+	}
+	if err := c.Add(StringNode("10.0.0.1:6379")); err == nil {
+		t.Fatal("Add of existing node should fail")
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		n, err := c.GetNode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, err := c.GetKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Key() != k {
+			t.Fatalf("GetNode(%s) = %s, GetKey = %s", key, n.Key(), k)
+		}
+	}
+
+	if err := c.RemoveKey("10.0.0.2:6379"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Remove(StringNode("10.0.0.1:6379")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveKey("10.0.0.1:6379"); err == nil {
+		t.Fatal("RemoveKey of removed node should fail")
+	}
+	if got := nodeKeys(c.Members()); !equalStrings(got, []string{"10.0.0.3:6379"}) {
+		t.Errorf("Members = %v", got)
+	}
+	if k, _ := c.GetKey("key"); k != "10.0.0.3:6379" {
+		t.Errorf("GetKey = %s, want 10.0.0.3:6379", k)
+	}
+}
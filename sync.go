@@ -0,0 +1,52 @@
+package consistent_hash
+
+import "sort"
+
+// Sync 把环调整为 desired 给出的结点集合：删除不在其中的结点，按 replicas 添加新结点，
+// 已存在的结点保持不变（虚拟结点不会重新生成），实现了 WeightedNode 的新结点副本数为 replicas 乘以 Weight()。返回新增和删除的 key，删除的 key 按字典序排列。
+// 全部变更在副本上完成后一次替换，失败时环保持不变。
+func (c *ConsistentHash) Sync(desired []Node, replicas int) (added, removed []string, err error) {
+	if err := c.checkReplicas(replicas); err != nil {
+		return nil, nil, err
+	}
+	want := make(map[string]int, len(desired))
+	points := 0
+	for _, node := range desired {
+		if err := checkNode(node); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := want[node.Key()]; ok {
+			return nil, nil, newNodeError(node.Key(), ErrNodeExists)
+		}
+		count, err := c.replicasFor(node, replicas)
+		if err != nil {
+			return nil, nil, err
+		}
+		want[node.Key()] = count
+		points += count
+	}
+
+	err = c.update(len(desired), points*c.pointsPerReplica(), func(staged *ConsistentHash) error {
+		for k := range staged.nodes {
+			if _, ok := want[k]; !ok {
+				removed = append(removed, k)
+			}
+		}
+		sort.Strings(removed)
+		staged.removeNodes(removed)
+		for _, node := range desired {
+			if _, ok := staged.nodes[node.Key()]; ok {
+				continue
+			}
+			if err := staged.addNode(node, want[node.Key()]); err != nil {
+				return err
+			}
+			added = append(added, node.Key())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return added, removed, nil
+}
@@ -0,0 +1,102 @@
+package consistent_hash
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_Sync(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 3), 10); err != nil {
+		t.Fatal(err)
+	}
+	before := append([]uint32(nil), c.nodes["node-1"].virtualNodes...)
+
+	added, removed, err := c.Sync([]Node{testNode{"node-1"}, testNode{"new"}, testNode{"node-2"}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(added, []string{"new"}) || !reflect.DeepEqual(removed, []string{"node-0"}) {
+		t.Fatalf("added = %v, removed = %v", added, removed)
+	}
+	if !reflect.DeepEqual(c.nodes["node-1"].virtualNodes, before) {
+		t.Fatal("virtual nodes of an unchanged node were regenerated")
+	}
+
+	added, removed, err = c.Sync(c.Members(), 10)
+	if err != nil || added != nil || removed != nil {
+		t.Fatalf("no-op Sync = %v, %v, %v", added, removed, err)
+	}
+
+	_, _, err = c.Sync([]Node{testNode{"a"}, testNode{"a"}}, 10)
+	if !errors.Is(err, ErrNodeExists) {
+		t.Fatalf("duplicate key err = %v", err)
+	}
+	if _, _, err = c.Sync(nil, 0); !errors.Is(err, ErrInvalidReplicas) {
+		t.Fatalf("invalid replicas err = %v", err)
+	}
+	if c.Len() != 3 {
+		t.Fatalf("failed Sync changed the ring: Len = %d", c.Len())
+	}
+
+	if _, removed, err = c.Sync(nil, 10); err != nil || len(removed) != 3 || c.Len() != 0 {
+		t.Fatalf("Sync to empty = %v, %v, Len %d", removed, err, c.Len())
+	}
+}
+
+func TestConsistentHash_SyncProperty(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	pool := batchTestNodes("node-", 20)
+	c := NewConsistentHash()
+	for round := 0; round < 50; round++ {
+		var desired []Node
+		want := make(map[string]bool)
+		for _, i := range r.Perm(len(pool))[:r.Intn(len(pool))] {
+			desired = append(desired, pool[i])
+			want[pool[i].Key()] = true
+		}
+
+		owners := make(map[string]string)
+		for i := 0; i < 200; i++ {
+			key := strconv.Itoa(round*1000 + i)
+			if n, err := c.GetNode(key); err == nil {
+				owners[key] = n.Key()
+			}
+		}
+
+		added, _, err := c.Sync(desired, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newNodes := make(map[string]bool)
+		for _, k := range added {
+			newNodes[k] = true
+		}
+
+		members := c.Members()
+		if len(members) != len(want) {
+			t.Fatalf("round %d: %d members, want %d", round, len(members), len(want))
+		}
+		for _, m := range members {
+			if !want[m.Key()] {
+				t.Fatalf("round %d: unexpected member %s", round, m.Key())
+			}
+		}
+		// 仍在环上的结点的 key 只能迁移到新加入的结点
+		for key, owner := range owners {
+			if !want[owner] {
+				continue
+			}
+			n, err := c.GetNode(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Key() != owner && !newNodes[n.Key()] {
+				t.Fatalf("round %d: key %s moved from unchanged %s to %s", round, key, owner, n.Key())
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+# 生成 ketama.golden: python3 testdata/ketama_golden.py > testdata/ketama.golden
+# 按 libketama ketama.c 的 ketama_create_continuum、ketama_hashi 和 ketama_get_server 编写，
+# 结点的内存相同，每个结点 40 个副本、160 个虚拟结点，不是 libketama 本身的输出
+import bisect
+import hashlib
+
+servers = ["10.0.1.%d:11211" % i for i in range(1, 6)]
+
+
+def point(digest, h):
+    return digest[3 + h * 4] << 24 | digest[2 + h * 4] << 16 | digest[1 + h * 4] << 8 | digest[h * 4]
+
+
+continuum = []
+for s in servers:
+    for i in range(40):
+        d = hashlib.md5(("%s-%d" % (s, i)).encode()).digest()
+        for h in range(4):
+            continuum.append((point(d, h), s))
+continuum.sort()
+points = [p for p, _ in continuum]
+
+print("# 由 testdata/ketama_golden.py 生成: key hash server，结点为 10.0.1.1:11211 .. 10.0.1.5:11211，每个 40 个副本")
+for key in ["key-%d" % i for i in range(200)] + ["", "foo", "bar", "memcached", "user:12345"]:
+    h = point(hashlib.md5(key.encode()).digest(), 0)
+    i = bisect.bisect_left(points, h)
+    if i == len(points):
+        i = 0
+    print("%s %d %s" % (key if key else '""', h, continuum[i][1]))
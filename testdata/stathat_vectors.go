@@ -0,0 +1,33 @@
+//go:build ignore
+
+// 用 stathat.com/c/consistent 本身检查 stathat_test.go 中的 stathatVectors，在一个临时的 module 中运行:
+//
+//	go mod init tmp && go get stathat.com/c/consistent
+//	go run stathat_vectors.go cacheA cacheB cacheC < keys.txt
+//
+// 参数为结点，标准输入每行一个 key，输出 key 和 stathat 选择的结点
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"stathat.com/c/consistent"
+)
+
+func main() {
+	c := consistent.New()
+	for _, m := range os.Args[1:] {
+		c.Add(m)
+	}
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		owner, err := c.Get(s.Text())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%q: %q,\n", s.Text(), owner)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * 生成 twemproxy_test.go 中的 twemproxyVectors:
+ *   cc -O2 -o twemproxy_vectors testdata/twemproxy_vectors.c -lcrypto -lm
+ *   ./twemproxy_vectors <hash> <key 数> <server>=<weight> ...
+ * 按 twemproxy 的 src/nc_ketama.c (ketama_update、ketama_dispatch) 和 src/hashkit 中的 hash 函数逐行翻译，
+ * 不是 nutcracker 本身的输出。char 按 x86-64 上的有符号处理，与 nutcracker 在该平台上的行为相同
+ */
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+#include <math.h>
+#include <openssl/md5.h>
+
+#define KETAMA_POINTS_PER_SERVER 160
+#define KETAMA_MAX_HOSTLEN 86
+
+static uint64_t FNV_64_INIT = UINT64_C(0xcbf29ce484222325);
+static uint64_t FNV_64_PRIME = UINT64_C(0x100000001b3);
+static uint32_t FNV_32_INIT = 2166136261UL;
+static uint32_t FNV_32_PRIME = 16777619;
+
+uint32_t hash_fnv1_64(const char *key, size_t n) { uint64_t h = FNV_64_INIT; for (size_t x = 0; x < n; x++) { h *= FNV_64_PRIME; h ^= (uint64_t)key[x]; } return (uint32_t)h; }
+uint32_t hash_fnv1a_64(const char *key, size_t n) { uint32_t h = (uint32_t)FNV_64_INIT; for (size_t x = 0; x < n; x++) { uint32_t v = (uint32_t)key[x]; h ^= v; h *= (uint32_t)FNV_64_PRIME; } return h; }
+uint32_t hash_fnv1_32(const char *key, size_t n) { uint32_t h = FNV_32_INIT; for (size_t x = 0; x < n; x++) { uint32_t v = (uint32_t)key[x]; h *= FNV_32_PRIME; h ^= v; } return h; }
+uint32_t hash_fnv1a_32(const char *key, size_t n) { uint32_t h = FNV_32_INIT; for (size_t x = 0; x < n; x++) { uint32_t v = (uint32_t)key[x]; h ^= v; h *= FNV_32_PRIME; } return h; }
+uint32_t hash_one_at_a_time(const char *key, size_t n) { const char *p = key; uint32_t value = 0; while (n--) { uint32_t val = (uint32_t)*p++; value += val; value += (value << 10); value ^= (value >> 6); } value += (value << 3); value ^= (value >> 11); value += (value << 15); return value; }
+static uint32_t crctab[256];
+uint32_t hash_crc32a(const char *key, size_t n) { uint32_t crc = ~0U; for (size_t x = 0; x < n; x++) crc = (crc >> 8) ^ crctab[(crc ^ key[x]) & 0xff]; return crc ^ ~0U; }
+uint32_t ketama_hash(const char *key, size_t n, uint32_t a) { unsigned char r[16]; MD5((const unsigned char*)key, n, r); return ((uint32_t)(r[3 + a * 4] & 0xFF) << 24) | ((uint32_t)(r[2 + a * 4] & 0xFF) << 16) | ((uint32_t)(r[1 + a * 4] & 0xFF) << 8) | (r[0 + a * 4] & 0xFF); }
+uint32_t hash_md5(const char *key, size_t n) { return ketama_hash(key, n, 0); }
+
+struct item { uint32_t index, value; };
+static int cmp(const void *a, const void *b) { const struct item *x = a, *y = b; return x->value < y->value ? -1 : x->value > y->value; }
+
+int main(int argc, char **argv) {
+	for (uint32_t i = 0; i < 256; i++) { uint32_t c = i; for (int k = 0; k < 8; k++) c = c & 1 ? 0xEDB88320U ^ (c >> 1) : c >> 1; crctab[i] = c; }
+	/* argv: hash nkeys name:weight ... */
+	const char *hn = argv[1]; int nkeys = atoi(argv[2]);
+	int nserver = argc - 3;
+	char *names[64]; int weights[64]; int total = 0;
+	for (int i = 0; i < nserver; i++) { names[i] = strdup(argv[3 + i]); char *c = strrchr(names[i], '='); *c = 0; weights[i] = atoi(c + 1); total += weights[i]; }
+	struct item *cont = malloc(sizeof(struct item) * 160 * 64 * 20); int idx = 0;
+	for (int s = 0; s < nserver; s++) {
+		float pct = (float)weights[s] / (float)total;
+		uint32_t pps = (uint32_t)((floorf((float)(pct * KETAMA_POINTS_PER_SERVER / 4 * (float)nserver + 0.0000000001))) * 4);
+		for (uint32_t pointer = 1; pointer <= pps / 4; pointer++) {
+			char host[KETAMA_MAX_HOSTLEN] = ""; size_t hostlen = snprintf(host, KETAMA_MAX_HOSTLEN, "%s-%u", names[s], pointer - 1);
+			for (uint32_t x = 0; x < 4; x++) { cont[idx].index = s; cont[idx++].value = ketama_hash(host, hostlen, x); }
+		}
+		fprintf(stderr, "%s points %u\n", names[s], pps);
+	}
+	qsort(cont, idx, sizeof(*cont), cmp);
+	uint32_t (*hf)(const char *, size_t) = 0;
+	if (!strcmp(hn, "md5")) hf = hash_md5; else if (!strcmp(hn, "crc32a")) hf = hash_crc32a; else if (!strcmp(hn, "fnv1_64")) hf = hash_fnv1_64; else if (!strcmp(hn, "fnv1a_64")) hf = hash_fnv1a_64; else if (!strcmp(hn, "fnv1_32")) hf = hash_fnv1_32; else if (!strcmp(hn, "fnv1a_32")) hf = hash_fnv1a_32; else hf = hash_one_at_a_time;
+	for (int k = 0; k < nkeys; k++) {
+		char key[64]; int n;
+		if (k % 10 == 9) n = snprintf(key, sizeof key, "user:\xc3\xa9t\xc3\xa9-%d", k); else n = snprintf(key, sizeof key, "key:%d", k);
+		uint32_t h = hf(key, n);
+		int l = 0, r = idx; while (l < r) { int m = l + (r - l) / 2; if (cont[m].value < h) l = m + 1; else r = m; }
+		if (r == idx) r = 0;
+		printf("%s\t%u\t%s\n", key, h, names[cont[r].index]);
+	}
+}
@@ -0,0 +1,114 @@
+package consistent_hash
+
+import (
+	"errors"
+	"sort"
+	"sync/atomic"
+)
+
+// WithLazyRemoval 开启延迟删除，适合结点频繁加入和离开的环。Remove、RemoveByKey、RemoveAll 和 RemoveWhere 原地删除结点，
+// 查询使用的快照不重新生成，只把结点标记为已删除，查询跳过它的虚拟结点继续顺时针查找，结果与立即删除相同。
+// 快照中已删除的虚拟结点超过 maxTombstones 的比例时重新生成快照，其他修改 (包括重新添加同一个结点，
+// 以及 Sync、Batch 等同时添加结点的操作) 也会重新生成。maxTombstones 的范围为 (0, 1]
+func WithLazyRemoval(maxTombstones float64) Option {
+	return func(c *ConsistentHash) error {
+		if !(maxTombstones > 0 && maxTombstones <= 1) {
+			return errors.New("max tombstones must be in (0, 1]")
+		}
+		c.maxTombstones = maxTombstones
+		return nil
+	}
+}
+
+// Tombstones 返回查询使用的快照中已删除的结点留下的虚拟结点数，见 WithLazyRemoval
+func (c *ConsistentHash) Tombstones() int {
+	s := c.loadSnapshot()
+	if s == nil {
+		return 0
+	}
+	return s.tombstones
+}
+
+func (c *ConsistentHash) removeLazy(key string, cond func(consistentNode) bool) (Node, error) {
+	c.lockWrite()
+	c.lazyInit()
+	cNode, ok := c.nodes[key]
+	if !ok {
+		c.unlock()
+		return nil, newNodeError(key, ErrNodeNotFound)
+	}
+	if cond != nil && !cond(cNode) {
+		c.unlock()
+		return nil, nil
+	}
+	var fraction float64
+	if c.metrics != nil {
+		fraction = c.ownedFraction(key)
+	}
+	// CollisionChain 下共用的虚拟结点由其他结点接替，不是墓碑
+	freed := 0
+	for _, p := range cNode.virtualNodes {
+		if _, shared := c.chains[p]; !shared {
+			freed++
+		}
+	}
+	c.unlinkNode(key)
+	c.tombstone([]string{key}, freed)
+	observe := c.observeTopology(0, 1, fraction)
+	c.unlock()
+	if observe != nil {
+		observe()
+	}
+	return cNode.node, nil
+}
+
+// RemoveAll、RemoveWhere 的延迟删除，keys 为环上已有的结点，调用方需持有写锁
+func (c *ConsistentHash) removeNodesLazy(keys []string) []Node {
+	if len(keys) == 0 {
+		return nil
+	}
+	points := map[uint32]struct{}{}
+	for _, k := range keys {
+		for _, p := range c.nodes[k].virtualNodes {
+			points[p] = struct{}{}
+		}
+	}
+	removed := c.removeNodes(keys)
+	// 仍在环上的虚拟结点由 CollisionChain 中的其他结点接替，不是墓碑
+	freed := 0
+	for p := range points {
+		if _, ok := c.circle[p]; !ok {
+			freed++
+		}
+	}
+	c.tombstone(keys, freed)
+	return removed
+}
+
+// 在当前快照上把结点标记为已删除，墓碑超过上限时不做处理，由 unlock 重新生成快照。调用方需持有写锁
+func (c *ConsistentHash) tombstone(keys []string, points int) {
+	s := c.loadSnapshot()
+	if s == nil || float64(s.tombstones+points) > c.maxTombstones*float64(len(s.points)) {
+		return
+	}
+	members := make([]*snapshotNode, 0, len(keys))
+	for _, key := range keys {
+		i := sort.Search(len(s.members), func(i int) bool { return s.members[i].key >= key })
+		if i == len(s.members) || s.members[i].key != key {
+			return
+		}
+		members = append(members, s.members[i])
+	}
+	// 除了版本、结点数和缓存，新快照与原来的共用
+	next := *s
+	next.version = c.version
+	next.nodeCount -= len(members)
+	next.tombstones += points
+	if s.cache != nil {
+		next.cache = newLookupCache(c.lookupCacheSize)
+	}
+	for _, n := range members {
+		atomic.StoreUint32(&n.removed, 1)
+	}
+	c.snapshot.Store(&next)
+}
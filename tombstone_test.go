@@ -0,0 +1,250 @@
+package consistent_hash
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// 延迟删除与立即删除的查询结果相同
+func assertSameLookups(t *testing.T, step string, eager, lazy *ConsistentHash) {
+	t.Helper()
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		want, wantErr := eager.GetNode(key)
+		got, err := lazy.GetNode(key)
+		if !errors.Is(err, wantErr) || (err == nil && got.Key() != want.Key()) {
+			t.Fatalf("%s: GetNode(%s) = %v, %v, want %v, %v", step, key, got, err, want, wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if !lazy.HasNode(got.Key()) {
+			t.Fatalf("%s: GetNode(%s) returned removed node %s", step, key, got.Key())
+		}
+		p1, b1, _ := eager.GetTwo(key)
+		p2, b2, _ := lazy.GetTwo(key)
+		if p1 != p2 || b1 != b2 {
+			t.Fatalf("%s: GetTwo(%s) differs", step, key)
+		}
+	}
+	var want, got []string
+	eager.Range(func(n Node) bool { want = append(want, n.Key()); return true })
+	lazy.Range(func(n Node) bool { got = append(got, n.Key()); return true })
+	if !equalStrings(got, want) {
+		t.Fatalf("%s: Range = %v, want %v", step, got, want)
+	}
+	if lazy.Checksum() != eager.Checksum() {
+		t.Fatalf("%s: checksums differ", step)
+	}
+}
+
+func TestWithLazyRemoval(t *testing.T) {
+	eager := NewConsistentHash()
+	lazy, err := New(WithLazyRemoval(0.5), WithLookupCache(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := batchTestNodes("node-", 20)
+	eager.AddAll(nodes, 10)
+	lazy.AddAll(nodes, 10)
+	assertSameLookups(t, "initial", eager, lazy)
+
+	r := rand.New(rand.NewSource(1))
+	for step := 0; step < 200; step++ {
+		n := nodes[r.Intn(len(nodes))]
+		name := "step " + strconv.Itoa(step)
+		if eager.HasNode(n.Key()) && r.Intn(3) > 0 {
+			eager.Remove(n)
+			if err := lazy.Remove(n); err != nil {
+				t.Fatal(err)
+			}
+		} else if !eager.HasNode(n.Key()) {
+			// 在清理墓碑之前重新添加同一个结点
+			eager.AddWithVirtualNode(n, 10)
+			if err := lazy.AddWithVirtualNode(n, 10); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assertSameLookups(t, name, eager, lazy)
+		if lazy.Version() != eager.Version() {
+			t.Fatalf("%s: version %d, want %d", name, lazy.Version(), eager.Version())
+		}
+	}
+
+	if _, err := lazy.RemoveByKey("missing"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("RemoveByKey(missing) err = %v", err)
+	}
+	for _, n := range nodes {
+		eager.Remove(n)
+		lazy.Remove(n)
+	}
+	if _, err := lazy.GetNode("k"); !errors.Is(err, ErrEmptyRing) {
+		t.Fatalf("GetNode on emptied ring err = %v", err)
+	}
+	for _, v := range []float64{0, -0.1, 1.5} {
+		if _, err := New(WithLazyRemoval(v)); err == nil {
+			t.Errorf("WithLazyRemoval(%v) accepted", v)
+		}
+	}
+}
+
+func TestWithLazyRemoval_Threshold(t *testing.T) {
+	c, _ := New(WithLazyRemoval(0.25))
+	c.AddAll(batchTestNodes("node-", 10), 10)
+	c.RemoveByKey("node-0")
+	c.RemoveByKey("node-1")
+	if got := c.Tombstones(); got != 20 {
+		t.Fatalf("Tombstones = %d, want 20", got)
+	}
+	if len(c.loadSnapshot().points) != 100 {
+		t.Fatal("snapshot was rebuilt before reaching the threshold")
+	}
+	// 第三个结点删除后墓碑超过 25%，重新生成快照
+	c.RemoveByKey("node-2")
+	if got := c.Tombstones(); got != 0 || len(c.loadSnapshot().points) != 70 {
+		t.Fatalf("Tombstones = %d with %d points", got, len(c.loadSnapshot().points))
+	}
+
+	c.RemoveByKey("node-3")
+	c.Add(testNode{"node-3"})
+	if got := c.Tombstones(); got != 0 {
+		t.Fatalf("Tombstones after Add = %d", got)
+	}
+}
+
+func TestWithLazyRemoval_Batch(t *testing.T) {
+	eager := NewConsistentHash()
+	lazy, _ := New(WithLazyRemoval(0.5))
+	for _, c := range []*ConsistentHash{eager, lazy} {
+		c.AddAll(batchTestNodes("node-", 10), 10)
+	}
+	for _, c := range []*ConsistentHash{eager, lazy} {
+		if err := c.RemoveAll([]string{"node-0", "node-1", "missing"}); !errors.Is(err, ErrNodeNotFound) {
+			t.Fatalf("RemoveAll err = %v", err)
+		}
+	}
+	assertSameLookups(t, "RemoveAll", eager, lazy)
+	if lazy.Tombstones() != 20 || len(lazy.loadSnapshot().points) != 100 {
+		t.Fatalf("Tombstones = %d with %d points after RemoveAll", lazy.Tombstones(), len(lazy.loadSnapshot().points))
+	}
+
+	// pred 中可以查询环
+	for _, c := range []*ConsistentHash{eager, lazy} {
+		removed := c.RemoveWhere(func(n Node) bool { return n.Key() == "node-5" && c.HasNode("node-5") })
+		if len(removed) != 1 {
+			t.Fatalf("RemoveWhere removed %v", nodeKeys(removed))
+		}
+	}
+	assertSameLookups(t, "RemoveWhere", eager, lazy)
+	if lazy.Tombstones() != 30 || lazy.Version() != eager.Version() {
+		t.Fatalf("Tombstones = %d, version %d, want %d", lazy.Tombstones(), lazy.Version(), eager.Version())
+	}
+
+	// 超过上限时重新生成快照
+	for _, c := range []*ConsistentHash{eager, lazy} {
+		c.RemoveAll([]string{"node-2", "node-3", "node-4"})
+	}
+	assertSameLookups(t, "threshold", eager, lazy)
+	if lazy.Tombstones() != 0 || len(lazy.loadSnapshot().points) != 40 {
+		t.Fatalf("Tombstones = %d with %d points", lazy.Tombstones(), len(lazy.loadSnapshot().points))
+	}
+}
+
+func TestWithLazyRemoval_Chain(t *testing.T) {
+	eager := collisionRing(t, CollisionChain)
+	lazy := collisionRing(t, CollisionChain)
+	WithLazyRemoval(1)(lazy)
+	for _, c := range []*ConsistentHash{eager, lazy} {
+		c.AddAll([]Node{testNode{"b"}, testNode{"c"}, testNode{"d"}}, 1)
+	}
+	for _, k := range []string{"a", "c", "b"} {
+		eager.RemoveByKey(k)
+		lazy.RemoveByKey(k)
+		if got, want := ownerOfHash(lazy, 5), ownerOfHash(eager, 5); got != want {
+			t.Fatalf("owner of 5 after removing %s = %s, want %s", k, got, want)
+		}
+		var got, want []string
+		eager.RangePoints(func(p uint32, n Node) bool { want = append(want, n.Key()); return true })
+		lazy.RangePoints(func(p uint32, n Node) bool { got = append(got, n.Key()); return true })
+		if !equalStrings(got, want) {
+			t.Fatalf("RangePoints after removing %s = %v, want %v", k, got, want)
+		}
+	}
+	// a 的 20 和最后剩下 b 时的 10
+	if lazy.Tombstones() != 2 {
+		t.Fatalf("Tombstones = %d, want 2", lazy.Tombstones())
+	}
+
+	// 同时删除 chain 中的全部结点时该虚拟结点成为墓碑
+	eager, lazy = collisionRing(t, CollisionChain), collisionRing(t, CollisionChain)
+	WithLazyRemoval(1)(lazy)
+	for _, c := range []*ConsistentHash{eager, lazy} {
+		c.AddAll([]Node{testNode{"b"}, testNode{"c"}, testNode{"d"}}, 1)
+		c.RemoveAll([]string{"b", "c"})
+	}
+	if got, want := ownerOfHash(lazy, 5), ownerOfHash(eager, 5); got != want || lazy.Tombstones() != 0 {
+		t.Fatalf("owner of 5 after RemoveAll(b, c) = %s, want %s, Tombstones = %d", got, want, lazy.Tombstones())
+	}
+	for _, c := range []*ConsistentHash{eager, lazy} {
+		c.RemoveAll([]string{"a", "d"})
+	}
+	if got, want := ownerOfHash(lazy, 5), ownerOfHash(eager, 5); got != want || lazy.Tombstones() != 3 {
+		t.Fatalf("owner of 5 after RemoveAll(a, d) = %s, want %s, Tombstones = %d", got, want, lazy.Tombstones())
+	}
+}
+
+func TestWithLazyRemoval_Concurrent(t *testing.T) {
+	c, _ := New(WithLazyRemoval(0.3))
+	nodes := batchTestNodes("node-", 10)
+	c.AddAll(nodes, 20)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				c.GetNode("key-" + strconv.Itoa(i))
+				c.GetTwo("key-" + strconv.Itoa(i))
+			}
+		}()
+	}
+	for i := 0; i < 300; i++ {
+		n := nodes[i%len(nodes)]
+		if i%20 < 10 {
+			c.Remove(n)
+		} else {
+			c.AddWithVirtualNode(n, 20)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// 从 2000 个结点的环上逐个删除结点，删空之后停止计时重新添加
+func benchmarkRemoveChurn(b *testing.B, opts ...Option) {
+	nodes := batchTestNodes("node-", 2000)
+	var c *ConsistentHash
+	for i := 0; i < b.N; i++ {
+		if i%len(nodes) == 0 {
+			b.StopTimer()
+			c, _ = New(opts...)
+			c.AddAll(nodes, 50)
+			b.StartTimer()
+		}
+		c.Remove(nodes[i%len(nodes)])
+	}
+}
+
+func BenchmarkConsistentHash_RemoveChurn(b *testing.B) {
+	b.Run("Eager", func(b *testing.B) { benchmarkRemoveChurn(b) })
+	b.Run("Lazy", func(b *testing.B) { benchmarkRemoveChurn(b, WithLazyRemoval(0.25)) })
+}
@@ -0,0 +1,113 @@
+package consistent_hash
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AddWithTTL 添加会过期的结点，结点需要在 ttl 内调用 Heartbeat 续期，
+// 过期的结点由 StartReaper 启动的协程删除，与 Remove 一样触发 OnNodeRemoved 和 Watch 的事件。
+// 删除之后可以重新添加
+func (c *ConsistentHash) AddWithTTL(node Node, replicas int, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl %v must be greater than 0", ttl)
+	}
+	if err := checkNode(node); err != nil {
+		return err
+	}
+	if err := c.checkReplicas(replicas); err != nil {
+		return err
+	}
+	var observe func()
+	err := c.update(1, replicas*c.pointsPerReplica(), func(staged *ConsistentHash) error {
+		if err := staged.addNode(node, replicas); err != nil {
+			return err
+		}
+		cNode := staged.nodes[node.Key()]
+		cNode.ttl = ttl
+		cNode.deadline = staged.now().Add(ttl)
+		staged.nodes[node.Key()] = cNode
+		if staged.metrics != nil {
+			observe = staged.observeTopology(1, 0, staged.ownedFraction(node.Key()))
+		}
+		return nil
+	})
+	if observe != nil {
+		observe()
+	}
+	return err
+}
+
+// Heartbeat 把结点的到期时间延长为当前时间加上 ttl。已经过期但还没有被删除的结点也可以续期。
+// 结点不存在时返回 ErrNodeNotFound，不是由 AddWithTTL 添加时返回 ErrNoTTL
+func (c *ConsistentHash) Heartbeat(nodeKey string) error {
+	c.lockWrite()
+	defer c.unlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return newNodeError(nodeKey, ErrNodeNotFound)
+	}
+	if cNode.ttl == 0 {
+		return newNodeError(nodeKey, ErrNoTTL)
+	}
+	cNode.deadline = c.now().Add(cNode.ttl)
+	c.nodes[nodeKey] = cNode
+	return nil
+}
+
+// StartReaper 启动后台协程，每隔 interval 删除过期的结点，ctx 取消后协程退出
+func (c *ConsistentHash) StartReaper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		panic("non-positive interval for StartReaper")
+	}
+	c.RLock()
+	clk := c.clock
+	c.RUnlock()
+	if clk == nil {
+		clk = realClock{}
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-clk.After(interval):
+				if ctx.Err() != nil {
+					return
+				}
+				c.reap()
+			}
+		}
+	}()
+}
+
+// 按 key 的顺序删除过期的结点，检查之后续期的结点不会被删除
+func (c *ConsistentHash) reap() {
+	c.RLock()
+	now := c.now()
+	var expired []string
+	for k, n := range c.nodes {
+		if n.expired(now) {
+			expired = append(expired, k)
+		}
+	}
+	c.RUnlock()
+	sort.Strings(expired)
+	for _, k := range expired {
+		c.removeIf(k, func(n consistentNode) bool { return n.expired(now) })
+	}
+}
+
+func (n consistentNode) expired(now time.Time) bool {
+	return n.ttl > 0 && !now.Before(n.deadline)
+}
+
+// 调用方需持有锁
+func (c *ConsistentHash) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
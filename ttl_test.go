@@ -0,0 +1,119 @@
+package consistent_hash
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startFakeReaper(t *testing.T, c *ConsistentHash) (*fakeClock, context.CancelFunc) {
+	clk := newFakeClock()
+	c.clock = clk
+	ctx, cancel := context.WithCancel(context.Background())
+	c.StartReaper(ctx, time.Second)
+	<-clk.waiting
+	return clk, cancel
+}
+
+func TestConsistentHash_TTL(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithLazyRemoval(0.5)}} {
+		c, _ := New(opts...)
+		clk, cancel := startFakeReaper(t, c)
+		var mu sync.Mutex
+		var removed []string
+		c.OnNodeRemoved(func(n Node) {
+			mu.Lock()
+			removed = append(removed, n.Key())
+			mu.Unlock()
+		})
+		events, stop := c.Watch(16)
+
+		c.AddWithTTL(testNode{"a"}, 10, 10*time.Second)
+		c.AddWithTTL(testNode{"b"}, 10, 10*time.Second)
+		c.Add(testNode{"static"})
+		<-events
+		<-events
+		<-events
+
+		clk.advance(6 * time.Second)
+		if err := c.Heartbeat("a"); err != nil {
+			t.Fatal(err)
+		}
+		clk.tick(t)
+		if len(c.Members()) != 3 {
+			t.Fatal("nodes expired before their deadline")
+		}
+
+		// b 到期，a 续期之后还有 6 秒
+		clk.advance(4 * time.Second)
+		clk.tick(t)
+		if got := nodeKeys(c.Members()); !equalStrings(got, []string{"a", "static"}) {
+			t.Fatalf("after b expired nodes = %v", got)
+		}
+		if e := <-events; e.Type != EventRemoved || e.NodeKey != "b" {
+			t.Fatalf("Watch event = %+v", e)
+		}
+
+		clk.advance(6 * time.Second)
+		clk.tick(t)
+		if got := nodeKeys(c.Members()); !equalStrings(got, []string{"static"}) {
+			t.Fatalf("after a expired nodes = %v", got)
+		}
+		if err := c.Heartbeat("a"); !errors.Is(err, ErrNodeNotFound) {
+			t.Fatalf("Heartbeat after expiry err = %v", err)
+		}
+
+		// 过期后重新注册，使用新的到期时间
+		if err := c.AddWithTTL(testNode{"a"}, 10, 10*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		clk.advance(9 * time.Second)
+		clk.tick(t)
+		if len(c.Members()) != 2 {
+			t.Fatal("re-registered node expired with the old deadline")
+		}
+		cancel()
+		stop()
+
+		mu.Lock()
+		if !reflect.DeepEqual(removed, []string{"b", "a"}) {
+			t.Errorf("OnNodeRemoved = %v", removed)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestConsistentHash_TTLErrors(t *testing.T) {
+	c := NewConsistentHash()
+	c.Add(testNode{"static"})
+	if err := c.Heartbeat("static"); !errors.Is(err, ErrNoTTL) {
+		t.Fatalf("Heartbeat on a node without ttl err = %v", err)
+	}
+	if err := c.Heartbeat("missing"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Heartbeat on a missing node err = %v", err)
+	}
+	if err := c.AddWithTTL(testNode{"a"}, 10, 0); err == nil {
+		t.Fatal("zero ttl accepted")
+	}
+	if err := c.AddWithTTL(testNode{"static"}, 10, time.Second); !errors.Is(err, ErrNodeExists) {
+		t.Fatalf("AddWithTTL on an existing node err = %v", err)
+	}
+}
+
+// 检查之后、删除之前续期的结点不会被删除
+func TestConsistentHash_ReapRenewed(t *testing.T) {
+	c := NewConsistentHash()
+	clk := newFakeClock()
+	c.clock = clk
+	c.AddWithTTL(testNode{"a"}, 10, time.Second)
+	clk.advance(time.Second)
+	now := clk.Now()
+	c.Heartbeat("a")
+	c.removeIf("a", func(n consistentNode) bool { return n.expired(now) })
+	if len(c.Members()) != 1 {
+		t.Fatal("renewed node was removed")
+	}
+}
@@ -0,0 +1,184 @@
+package consistent_hash
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// twemproxy 中权重相同的服务器各有 160 个虚拟结点，即 40 个副本
+const twemproxyPointsPerServer = 160
+
+// twemproxy 的 hash 配置项可选的算法，与 nc_hash.c 的实现相同。
+// key 的字节按 x86 上有符号的 char 处理，大于 0x7f 的字节做符号扩展
+var twemproxyHashes = map[string]func(key string) uint32{
+	"md5":           ketamaHash,
+	"crc32a":        defaultHash,
+	"fnv1_64":       twemproxyFNV1_64,
+	"fnv1a_64":      twemproxyFNV1a_64,
+	"fnv1_32":       twemproxyFNV1_32,
+	"fnv1a_32":      twemproxyFNV1a_32,
+	"one_at_a_time": twemproxyOneAtATime,
+}
+
+// WithTwemproxy 使环与 twemproxy 的 distribution: ketama 选择相同的服务器，hash 为 twemproxy 的 hash 配置项，
+// 例如 "fnv1a_64" 或 "md5"。虚拟结点与 WithKetama 相同，结点的 key 必须是 twemproxy 中服务器的名字
+// (没有配置名字时为 host:port)，Add 默认 40 个副本，即 160 个虚拟结点。
+// 服务器的权重不同时每个服务器的虚拟结点数取决于全部服务器的权重之和，需要用 SetTwemproxyServers 修改结点。
+// 不能与 WithHash、WithSeed 等自定义 hash 的选项一起使用
+func WithTwemproxy(hash string) Option {
+	return func(c *ConsistentHash) error {
+		h, ok := twemproxyHashes[hash]
+		if !ok {
+			return fmt.Errorf("unknown twemproxy hash %q", hash)
+		}
+		if c.hashName != hashCRC32 {
+			return errors.New("twemproxy mode can't use a custom hash")
+		}
+		c.ketama = true
+		c.defaultReplicas = twemproxyPointsPerServer / ketamaPointsPerReplica
+		c.hash = h
+		c.bytesHash = nil
+		c.hashName = twemproxyHashName(hash)
+		c.twemproxyHash = hash
+		return nil
+	}
+}
+
+func twemproxyHashName(hash string) string {
+	return "twemproxy/" + hash
+}
+
+// SetTwemproxyServers 与 Sync 相同地把环调整为 servers，并按 twemproxy 的规则根据全部服务器的权重重新计算
+// 每个服务器的副本数：floor(weight / 总权重 * 40 * 服务器数)。实现了 WeightedNode 的结点使用 Weight()，
+// 其他结点的权重为 1。副本数变化的已有结点只增减编号最大的副本，其他虚拟结点不变。
+// twemproxy 自动摘除的服务器不参与计算，调用方需要从 servers 中去掉，MarkDown 不会改变虚拟结点
+func (c *ConsistentHash) SetTwemproxyServers(servers []Node) (added, removed []string, err error) {
+	if c.twemproxyHash == "" {
+		return nil, nil, errors.New("ring is not in twemproxy mode")
+	}
+	weights := make([]int, len(servers))
+	total := 0
+	seen := make(map[string]struct{}, len(servers))
+	for i, node := range servers {
+		if err := checkNode(node); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := seen[node.Key()]; ok {
+			return nil, nil, newNodeError(node.Key(), ErrNodeExists)
+		}
+		seen[node.Key()] = struct{}{}
+		weights[i] = 1
+		if wn, ok := node.(WeightedNode); ok {
+			if weights[i] = wn.Weight(); weights[i] <= 0 {
+				return nil, nil, newNodeError(node.Key(), fmt.Errorf("%w, got %d", ErrInvalidWeight, weights[i]))
+			}
+		}
+		total += weights[i]
+	}
+	want := make(map[string]int, len(servers))
+	points := 0
+	for i, node := range servers {
+		count := twemproxyReplicas(weights[i], total, len(servers))
+		if err := c.checkReplicas(count); err != nil {
+			return nil, nil, newNodeError(node.Key(), err)
+		}
+		want[node.Key()] = count
+		points += count * ketamaPointsPerReplica
+	}
+
+	err = c.update(len(servers), points, func(staged *ConsistentHash) error {
+		for k := range staged.nodes {
+			if _, ok := want[k]; !ok {
+				removed = append(removed, k)
+			}
+		}
+		sort.Strings(removed)
+		staged.removeNodes(removed)
+		for _, node := range servers {
+			k := node.Key()
+			cNode, ok := staged.nodes[k]
+			if !ok {
+				if err := staged.addNode(node, want[k]); err != nil {
+					return err
+				}
+				added = append(added, k)
+				continue
+			}
+			current := len(cNode.virtualNodes) / ketamaPointsPerReplica
+			var err error
+			if current < want[k] {
+				err = staged.grow(k, want[k]-current)
+			} else if current > want[k] {
+				err = staged.shrink(k, func(current int) int { return current - want[k] })
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return added, removed, nil
+}
+
+// 与 nc_ketama.c 相同，使用 float 计算
+func twemproxyReplicas(weight, total, servers int) int {
+	pct := float32(weight) / float32(total)
+	// 显式转换，避免合并为 FMA 改变舍入
+	points := float32(float32(pct*twemproxyPointsPerServer)/4) * float32(servers)
+	return int(math.Floor(float64(float32(float64(points) + 0.0000000001))))
+}
+
+func twemproxyFNV1_64(key string) uint32 {
+	h := uint64(0xcbf29ce484222325)
+	for i := 0; i < len(key); i++ {
+		h *= 0x100000001b3
+		h ^= uint64(int8(key[i]))
+	}
+	return uint32(h)
+}
+
+// twemproxy 的 fnv1a_64 把 64 位的初始值和质数截断为 32 位计算
+func twemproxyFNV1a_64(key string) uint32 {
+	h := uint32(0xcbf29ce484222325 & math.MaxUint32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(int8(key[i]))
+		h *= 0x100000001b3 & math.MaxUint32
+	}
+	return h
+}
+
+func twemproxyFNV1_32(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h *= 16777619
+		h ^= uint32(int8(key[i]))
+	}
+	return h
+}
+
+func twemproxyFNV1a_32(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(int8(key[i]))
+		h *= 16777619
+	}
+	return h
+}
+
+func twemproxyOneAtATime(key string) uint32 {
+	var h uint32
+	for i := 0; i < len(key); i++ {
+		h += uint32(int8(key[i]))
+		h += h << 10
+		h ^= h >> 6
+	}
+	h += h << 3
+	h ^= h >> 11
+	h += h << 15
+	return h
+}
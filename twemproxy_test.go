@@ -0,0 +1,312 @@
+package consistent_hash
+
+import (
+	"errors"
+	"testing"
+)
+
+type twemproxyServer struct {
+	name   string
+	weight int
+}
+
+func (s twemproxyServer) Key() string { return s.name }
+
+func (s twemproxyServer) Weight() int { return s.weight }
+
+// 由 testdata/twemproxy_vectors.c 在 x86-64 上生成，包括非 ASCII 的 key 和不同的权重。该程序按 twemproxy 的
+// nc_ketama.c 和 hashkit 逐行翻译，不是 nutcracker 本身的输出，只能发现与翻译不一致的错误。
+// 同一个 hash 出现两次时在原来的环上调用 SetTwemproxyServers，验证服务器集合变化之后的结果
+var twemproxyVectors = []struct {
+	hash    string
+	servers []twemproxyServer
+	want    map[string]string
+}{
+	{"fnv1a_64", []twemproxyServer{{"10.0.0.1:11211", 1}, {"10.0.0.2:11211", 1}, {"10.0.0.3:11211", 1}}, map[string]string{
+		"key:0":                 "10.0.0.1:11211",
+		"key:1":                 "10.0.0.1:11211",
+		"key:2":                 "10.0.0.1:11211",
+		"key:3":                 "10.0.0.1:11211",
+		"key:4":                 "10.0.0.1:11211",
+		"key:5":                 "10.0.0.1:11211",
+		"key:6":                 "10.0.0.1:11211",
+		"key:7":                 "10.0.0.1:11211",
+		"key:8":                 "10.0.0.1:11211",
+		"user:\u00e9t\u00e9-9":  "10.0.0.3:11211",
+		"key:10":                "10.0.0.3:11211",
+		"key:11":                "10.0.0.3:11211",
+		"key:12":                "10.0.0.3:11211",
+		"key:13":                "10.0.0.3:11211",
+		"key:14":                "10.0.0.3:11211",
+		"key:15":                "10.0.0.3:11211",
+		"key:16":                "10.0.0.3:11211",
+		"key:17":                "10.0.0.3:11211",
+		"key:18":                "10.0.0.3:11211",
+		"user:\u00e9t\u00e9-19": "10.0.0.3:11211",
+		"key:20":                "10.0.0.3:11211",
+		"key:21":                "10.0.0.3:11211",
+		"key:22":                "10.0.0.3:11211",
+		"key:23":                "10.0.0.3:11211",
+		"key:24":                "10.0.0.3:11211",
+		"key:25":                "10.0.0.3:11211",
+		"key:26":                "10.0.0.3:11211",
+		"key:27":                "10.0.0.3:11211",
+		"key:28":                "10.0.0.3:11211",
+		"user:\u00e9t\u00e9-29": "10.0.0.3:11211",
+	}},
+	{"md5", []twemproxyServer{{"cache-a", 1}, {"cache-b", 2}, {"cache-c", 3}}, map[string]string{
+		"key:0":                 "cache-b",
+		"key:1":                 "cache-b",
+		"key:2":                 "cache-b",
+		"key:3":                 "cache-c",
+		"key:4":                 "cache-c",
+		"key:5":                 "cache-c",
+		"key:6":                 "cache-c",
+		"key:7":                 "cache-b",
+		"key:8":                 "cache-c",
+		"user:\u00e9t\u00e9-9":  "cache-b",
+		"key:10":                "cache-b",
+		"key:11":                "cache-b",
+		"key:12":                "cache-c",
+		"key:13":                "cache-b",
+		"key:14":                "cache-b",
+		"key:15":                "cache-b",
+		"key:16":                "cache-b",
+		"key:17":                "cache-b",
+		"key:18":                "cache-a",
+		"user:\u00e9t\u00e9-19": "cache-a",
+		"key:20":                "cache-c",
+		"key:21":                "cache-c",
+		"key:22":                "cache-b",
+		"key:23":                "cache-a",
+		"key:24":                "cache-c",
+		"key:25":                "cache-b",
+		"key:26":                "cache-b",
+		"key:27":                "cache-c",
+		"key:28":                "cache-c",
+		"user:\u00e9t\u00e9-29": "cache-b",
+	}},
+	{"fnv1_64", []twemproxyServer{{"10.0.1.1:6379", 1}, {"10.0.1.2:6379", 1}, {"10.0.1.3:6379", 5}, {"10.0.1.4:6379", 2}}, map[string]string{
+		"key:0":                 "10.0.1.2:6379",
+		"key:1":                 "10.0.1.2:6379",
+		"key:2":                 "10.0.1.2:6379",
+		"key:3":                 "10.0.1.2:6379",
+		"key:4":                 "10.0.1.2:6379",
+		"key:5":                 "10.0.1.2:6379",
+		"key:6":                 "10.0.1.2:6379",
+		"key:7":                 "10.0.1.2:6379",
+		"key:8":                 "10.0.1.2:6379",
+		"user:\u00e9t\u00e9-9":  "10.0.1.2:6379",
+		"key:10":                "10.0.1.1:6379",
+		"key:11":                "10.0.1.1:6379",
+		"key:12":                "10.0.1.1:6379",
+		"key:13":                "10.0.1.1:6379",
+		"key:14":                "10.0.1.1:6379",
+		"key:15":                "10.0.1.1:6379",
+		"key:16":                "10.0.1.1:6379",
+		"key:17":                "10.0.1.1:6379",
+		"key:18":                "10.0.1.1:6379",
+		"user:\u00e9t\u00e9-19": "10.0.1.4:6379",
+	}},
+	{"fnv1a_32", []twemproxyServer{{"10.0.1.1:6379", 1}, {"10.0.1.2:6379", 1}, {"10.0.1.3:6379", 5}, {"10.0.1.4:6379", 2}}, map[string]string{
+		"key:0":                 "10.0.1.3:6379",
+		"key:1":                 "10.0.1.3:6379",
+		"key:2":                 "10.0.1.1:6379",
+		"key:3":                 "10.0.1.3:6379",
+		"key:4":                 "10.0.1.3:6379",
+		"key:5":                 "10.0.1.3:6379",
+		"key:6":                 "10.0.1.4:6379",
+		"key:7":                 "10.0.1.2:6379",
+		"key:8":                 "10.0.1.4:6379",
+		"user:\u00e9t\u00e9-9":  "10.0.1.3:6379",
+		"key:10":                "10.0.1.3:6379",
+		"key:11":                "10.0.1.3:6379",
+		"key:12":                "10.0.1.3:6379",
+		"key:13":                "10.0.1.3:6379",
+		"key:14":                "10.0.1.3:6379",
+		"key:15":                "10.0.1.3:6379",
+		"key:16":                "10.0.1.3:6379",
+		"key:17":                "10.0.1.3:6379",
+		"key:18":                "10.0.1.1:6379",
+		"user:\u00e9t\u00e9-19": "10.0.1.3:6379",
+	}},
+	{"fnv1_32", []twemproxyServer{{"10.0.1.1:6379", 1}, {"10.0.1.2:6379", 1}, {"10.0.1.3:6379", 5}, {"10.0.1.4:6379", 2}}, map[string]string{
+		"key:0":                 "10.0.1.3:6379",
+		"key:1":                 "10.0.1.3:6379",
+		"key:2":                 "10.0.1.3:6379",
+		"key:3":                 "10.0.1.3:6379",
+		"key:4":                 "10.0.1.3:6379",
+		"key:5":                 "10.0.1.3:6379",
+		"key:6":                 "10.0.1.3:6379",
+		"key:7":                 "10.0.1.3:6379",
+		"key:8":                 "10.0.1.3:6379",
+		"user:\u00e9t\u00e9-9":  "10.0.1.3:6379",
+		"key:10":                "10.0.1.4:6379",
+		"key:11":                "10.0.1.4:6379",
+		"key:12":                "10.0.1.4:6379",
+		"key:13":                "10.0.1.4:6379",
+		"key:14":                "10.0.1.4:6379",
+		"key:15":                "10.0.1.4:6379",
+		"key:16":                "10.0.1.4:6379",
+		"key:17":                "10.0.1.4:6379",
+		"key:18":                "10.0.1.4:6379",
+		"user:\u00e9t\u00e9-19": "10.0.1.4:6379",
+	}},
+	{"one_at_a_time", []twemproxyServer{{"10.0.1.1:6379", 1}, {"10.0.1.2:6379", 1}, {"10.0.1.3:6379", 5}, {"10.0.1.4:6379", 2}}, map[string]string{
+		"key:0":                 "10.0.1.3:6379",
+		"key:1":                 "10.0.1.3:6379",
+		"key:2":                 "10.0.1.4:6379",
+		"key:3":                 "10.0.1.3:6379",
+		"key:4":                 "10.0.1.4:6379",
+		"key:5":                 "10.0.1.4:6379",
+		"key:6":                 "10.0.1.4:6379",
+		"key:7":                 "10.0.1.3:6379",
+		"key:8":                 "10.0.1.4:6379",
+		"user:\u00e9t\u00e9-9":  "10.0.1.2:6379",
+		"key:10":                "10.0.1.1:6379",
+		"key:11":                "10.0.1.3:6379",
+		"key:12":                "10.0.1.3:6379",
+		"key:13":                "10.0.1.4:6379",
+		"key:14":                "10.0.1.4:6379",
+		"key:15":                "10.0.1.3:6379",
+		"key:16":                "10.0.1.2:6379",
+		"key:17":                "10.0.1.3:6379",
+		"key:18":                "10.0.1.3:6379",
+		"user:\u00e9t\u00e9-19": "10.0.1.4:6379",
+	}},
+	{"crc32a", []twemproxyServer{{"10.0.1.1:6379", 1}, {"10.0.1.2:6379", 1}, {"10.0.1.3:6379", 5}, {"10.0.1.4:6379", 2}}, map[string]string{
+		"key:0":                 "10.0.1.4:6379",
+		"key:1":                 "10.0.1.3:6379",
+		"key:2":                 "10.0.1.4:6379",
+		"key:3":                 "10.0.1.3:6379",
+		"key:4":                 "10.0.1.3:6379",
+		"key:5":                 "10.0.1.3:6379",
+		"key:6":                 "10.0.1.2:6379",
+		"key:7":                 "10.0.1.3:6379",
+		"key:8":                 "10.0.1.3:6379",
+		"user:\u00e9t\u00e9-9":  "10.0.1.4:6379",
+		"key:10":                "10.0.1.3:6379",
+		"key:11":                "10.0.1.4:6379",
+		"key:12":                "10.0.1.3:6379",
+		"key:13":                "10.0.1.3:6379",
+		"key:14":                "10.0.1.2:6379",
+		"key:15":                "10.0.1.3:6379",
+		"key:16":                "10.0.1.3:6379",
+		"key:17":                "10.0.1.4:6379",
+		"key:18":                "10.0.1.1:6379",
+		"user:\u00e9t\u00e9-19": "10.0.1.3:6379",
+	}},
+	{"crc32a", []twemproxyServer{{"10.0.1.1:6379", 1}, {"10.0.1.2:6379", 1}, {"10.0.1.4:6379", 2}}, map[string]string{
+		"key:0":                 "10.0.1.4:6379",
+		"key:1":                 "10.0.1.2:6379",
+		"key:2":                 "10.0.1.4:6379",
+		"key:3":                 "10.0.1.4:6379",
+		"key:4":                 "10.0.1.4:6379",
+		"key:5":                 "10.0.1.2:6379",
+		"key:6":                 "10.0.1.2:6379",
+		"key:7":                 "10.0.1.1:6379",
+		"key:8":                 "10.0.1.4:6379",
+		"user:\u00e9t\u00e9-9":  "10.0.1.4:6379",
+		"key:10":                "10.0.1.4:6379",
+		"key:11":                "10.0.1.4:6379",
+		"key:12":                "10.0.1.1:6379",
+		"key:13":                "10.0.1.4:6379",
+		"key:14":                "10.0.1.2:6379",
+		"key:15":                "10.0.1.2:6379",
+		"key:16":                "10.0.1.2:6379",
+		"key:17":                "10.0.1.4:6379",
+		"key:18":                "10.0.1.1:6379",
+		"user:\u00e9t\u00e9-19": "10.0.1.2:6379",
+	}},
+}
+
+func TestWithTwemproxy_Vectors(t *testing.T) {
+	rings := map[string]*ConsistentHash{}
+	for _, tt := range twemproxyVectors {
+		c, ok := rings[tt.hash]
+		if !ok {
+			var err error
+			if c, err = New(WithTwemproxy(tt.hash)); err != nil {
+				t.Fatal(err)
+			}
+			rings[tt.hash] = c
+		}
+		servers := make([]Node, len(tt.servers))
+		for i, s := range tt.servers {
+			servers[i] = s
+		}
+		if _, _, err := c.SetTwemproxyServers(servers); err != nil {
+			t.Fatal(err)
+		}
+		for key, want := range tt.want {
+			n, err := c.GetNode(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Key() != want {
+				t.Errorf("%s %v: GetNode(%q) = %s, want %s", tt.hash, tt.servers, key, n.Key(), want)
+			}
+		}
+		if err := c.VerifyRing(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConsistentHash_SetTwemproxyServers(t *testing.T) {
+	c, _ := New(WithTwemproxy("md5"))
+	servers := []Node{twemproxyServer{"a", 1}, twemproxyServer{"b", 1}, twemproxyServer{"c", 5}, twemproxyServer{"d", 2}}
+	added, removed, err := c.SetTwemproxyServers(servers)
+	if err != nil || !equalStrings(added, []string{"a", "b", "c", "d"}) || removed != nil {
+		t.Fatalf("SetTwemproxyServers = %v, %v, %v", added, removed, err)
+	}
+	// floor(w / 9 * 40 * 4)
+	for k, want := range map[string]int{"a": 17, "b": 17, "c": 88, "d": 35} {
+		if n, _ := c.VirtualNodeCount(k); n != want {
+			t.Errorf("%s has %d replicas, want %d", k, n, want)
+		}
+	}
+
+	// 删除 c 之后其他服务器的副本数重新计算，与重新创建的环相同
+	added, removed, err = c.SetTwemproxyServers([]Node{servers[0], servers[1], servers[3]})
+	if err != nil || added != nil || !equalStrings(removed, []string{"c"}) {
+		t.Fatalf("SetTwemproxyServers = %v, %v, %v", added, removed, err)
+	}
+	fresh, _ := New(WithTwemproxy("md5"))
+	fresh.SetTwemproxyServers([]Node{servers[0], servers[1], servers[3]})
+	if c.Checksum() != fresh.Checksum() {
+		t.Fatal("ring differs from a freshly built one")
+	}
+
+	// 权重相同时 Add 与 SetTwemproxyServers 的结果相同
+	byAdd, _ := New(WithTwemproxy("md5"))
+	byAdd.Add(testNode{"x"})
+	byAdd.Add(testNode{"y"})
+	bySet, _ := New(WithTwemproxy("md5"))
+	bySet.SetTwemproxyServers([]Node{testNode{"x"}, testNode{"y"}})
+	if byAdd.Checksum() != bySet.Checksum() || byAdd.TotalPoints() != 2*twemproxyPointsPerServer {
+		t.Fatal("Add differs from SetTwemproxyServers with equal weights")
+	}
+
+	if _, _, err := c.SetTwemproxyServers([]Node{twemproxyServer{"a", 0}}); !errors.Is(err, ErrInvalidWeight) {
+		t.Fatalf("zero weight err = %v", err)
+	}
+	if _, _, err := NewConsistentHash().SetTwemproxyServers(servers); err == nil {
+		t.Fatal("SetTwemproxyServers accepted a ring not in twemproxy mode")
+	}
+}
+
+func TestWithTwemproxy_RefusesCustomHash(t *testing.T) {
+	if _, err := New(WithTwemproxy("murmur")); err == nil {
+		t.Fatal("unknown hash accepted")
+	}
+	for _, opts := range [][]Option{
+		{WithBytesHash(fnvBytes), WithTwemproxy("md5")},
+		{WithTwemproxy("md5"), WithBytesHash(fnvBytes)},
+		{WithTwemproxy("fnv1a_64"), WithSeed(1)},
+	} {
+		if _, err := New(opts...); err == nil {
+			t.Error("custom hash accepted in twemproxy mode")
+		}
+	}
+}
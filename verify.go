@@ -0,0 +1,93 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VerifyError 列出 VerifyRing 发现的所有问题，满足 errors.Is(err, ErrInconsistent)
+type VerifyError struct {
+	Violations []string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrInconsistent, strings.Join(e.Violations, "; "))
+}
+
+func (e *VerifyError) Unwrap() error {
+	return ErrInconsistent
+}
+
+// VerifyRing 在读锁内检查环的内部数据是否一致，发现问题时返回 *VerifyError
+func (c *ConsistentHash) VerifyRing() error {
+	c.RLock()
+	defer c.RUnlock()
+
+	var violations []string
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+
+	sorted := c.hashSortedNodes
+	// hashSortedNodes 本身可能无序，不能用二分查找
+	inSorted := make(map[uint32]struct{}, len(sorted))
+	for i, p := range sorted {
+		inSorted[p] = struct{}{}
+		if i > 0 && sorted[i-1] >= p {
+			fail("sorted points not strictly increasing at %d: %d, %d", i, sorted[i-1], p)
+		}
+		if _, ok := c.circle[p]; !ok {
+			fail("sorted point %d missing from circle", p)
+		}
+	}
+
+	// map 的遍历顺序不固定，排序后报告结果才稳定
+	points := make([]uint32, 0, len(c.circle))
+	for p := range c.circle {
+		points = append(points, p)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+	for _, p := range points {
+		if _, ok := c.nodes[c.circle[p]]; !ok {
+			fail("point %d owned by unknown node %q", p, c.circle[p])
+		}
+	}
+
+	keys := make([]string, 0, len(c.nodes))
+	for k := range c.nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	total := 0
+	for _, k := range keys {
+		cNode := c.nodes[k]
+		total += len(cNode.virtualNodes)
+		for _, v := range cNode.virtualNodes {
+			if owner, ok := c.circle[v]; !ok {
+				fail("point %d of node %q missing from circle", v, k)
+			} else if owner != k && !c.chainedTo(v, k) {
+				fail("point %d of node %q owned by %q in circle", v, k, owner)
+			}
+			if _, ok := inSorted[v]; !ok {
+				fail("point %d of node %q missing from sorted points", v, k)
+			}
+		}
+	}
+
+	// 共用的虚拟结点在每个结点中各记录一次
+	shared := 0
+	for p, owners := range c.chains {
+		shared += len(owners) - 1
+		if len(owners) < 2 || c.circle[p] != owners[0] {
+			fail("chain of point %d is %v, circle owner %q", p, owners, c.circle[p])
+		}
+	}
+	if len(sorted) != len(c.circle) || total != len(c.circle)+shared {
+		fail("point counts disagree: sorted %d, circle %d, nodes %d", len(sorted), len(c.circle), total)
+	}
+	if len(violations) > 0 {
+		return &VerifyError{Violations: violations}
+	}
+	return nil
+}
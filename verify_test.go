@@ -0,0 +1,88 @@
+package consistent_hash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func verifyTestRing(t *testing.T) *ConsistentHash {
+	t.Helper()
+	c := NewConsistentWithCustomHash(tableHash(map[string]uint32{
+		"a#0#0": 100, "a#1#0": 300, "b#0#0": 200,
+	}))
+	if err := c.AddKey("a", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddKey("b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestConsistentHash_VerifyRing(t *testing.T) {
+	if err := NewConsistentHash().VerifyRing(); err != nil {
+		t.Fatalf("empty ring: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(c *ConsistentHash)
+		want    []string
+	}{
+		{"unsorted", func(c *ConsistentHash) {
+			c.hashSortedNodes[0], c.hashSortedNodes[1] = c.hashSortedNodes[1], c.hashSortedNodes[0]
+		}, []string{"not strictly increasing at 1: 200, 100"}},
+		{"duplicate", func(c *ConsistentHash) {
+			c.hashSortedNodes = append(c.hashSortedNodes, 300)
+		}, []string{"not strictly increasing at 3", "counts disagree: sorted 4, circle 3, nodes 3"}},
+		{"sorted point not in circle", func(c *ConsistentHash) {
+			c.hashSortedNodes = append(c.hashSortedNodes, 400)
+		}, []string{"sorted point 400 missing from circle", "sorted 4"}},
+		{"unknown owner", func(c *ConsistentHash) {
+			c.circle[200] = "ghost"
+		}, []string{`point 200 owned by unknown node "ghost"`, `point 200 of node "b" owned by "ghost"`}},
+		{"node point missing", func(c *ConsistentHash) {
+			delete(c.circle, 300)
+			c.hashSortedNodes = c.hashSortedNodes[:2]
+		}, []string{`point 300 of node "a" missing from circle`, `point 300 of node "a" missing from sorted points`, "nodes 3"}},
+		{"extra circle entry", func(c *ConsistentHash) {
+			c.circle[500] = "b"
+		}, []string{"circle 4"}},
+	}
+	for _, tt := range tests {
+		c := verifyTestRing(t)
+		tt.corrupt(c)
+		err := c.VerifyRing()
+		var verr *VerifyError
+		if !errors.As(err, &verr) || !errors.Is(err, ErrInconsistent) {
+			t.Fatalf("%s: VerifyRing() = %v", tt.name, err)
+		}
+		if len(verr.Violations) != len(tt.want) {
+			t.Errorf("%s: violations = %q", tt.name, verr.Violations)
+			continue
+		}
+		for i, w := range tt.want {
+			if !strings.Contains(verr.Violations[i], w) {
+				t.Errorf("%s: violation %d = %q, want %q", tt.name, i, verr.Violations[i], w)
+			}
+		}
+	}
+}
+
+func TestConsistentHash_VerifyRingAfterMutations(t *testing.T) {
+	c := NewConsistentHash()
+	if err := c.AddAll(batchTestNodes("node-", 10), 20); err != nil {
+		t.Fatal(err)
+	}
+	c.RemoveAll([]string{"node-1", "node-5"})
+	c.SetVirtualNodeCount("node-2", 5)
+	c.SetVirtualNodeCount("node-3", 50)
+	c.Sync(batchTestNodes("node-", 12), 10)
+	if err := c.VerifyRing(); err != nil {
+		t.Fatal(err)
+	}
+}
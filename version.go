@@ -0,0 +1,37 @@
+package consistent_hash
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Version 返回环的版本号，每次成功的添加、删除、修改虚拟结点数和上下线都会加 1
+func (c *ConsistentHash) Version() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.version
+}
+
+// Checksum 按 hash 顺序对所有 (虚拟结点, 结点 key) 计算 FNV-1a，与添加顺序无关，
+// 虚拟结点分布相同的两个环返回相同的结果。结点的上下线状态不影响 Checksum
+func (c *ConsistentHash) Checksum() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.checksumLocked()
+}
+
+// 调用方需持有锁
+func (c *ConsistentHash) checksumLocked() uint64 {
+	h := fnv.New64a()
+	var buf [4]byte
+	for _, p := range c.hashSortedNodes {
+		binary.BigEndian.PutUint32(buf[:], p)
+		h.Write(buf[:])
+		owner := c.circle[p]
+		// 写入长度避免 key 拼接产生歧义
+		binary.BigEndian.PutUint32(buf[:], uint32(len(owner)))
+		h.Write(buf[:])
+		h.Write([]byte(owner))
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,137 @@
+package consistent_hash
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConsistentHash_ChecksumOrderIndependent(t *testing.T) {
+	nodes := batchTestNodes("node-", 8)
+	a := NewConsistentHash()
+	for _, n := range nodes {
+		if err := a.AddWithVirtualNode(n, 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := NewConsistentHash()
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if err := b.AddWithVirtualNode(nodes[i], 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.Checksum() != b.Checksum() {
+		t.Fatal("checksum depends on insertion order")
+	}
+	if a.Version() != b.Version() {
+		t.Fatalf("versions = %d, %d", a.Version(), b.Version())
+	}
+}
+
+func TestConsistentHash_ChecksumChanges(t *testing.T) {
+	c := NewConsistentHash()
+	seen := map[uint64]string{c.Checksum(): "empty"}
+	check := func(step string) {
+		t.Helper()
+		sum := c.Checksum()
+		if prev, ok := seen[sum]; ok {
+			t.Fatalf("checksum after %s equals checksum after %s", step, prev)
+		}
+		seen[sum] = step
+	}
+
+	if err := c.AddWithVirtualNode(testNode{"a"}, 10); err != nil {
+		t.Fatal(err)
+	}
+	check("add a")
+	if err := c.AddWithVirtualNode(testNode{"b"}, 10); err != nil {
+		t.Fatal(err)
+	}
+	check("add b")
+	if err := c.SetVirtualNodeCount("b", 5); err != nil {
+		t.Fatal(err)
+	}
+	check("shrink b")
+	if _, err := c.RemoveByKey("a"); err != nil {
+		t.Fatal(err)
+	}
+	check("remove a")
+
+	// 同样的分布换一个 key 也会改变 Checksum
+	d := NewConsistentWithCustomHash(tableHash(map[string]uint32{"x#0#0": 1}))
+	e := NewConsistentWithCustomHash(tableHash(map[string]uint32{"y#0#0": 1}))
+	d.AddKey("x", 1)
+	e.AddKey("y", 1)
+	if d.Checksum() == e.Checksum() {
+		t.Fatal("checksum ignores the owner key")
+	}
+}
+
+func TestConsistentHash_Version(t *testing.T) {
+	c := NewConsistentHash()
+	steps := []struct {
+		name string
+		op   func() error
+	}{
+		{"add", func() error { return c.AddWithVirtualNode(testNode{"a"}, 10) }},
+		{"add b", func() error { return c.AddWithVirtualNode(testNode{"b"}, 10) }},
+		{"set count", func() error { return c.SetVirtualNodeCount("a", 20) }},
+		{"shrink", func() error { return c.ShrinkVirtualNodes("a", 5) }},
+		{"mark down", func() error { return c.MarkDown("a") }},
+		{"mark up", func() error { return c.MarkUp("a") }},
+		{"remove", func() error { _, err := c.RemoveByKey("a"); return err }},
+	}
+	last := c.Version()
+	for _, s := range steps {
+		if err := s.op(); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if v := c.Version(); v <= last {
+			t.Fatalf("%s: version %d, previous %d", s.name, v, last)
+		} else {
+			last = v
+		}
+	}
+
+	// 失败的操作不改变版本
+	c.RemoveByKey("a")
+	c.AddWithVirtualNode(testNode{"b"}, 10)
+	if c.Version() != last {
+		t.Fatalf("failed mutations changed version to %d", c.Version())
+	}
+	if _, v, err := c.GetNodeVersioned("k"); err != nil || v != last {
+		t.Fatalf("GetNodeVersioned = %d, %v", v, err)
+	}
+}
+
+func TestConsistentHash_VersionConcurrentWriters(t *testing.T) {
+	c := NewConsistentHash()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			node := testNode{"node-" + string(rune('a'+g))}
+			for i := 0; i < 100; i++ {
+				c.AddWithVirtualNode(node, 3)
+				c.Remove(node)
+			}
+		}(g)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var last uint64
+		for i := 0; i < 1000; i++ {
+			_, v, _ := c.GetNodeVersioned("k")
+			if v < last {
+				t.Errorf("version went backwards: %d after %d", v, last)
+				return
+			}
+			last = v
+		}
+	}()
+	wg.Wait()
+	if v := c.Version(); v != 800 {
+		t.Fatalf("version = %d, want 800", v)
+	}
+}
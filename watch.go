@@ -0,0 +1,99 @@
+package consistent_hash
+
+import "fmt"
+
+type EventType int
+
+const (
+	EventAdded EventType = iota + 1
+	EventRemoved
+	// 结点被同 key 的新结点替换，虚拟结点不变
+	EventReplaced
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "Added"
+	case EventRemoved:
+		return "Removed"
+	case EventReplaced:
+		return "Replaced"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event 是 Watch 推送的结点变化，Version 为变化之后环的版本
+type Event struct {
+	Type    EventType
+	NodeKey string
+	Version uint64
+}
+
+type watcher struct {
+	ch      chan Event
+	dropped uint64
+}
+
+// Watch 订阅环的结点变化，事件按提交顺序推送，返回的函数取消订阅并关闭 channel。
+// 推送不会阻塞写操作：channel 满时丢弃最旧的事件，丢弃的个数可以通过 Dropped 查询。
+// buffer 小于 1 时按 1 处理
+func (c *ConsistentHash) Watch(buffer int) (<-chan Event, func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	w := &watcher{ch: make(chan Event, buffer)}
+	n := &c.notifier
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.watchers == nil {
+		n.watchers = make(map[<-chan Event]*watcher)
+	}
+	n.watchers[w.ch] = w
+
+	var ch <-chan Event = w.ch
+	return ch, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if _, ok := n.watchers[ch]; ok {
+			delete(n.watchers, ch)
+			close(w.ch)
+		}
+	}
+}
+
+// Dropped 返回 Watch 订阅因为 channel 满而丢弃的事件数，ch 不是当前的订阅时返回 0
+func (c *ConsistentHash) Dropped(ch <-chan Event) uint64 {
+	c.notifier.mu.Lock()
+	defer c.notifier.mu.Unlock()
+	if w, ok := c.notifier.watchers[ch]; ok {
+		return w.dropped
+	}
+	return 0
+}
+
+// 推送给所有订阅，调用方需持有 n.mu
+func (n *notifier) publish(e ringEvent) {
+	if len(n.watchers) == 0 {
+		return
+	}
+	event := Event{Type: e.typ, NodeKey: e.node.Key(), Version: e.version}
+	for _, w := range n.watchers {
+		select {
+		case w.ch <- event:
+			continue
+		default:
+		}
+		// 只有这里发送，取出一个之后一定有空位
+		select {
+		case <-w.ch:
+			w.dropped++
+		default:
+		}
+		select {
+		case w.ch <- event:
+		default:
+			w.dropped++
+		}
+	}
+}
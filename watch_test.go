@@ -0,0 +1,101 @@
+package consistent_hash
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestConsistentHash_Watch(t *testing.T) {
+	c := NewConsistentHash()
+	first, cancelFirst := c.Watch(10)
+	second, cancelSecond := c.Watch(10)
+	defer cancelSecond()
+
+	if err := c.Add(testNode{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Batch().Remove("a").Add(testNode{"b"}, 1).Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// 失败的操作不产生事件
+	c.Add(testNode{"b"})
+
+	want := []Event{
+		{EventAdded, "a", 1},
+		{EventRemoved, "a", 2},
+		{EventAdded, "b", 3},
+	}
+	for _, ch := range []<-chan Event{first, second} {
+		var got []Event
+		for range want {
+			got = append(got, <-ch)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("events = %v, want %v", got, want)
+		}
+		select {
+		case e := <-ch:
+			t.Fatalf("unexpected event %v", e)
+		default:
+		}
+	}
+
+	cancelFirst()
+	cancelFirst()
+	if _, ok := <-first; ok {
+		t.Fatal("channel still open after unsubscribe")
+	}
+	if _, err := c.RemoveByKey("b"); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-second; e != (Event{EventRemoved, "b", 4}) {
+		t.Fatalf("event = %v", e)
+	}
+}
+
+func TestConsistentHash_WatchDropOldest(t *testing.T) {
+	c := NewConsistentHash()
+	ch, cancel := c.Watch(2)
+	defer cancel()
+	if err := c.AddAll(batchTestNodes("node-", 5), 1); err != nil {
+		t.Fatal(err)
+	}
+	// 只保留最新的两个事件
+	if e := <-ch; e.NodeKey != "node-3" || e.Version != 4 {
+		t.Fatalf("oldest kept event = %v", e)
+	}
+	if e := <-ch; e.NodeKey != "node-4" {
+		t.Fatalf("newest event = %v", e)
+	}
+	if n := c.Dropped(ch); n != 3 {
+		t.Fatalf("Dropped = %d, want 3", n)
+	}
+	if n := c.Dropped(make(chan Event)); n != 0 {
+		t.Fatalf("Dropped of an unknown channel = %d", n)
+	}
+}
+
+func TestConsistentHash_WatchUnsubscribeDuringDelivery(t *testing.T) {
+	c := NewConsistentHash()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				ch, cancel := c.Watch(1)
+				go func() {
+					for range ch {
+					}
+				}()
+				cancel()
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		c.Add(testNode{"x"})
+		c.RemoveByKey("x")
+	}
+	wg.Wait()
+}
@@ -0,0 +1,33 @@
+package consistent_hash
+
+import (
+	"fmt"
+	"math"
+)
+
+// WeightedNode 是自带权重的结点，Add、AddAll 和 Sync 按基础副本数乘以 Weight() 添加虚拟结点，
+// 实际的副本数可以通过 VirtualNodeCount 查询
+type WeightedNode interface {
+	Node
+	Weight() int
+}
+
+// 返回 node 的副本数，没有实现 WeightedNode 时为 base
+func (c *ConsistentHash) replicasFor(node Node, base int) (int, error) {
+	wn, ok := node.(WeightedNode)
+	if !ok {
+		return base, nil
+	}
+	w := wn.Weight()
+	if w <= 0 {
+		return 0, newNodeError(node.Key(), fmt.Errorf("%w, got %d", ErrInvalidWeight, w))
+	}
+	count := int64(w) * int64(base)
+	if count > math.MaxInt32 {
+		count = math.MaxInt32
+	}
+	if err := c.checkReplicas(int(count)); err != nil {
+		return 0, newNodeError(node.Key(), err)
+	}
+	return int(count), nil
+}
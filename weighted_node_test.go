@@ -0,0 +1,85 @@
+package consistent_hash
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type weightedNode struct {
+	key    string
+	weight int
+}
+
+func (n weightedNode) Key() string {
+	return n.key
+}
+
+func (n weightedNode) Weight() int {
+	return n.weight
+}
+
+func TestWeightedNode(t *testing.T) {
+	c, _ := New(WithDefaultReplicas(200))
+	if err := c.Add(weightedNode{"w3", 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(testNode{"plain"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddAll([]Node{weightedNode{"w2", 2}, testNode{"plain-2"}}, 200); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Sync(append(c.Members(), weightedNode{"w4", 4}), 200); err != nil {
+		t.Fatal(err)
+	}
+	// 显式指定副本数时不使用权重
+	if err := c.AddWithVirtualNode(weightedNode{"explicit", 9}, 200); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"w3": 600, "plain": 200, "w2": 400, "plain-2": 200, "w4": 800, "explicit": 200}
+	for k, n := range want {
+		if got, _ := c.VirtualNodeCount(k); got != n {
+			t.Errorf("VirtualNodeCount(%s) = %d, want %d", k, got, n)
+		}
+	}
+
+	counts := map[string]int{}
+	const keys = 60000
+	for i := 0; i < keys; i++ {
+		n, _ := c.GetNode("key-" + strconv.Itoa(i))
+		counts[n.Key()]++
+	}
+	total := 0
+	for _, n := range want {
+		total += n
+	}
+	for k, n := range want {
+		expected := float64(keys) * float64(n) / float64(total)
+		if got := float64(counts[k]); got < expected*0.6 || got > expected*1.4 {
+			t.Errorf("%s owns %v keys, want about %.0f", k, got, expected)
+		}
+	}
+
+	checksum := c.Checksum()
+	for _, w := range []int{0, -1} {
+		err := c.Add(weightedNode{"bad", w})
+		var nodeErr *NodeError
+		if !errors.Is(err, ErrInvalidWeight) || !errors.As(err, &nodeErr) || nodeErr.Key != "bad" {
+			t.Fatalf("Add with weight %d err = %v", w, err)
+		}
+		if err := c.AddAll([]Node{testNode{"ok"}, weightedNode{"bad", w}}, 10); !errors.Is(err, ErrInvalidWeight) {
+			t.Fatalf("AddAll with weight %d err = %v", w, err)
+		}
+		if _, _, err := c.Sync([]Node{weightedNode{"bad", w}}, 10); !errors.Is(err, ErrInvalidWeight) {
+			t.Fatalf("Sync with weight %d err = %v", w, err)
+		}
+	}
+	if err := c.Add(weightedNode{"huge", 1 << 40}); !errors.Is(err, ErrInvalidReplicas) {
+		t.Fatalf("Add with huge weight err = %v", err)
+	}
+	if c.Checksum() != checksum {
+		t.Fatal("rejected weights changed the ring")
+	}
+}
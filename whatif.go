@@ -0,0 +1,144 @@
+package consistent_hash
+
+import "sort"
+
+// ChangePreview 是 WhatIfAdd、WhatIfRemove 预测的修改结果
+type ChangePreview struct {
+	// 修改之后每个结点拥有的 hash 空间比例，与修改之后 Stats().Shares 相同
+	Shares map[string]float64
+	// 归属发生变化的 hash 区间和它们占 hash 空间的比例，与 RemapOnAdd、RemapOnRemove 的结果相同
+	Moves []RangeMove
+	Moved float64
+}
+
+// WhatIfAdd 预测添加 node 之后的分布，不修改环。
+// 与 RemapOnAdd 不同，只生成 node 的虚拟结点并与环上的虚拟结点合并，不复制整个环。
+// node 无法添加时返回零值
+func (c *ConsistentHash) WhatIfAdd(node Node, replicas int) ChangePreview {
+	if checkNode(node) != nil || c.checkReplicas(replicas) != nil {
+		return ChangePreview{}
+	}
+	c.RLock()
+	defer c.RUnlock()
+	key := node.Key()
+	if _, ok := c.nodes[key]; ok {
+		return ChangePreview{}
+	}
+
+	var added []uint32
+	owners := map[uint32]string{}
+	if c.sharesPoints() {
+		added = c.previewSharedPoints(key, replicas, owners)
+	} else {
+		if _, err := c.stageVirtualNodes(key, 0, replicas, owners); err != nil {
+			return ChangePreview{}
+		}
+		added = make([]uint32, 0, len(owners))
+		for k := range owners {
+			added = append(added, k)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i] < added[j] })
+	v := &ringView{base: c, points: mergePoints(c.hashSortedNodes, added), owners: owners}
+	return c.preview(v, key, "")
+}
+
+// 与 addSharedPoints 相同地放置 nodeKey 的虚拟结点，归属变化记录到 owners，返回新增的位置。调用方需持有锁
+func (c *ConsistentHash) previewSharedPoints(nodeKey string, replicas int, owners map[uint32]string) []uint32 {
+	var added, points []uint32
+	var buf []byte
+	for i := 0; i < replicas; i++ {
+		points, buf = c.replicaPoints(points[:0], buf, nodeKey, i)
+		for _, k := range points {
+			if _, ok := owners[k]; ok {
+				// 同一结点的两个副本冲突
+				continue
+			}
+			if _, ok := c.circle[k]; !ok {
+				added = append(added, k)
+				owners[k] = nodeKey
+			} else if c.collisionPolicy() == CollisionOverwrite {
+				owners[k] = nodeKey
+			}
+			// CollisionChain 下原结点仍是该虚拟结点的归属
+		}
+	}
+	return added
+}
+
+// WhatIfRemove 预测删除结点之后的分布，不修改环，见 WhatIfAdd。结点不存在时返回零值
+func (c *ConsistentHash) WhatIfRemove(nodeKey string) ChangePreview {
+	c.RLock()
+	defer c.RUnlock()
+	cNode, ok := c.nodes[nodeKey]
+	if !ok {
+		return ChangePreview{}
+	}
+
+	owners := map[uint32]string{}
+	freed := make(map[uint32]bool, len(cNode.virtualNodes))
+	for _, p := range cNode.virtualNodes {
+		chain, ok := c.chains[p]
+		if !ok {
+			freed[p] = true
+			continue
+		}
+		// 与 releasePoints 相同，由 chain 中的下一个结点接替
+		for _, owner := range chain {
+			if owner != nodeKey {
+				owners[p] = owner
+				break
+			}
+		}
+	}
+	points := make([]uint32, 0, len(c.hashSortedNodes)-len(freed))
+	for _, p := range c.hashSortedNodes {
+		if !freed[p] {
+			points = append(points, p)
+		}
+	}
+	return c.preview(&ringView{base: c, points: points, owners: owners}, "", nodeKey)
+}
+
+// 修改之后的环，points 为修改之后的全部虚拟结点，owners 记录归属变化的虚拟结点，其余的归属与 base 相同
+type ringView struct {
+	base   *ConsistentHash
+	points []uint32
+	owners map[uint32]string
+}
+
+func (v *ringView) ownerOf(hash uint32) string {
+	if len(v.points) == 0 {
+		return ""
+	}
+	i := searchPoints(v.points, hash)
+	if i == len(v.points) {
+		i = 0
+	}
+	if owner, ok := v.owners[v.points[i]]; ok {
+		return owner
+	}
+	return v.base.circle[v.points[i]]
+}
+
+// 按 Stats 和 remapRanges 的方式计算 v 的分布，added、removed 为增加、删除的结点，调用方需持有锁
+func (c *ConsistentHash) preview(v *ringView, added, removed string) ChangePreview {
+	preview := ChangePreview{Shares: make(map[string]float64, len(c.nodes)+1)}
+	for k := range c.nodes {
+		if k != removed {
+			preview.Shares[k] = 0
+		}
+	}
+	if added != "" {
+		preview.Shares[added] = 0
+	}
+	for i, p := range v.points {
+		owner, ok := v.owners[p]
+		if !ok {
+			owner = c.circle[p]
+		}
+		preview.Shares[owner] += pointWidthIn(v.points, c.reserved, i) / (1 << 32)
+	}
+	preview.Moves, preview.Moved = remapPoints(c.hashSortedNodes, v.points, c.ownerOf, v.ownerOf)
+	return preview
+}
@@ -0,0 +1,83 @@
+package consistent_hash
+
+import (
+	"hash/crc32"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash_WhatIf(t *testing.T) {
+	// hash 空间很小，虚拟结点经常冲突
+	small := WithHash(func(key string) uint32 { return crc32.ChecksumIEEE([]byte(key)) * 2654435761 >> 20 })
+	for name, opts := range map[string][]Option{
+		"default":   nil,
+		"probe":     {small},
+		"overwrite": {small, WithCollisionPolicy(CollisionOverwrite)},
+		"chain":     {small, WithCollisionPolicy(CollisionChain)},
+		"ketama":    {WithKetama()},
+	} {
+		c, err := New(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.ReserveRange(math.MaxUint32-1000, 100, "wrap"); err != nil {
+			t.Fatal(err)
+		}
+
+		check := func(op string, p ChangePreview, moves []RangeMove, moved float64) {
+			t.Helper()
+			if stats := c.Stats(); !reflect.DeepEqual(p.Shares, stats.Shares) {
+				t.Errorf("%s %s: Shares = %v, Stats = %v", name, op, p.Shares, stats.Shares)
+			}
+			if !reflect.DeepEqual(p.Moves, moves) || p.Moved != moved {
+				t.Errorf("%s %s: moves differ from Remap (%d vs %d ranges, %v vs %v)", name, op, len(p.Moves), len(moves), p.Moved, moved)
+			}
+		}
+		for i := 0; i < 6; i++ {
+			node := testNode{"node-" + strconv.Itoa(i)}
+			checksum := c.Checksum()
+			p := c.WhatIfAdd(node, 40)
+			moves, moved := c.RemapOnAdd(node, 40)
+			if c.Checksum() != checksum {
+				t.Fatalf("%s: WhatIfAdd changed the ring", name)
+			}
+			if err := c.AddWithVirtualNode(node, 40); err != nil {
+				t.Fatal(err)
+			}
+			check("add "+node.key, p, moves, moved)
+		}
+		for _, k := range []string{"node-2", "node-0", "node-5"} {
+			p := c.WhatIfRemove(k)
+			moves, moved := c.RemapOnRemove(k)
+			if _, err := c.RemoveByKey(k); err != nil {
+				t.Fatal(err)
+			}
+			check("remove "+k, p, moves, moved)
+		}
+
+		if p := c.WhatIfAdd(testNode{"node-1"}, 40); p.Shares != nil {
+			t.Errorf("%s: WhatIfAdd of an existing node = %v", name, p)
+		}
+		if p := c.WhatIfRemove("node-0"); p.Shares != nil {
+			t.Errorf("%s: WhatIfRemove of a missing node = %v", name, p)
+		}
+	}
+}
+
+func BenchmarkConsistentHash_WhatIfAdd(b *testing.B) {
+	c := NewConsistentHash()
+	c.AddAll(batchTestNodes("node-", 500), 200)
+	node := testNode{"new"}
+	b.Run("WhatIfAdd", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.WhatIfAdd(node, 200)
+		}
+	})
+	b.Run("RemapOnAdd", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.RemapOnAdd(node, 200)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package consistent_hash
+
+import "math/bits"
+
+// xxHash32 (https://github.com/Cyan4973/xxHash)，比 crc32 分布更均匀，
+// 对 "node-1"、"node-2" 这类相近的短 key 也能打散
+
+const (
+	xxPrime1 uint32 = 2654435761
+	xxPrime2 uint32 = 2246822519
+	xxPrime3 uint32 = 3266489917
+	xxPrime4 uint32 = 668265263
+	xxPrime5 uint32 = 374761393
+)
+
+func NewConsistentHashXX() *ConsistentHash {
+	c := NewConsistentWithCustomHash(xxhash32)
+	c.hashName = hashXXHash32
+	return c
+}
+
+func xxhash32(key string) uint32 {
+	return xxhash32Seed(key, 0)
+}
+
+func xxhash32Seed(key string, seed uint32) uint32 {
+	n := len(key)
+	var h uint32
+	if n >= 16 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for len(key) >= 16 {
+			v1 = xxRound(v1, xxRead32(key))
+			v2 = xxRound(v2, xxRead32(key[4:]))
+			v3 = xxRound(v3, xxRead32(key[8:]))
+			v4 = xxRound(v4, xxRead32(key[12:]))
+			key = key[16:]
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) + bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxPrime5
+	}
+
+	h += uint32(n)
+	for ; len(key) >= 4; key = key[4:] {
+		h += xxRead32(key) * xxPrime3
+		h = bits.RotateLeft32(h, 17) * xxPrime4
+	}
+	for i := 0; i < len(key); i++ {
+		h += uint32(key[i]) * xxPrime5
+		h = bits.RotateLeft32(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 15
+	h *= xxPrime2
+	h ^= h >> 13
+	h *= xxPrime3
+	h ^= h >> 16
+	return h
+}
+
+func xxRound(acc, input uint32) uint32 {
+	acc += input * xxPrime2
+	return bits.RotateLeft32(acc, 13) * xxPrime1
+}
+
+func xxRead32(s string) uint32 {
+	return uint32(s[0]) | uint32(s[1])<<8 | uint32(s[2])<<16 | uint32(s[3])<<24
+}
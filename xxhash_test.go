@@ -0,0 +1,99 @@
+package consistent_hash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestXXHash32(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint32
+	}{
+		{"", 0x02CC5D05},
+		{"a", 0x550D7456},
+		{"abc", 0x32D153FF},
+		{"Nobody inspects the spammish repetition", 0xE2293B2F},
+	}
+	for _, tt := range tests {
+		if got := xxhash32(tt.in); got != tt.want {
+			t.Errorf("xxhash32(%q) = %#08x, want %#08x", tt.in, got, tt.want)
+		}
+	}
+}
+
+// 根据虚拟结点位置计算每个结点占据的 hash 空间比例
+func ownershipShares(c *ConsistentHash) map[string]float64 {
+	shares := map[string]float64{}
+	points := c.hashSortedNodes
+	for i, p := range points {
+		var width uint32
+		if i == 0 {
+			// 最小的虚拟结点拥有环顶部回绕过来的区间
+			width = p - points[len(points)-1]
+		} else {
+			width = p - points[i-1]
+		}
+		shares[c.circle[p]] += float64(width) / (1 << 32)
+	}
+	return shares
+}
+
+func shareStddev(shares map[string]float64) float64 {
+	mean := 1 / float64(len(shares))
+	var sum float64
+	for _, s := range shares {
+		sum += (s - mean) * (s - mean)
+	}
+	return math.Sqrt(sum / float64(len(shares)))
+}
+
+func TestConsistentHashXX_Distribution(t *testing.T) {
+	// 单个环的结果受结点名影响较大，取 20 组结点名的平均值
+	var crcStddev, xxStddev float64
+	for p := 0; p < 20; p++ {
+		crc := NewConsistentHash()
+		xx := NewConsistentHashXX()
+		for i := 1; i <= 10; i++ {
+			key := "node" + strconv.Itoa(p) + "-" + strconv.Itoa(i)
+			if err := crc.AddWithVirtualNode(testNode{key}, 50); err != nil {
+				t.Fatal(err)
+			}
+			if err := xx.AddWithVirtualNode(testNode{key}, 50); err != nil {
+				t.Fatal(err)
+			}
+		}
+		crcStddev += shareStddev(ownershipShares(crc)) / 20
+		xxStddev += shareStddev(ownershipShares(xx)) / 20
+	}
+
+	t.Logf("ownership stddev: crc32 %.4f, xxhash32 %.4f", crcStddev, xxStddev)
+	if xxStddev >= crcStddev {
+		t.Errorf("xxhash32 stddev %.4f isn't lower than crc32 %.4f", xxStddev, crcStddev)
+	}
+}
+
+func benchmarkGetNode(b *testing.B, c *ConsistentHash) {
+	for i := 0; i < 10; i++ {
+		if err := c.AddWithVirtualNode(testNode{"node-" + strconv.Itoa(i)}, 50); err != nil {
+			b.Fatal(err)
+		}
+	}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetNode(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkConsistentHash_GetNodeCRC32(b *testing.B) {
+	benchmarkGetNode(b, NewConsistentHash())
+}
+
+func BenchmarkConsistentHash_GetNodeXXHash(b *testing.B) {
+	benchmarkGetNode(b, NewConsistentHashXX())
+}
@@ -0,0 +1,56 @@
+package consistent_hash
+
+import "fmt"
+
+// ZonedNode 是带可用区的结点，GetNSpread 会尽量把副本分散到不同的可用区
+type ZonedNode interface {
+	Node
+	Zone() string
+}
+
+// 没有实现 ZonedNode 的结点可用区为空
+func nodeZone(n Node) string {
+	if z, ok := n.(ZonedNode); ok {
+		return z.Zone()
+	}
+	return ""
+}
+
+// GetNSpread 与 GetN 一样顺时针返回 n 个不同的在线结点，但优先选择可用区尚未出现过的结点，
+// 只有可用区不够时才按环上的顺序补充已出现可用区的结点。结果中先是分散到不同可用区的结点，
+// 再是补充的结点，两部分各自保持环上的顺序。在线结点不足时的行为与 GetN 相同
+func (c *ConsistentHash) GetNSpread(key string, n int) ([]Node, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%w %d, can't less 1", ErrInvalidCount, n)
+	}
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.nodes) == 0 {
+		return nil, ErrEmptyRing
+	}
+	candidates, err := c.walkKey(key, len(c.nodes))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkReplicaCount(len(candidates), n); err != nil {
+		return nil, err
+	}
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+
+	zones := make(map[string]struct{})
+	spread := make([]Node, 0, n)
+	var rest []Node
+	for _, node := range candidates {
+		zone := nodeZone(node)
+		if _, ok := zones[zone]; ok || len(spread) == n {
+			rest = append(rest, node)
+			continue
+		}
+		zones[zone] = struct{}{}
+		spread = append(spread, node)
+	}
+	return append(spread, rest[:n-len(spread)]...), nil
+}
@@ -0,0 +1,90 @@
+package consistent_hash
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type zonedNode struct {
+	key, zone string
+}
+
+func (n zonedNode) Key() string  { return n.key }
+func (n zonedNode) Zone() string { return n.zone }
+
+func zonedRing(t *testing.T, zones ...string) *ConsistentHash {
+	c := NewConsistentHashXX()
+	for i := 0; i < 12; i++ {
+		n := zonedNode{"node-" + strconv.Itoa(i), zones[i%len(zones)]}
+		if err := c.AddWithVirtualNode(n, 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestConsistentHash_GetNSpread(t *testing.T) {
+	c := zonedRing(t, "a", "b", "c")
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		nodes, err := c.GetNSpread(key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zones := make(map[string]bool)
+		for _, n := range nodes {
+			zones[nodeZone(n)] = true
+		}
+		if len(nodes) != 3 || len(zones) != 3 {
+			t.Fatalf("GetNSpread(%s) = %v", key, nodes)
+		}
+		// 第一个结点总是 key 的所属结点
+		if owner, _ := c.GetNode(key); nodes[0] != owner {
+			t.Fatalf("GetNSpread(%s)[0] = %s, want %s", key, nodes[0].Key(), owner.Key())
+		}
+
+		// 可用区不够时先分散再补充，补充部分保持环上的顺序
+		nodes, _ = c.GetNSpread(key, 5)
+		zones = make(map[string]bool)
+		for _, n := range nodes[:3] {
+			zones[nodeZone(n)] = true
+		}
+		if len(nodes) != 5 || len(zones) != 3 {
+			t.Fatalf("GetNSpread(%s, 5) = %v", key, nodes)
+		}
+		order := make(map[string]int)
+		all, _ := c.GetN(key, 12)
+		for j, n := range all {
+			order[n.Key()] = j
+		}
+		if order[nodes[3].Key()] > order[nodes[4].Key()] {
+			t.Fatalf("GetNSpread(%s, 5) fills out of ring order: %v", key, nodes)
+		}
+	}
+}
+
+func TestConsistentHash_GetNSpreadSingleZone(t *testing.T) {
+	// 只有一个可用区以及没有实现 ZonedNode 的结点退化为 GetN
+	for _, c := range []*ConsistentHash{zonedRing(t, "a"), zonedRing(t, "")} {
+		for i := 0; i < 100; i++ {
+			key := "key-" + strconv.Itoa(i)
+			got, _ := c.GetNSpread(key, 3)
+			want, _ := c.GetN(key, 3)
+			if !equalStrings(nodeKeys(got), nodeKeys(want)) {
+				t.Fatalf("GetNSpread(%s) = %v, want %v", key, nodeKeys(got), nodeKeys(want))
+			}
+		}
+	}
+
+	plain := NewConsistentHash()
+	if err := plain.AddAll(batchTestNodes("node-", 3), 10); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := plain.GetNSpread("key", 10); !errors.Is(err, ErrInsufficientNodes) {
+		t.Fatalf("GetNSpread with n > nodes = %v, %v", nodeKeys(got), err)
+	}
+	if _, err := plain.GetNSpread("key", 0); !errors.Is(err, ErrInvalidCount) {
+		t.Fatalf("GetNSpread(0) err = %v", err)
+	}
+}